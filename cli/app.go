@@ -130,6 +130,7 @@ type App struct {
 	password                      string
 	configPath                    string
 	traceStorage                  bool
+	storageFaultProfile           string
 	keyRingEnabled                bool
 	persistCredentials            bool
 	disableRepositoryLog          bool
@@ -273,6 +274,7 @@ func (c *App) setup(app *kingpin.Application) {
 	app.Flag("update-available-notify-interval", "Interval between update notifications").Default("1h").Hidden().Envar(c.EnvName("KOPIA_UPDATE_NOTIFY_INTERVAL")).DurationVar(&c.updateAvailableNotifyInterval)
 	app.Flag("config-file", "Specify the config file to use").Default("repository.config").Envar(c.EnvName("KOPIA_CONFIG_PATH")).StringVar(&c.configPath)
 	app.Flag("trace-storage", "Enables tracing of storage operations.").Default("true").Hidden().BoolVar(&c.traceStorage)
+	app.Flag("storage-fault-profile", "Inject storage latency and failures described in the provided JSON file, for disaster recovery drills. Maintenance and garbage collection are disabled while faults are injected.").Envar(c.EnvName("KOPIA_STORAGE_FAULT_PROFILE")).StringVar(&c.storageFaultProfile)
 	app.Flag("timezone", "Format time according to specified time zone (local, utc, original or time zone name)").Hidden().StringVar(&timeZone)
 	app.Flag("password", "Repository password.").Envar(c.EnvName("KOPIA_PASSWORD")).Short('p').StringVar(&c.password)
 	app.Flag("persist-credentials", "Persist credentials").Default("true").Envar(c.EnvName("KOPIA_PERSIST_CREDENTIALS_ON_CONNECT")).BoolVar(&c.persistCredentials)
@@ -602,7 +604,12 @@ func (c *App) maybeRunMaintenance(ctx context.Context, rep repo.Repository) erro
 		Purpose:  "maybeRunMaintenance",
 		OnUpload: c.progress.UploadedBytes,
 	}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
-		return snapshotmaintenance.Run(ctx, w, maintenance.ModeAuto, false, maintenance.SafetyFull)
+		report, err := snapshotmaintenance.RunWithReport(ctx, w, maintenance.ModeAuto, false, maintenance.SafetyFull)
+		if report != nil {
+			notification.Send(ctx, w, "maintenance-report", report, snapshotmaintenance.ReportSeverity(report), c.notificationTemplateOptions())
+		}
+
+		return err //nolint:wrapcheck
 	})
 
 	var noe maintenance.NotOwnedError
@@ -1,12 +1,14 @@
 package cli
 
 type commandBlob struct {
-	delete commandBlobDelete
-	gc     commandBlobGC
-	list   commandBlobList
-	shards commandBlobShards
-	show   commandBlobShow
-	stats  commandBlobStats
+	delete          commandBlobDelete
+	gc              commandBlobGC
+	list            commandBlobList
+	shards          commandBlobShards
+	show            commandBlobShow
+	stats           commandBlobStats
+	verify          commandBlobVerify
+	retentionHelper commandBlobFilesystemRetentionHelper
 }
 
 func (c *commandBlob) setup(svc appServices, parent commandParent) {
@@ -18,4 +20,6 @@ func (c *commandBlob) setup(svc appServices, parent commandParent) {
 	c.shards.setup(svc, cmd)
 	c.show.setup(svc, cmd)
 	c.stats.setup(svc, cmd)
+	c.verify.setup(svc, cmd)
+	c.retentionHelper.setup(svc, cmd)
 }
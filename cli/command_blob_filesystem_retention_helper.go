@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/kopia/kopia/repo/blob/filesystem"
+)
+
+type commandBlobFilesystemRetentionHelper struct {
+	root string
+	args []string
+}
+
+func (c *commandBlobFilesystemRetentionHelper) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("filesystem-retention-helper", "Apply or remove retention of a filesystem blob by changing its immutable attribute, requires privileges to do so.").Hidden()
+	cmd.Flag("root", "Repository root directory, only blob files under it are accepted").Required().StringVar(&c.root)
+	cmd.Arg("args", "'lock <file> <mode> <retain-until>' or 'unlock <file>'").Required().StringsVar(&c.args)
+	cmd.Action(svc.noRepositoryAction(c.run))
+}
+
+func (c *commandBlobFilesystemRetentionHelper) run(ctx context.Context) error {
+	//nolint:wrapcheck
+	return filesystem.RunRetentionHelper(ctx, c.root, c.args)
+}
@@ -8,6 +8,7 @@
 	"github.com/kopia/kopia/internal/repodiag"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/content/indexblob"
 )
 
@@ -70,6 +71,10 @@ func (c *commandBlobList) shouldInclude(b blob.Metadata) bool {
 			return false
 		}
 
+		if strings.HasPrefix(string(b.BlobID), string(content.BlobIDPrefixPackChecksums)) {
+			return false
+		}
+
 		if strings.HasPrefix(string(b.BlobID), "kopia.") {
 			return false
 		}
@@ -83,7 +83,7 @@ func (c *commandBlobShow) maybeDecryptBlob(ctx context.Context, w io.Writer, rep
 
 func canDecryptBlob(b blob.ID) bool {
 	switch b[0] {
-	case '_', 'n', 'm', 'l':
+	case '_', 'n', 'm', 'l', 'c':
 		return true
 	default:
 		return false
@@ -92,7 +92,7 @@ func canDecryptBlob(b blob.ID) bool {
 
 func isJSONBlob(b blob.ID) bool {
 	switch b[0] {
-	case 'm', 'l':
+	case 'm', 'l', 'c':
 		return true
 	default:
 		return false
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/timetrack"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+)
+
+type commandBlobVerify struct {
+	parallel         int
+	remoteChecksum   bool
+	progressInterval time.Duration
+
+	out textOutput
+}
+
+func (c *commandBlobVerify) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("verify", "Verify pack blobs against checksums recorded when they were written")
+	cmd.Flag("parallel", "Parallelism").Default("16").IntVar(&c.parallel)
+	cmd.Flag("remote-checksum", "Compare against checksums reported by the storage provider instead of downloading blobs").BoolVar(&c.remoteChecksum)
+	cmd.Flag("progress-interval", "Progress output interval").Default("3s").DurationVar(&c.progressInterval)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+
+	c.out.setup(svc)
+}
+
+type blobVerifyStats struct {
+	verified   atomic.Int32
+	unverified atomic.Int32
+	errors     atomic.Int32
+}
+
+func (c *commandBlobVerify) run(ctx context.Context, rep repo.DirectRepository) error {
+	expected, err := rep.ContentReader().ReadPackChecksums(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to read pack checksums")
+	}
+
+	if len(expected) == 0 {
+		return errors.New("no pack checksums found in the repository, enable them with 'kopia repository set-parameters --pack-checksums=true'")
+	}
+
+	var (
+		stats    blobVerifyStats
+		throttle timetrack.Throttle
+		mu       sync.Mutex
+	)
+
+	est := timetrack.Start()
+
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(c.parallel, 1))
+
+	for _, prefix := range content.PackBlobIDPrefixes {
+		if err := rep.BlobReader().ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			want, ok := expected[bm.BlobID]
+			if !ok {
+				stats.unverified.Add(1)
+				return nil
+			}
+
+			eg.Go(func() error {
+				if err := c.verifyBlob(ctx, rep.BlobReader(), bm, want); err != nil {
+					log(ctx).Errorf("%v", err)
+					stats.errors.Add(1)
+				} else {
+					stats.verified.Add(1)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if throttle.ShouldOutput(c.progressInterval) {
+					done := stats.verified.Load() + stats.errors.Load()
+
+					if timings, ok := est.Estimate(float64(done), float64(len(expected))); ok {
+						log(ctx).Infof("  Verified %v of %v blobs (%.1f%%), %v errors, remaining %v, ETA %v",
+							done, len(expected), timings.PercentComplete, stats.errors.Load(), timings.Remaining, formatTimestamp(timings.EstimatedEndTime))
+					}
+				}
+
+				return nil
+			})
+
+			return nil
+		}); err != nil {
+			return errors.Wrapf(err, "error listing blobs with prefix %q", prefix)
+		}
+	}
+
+	if err := eg.Wait(); err != nil {
+		return errors.Wrap(err, "error verifying blobs")
+	}
+
+	c.out.printStdout("Verified %v blobs, %v errors, %v blobs without recorded checksums.\n",
+		stats.verified.Load(), stats.errors.Load(), stats.unverified.Load())
+
+	if n := stats.errors.Load(); n > 0 {
+		return errors.Errorf("encountered %v errors", n)
+	}
+
+	return nil
+}
+
+func (c *commandBlobVerify) verifyBlob(ctx context.Context, br blob.Reader, bm blob.Metadata, want []blob.Checksum) error {
+	actual, err := c.actualChecksums(ctx, br, bm, want)
+	if err != nil {
+		return err
+	}
+
+	compared, mismatches := blob.CompareChecksums(want, actual)
+	if len(mismatches) > 0 {
+		return errors.Errorf("checksum mismatch on blob %v: %v", bm.BlobID, mismatches)
+	}
+
+	if compared == 0 {
+		return errors.Errorf("storage provider did not report a usable checksum for blob %v", bm.BlobID)
+	}
+
+	return nil
+}
+
+func (c *commandBlobVerify) actualChecksums(ctx context.Context, br blob.Reader, bm blob.Metadata, want []blob.Checksum) ([]blob.Checksum, error) {
+	if !c.remoteChecksum {
+		var tmp gather.WriteBuffer
+		defer tmp.Close()
+
+		if err := br.GetBlob(ctx, bm.BlobID, 0, -1, &tmp); err != nil {
+			return nil, errors.Wrapf(err, "error reading blob %v", bm.BlobID)
+		}
+
+		var algorithms []blob.ChecksumAlgorithm
+		for _, w := range want {
+			algorithms = append(algorithms, w.Algorithm)
+		}
+
+		//nolint:wrapcheck
+		return blob.ComputeChecksums(tmp.Bytes(), algorithms...)
+	}
+
+	// some providers report checksums when listing, otherwise fetch metadata for each blob.
+	if len(bm.Checksums) > 0 {
+		return bm.Checksums, nil
+	}
+
+	md, err := br.GetMetadata(ctx, bm.BlobID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting metadata of blob %v", bm.BlobID)
+	}
+
+	return md.Checksums, nil
+}
@@ -0,0 +1,37 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func (s *formatSpecificTestSuite) TestBlobVerify(t *testing.T) {
+	env := testenv.NewCLITest(t, s.formatFlags, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// no checksums recorded yet.
+	env.RunAndExpectFailure(t, "blob", "verify")
+
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--pack-checksums=true")
+	require.Contains(t, env.RunAndExpectSuccess(t, "repository", "status"), "Pack checksums:      true")
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	env.RunAndExpectSuccess(t, "blob", "verify")
+
+	// filesystem storage does not report checksums.
+	env.RunAndExpectFailure(t, "blob", "verify", "--remote-checksum")
+
+	env.TweakFile(t, env.RepoDir, "p*/*/*.f")
+	env.RunAndExpectFailure(t, "blob", "verify")
+}
@@ -3,23 +3,30 @@
 import (
 	"context"
 
+	"github.com/kopia/kopia/notification"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 )
 
 type commandMaintenanceRun struct {
-	maintenanceRunFull  bool
-	maintenanceRunForce bool
-	safety              maintenance.SafetyParameters
+	maintenanceRunFull    bool
+	maintenanceRunForce   bool
+	sendMaintenanceReport bool
+	safety                maintenance.SafetyParameters
+
+	svc appServices
 }
 
 func (c *commandMaintenanceRun) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("run", "Run repository maintenance")
 	cmd.Flag("full", "Full maintenance").BoolVar(&c.maintenanceRunFull)
 	cmd.Flag("force", "Run maintenance even if not owned (unsafe)").Hidden().BoolVar(&c.maintenanceRunForce)
+	cmd.Flag("send-maintenance-report", "Send a maintenance report notification using configured notification profiles").Default("true").BoolVar(&c.sendMaintenanceReport)
 	safetyFlagVar(cmd, &c.safety)
 
+	c.svc = svc
+
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
 }
 
@@ -30,6 +37,12 @@ func (c *commandMaintenanceRun) run(ctx context.Context, rep repo.DirectReposito
 		mode = maintenance.ModeFull
 	}
 
+	report, err := snapshotmaintenance.RunWithReport(ctx, rep, mode, c.maintenanceRunForce, c.safety)
+
+	if report != nil && c.sendMaintenanceReport {
+		notification.Send(ctx, rep, "maintenance-report", report, snapshotmaintenance.ReportSeverity(report), c.svc.notificationTemplateOptions())
+	}
+
 	//nolint:wrapcheck
-	return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety)
+	return err
 }
@@ -0,0 +1,33 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestMaintenanceRunReport(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=mysender", "--min-severity=verbose")
+
+	n := len(e.NotificationsSent())
+
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full")
+	require.Len(t, e.NotificationsSent(), n+1)
+	require.Contains(t, e.NotificationsSent()[n].Subject, "Successfully ran full maintenance")
+	require.Contains(t, e.NotificationsSent()[n].Body, "<td>snapshot-gc</td>")
+
+	e.RunAndExpectSuccess(t, "maintenance", "run")
+	require.Len(t, e.NotificationsSent(), n+2)
+	require.Contains(t, e.NotificationsSent()[n+1].Subject, "Successfully ran quick maintenance")
+
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--no-send-maintenance-report")
+	require.Len(t, e.NotificationsSent(), n+2)
+}
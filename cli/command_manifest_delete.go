@@ -2,10 +2,14 @@
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot/legalhold"
 )
 
 type commandManifestDelete struct {
@@ -26,6 +30,20 @@ func (c *commandManifestDelete) run(ctx context.Context, rep repo.RepositoryWrit
 	c.svc.dangerousCommand()
 
 	for _, it := range toManifestIDs(c.manifestRemoveItems) {
+		var data json.RawMessage
+
+		em, err := rep.GetManifest(ctx, it, &data)
+		if err != nil && !errors.Is(err, manifest.ErrNotFound) {
+			return errors.Wrapf(err, "unable to get manifest %v", it)
+		}
+
+		// legal holds and held snapshots can't be removed by bypassing 'snapshot hold release'.
+		if em != nil {
+			if err := legalhold.CheckDeleteManifest(ctx, rep, clock.Now(), em); err != nil {
+				return errors.Wrapf(err, "unable to delete manifest %v", it)
+			}
+		}
+
 		if err := rep.DeleteManifest(ctx, it); err != nil {
 			return errors.Wrapf(err, "unable to delete manifest %v", it)
 		}
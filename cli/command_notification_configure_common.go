@@ -2,8 +2,11 @@
 
 import (
 	"context"
+	"fmt"
 	"maps"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
@@ -19,12 +22,179 @@ type commonNotificationOptions struct {
 	notificationProfileFlag
 	sendTestNotification bool
 	minSeverity          string
+
+	routes      []string
+	clearRoutes bool
+
+	dedupInterval     time.Duration
+	rateLimit         int
+	rateLimitInterval time.Duration
+	clearRateLimit    bool
+
+	digestSchedule []string
+	clearDigest    bool
 }
 
+const defaultNotificationRateLimitInterval = time.Hour
+
 func (c *commonNotificationOptions) setup(svc appServices, cmd *kingpin.CmdClause) {
 	c.notificationProfileFlag.setup(svc, cmd)
 	cmd.Flag("send-test-notification", "Test the notification").BoolVar(&c.sendTestNotification)
 	cmd.Flag("min-severity", "Minimum severity").EnumVar(&c.minSeverity, mapKeys(notification.SeverityToNumber)...)
+	cmd.Flag("route", "Only deliver notifications matching the routing rule (event=<template>,host=<pattern>,user=<pattern>,path=<pattern>,min-severity=<severity>), can be repeated").StringsVar(&c.routes)
+	cmd.Flag("clear-routes", "Remove all routing rules").BoolVar(&c.clearRoutes)
+	cmd.Flag("dedup-interval", "Suppress identical notifications delivered within the interval").DurationVar(&c.dedupInterval)
+	cmd.Flag("rate-limit", "Maximum number of notifications delivered within the rate limit interval").IntVar(&c.rateLimit)
+	cmd.Flag("rate-limit-interval", "Rate limit interval").DurationVar(&c.rateLimitInterval)
+	cmd.Flag("clear-rate-limit", "Remove deduplication and rate limits").BoolVar(&c.clearRateLimit)
+	cmd.Flag("digest-schedule", "Deliver snapshot and maintenance reports as a single digest on the cron schedule, can be repeated. Digests are only delivered by the server and reports not yet delivered are lost when it restarts").StringsVar(&c.digestSchedule)
+	cmd.Flag("clear-digest", "Deliver snapshot and maintenance reports immediately").BoolVar(&c.clearDigest)
+}
+
+// applyRouting updates the routing rules, digest schedule and rate limits of the profile based on the flags.
+func (c *commonNotificationOptions) applyRouting(pc *notifyprofile.Config) error {
+	if c.clearRoutes {
+		pc.Rules = nil
+	}
+
+	if len(c.routes) > 0 {
+		pc.Rules = nil
+
+		for _, r := range c.routes {
+			rule, err := parseNotificationRule(r)
+			if err != nil {
+				return errors.Wrapf(err, "invalid --route %q", r)
+			}
+
+			pc.Rules = append(pc.Rules, rule)
+		}
+	}
+
+	if c.clearDigest {
+		pc.Digest = nil
+	}
+
+	if len(c.digestSchedule) > 0 {
+		d := &notifyprofile.Digest{Cron: c.digestSchedule}
+		if err := d.Validate(); err != nil {
+			return errors.Wrap(err, "invalid --digest-schedule")
+		}
+
+		pc.Digest = d
+	}
+
+	if c.clearRateLimit {
+		pc.RateLimit = nil
+	}
+
+	if c.dedupInterval == 0 && c.rateLimit == 0 && c.rateLimitInterval == 0 {
+		return nil
+	}
+
+	rl := pc.RateLimit
+	if rl == nil {
+		rl = &notifyprofile.RateLimit{}
+	}
+
+	if c.dedupInterval != 0 {
+		rl.DedupInterval = c.dedupInterval
+	}
+
+	if c.rateLimit != 0 {
+		rl.MaxNotifications = c.rateLimit
+	}
+
+	if c.rateLimitInterval != 0 {
+		rl.Interval = c.rateLimitInterval
+	}
+
+	if rl.MaxNotifications > 0 && rl.Interval == 0 {
+		rl.Interval = defaultNotificationRateLimitInterval
+	}
+
+	if rl.DedupInterval < 0 || rl.MaxNotifications < 0 || rl.Interval < 0 {
+		return errors.New("rate limits cannot be negative")
+	}
+
+	pc.RateLimit = rl
+
+	return nil
+}
+
+// parseNotificationRule parses a routing rule in the form key=value[,key=value...].
+func parseNotificationRule(s string) (notifyprofile.Rule, error) {
+	var r notifyprofile.Rule
+
+	for _, part := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || value == "" {
+			return r, errors.Errorf("invalid condition %q, must be key=value", part)
+		}
+
+		switch key {
+		case "event":
+			r.EventTypes = append(r.EventTypes, value)
+		case "host":
+			r.Hosts = append(r.Hosts, value)
+		case "user":
+			r.Users = append(r.Users, value)
+		case "path":
+			r.Paths = append(r.Paths, value)
+		case "min-severity":
+			sev, ok := notification.SeverityToNumber[value]
+			if !ok {
+				return r, errors.Errorf("invalid severity %q", value)
+			}
+
+			r.MinSeverity = &sev
+		default:
+			return r, errors.Errorf("unsupported condition %q", key)
+		}
+	}
+
+	return r, r.Validate()
+}
+
+// formatNotificationRule returns the string representation of a routing rule as accepted by --route.
+func formatNotificationRule(r notifyprofile.Rule) string {
+	var parts []string
+
+	for _, v := range r.EventTypes {
+		parts = append(parts, "event="+v)
+	}
+
+	for _, v := range r.Hosts {
+		parts = append(parts, "host="+v)
+	}
+
+	for _, v := range r.Users {
+		parts = append(parts, "user="+v)
+	}
+
+	for _, v := range r.Paths {
+		parts = append(parts, "path="+v)
+	}
+
+	if r.MinSeverity != nil {
+		parts = append(parts, "min-severity="+notification.SeverityToString[*r.MinSeverity])
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// formatNotificationRateLimit returns human-readable description of the rate limit.
+func formatNotificationRateLimit(rl *notifyprofile.RateLimit) string {
+	var parts []string
+
+	if rl.DedupInterval > 0 {
+		parts = append(parts, fmt.Sprintf("suppress duplicates within %v", rl.DedupInterval))
+	}
+
+	if rl.MaxNotifications > 0 && rl.Interval > 0 {
+		parts = append(parts, fmt.Sprintf("at most %v notifications per %v", rl.MaxNotifications, rl.Interval))
+	}
+
+	return strings.Join(parts, ", ")
 }
 
 // configureNotificationAction is a helper function that creates a Kingpin action that
@@ -53,6 +223,10 @@ func configureNotificationAction[T any](
 		sev := notification.SeverityDefault
 		exists := err == nil
 
+		newProfile := notifyprofile.Config{
+			ProfileName: c.profileName,
+		}
+
 		if exists {
 			if oldProfile.MethodConfig.Type != senderMethod {
 				return errors.Errorf("profile %q already exists but is not of type %q", c.profileName, senderMethod)
@@ -66,6 +240,9 @@ func configureNotificationAction[T any](
 
 			mergedOptions = &parsedT
 			sev = oldProfile.MinSeverity
+			newProfile.Rules = oldProfile.Rules
+			newProfile.RateLimit = oldProfile.RateLimit
+			newProfile.Digest = oldProfile.Digest
 		} else {
 			mergedOptions = &defaultT
 		}
@@ -79,6 +256,10 @@ func configureNotificationAction[T any](
 			sev = notification.SeverityToNumber[c.minSeverity]
 		}
 
+		if err := c.applyRouting(&newProfile); err != nil {
+			return err
+		}
+
 		s, err := sender.GetSender(ctx, c.profileName, senderMethod, mergedOptions)
 		if err != nil {
 			return errors.Wrap(err, "unable to get notification provider")
@@ -92,14 +273,13 @@ func configureNotificationAction[T any](
 
 		log(ctx).Infof("Saving notification profile %q of type %q with severity %q.", c.profileName, senderMethod, notification.SeverityToString[sev])
 
-		return notifyprofile.SaveProfile(ctx, rep, notifyprofile.Config{
-			ProfileName: c.profileName,
-			MethodConfig: sender.MethodConfig{
-				Type:   senderMethod,
-				Config: mergedOptions,
-			},
-			MinSeverity: sev,
-		})
+		newProfile.MethodConfig = sender.MethodConfig{
+			Type:   senderMethod,
+			Config: mergedOptions,
+		}
+		newProfile.MinSeverity = sev
+
+		return notifyprofile.SaveProfile(ctx, rep, newProfile)
 	})
 }
 
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/notification/sender/gotify"
+)
+
+type commandNotificationConfigureGotify struct {
+	common commonNotificationOptions
+
+	opt gotify.Options
+}
+
+func (c *commandNotificationConfigureGotify) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("gotify", "Gotify notification.")
+
+	c.common.setup(svc, cmd)
+
+	cmd.Flag("server-url", "Gotify server URL").StringVar(&c.opt.ServerURL)
+	cmd.Flag("app-token", "Gotify application token").Envar(svc.EnvName("KOPIA_GOTIFY_APP_TOKEN")).StringVar(&c.opt.AppToken)
+	cmd.Flag("click-url", "URL to open when the notification is clicked").StringVar(&c.opt.ClickURL)
+
+	cmd.Action(configureNotificationAction(svc, &c.common, gotify.ProviderType, &c.opt, gotify.MergeOptions))
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/notification/sender/matrix"
+)
+
+type commandNotificationConfigureMatrix struct {
+	common commonNotificationOptions
+
+	opt matrix.Options
+}
+
+func (c *commandNotificationConfigureMatrix) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("matrix", "Matrix notification.")
+
+	c.common.setup(svc, cmd)
+
+	cmd.Flag("homeserver-url", "Matrix homeserver URL").StringVar(&c.opt.HomeserverURL)
+	cmd.Flag("access-token", "Matrix access token").StringVar(&c.opt.AccessToken)
+	cmd.Flag("room-id", "Matrix room ID").StringVar(&c.opt.RoomID)
+
+	cmd.Action(configureNotificationAction(svc, &c.common, matrix.ProviderType, &c.opt, matrix.MergeOptions))
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/notification/sender/ntfy"
+)
+
+type commandNotificationConfigureNtfy struct {
+	common commonNotificationOptions
+
+	opt ntfy.Options
+}
+
+func (c *commandNotificationConfigureNtfy) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("ntfy", "ntfy notification.")
+
+	c.common.setup(svc, cmd)
+
+	cmd.Flag("server-url", "ntfy server URL (defaults to https://ntfy.sh)").StringVar(&c.opt.ServerURL)
+	cmd.Flag("topic", "ntfy topic").StringVar(&c.opt.Topic)
+	cmd.Flag("access-token", "ntfy access token").Envar(svc.EnvName("KOPIA_NTFY_ACCESS_TOKEN")).StringVar(&c.opt.AccessToken)
+	cmd.Flag("tags", "Comma-separated list of tags or emoji short codes").StringVar(&c.opt.Tags)
+	cmd.Flag("click-url", "URL to open when the notification is clicked").StringVar(&c.opt.ClickURL)
+
+	cmd.Action(configureNotificationAction(svc, &c.common, ntfy.ProviderType, &c.opt, ntfy.MergeOptions))
+}
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/notification/sender/slack"
+)
+
+type commandNotificationConfigureSlack struct {
+	common commonNotificationOptions
+
+	opt slack.Options
+}
+
+func (c *commandNotificationConfigureSlack) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("slack", "Slack notification.")
+
+	c.common.setup(svc, cmd)
+
+	cmd.Flag("webhook-url", "Slack incoming webhook URL").StringVar(&c.opt.WebhookURL)
+
+	cmd.Action(configureNotificationAction(svc, &c.common, slack.ProviderType, &c.opt, slack.MergeOptions))
+}
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/notification/sender/teams"
+)
+
+type commandNotificationConfigureTeams struct {
+	common commonNotificationOptions
+
+	opt teams.Options
+}
+
+func (c *commandNotificationConfigureTeams) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("teams", "Microsoft Teams notification.")
+
+	c.common.setup(svc, cmd)
+
+	cmd.Flag("webhook-url", "Microsoft Teams incoming webhook or workflow URL").StringVar(&c.opt.WebhookURL)
+
+	cmd.Action(configureNotificationAction(svc, &c.common, teams.ProviderType, &c.opt, teams.MergeOptions))
+}
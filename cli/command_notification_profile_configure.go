@@ -2,7 +2,12 @@
 
 type commandNotificationProfileConfigure struct {
 	commandNotificationConfigureEmail
+	commandNotificationConfigureGotify
+	commandNotificationConfigureMatrix
+	commandNotificationConfigureNtfy
 	commandNotificationConfigurePushover
+	commandNotificationConfigureSlack
+	commandNotificationConfigureTeams
 	commandNotificationConfigureWebhook
 	commandNotificationConfigureTestSender
 }
@@ -10,7 +15,12 @@ type commandNotificationProfileConfigure struct {
 func (c *commandNotificationProfileConfigure) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("configure", "Setup notifications").Alias("setup")
 	c.commandNotificationConfigureEmail.setup(svc, cmd)
+	c.commandNotificationConfigureGotify.setup(svc, cmd)
+	c.commandNotificationConfigureMatrix.setup(svc, cmd)
+	c.commandNotificationConfigureNtfy.setup(svc, cmd)
 	c.commandNotificationConfigurePushover.setup(svc, cmd)
+	c.commandNotificationConfigureSlack.setup(svc, cmd)
+	c.commandNotificationConfigureTeams.setup(svc, cmd)
 	c.commandNotificationConfigureWebhook.setup(svc, cmd)
 
 	if svc.enableTestOnlyFlags() {
@@ -48,7 +48,12 @@ func (c *commandNotificationProfileList) run(ctx context.Context, rep repo.Repos
 
 		if c.jo.jsonOutput {
 			if c.raw {
-				jl.emit(pc)
+				scrubbed, err := pc.Scrubbed()
+				if err != nil {
+					return errors.Wrap(err, "unable to scrub notification profile")
+				}
+
+				jl.emit(scrubbed)
 			} else {
 				jl.emit(summ)
 			}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -45,11 +46,28 @@ func (c *commandNotificationProfileShow) run(ctx context.Context, rep repo.Repos
 			notification.SeverityToString[pc.MinSeverity],
 			summ.Summary)
 
+		for _, r := range pc.Rules {
+			c.out.printStdout("Route: %v\n", formatNotificationRule(r))
+		}
+
+		if !pc.RateLimit.IsEmpty() {
+			c.out.printStdout("Rate Limit: %v\n", formatNotificationRateLimit(pc.RateLimit))
+		}
+
+		if pc.Digest != nil {
+			c.out.printStdout("Digest: %v\n", strings.Join(pc.Digest.Cron, "; "))
+		}
+
 		return nil
 	}
 
 	if c.raw {
-		c.out.printStdout("%s\n", c.jo.jsonBytes(pc))
+		scrubbed, err := pc.Scrubbed()
+		if err != nil {
+			return errors.Wrap(err, "unable to scrub notification profile")
+		}
+
+		c.out.printStdout("%s\n", c.jo.jsonBytes(scrubbed))
 	} else {
 		c.out.printStdout("%s\n", c.jo.jsonBytes(summ))
 	}
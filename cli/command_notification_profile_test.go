@@ -1,12 +1,15 @@
 package cli_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/sender/gotify"
+	"github.com/kopia/kopia/notification/sender/ntfy"
 	"github.com/kopia/kopia/notification/sender/webhook"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -64,6 +67,46 @@ func TestNotificationProfile(t *testing.T) {
 	require.Empty(t, e.RunAndExpectSuccess(t, "notification", "profile", "list"))
 }
 
+func TestNotificationProfile_SensitiveOptionsAreScrubbed(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "ntfy", "--profile-name=myntfy", "--topic=mytopic", "--access-token=secret-token")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "gotify", "--profile-name=mygotify", "--server-url=https://gotify.example.com", "--app-token=secret-app-token")
+
+	var (
+		cfg       notifyprofile.Config
+		ntfyOpt   ntfy.Options
+		gotifyOpt gotify.Options
+	)
+
+	out := e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=myntfy", "--json", "--raw")
+	require.NotContains(t, strings.Join(out, "\n"), "secret-token")
+	testutil.MustParseJSONLines(t, out, &cfg)
+	require.NoError(t, cfg.MethodConfig.Options(&ntfyOpt))
+	require.Equal(t, "mytopic", ntfyOpt.Topic)
+	require.Equal(t, "************", ntfyOpt.AccessToken)
+
+	out = e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=mygotify", "--json", "--raw")
+	require.NotContains(t, strings.Join(out, "\n"), "secret-app-token")
+	testutil.MustParseJSONLines(t, out, &cfg)
+	require.NoError(t, cfg.MethodConfig.Options(&gotifyOpt))
+	require.Equal(t, "https://gotify.example.com", gotifyOpt.ServerURL)
+	require.Equal(t, "****************", gotifyOpt.AppToken)
+
+	var profiles []notifyprofile.Config
+
+	out = e.RunAndExpectSuccess(t, "notification", "profile", "list", "--json", "--raw")
+	require.NotContains(t, strings.Join(out, "\n"), "secret")
+	testutil.MustParseJSONLines(t, out, &profiles)
+	require.Len(t, profiles, 2)
+}
+
 func TestNotificationProfile_WebHook(t *testing.T) {
 	t.Parallel()
 
@@ -128,3 +171,59 @@ func TestNotificationProfile_WebHook(t *testing.T) {
 	// no profiles left
 	require.Empty(t, e.RunAndExpectSuccess(t, "notification", "profile", "list"))
 }
+
+func TestNotificationProfile_ChatSenders(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "slack", "--profile-name=myslack", "--webhook-url=https://hooks.slack.com/services/xxx")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "teams", "--profile-name=myteams", "--min-severity=warning", "--webhook-url=https://example.webhook.office.com/webhookb2/xxx")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "matrix", "--profile-name=mymatrix", "--homeserver-url=https://matrix.org", "--access-token=token1", "--room-id=!room1:matrix.org")
+	e.RunAndExpectFailure(t, "notification", "profile", "configure", "matrix", "--profile-name=mymatrix2", "--homeserver-url=https://matrix.org")
+
+	lines := e.RunAndExpectSuccess(t, "notification", "profile", "list")
+
+	require.Contains(t, lines, "Profile \"myslack\" Type \"slack\" Minimum Severity: report")
+	require.Contains(t, lines, "Profile \"myteams\" Type \"teams\" Minimum Severity: warning")
+	require.Contains(t, lines, "Profile \"mymatrix\" Type \"matrix\" Minimum Severity: report")
+
+	// partial update
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "matrix", "--profile-name=mymatrix", "--room-id=!room2:matrix.org")
+
+	require.Equal(t, []string{
+		"Profile \"mymatrix\" Type \"matrix\" Minimum Severity: report",
+		"Matrix room \"!room2:matrix.org\" on https://matrix.org",
+	}, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=mymatrix"))
+}
+
+func TestNotificationProfile_PushSenders(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "ntfy", "--profile-name=myntfy", "--topic=backups", "--access-token=tk_secret", "--tags=floppy_disk")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "gotify", "--profile-name=mygotify", "--server-url=https://gotify.example.com", "--app-token=secret")
+	e.RunAndExpectFailure(t, "notification", "profile", "configure", "gotify", "--profile-name=mygotify2", "--server-url=https://gotify.example.com")
+
+	require.Equal(t, []string{
+		"Profile \"myntfy\" Type \"ntfy\" Minimum Severity: report",
+		"ntfy topic \"backups\" on https://ntfy.sh",
+	}, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=myntfy"))
+
+	// partial update
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "gotify", "--profile-name=mygotify", "--server-url=https://gotify2.example.com")
+
+	require.Equal(t, []string{
+		"Profile \"mygotify\" Type \"gotify\" Minimum Severity: report",
+		"Gotify server https://gotify2.example.com",
+	}, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=mygotify"))
+}
@@ -0,0 +1,123 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestNotificationRouting(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	dir1 := makeNotificationTestDir(t)
+	dir2 := makeNotificationTestDir(t)
+
+	e.RunAndExpectFailure(t, "notification", "profile", "configure", "testsender", "--profile-name=routed", "--route=no-such-key=x")
+	e.RunAndExpectFailure(t, "notification", "profile", "configure", "testsender", "--profile-name=routed", "--route=host=[")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=routed", "--min-severity=verbose",
+		"--route=event=snapshot-report,path="+dir1)
+
+	require.Contains(t, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=routed"),
+		"Route: event=snapshot-report,path="+dir1)
+
+	n := len(e.NotificationsSent())
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	require.Len(t, e.NotificationsSent(), n+1)
+	require.Contains(t, e.NotificationsSent()[n].Subject, dir1)
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+	require.Len(t, e.NotificationsSent(), n+1)
+
+	// updating other settings preserves the routes.
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=routed", "--min-severity=verbose")
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+	require.Len(t, e.NotificationsSent(), n+1)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=routed", "--clear-routes")
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+	require.Len(t, e.NotificationsSent(), n+2)
+}
+
+func TestNotificationRateLimit(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	dir1 := makeNotificationTestDir(t)
+	dir2 := makeNotificationTestDir(t)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=rate-limited", "--min-severity=verbose",
+		"--route=event=snapshot-report", "--dedup-interval=1h")
+
+	require.Contains(t, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=rate-limited"),
+		"Rate Limit: suppress duplicates within 1h0m0s")
+
+	n := len(e.NotificationsSent())
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	require.Len(t, e.NotificationsSent(), n+1)
+
+	// identical outcome is suppressed
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	require.Len(t, e.NotificationsSent(), n+1)
+
+	// next notification is delivered followed by the digest of suppressed ones
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+	require.Len(t, e.NotificationsSent(), n+3)
+	require.Contains(t, e.NotificationsSent()[n+1].Subject, dir2)
+	require.Contains(t, e.NotificationsSent()[n+2].Subject, "2 notifications were suppressed")
+	require.Contains(t, e.NotificationsSent()[n+2].Body, dir1)
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=rate-limited", "--clear-rate-limit")
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	require.Len(t, e.NotificationsSent(), n+4)
+}
+
+func makeNotificationTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "some-file"), []byte{1, 2, 3}, 0o755))
+
+	return dir
+}
+
+func TestNotificationDigest(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectFailure(t, "notification", "profile", "configure", "testsender", "--profile-name=digest", "--digest-schedule=not-a-schedule")
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=digest",
+		"--digest-schedule=0 8 * * *", "--digest-schedule=0 20 * * 5")
+
+	require.Contains(t, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=digest"),
+		"Digest: 0 8 * * *; 0 20 * * 5")
+
+	// updating other settings preserves the digest schedule.
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=digest", "--min-severity=warning")
+	require.Contains(t, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=digest"),
+		"Digest: 0 8 * * *; 0 20 * * 5")
+
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=digest", "--clear-digest")
+	require.NotContains(t, e.RunAndExpectSuccess(t, "notification", "profile", "show", "--profile-name=digest"),
+		"Digest:")
+}
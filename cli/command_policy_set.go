@@ -18,6 +18,7 @@ type commandPolicySet struct {
 	inherit []bool // not really a list, just an optional boolean
 
 	policyActionFlags
+	policyCommandSourceFlags
 	policyCompressionFlags
 	policyMetadataCompressionFlags
 	policySplitterFlags
@@ -28,6 +29,7 @@ type commandPolicySet struct {
 	policySchedulingFlags
 	policyOSSnapshotFlags
 	policyUploadFlags
+	policyQuotaFlags
 }
 
 func (c *commandPolicySet) setup(svc appServices, parent commandParent) {
@@ -36,6 +38,7 @@ func (c *commandPolicySet) setup(svc appServices, parent commandParent) {
 	cmd.Flag(inheritPolicyString, "Enable or disable inheriting policies from the parent").BoolListVar(&c.inherit)
 
 	c.policyActionFlags.setup(cmd)
+	c.policyCommandSourceFlags.setup(cmd)
 	c.policyCompressionFlags.setup(cmd)
 	c.policyMetadataCompressionFlags.setup(cmd)
 	c.policySplitterFlags.setup(cmd)
@@ -46,6 +49,7 @@ func (c *commandPolicySet) setup(svc appServices, parent commandParent) {
 	c.policySchedulingFlags.setup(cmd)
 	c.policyOSSnapshotFlags.setup(cmd)
 	c.policyUploadFlags.setup(cmd)
+	c.policyQuotaFlags.setup(cmd)
 
 	cmd.Action(svc.repositoryWriterAction(c.run))
 }
@@ -126,6 +130,10 @@ func (c *commandPolicySet) setPolicyFromFlags(ctx context.Context, p *policy.Pol
 		return errors.Wrap(err, "actions policy")
 	}
 
+	if err := c.setCommandSourcePolicyFromFlags(ctx, &p.CommandSource, changeCount); err != nil {
+		return errors.Wrap(err, "command source policy")
+	}
+
 	if err := c.setOSSnapshotPolicyFromFlags(ctx, &p.OSSnapshotPolicy, changeCount); err != nil {
 		return errors.Wrap(err, "OS snapshot policy")
 	}
@@ -138,6 +146,10 @@ func (c *commandPolicySet) setPolicyFromFlags(ctx context.Context, p *policy.Pol
 		return errors.Wrap(err, "upload policy")
 	}
 
+	if err := c.setQuotaPolicyFromFlags(ctx, &p.QuotaPolicy, changeCount); err != nil {
+		return errors.Wrap(err, "quota policy")
+	}
+
 	// It's not really a list, just optional boolean, last one wins.
 	for _, inherit := range c.inherit {
 		*changeCount++
@@ -219,6 +231,36 @@ func applyOptionalInt(ctx context.Context, desc string, val **policy.OptionalInt
 	return nil
 }
 
+func applyOptionalDuration(ctx context.Context, desc string, val **policy.OptionalDuration, str string, changeCount *int) error {
+	if str == "" {
+		// not changed
+		return nil
+	}
+
+	if str == inheritPolicyString || str == defaultPolicyString {
+		*changeCount++
+
+		log(ctx).Infof(" - resetting %q to a default value inherited from parent.", desc)
+
+		*val = nil
+
+		return nil
+	}
+
+	v, err := policy.ParseDuration(str)
+	if err != nil {
+		return errors.Wrapf(err, "can't parse the %v %q", desc, str)
+	}
+
+	d := policy.OptionalDuration(v)
+	*changeCount++
+
+	log(ctx).Infof(" - setting %q to %v.", desc, d)
+	*val = &d
+
+	return nil
+}
+
 func applyOptionalInt64MiB(ctx context.Context, desc string, val **policy.OptionalInt64, str string, changeCount *int) error {
 	if str == "" {
 		// not changed
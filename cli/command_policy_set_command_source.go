@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+type policyCommandSourceFlags struct {
+	policySetAddCommandStream     []string
+	policySetRemoveCommandStream  []string
+	policySetClearCommandStreams  bool
+	policySetCommandStreamTimeout time.Duration
+}
+
+func (c *policyCommandSourceFlags) setup(cmd *kingpin.CmdClause) {
+	cmd.Flag("add-command-stream", "Snapshot standard output of a command as a file with the given name instead of the source directory (not inherited)").PlaceHolder("NAME=COMMAND").StringsVar(&c.policySetAddCommandStream)
+	cmd.Flag("remove-command-stream", "Remove command stream with the given name").PlaceHolder("NAME").StringsVar(&c.policySetRemoveCommandStream)
+	cmd.Flag("clear-command-streams", "Remove all command streams").BoolVar(&c.policySetClearCommandStreams)
+	cmd.Flag("command-stream-timeout", "Max time allowed for commands added with this command to run (0 means no limit)").DurationVar(&c.policySetCommandStreamTimeout)
+}
+
+func (c *policyCommandSourceFlags) setCommandSourcePolicyFromFlags(ctx context.Context, p *policy.CommandSourcePolicy, changeCount *int) error {
+	if c.policySetClearCommandStreams {
+		log(ctx).Info(" - removing all command streams")
+
+		*changeCount++
+
+		p.Streams = nil
+	}
+
+	for _, name := range c.policySetRemoveCommandStream {
+		n := len(p.Streams)
+
+		p.Streams = slices.DeleteFunc(p.Streams, func(s policy.CommandStream) bool {
+			return s.Name == name
+		})
+
+		if len(p.Streams) == n {
+			return errors.Errorf("command stream %q not found", name)
+		}
+
+		log(ctx).Infof(" - removing command stream %v", name)
+
+		*changeCount++
+	}
+
+	for _, v := range c.policySetAddCommandStream {
+		s, err := parseCommandStream(v)
+		if err != nil {
+			return err
+		}
+
+		s.TimeoutSeconds = int(c.policySetCommandStreamTimeout.Seconds())
+
+		p.Streams = slices.DeleteFunc(p.Streams, func(e policy.CommandStream) bool {
+			return e.Name == s.Name
+		})
+		p.Streams = append(p.Streams, s)
+
+		log(ctx).Infof(" - adding command stream %v running %v", s.Name, quoteArguments(append([]string{s.Command}, s.Arguments...)...))
+
+		*changeCount++
+	}
+
+	return nil
+}
+
+// parseCommandStream parses NAME=COMMAND, where the command is space-separated and may use quotes.
+func parseCommandStream(v string) (policy.CommandStream, error) {
+	name, command, ok := strings.Cut(v, "=")
+	if !ok || name == "" || command == "" {
+		return policy.CommandStream{}, errors.Errorf("invalid command stream %q, expected NAME=COMMAND", v)
+	}
+
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return policy.CommandStream{}, errors.Errorf("invalid command stream name %q", name)
+	}
+
+	// parse command as CSV as if space was the separator, this automatically takes care of quotations
+	r := csv.NewReader(strings.NewReader(command))
+	r.Comma = ' ' // space
+
+	fields, err := r.Read()
+	if err != nil {
+		return policy.CommandStream{}, errors.Wrapf(err, "error parsing command of stream %v", name)
+	}
+
+	return policy.CommandStream{
+		Name:      name,
+		Command:   fields[0],
+		Arguments: fields[1:],
+	}, nil
+}
@@ -14,6 +14,7 @@ type policyCompressionFlags struct {
 	policySetCompressionAlgorithm string
 	policySetCompressionMinSize   string
 	policySetCompressionMaxSize   string
+	policySetAutoCompression      string
 
 	policySetAddOnlyCompress    []string
 	policySetRemoveOnlyCompress []string
@@ -58,6 +59,7 @@ func (c *policyMetadataCompressionFlags) setMetadataCompressionPolicyFromFlags(
 func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
 	// Name of compression algorithm.
 	cmd.Flag("compression", "Compression algorithm").EnumVar(&c.policySetCompressionAlgorithm, supportedCompressionAlgorithms()...)
+	cmd.Flag("auto-compression", "Choose no, fast or strong compression for each compressed file by sampling its contents, older clients use --compression ('true', 'false', 'inherit')").EnumVar(&c.policySetAutoCompression, booleanEnumValues...)
 	cmd.Flag("compression-min-size", "Min size of file to attempt compression for").StringVar(&c.policySetCompressionMinSize)
 	cmd.Flag("compression-max-size", "Max size of file to attempt compression for").StringVar(&c.policySetCompressionMaxSize)
 
@@ -95,6 +97,10 @@ func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Conte
 		}
 	}
 
+	if err := applyPolicyBoolPtr(ctx, "automatic compression", &p.AutoCompression, c.policySetAutoCompression, changeCount); err != nil {
+		return errors.Wrap(err, "automatic compression")
+	}
+
 	applyPolicyStringList(ctx, "only-compress extensions",
 		&p.OnlyCompress, c.policySetAddOnlyCompress, c.policySetRemoveOnlyCompress, c.policySetClearOnlyCompress, changeCount)
 
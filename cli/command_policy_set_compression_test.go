@@ -0,0 +1,40 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSetAutoCompressionPolicy(t *testing.T) {
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	td := testutil.TempDirectory(t)
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression=zstd", "--auto-compression=true")
+
+	lines := compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " Compressor: zstd inherited from (global)")
+	require.Contains(t, lines, " Choose no, fast or strong compression for each file by sampling its contents. inherited from (global)")
+
+	// clients which don't support automatic compression keep using the compressor.
+	var pol policy.Policy
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "policy", "show", "--global", "--json"), &pol)
+	require.Equal(t, "zstd", string(pol.CompressionPolicy.CompressorName))
+	require.True(t, pol.CompressionPolicy.AutoCompression.OrDefault(false))
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--auto-compression=false")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.NotContains(t, lines, " Choose no, fast or strong compression for each file by sampling its contents.")
+
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--compression=auto")
+}
@@ -6,17 +6,22 @@
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/fssnapshot"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
 type policyOSSnapshotFlags struct {
 	policyEnableVolumeShadowCopy string
+	policyLinuxSnapshot          string
+	policyLinuxSnapshotProvider  string
 }
 
 func (c *policyOSSnapshotFlags) setup(cmd *kingpin.CmdClause) {
 	osSnapshotMode := []string{policy.OSSnapshotNeverString, policy.OSSnapshotAlwaysString, policy.OSSnapshotWhenAvailableString, inheritPolicyString}
 
 	cmd.Flag("enable-volume-shadow-copy", "Enable Volume Shadow Copy snapshots ('never', 'always', 'when-available', 'inherit')").PlaceHolder("MODE").EnumVar(&c.policyEnableVolumeShadowCopy, osSnapshotMode...)
+	cmd.Flag("linux-snapshot", "Enable btrfs, LVM thin or ZFS snapshots on Linux ('never', 'always', 'when-available', 'inherit')").PlaceHolder("MODE").EnumVar(&c.policyLinuxSnapshot, osSnapshotMode...)
+	cmd.Flag("linux-snapshot-provider", "Linux snapshot provider ('auto', 'btrfs', 'lvm', 'zfs', 'inherit')").PlaceHolder("PROVIDER").EnumVar(&c.policyLinuxSnapshotProvider, append(fssnapshot.SupportedProviders(), inheritPolicyString)...)
 }
 
 func (c *policyOSSnapshotFlags) setOSSnapshotPolicyFromFlags(ctx context.Context, fp *policy.OSSnapshotPolicy, changeCount *int) error {
@@ -24,6 +29,24 @@ func (c *policyOSSnapshotFlags) setOSSnapshotPolicyFromFlags(ctx context.Context
 		return errors.Wrap(err, "enable volume shadow copy")
 	}
 
+	if err := applyPolicyOSSnapshotMode(ctx, "linux snapshot", &fp.LinuxSnapshot.Enable, c.policyLinuxSnapshot, changeCount); err != nil {
+		return errors.Wrap(err, "linux snapshot")
+	}
+
+	if v := c.policyLinuxSnapshotProvider; v != "" {
+		*changeCount++
+
+		if v == inheritPolicyString {
+			log(ctx).Info(" - resetting linux snapshot provider to default value inherited from parent")
+
+			fp.LinuxSnapshot.Provider = ""
+		} else {
+			log(ctx).Infof(" - setting linux snapshot provider to %v", v)
+
+			fp.LinuxSnapshot.Provider = v
+		}
+	}
+
 	return nil
 }
 
@@ -46,4 +46,28 @@ func TestSetOSSnapshotPolicy(t *testing.T) {
 	lines = compressSpaces(lines)
 
 	require.Contains(t, lines, " Volume Shadow Copy: never (defined for this target)")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+
+	require.Contains(t, lines, " Linux filesystem snapshot: never inherited from (global)")
+	require.Contains(t, lines, " Linux snapshot provider: auto inherited from (global)")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--linux-snapshot=when-available", "--linux-snapshot-provider=btrfs", td)
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+
+	require.Contains(t, lines, " Linux filesystem snapshot: when-available (defined for this target)")
+	require.Contains(t, lines, " Linux snapshot provider: btrfs (defined for this target)")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--linux-snapshot=inherit", "--linux-snapshot-provider=inherit", td)
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+
+	require.Contains(t, lines, " Linux filesystem snapshot: never inherited from (global)")
+	require.Contains(t, lines, " Linux snapshot provider: auto inherited from (global)")
+
+	e.RunAndExpectFailure(t, "policy", "set", "--linux-snapshot-provider=xfs", td)
 }
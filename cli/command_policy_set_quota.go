@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+type policyQuotaFlags struct {
+	maxSnapshotSizeMiB string
+	maxNewDataMiB      string
+	maxFileCount       string
+	quotaPreflight     string
+	maxGrowthPercent   string
+}
+
+func (c *policyQuotaFlags) setup(cmd *kingpin.CmdClause) {
+	cmd.Flag("max-snapshot-size-mib", "Stop the snapshot when the total size of files exceeds the quota").PlaceHolder("MIB").StringVar(&c.maxSnapshotSizeMiB)
+	cmd.Flag("max-new-data-mib", "Stop the snapshot when the amount of data uploaded exceeds the quota").PlaceHolder("MIB").StringVar(&c.maxNewDataMiB)
+	cmd.Flag("max-file-count", "Stop the snapshot when the number of files exceeds the quota").PlaceHolder("N").StringVar(&c.maxFileCount)
+	cmd.Flag("quota-preflight", "Estimate the source before snapshotting and warn or fail if it exceeds quotas ('none', 'warn', 'fail', 'inherit')").EnumVar(&c.quotaPreflight,
+		policy.QuotaPreflightNone, policy.QuotaPreflightWarn, policy.QuotaPreflightFail, inheritPolicyString)
+	cmd.Flag("max-growth-percent", "Maximum growth of the estimated source size since the previous snapshot checked during pre-flight").PlaceHolder("PERCENT").StringVar(&c.maxGrowthPercent)
+}
+
+func (c *policyQuotaFlags) setQuotaPolicyFromFlags(ctx context.Context, qp *policy.QuotaPolicy, changeCount *int) error {
+	if err := applyOptionalInt64MiB(ctx, "max snapshot size", &qp.MaxTotalSize, c.maxSnapshotSizeMiB, changeCount); err != nil {
+		return err
+	}
+
+	if err := applyOptionalInt64MiB(ctx, "max new data", &qp.MaxNewBytes, c.maxNewDataMiB, changeCount); err != nil {
+		return err
+	}
+
+	if err := applyOptionalInt64(ctx, "max file count", &qp.MaxFileCount, c.maxFileCount, changeCount); err != nil {
+		return err
+	}
+
+	if err := applyOptionalInt(ctx, "max growth percent", &qp.MaxGrowthPercent, c.maxGrowthPercent, changeCount); err != nil {
+		return err
+	}
+
+	if v := c.quotaPreflight; v != "" {
+		*changeCount++
+
+		if v == inheritPolicyString {
+			log(ctx).Info(" - resetting quota pre-flight mode to default value inherited from parent")
+
+			qp.Preflight = ""
+		} else {
+			log(ctx).Infof(" - setting quota pre-flight mode to %v", v)
+
+			qp.Preflight = v
+		}
+	}
+
+	return nil
+}
+
+func applyOptionalInt64(ctx context.Context, desc string, val **policy.OptionalInt64, str string, changeCount *int) error {
+	if str == "" {
+		// not changed
+		return nil
+	}
+
+	if str == inheritPolicyString || str == defaultPolicyString {
+		*changeCount++
+
+		log(ctx).Infof(" - resetting %q to a default value inherited from parent.", desc)
+
+		*val = nil
+
+		return nil
+	}
+
+	v, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "can't parse the %v %q", desc, str)
+	}
+
+	i := policy.OptionalInt64(v)
+	*changeCount++
+
+	log(ctx).Infof(" - setting %q to %v.", desc, i)
+	*val = &i
+
+	return nil
+}
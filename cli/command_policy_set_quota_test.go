@@ -0,0 +1,70 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/upload"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSetQuotaPolicy(t *testing.T) {
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=mysender", "--min-severity=warning")
+
+	td := testutil.TempDirectory(t)
+
+	for _, n := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(filepath.Join(td, n), []byte("some data "+n), 0o600))
+	}
+
+	lines := compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " Max file count: - inherited from (global)")
+	require.Contains(t, lines, " Pre-flight check: none inherited from (global)")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--max-file-count=2", "--max-snapshot-size-mib=100", "--quota-preflight=fail", "--max-growth-percent=50")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " Max snapshot size: 104.9 MB inherited from (global)")
+	require.Contains(t, lines, " Max file count: 2 inherited from (global)")
+	require.Contains(t, lines, " Pre-flight check: fail inherited from (global)")
+	require.Contains(t, lines, " Max growth since previous (%): 50 inherited from (global)")
+
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--quota-preflight=bogus")
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--max-file-count=-1")
+
+	// pre-flight check refuses to start the snapshot and sends an error notification.
+	n := len(e.NotificationsSent())
+
+	e.RunAndExpectFailure(t, "snapshot", "create", td, "--no-send-snapshot-report")
+	require.Len(t, e.NotificationsSent(), n+1)
+	require.Contains(t, e.NotificationsSent()[n].Body, "exceeds quota")
+	require.Equal(t, notification.SeverityError, e.NotificationsSent()[n].Severity)
+
+	// in warn mode the snapshot proceeds but stops when quota is exceeded.
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--quota-preflight=warn")
+
+	var man snapshot.Manifest
+
+	n = len(e.NotificationsSent())
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", td, "--json", "--parallel=1", "--no-send-snapshot-report"), &man)
+	require.Equal(t, upload.IncompleteReasonFileCountQuota, man.IncompleteReason)
+	require.Len(t, e.NotificationsSent(), n+1)
+	require.Equal(t, notification.SeverityWarning, e.NotificationsSent()[n].Severity)
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--max-file-count=inherit", "--quota-preflight=inherit")
+
+	man = snapshot.Manifest{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "create", td, "--json"), &man)
+	require.Empty(t, man.IncompleteReason)
+}
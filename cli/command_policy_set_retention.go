@@ -16,6 +16,13 @@ type policyRetentionFlags struct {
 	policySetKeepMonthly              string
 	policySetKeepAnnual               string
 	policySetIgnoreIdenticalSnapshots string
+
+	policySetKeepWithin        string
+	policySetKeepHourlyWithin  string
+	policySetKeepDailyWithin   string
+	policySetKeepWeeklyWithin  string
+	policySetKeepMonthlyWithin string
+	policySetKeepAnnualWithin  string
 }
 
 func (c *policyRetentionFlags) setup(cmd *kingpin.CmdClause) {
@@ -26,6 +33,12 @@ func (c *policyRetentionFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("keep-monthly", "Number of most-recent monthly backups to keep per source (or 'inherit')").PlaceHolder("N").StringVar(&c.policySetKeepMonthly)
 	cmd.Flag("keep-annual", "Number of most-recent annual backups to keep per source (or 'inherit')").PlaceHolder("N").StringVar(&c.policySetKeepAnnual)
 	cmd.Flag("ignore-identical-snapshots", "Do not save identical snapshots (or 'inherit')").StringVar(&c.policySetIgnoreIdenticalSnapshots)
+	cmd.Flag("keep-within", "Keep all backups taken within the duration (e.g. 7d) of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepWithin)
+	cmd.Flag("keep-hourly-within", "Keep hourly backups taken within the duration of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepHourlyWithin)
+	cmd.Flag("keep-daily-within", "Keep daily backups taken within the duration (e.g. 90d) of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepDailyWithin)
+	cmd.Flag("keep-weekly-within", "Keep weekly backups taken within the duration of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepWeeklyWithin)
+	cmd.Flag("keep-monthly-within", "Keep monthly backups taken within the duration of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepMonthlyWithin)
+	cmd.Flag("keep-annual-within", "Keep annual backups taken within the duration (e.g. 7y) of the latest backup (or 'inherit')").PlaceHolder("DURATION").StringVar(&c.policySetKeepAnnualWithin)
 }
 
 func (c *policyRetentionFlags) setRetentionPolicyFromFlags(ctx context.Context, rp *policy.RetentionPolicy, changeCount *int) error {
@@ -48,5 +61,24 @@ func (c *policyRetentionFlags) setRetentionPolicyFromFlags(ctx context.Context,
 		}
 	}
 
+	durationCases := []struct {
+		desc      string
+		max       **policy.OptionalDuration
+		flagValue string
+	}{
+		{"duration to keep annual backups", &rp.KeepAnnualWithin, c.policySetKeepAnnualWithin},
+		{"duration to keep monthly backups", &rp.KeepMonthlyWithin, c.policySetKeepMonthlyWithin},
+		{"duration to keep weekly backups", &rp.KeepWeeklyWithin, c.policySetKeepWeeklyWithin},
+		{"duration to keep daily backups", &rp.KeepDailyWithin, c.policySetKeepDailyWithin},
+		{"duration to keep hourly backups", &rp.KeepHourlyWithin, c.policySetKeepHourlyWithin},
+		{"duration to keep all backups", &rp.KeepWithin, c.policySetKeepWithin},
+	}
+
+	for _, c := range durationCases {
+		if err := applyOptionalDuration(ctx, c.desc, c.max, c.flagValue, changeCount); err != nil {
+			return err
+		}
+	}
+
 	return applyPolicyBoolPtr(ctx, "do not save identical snapshots", &rp.IgnoreIdenticalSnapshots, c.policySetIgnoreIdenticalSnapshots, changeCount)
 }
@@ -0,0 +1,73 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSetDurationRetentionPolicy(t *testing.T) {
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	td := testutil.TempDirectory(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a"), []byte("some data"), 0o600))
+
+	lines := compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.NotContains(t, lines, " All snapshots within: - inherited from (global)")
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global",
+		"--keep-latest=0", "--keep-hourly=0", "--keep-daily=0", "--keep-weekly=0", "--keep-monthly=0", "--keep-annual=0",
+		"--keep-within=7d", "--keep-daily-within=90d")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " All snapshots within: 7d inherited from (global)")
+	require.Contains(t, lines, " Daily snapshots within: 90d inherited from (global)")
+	require.Contains(t, lines, " Hourly snapshots within: - inherited from (global)")
+
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--keep-within=7x")
+
+	for range 3 {
+		e.RunAndExpectSuccess(t, "snapshot", "create", td)
+	}
+
+	// all snapshots are retained because they were taken within 7 days of the latest one.
+	var snapshots []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", td, "--json"), &snapshots)
+	require.Len(t, snapshots, 3)
+
+	var reasons []string
+
+	for _, s := range snapshots {
+		reasons = append(reasons, s.RetentionReasons...)
+	}
+
+	require.ElementsMatch(t, []string{"within-1", "within-2", "within-3", "daily-within-1"}, reasons)
+
+	e.RunAndExpectSuccess(t, "snapshot", "expire", td, "--delete")
+
+	snapshots = nil
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", td, "--json"), &snapshots)
+	require.Len(t, snapshots, 3)
+
+	// without keep-within only the latest snapshot of the day is retained.
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--keep-within=inherit")
+	e.RunAndExpectSuccess(t, "snapshot", "expire", td, "--delete")
+
+	snapshots = nil
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", td, "--json"), &snapshots)
+	require.Len(t, snapshots, 1)
+	require.Equal(t, []string{"daily-within-1"}, snapshots[0].RetentionReasons)
+}
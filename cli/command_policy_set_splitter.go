@@ -2,20 +2,40 @@
 
 import (
 	"context"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo/splitter"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
 type policySplitterFlags struct {
 	policySetSplitterAlgorithmOverride string
+
+	policySetAddSplitterRule      []string
+	policySetRemoveSplitterRule   []string
+	policySetClearSplitterRules   bool
+	policySetSplitterRuleMinSize  string
+	policySetSplitterRuleMaxSize  string
+	policySetInheritSplitterRules string
 }
 
 func (c *policySplitterFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("splitter", "Splitter algorithm override").EnumVar(&c.policySetSplitterAlgorithmOverride, supportedSplitterAlgorithms()...)
+
+	// Per-file splitter rules.
+	cmd.Flag("add-splitter-rule", "Add rule selecting splitter for files with given extensions (all files if none are given)").PlaceHolder("ALGORITHM[=EXT,...]").StringsVar(&c.policySetAddSplitterRule)
+	cmd.Flag("remove-splitter-rule", "Remove rules selecting given splitter").PlaceHolder("ALGORITHM").StringsVar(&c.policySetRemoveSplitterRule)
+	cmd.Flag("clear-splitter-rules", "Clear list of splitter rules").BoolVar(&c.policySetClearSplitterRules)
+	cmd.Flag("splitter-rule-min-size", "Min size of file matched by splitter rules added with this command").StringVar(&c.policySetSplitterRuleMinSize)
+	cmd.Flag("splitter-rule-max-size", "Max size of file matched by splitter rules added with this command").StringVar(&c.policySetSplitterRuleMaxSize)
+	cmd.Flag("inherit-splitter-rules", "Whether splitter rules of parent policies apply after the rules of this policy").EnumVar(&c.policySetInheritSplitterRules, "true", "false")
 }
 
 //nolint:unparam
@@ -34,9 +54,128 @@ func (c *policySplitterFlags) setSplitterPolicyFromFlags(ctx context.Context, p
 		*changeCount++
 	}
 
+	return c.applySplitterRules(ctx, p, changeCount)
+}
+
+func (c *policySplitterFlags) applySplitterRules(ctx context.Context, p *policy.SplitterPolicy, changeCount *int) error {
+	if c.policySetClearSplitterRules {
+		log(ctx).Info(" - removing all splitter rules")
+
+		p.Rules = nil
+		*changeCount++
+	}
+
+	if v := c.policySetInheritSplitterRules; v != "" {
+		log(ctx).Infof(" - setting inheriting of splitter rules to %v", v)
+
+		p.NoParentRules = v == "false"
+		*changeCount++
+	}
+
+	for _, alg := range c.policySetRemoveSplitterRule {
+		log(ctx).Infof(" - removing splitter rules for %v", alg)
+
+		p.Rules = slices.DeleteFunc(p.Rules, func(r policy.SplitterRule) bool {
+			return r.Algorithm == alg
+		})
+		*changeCount++
+	}
+
+	if len(c.policySetAddSplitterRule) == 0 {
+		if c.policySetSplitterRuleMinSize != "" || c.policySetSplitterRuleMaxSize != "" {
+			return errors.New("--splitter-rule-min-size and --splitter-rule-max-size require --add-splitter-rule")
+		}
+
+		return nil
+	}
+
+	minSize, err := parseSplitterRuleSize(c.policySetSplitterRuleMinSize)
+	if err != nil {
+		return errors.Wrap(err, "minimum file size for splitter rule")
+	}
+
+	maxSize, err := parseSplitterRuleSize(c.policySetSplitterRuleMaxSize)
+	if err != nil {
+		return errors.Wrap(err, "maximum file size for splitter rule")
+	}
+
+	for _, spec := range c.policySetAddSplitterRule {
+		r, err := parseSplitterRule(spec)
+		if err != nil {
+			return err
+		}
+
+		r.MinSize = minSize
+		r.MaxSize = maxSize
+
+		log(ctx).Infof(" - adding splitter rule %v", splitterRuleString(r))
+
+		p.Rules = append(p.Rules, r)
+		*changeCount++
+	}
+
 	return nil
 }
 
+func parseSplitterRule(spec string) (policy.SplitterRule, error) {
+	alg, exts, _ := strings.Cut(spec, "=")
+
+	if !slices.Contains(splitter.SupportedAlgorithms(), alg) {
+		return policy.SplitterRule{}, errors.Errorf("unsupported splitter %q in rule %q", alg, spec)
+	}
+
+	r := policy.SplitterRule{Algorithm: alg}
+
+	for e := range strings.SplitSeq(exts, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+
+		r.Extensions = append(r.Extensions, e)
+	}
+
+	return r, nil
+}
+
+func parseSplitterRuleSize(str string) (int64, error) {
+	if str == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "can't parse %q", str)
+	}
+
+	return v, nil
+}
+
+func splitterRuleString(r policy.SplitterRule) string {
+	var conds []string
+
+	if len(r.Extensions) > 0 {
+		conds = append(conds, "extensions "+strings.Join(r.Extensions, ", "))
+	}
+
+	if r.MinSize > 0 {
+		conds = append(conds, "at least "+units.BytesString(r.MinSize))
+	}
+
+	if r.MaxSize > 0 {
+		conds = append(conds, "at most "+units.BytesString(r.MaxSize))
+	}
+
+	if len(conds) == 0 {
+		return r.Algorithm + " for all files"
+	}
+
+	return r.Algorithm + " for files with " + strings.Join(conds, " and ")
+}
+
 func supportedSplitterAlgorithms() []string {
 	res := append([]string{inheritPolicyString}, splitter.SupportedAlgorithms()...)
 
@@ -39,4 +39,50 @@ func TestSetSplitterPolicy(t *testing.T) {
 	require.Contains(t, lines, " Algorithm override: (repository default) inherited from (global)")
 
 	e.RunAndExpectFailure(t, "policy", "set", td, "--splitter=NO-SUCH_SPLITTER")
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--add-splitter-rule=FIXED-4M=vmdk,.qcow2")
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--add-splitter-rule=DYNAMIC-128K-BUZHASH=.txt", "--splitter-rule-max-size=1000000")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+	require.Contains(t, lines, " Per-file rules (first match wins, other files use algorithm override): (defined for this target)")
+	require.Contains(t, lines, " FIXED-4M for files with extensions .vmdk, .qcow2")
+	require.Contains(t, lines, " DYNAMIC-128K-BUZHASH for files with extensions .txt and at most 1 MB")
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--remove-splitter-rule=FIXED-4M")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+	require.NotContains(t, lines, " FIXED-4M for files with extensions .vmdk, .qcow2")
+	require.Contains(t, lines, " DYNAMIC-128K-BUZHASH for files with extensions .txt and at most 1 MB")
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--clear-splitter-rules")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+	require.NotContains(t, lines, " Per-file rules (first match wins, other files use algorithm override): (defined for this target)")
+
+	// rules of parent policies apply after the rules of the target, unless inheriting them is disabled.
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--add-splitter-rule=FIXED-4M=.vmdk")
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--add-splitter-rule=DYNAMIC-128K-BUZHASH=.txt")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " DYNAMIC-128K-BUZHASH for files with extensions .txt")
+	require.Contains(t, lines, " FIXED-4M for files with extensions .vmdk")
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--inherit-splitter-rules=false")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " DYNAMIC-128K-BUZHASH for files with extensions .txt")
+	require.NotContains(t, lines, " FIXED-4M for files with extensions .vmdk")
+	require.Contains(t, lines, " Rules of parent policies are not inherited.")
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--inherit-splitter-rules=true")
+
+	lines = compressSpaces(e.RunAndExpectSuccess(t, "policy", "show", td))
+	require.Contains(t, lines, " FIXED-4M for files with extensions .vmdk")
+
+	e.RunAndExpectFailure(t, "policy", "set", td, "--inherit-splitter-rules=maybe")
+	e.RunAndExpectFailure(t, "policy", "set", td, "--add-splitter-rule=NO-SUCH_SPLITTER=.txt")
+	e.RunAndExpectFailure(t, "policy", "set", td, "--splitter-rule-min-size=1000")
 }
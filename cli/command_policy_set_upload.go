@@ -12,12 +12,14 @@ type policyUploadFlags struct {
 	maxParallelUploads            string
 	maxParallelFileReads          string
 	parallelizeUploadAboveSizeMiB string
+	splitArchiveMembers           string
 }
 
 func (c *policyUploadFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("max-parallel-file-reads", "Maximum number of parallel file reads").StringVar(&c.maxParallelFileReads)
 	cmd.Flag("max-parallel-snapshots", "Maximum number of parallel snapshots (server, KopiaUI only)").StringVar(&c.maxParallelUploads)
 	cmd.Flag("parallel-upload-above-size-mib", "Use parallel uploads above size").StringVar(&c.parallelizeUploadAboveSizeMiB)
+	cmd.Flag("split-archive-members", "Split uncompressed tar archives on member boundaries to improve deduplication ('true', 'false', 'inherit')").EnumVar(&c.splitArchiveMembers, booleanEnumValues...)
 }
 
 func (c *policyUploadFlags) setUploadPolicyFromFlags(ctx context.Context, up *policy.UploadPolicy, changeCount *int) error {
@@ -29,5 +31,9 @@ func (c *policyUploadFlags) setUploadPolicyFromFlags(ctx context.Context, up *po
 		return err
 	}
 
-	return applyOptionalInt64MiB(ctx, "parallel upload above size", &up.ParallelUploadAboveSize, c.parallelizeUploadAboveSizeMiB, changeCount)
+	if err := applyOptionalInt64MiB(ctx, "parallel upload above size", &up.ParallelUploadAboveSize, c.parallelizeUploadAboveSizeMiB, changeCount); err != nil {
+		return err
+	}
+
+	return applyPolicyBoolPtr(ctx, "split archive members", &up.SplitArchiveMembers, c.splitArchiveMembers, changeCount)
 }
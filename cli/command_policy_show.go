@@ -8,6 +8,7 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/fssnapshot"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/snapshot"
@@ -124,6 +125,8 @@ func printPolicy(out *textOutput, p *policy.Policy, def *policy.Definition) {
 	rows = append(rows, policyTableRow{})
 	rows = appendUploadPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
+	rows = appendQuotaPolicyRows(rows, p, def)
+	rows = append(rows, policyTableRow{})
 	rows = appendCompressionPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
 	rows = appendMetadataCompressionPolicyRows(rows, p, def)
@@ -132,6 +135,7 @@ func printPolicy(out *textOutput, p *policy.Policy, def *policy.Definition) {
 	rows = append(rows, policyTableRow{})
 	rows = appendActionsPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
+	rows = appendCommandSourcePolicyRows(rows, p)
 	rows = appendOSSnapshotPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
 	rows = appendLoggingPolicyRows(rows, p, def)
@@ -140,7 +144,7 @@ func printPolicy(out *textOutput, p *policy.Policy, def *policy.Definition) {
 }
 
 func appendRetentionPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
-	return append(rows,
+	rows = append(rows,
 		policyTableRow{"Retention:", "", ""},
 		policyTableRow{"  Annual snapshots:", valueOrNotSet(p.RetentionPolicy.KeepAnnual), definitionPointToString(p.Target(), def.RetentionPolicy.KeepAnnual)},
 		policyTableRow{"  Monthly snapshots:", valueOrNotSet(p.RetentionPolicy.KeepMonthly), definitionPointToString(p.Target(), def.RetentionPolicy.KeepMonthly)},
@@ -150,6 +154,21 @@ func appendRetentionPolicyRows(rows []policyTableRow, p *policy.Policy, def *pol
 		policyTableRow{"  Latest snapshots:", valueOrNotSet(p.RetentionPolicy.KeepLatest), definitionPointToString(p.Target(), def.RetentionPolicy.KeepLatest)},
 		policyTableRow{"  Ignore identical snapshots:", boolToString(p.RetentionPolicy.IgnoreIdenticalSnapshots.OrDefault(false)), definitionPointToString(p.Target(), def.RetentionPolicy.IgnoreIdenticalSnapshots)},
 	)
+
+	rp := p.RetentionPolicy
+
+	if rp.KeepAnnualWithin == nil && rp.KeepMonthlyWithin == nil && rp.KeepWeeklyWithin == nil && rp.KeepDailyWithin == nil && rp.KeepHourlyWithin == nil && rp.KeepWithin == nil {
+		return rows
+	}
+
+	return append(rows,
+		policyTableRow{"  Annual snapshots within:", durationOrNotSet(rp.KeepAnnualWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepAnnualWithin)},
+		policyTableRow{"  Monthly snapshots within:", durationOrNotSet(rp.KeepMonthlyWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepMonthlyWithin)},
+		policyTableRow{"  Weekly snapshots within:", durationOrNotSet(rp.KeepWeeklyWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepWeeklyWithin)},
+		policyTableRow{"  Daily snapshots within:", durationOrNotSet(rp.KeepDailyWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepDailyWithin)},
+		policyTableRow{"  Hourly snapshots within:", durationOrNotSet(rp.KeepHourlyWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepHourlyWithin)},
+		policyTableRow{"  All snapshots within:", durationOrNotSet(rp.KeepWithin), definitionPointToString(p.Target(), def.RetentionPolicy.KeepWithin)},
+	)
 }
 
 func boolToString(v bool) string {
@@ -277,6 +296,23 @@ func appendUploadPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy
 		policyTableRow{"  Max parallel snapshots (server/UI):", valueOrNotSet(p.UploadPolicy.MaxParallelSnapshots), definitionPointToString(p.Target(), def.UploadPolicy.MaxParallelSnapshots)},
 		policyTableRow{"  Max parallel file reads:", valueOrNotSet(p.UploadPolicy.MaxParallelFileReads), definitionPointToString(p.Target(), def.UploadPolicy.MaxParallelFileReads)},
 		policyTableRow{"  Parallel upload above size:", valueOrNotSetOptionalInt64Bytes(p.UploadPolicy.ParallelUploadAboveSize), definitionPointToString(p.Target(), def.UploadPolicy.ParallelUploadAboveSize)},
+		policyTableRow{"  Split archive members:", boolToString(p.UploadPolicy.SplitArchiveMembers.OrDefault(false)), definitionPointToString(p.Target(), def.UploadPolicy.SplitArchiveMembers)},
+	)
+}
+
+func appendQuotaPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
+	preflight := p.QuotaPolicy.Preflight
+	if preflight == "" {
+		preflight = policy.QuotaPreflightNone
+	}
+
+	return append(rows,
+		policyTableRow{"Quotas:", "", ""},
+		policyTableRow{"  Max snapshot size:", valueOrNotSetOptionalInt64Bytes(p.QuotaPolicy.MaxTotalSize), definitionPointToString(p.Target(), def.QuotaPolicy.MaxTotalSize)},
+		policyTableRow{"  Max new data per snapshot:", valueOrNotSetOptionalInt64Bytes(p.QuotaPolicy.MaxNewBytes), definitionPointToString(p.Target(), def.QuotaPolicy.MaxNewBytes)},
+		policyTableRow{"  Max file count:", valueOrNotSetOptionalInt64(p.QuotaPolicy.MaxFileCount), definitionPointToString(p.Target(), def.QuotaPolicy.MaxFileCount)},
+		policyTableRow{"  Pre-flight check:", preflight, definitionPointToString(p.Target(), def.QuotaPolicy.Preflight)},
+		policyTableRow{"  Max growth since previous (%):", valueOrNotSet(p.QuotaPolicy.MaxGrowthPercent), definitionPointToString(p.Target(), def.QuotaPolicy.MaxGrowthPercent)},
 	)
 }
 
@@ -346,6 +382,13 @@ func appendCompressionPolicyRows(rows []policyTableRow, p *policy.Policy, def *p
 		policyTableRow{"Compression:", "", ""},
 		policyTableRow{"  Compressor:", string(p.CompressionPolicy.CompressorName), definitionPointToString(p.Target(), def.CompressionPolicy.CompressorName)})
 
+	if p.CompressionPolicy.AutoCompression.OrDefault(false) {
+		rows = append(rows, policyTableRow{
+			"  Choose no, fast or strong compression for each file by sampling its contents.", "",
+			definitionPointToString(p.Target(), def.CompressionPolicy.AutoCompression),
+		})
+	}
+
 	switch {
 	case len(p.CompressionPolicy.OnlyCompress) > 0:
 		rows = append(rows, policyTableRow{
@@ -411,6 +454,23 @@ func appendSplitterPolicyRows(rows []policyTableRow, p *policy.Policy, def *poli
 		policyTableRow{"Splitter:", "", ""},
 		policyTableRow{"  Algorithm override:", algorithm, definitionPointToString(p.Target(), def.SplitterPolicy.Algorithm)})
 
+	if len(p.SplitterPolicy.Rules) == 0 {
+		return rows
+	}
+
+	rows = append(rows, policyTableRow{
+		"  Per-file rules (first match wins, other files use algorithm override):", "",
+		definitionPointToString(p.Target(), def.SplitterPolicy.Rules),
+	})
+
+	for _, r := range p.SplitterPolicy.Rules {
+		rows = append(rows, policyTableRow{"    " + splitterRuleString(r), "", ""})
+	}
+
+	if p.SplitterPolicy.NoParentRules {
+		rows = append(rows, policyTableRow{"    Rules of parent policies are not inherited.", "", ""})
+	}
+
 	return rows
 }
 
@@ -453,6 +513,29 @@ func appendActionsPolicyRows(rows []policyTableRow, p *policy.Policy, def *polic
 	return rows
 }
 
+func appendCommandSourcePolicyRows(rows []policyTableRow, p *policy.Policy) []policyTableRow {
+	if !p.CommandSource.IsCommandSource() {
+		return rows
+	}
+
+	rows = append(rows, policyTableRow{"Snapshot output of commands instead of directory:", "", "(non-inheritable)"})
+
+	for _, s := range p.CommandSource.Streams {
+		timeout := "none"
+		if s.TimeoutSeconds != 0 {
+			timeout = (time.Second * time.Duration(s.TimeoutSeconds)).String()
+		}
+
+		rows = append(rows,
+			policyTableRow{"  " + s.Name + ":", "", ""},
+			policyTableRow{"    Command:", strings.Join(append([]string{s.Command}, s.Arguments...), " "), ""},
+			policyTableRow{"    Timeout:", timeout, ""},
+		)
+	}
+
+	return append(rows, policyTableRow{})
+}
+
 func appendActionCommandRows(rows []policyTableRow, h *policy.ActionCommand) []policyTableRow {
 	if h.Script != "" {
 		rows = append(rows,
@@ -480,6 +563,11 @@ func appendActionCommandRows(rows []policyTableRow, h *policy.ActionCommand) []p
 }
 
 func appendOSSnapshotPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
+	linuxProvider := p.OSSnapshotPolicy.LinuxSnapshot.Provider
+	if linuxProvider == "" {
+		linuxProvider = fssnapshot.ProviderAuto
+	}
+
 	rows = append(rows,
 		policyTableRow{"OS-level snapshot support:", "", ""},
 		policyTableRow{
@@ -487,6 +575,16 @@ func appendOSSnapshotPolicyRows(rows []policyTableRow, p *policy.Policy, def *po
 			p.OSSnapshotPolicy.VolumeShadowCopy.Enable.OrDefault(policy.OSSnapshotNever).String(),
 			definitionPointToString(p.Target(), def.OSSnapshotPolicy.VolumeShadowCopy.Enable),
 		},
+		policyTableRow{
+			"  Linux filesystem snapshot:",
+			p.OSSnapshotPolicy.LinuxSnapshot.Enable.OrDefault(policy.OSSnapshotNever).String(),
+			definitionPointToString(p.Target(), def.OSSnapshotPolicy.LinuxSnapshot.Enable),
+		},
+		policyTableRow{
+			"  Linux snapshot provider:",
+			linuxProvider,
+			definitionPointToString(p.Target(), def.OSSnapshotPolicy.LinuxSnapshot.Provider),
+		},
 	)
 
 	return rows
@@ -500,6 +598,22 @@ func valueOrNotSet(p *policy.OptionalInt) string {
 	return fmt.Sprintf("%v", *p)
 }
 
+func durationOrNotSet(p *policy.OptionalDuration) string {
+	if p == nil {
+		return "-"
+	}
+
+	return p.String()
+}
+
+func valueOrNotSetOptionalInt64(p *policy.OptionalInt64) string {
+	if p == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%v", *p)
+}
+
 func valueOrNotSetOptionalInt64Bytes(p *policy.OptionalInt64) string {
 	if p == nil {
 		return "-"
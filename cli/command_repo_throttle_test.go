@@ -1,6 +1,7 @@
 package cli_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -78,4 +79,27 @@ func TestRepoThrottle(t *testing.T) {
 		ConcurrentReads:        300,
 		ConcurrentWrites:       400,
 	}, limits)
+
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--add-schedule-window=mon-fri 08:00-18:00")
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set",
+		"--add-schedule-window=mon-fri 08:00-18:00 upload=2000000",
+		"--add-schedule-window=* 22:00-06:00 download=1000 concurrent-reads=5",
+	)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--json"), &limits)
+	require.Equal(t, []throttling.ScheduleWindow{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00", Limits: throttling.Limits{UploadBytesPerSecond: 2000000}},
+		{Start: "22:00", End: "06:00", Limits: throttling.Limits{DownloadBytesPerSecond: 1000, ConcurrentReads: 5}},
+	}, limits.Schedule)
+
+	out := env.RunAndExpectSuccess(t, "repo", "throttle", "get")
+	require.Contains(t, out, "Schedule:")
+	require.Contains(t, strings.Join(out, "\n"), "mon,tue,wed,thu,fri 08:00-18:00: upload 2 MB/s")
+	require.Contains(t, strings.Join(out, "\n"), "every day 22:00-06:00: download 1 KB/s, concurrent reads 5")
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set", "--clear-schedule")
+
+	limits = throttling.Limits{}
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--json"), &limits)
+	require.Empty(t, limits.Schedule)
 }
@@ -2,6 +2,7 @@
 
 type commandRepository struct {
 	connect          commandRepositoryConnect
+	conformanceTest  commandRepositoryConformanceTest
 	create           commandRepositoryCreate
 	disconnect       commandRepositoryDisconnect
 	repair           commandRepositoryRepair
@@ -19,6 +20,7 @@ func (c *commandRepository) setup(svc advancedAppServices, parent commandParent)
 	cmd := parent.Command("repository", "Commands to manipulate repository.").Alias("repo")
 
 	c.connect.setup(svc, cmd)
+	c.conformanceTest.setup(svc, cmd)
 	c.create.setup(svc, cmd)
 	c.disconnect.setup(svc, cmd)
 	c.repair.setup(svc, cmd)
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/providervalidation"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+type commandRepositoryConformanceTest struct {
+	opt           providervalidation.ConformanceOptions
+	retentionMode string
+	reportFile    string
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositoryConformanceTest) setup(svc advancedAppServices, parent commandParent) {
+	cmd := parent.Command("conformance-test", "Runs the storage conformance suite against a storage location, without requiring a repository")
+
+	c.opt = providervalidation.DefaultConformanceOptions
+
+	cmd.Flag("test", "Conformance test to run (may be specified multiple times, default all): "+strings.Join(providervalidation.ConformanceTestNames(), ", ")).StringsVar(&c.opt.Tests)
+	cmd.Flag("report-file", "Write JSON report to the provided file").StringVar(&c.reportFile)
+	cmd.Flag("num-storage-connections", "Number of storage connections").IntVar(&c.opt.NumEquivalentStorageConnections)
+	cmd.Flag("concurrency-test-duration", "Duration of concurrency test").DurationVar(&c.opt.ConcurrencyTestDuration)
+	cmd.Flag("put-blob-workers", "Number of PutBlob workers").IntVar(&c.opt.NumPutBlobWorkers)
+	cmd.Flag("get-blob-workers", "Number of GetBlob workers").IntVar(&c.opt.NumGetBlobWorkers)
+	cmd.Flag("get-metadata-workers", "Number of GetMetadata workers").IntVar(&c.opt.NumGetMetadataWorkers)
+	cmd.Flag("max-clock-drift", "Maximum allowed difference between storage and local clock").DurationVar(&c.opt.MaxClockDrift)
+	cmd.Flag("pagination-blobs", "Number of blobs written to exercise listing pagination").IntVar(&c.opt.NumPaginationBlobs)
+	cmd.Flag("large-blob-size", "Size of the blob used in the large blob test").IntVar(&c.opt.LargeBlobLength)
+	cmd.Flag("consistency-timeout", "Maximum time to wait for writes and deletes to be visible in listings").DurationVar(&c.opt.ConsistencyTimeout)
+	cmd.Flag("retention-mode", "Retention mode to test, blobs written by the retention test can't be removed until retention expires").EnumVar(&c.retentionMode, string(blob.Governance), string(blob.Compliance))
+	cmd.Flag("retention-period", "Retention period to test").DurationVar(&c.opt.RetentionPeriod)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	for _, prov := range svc.storageProviders() {
+		f := prov.NewFlags()
+		cc := cmd.Command(prov.Name, "Run conformance tests against "+prov.Description)
+		f.Setup(svc, cc)
+		cc.Action(func(kpc *kingpin.ParseContext) error {
+			return svc.runAppWithContext(kpc.SelectedCommand, func(ctx context.Context) error {
+				st, err := f.Connect(ctx, false, 0)
+				if err != nil {
+					return errors.Wrap(err, "can't connect to storage")
+				}
+
+				defer st.Close(ctx) //nolint:errcheck
+
+				return c.run(ctx, st)
+			})
+		})
+	}
+}
+
+func (c *commandRepositoryConformanceTest) run(ctx context.Context, st blob.Storage) error {
+	c.opt.RetentionMode = blob.RetentionMode(c.retentionMode)
+
+	report, err := providervalidation.RunConformanceSuite(ctx, st, c.opt)
+	if err != nil {
+		return errors.Wrap(err, "unable to run conformance suite")
+	}
+
+	if c.reportFile != "" {
+		if err := os.WriteFile(c.reportFile, c.jo.jsonIndentedBytes(report, "  "), 0o600); err != nil {
+			return errors.Wrap(err, "unable to write report")
+		}
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(report))
+	} else {
+		c.out.printStdout("%-20v %-6v %12v %v\n", "TEST", "RESULT", "DURATION", "DETAILS")
+
+		for _, r := range report.Results {
+			details := r.Error
+			if details == "" {
+				details = r.Description
+			}
+
+			c.out.printStdout("%-20v %-6v %12v %v\n", r.Name, strings.ToUpper(string(r.Status)), r.Duration.Round(time.Millisecond), details)
+		}
+
+		c.out.printStdout("\nPassed: %v, failed: %v, skipped: %v\n", report.Passed, report.Failed, report.Skipped)
+	}
+
+	if !report.Succeeded() {
+		return errors.Errorf("%v conformance tests failed", report.Failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/providervalidation"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryConformanceTest(t *testing.T) {
+	env := testenv.NewCLITest(t, nil, testenv.NewInProcRunner(t))
+
+	storageDir := testutil.TempDirectory(t)
+	reportFile := filepath.Join(testutil.TempDirectory(t), "report.json")
+
+	out := env.RunAndExpectSuccess(t, "repo", "conformance-test", "filesystem", "--path", storageDir,
+		"--test=range-reads", "--test=list-pagination", "--test=retention",
+		"--pagination-blobs=20", "--report-file", reportFile)
+
+	require.Contains(t, out[0], "RESULT")
+	require.Contains(t, strings.Join(out, "\n"), "Passed: 2, failed: 0, skipped: 1")
+
+	b, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+
+	var report providervalidation.ConformanceReport
+
+	require.NoError(t, json.Unmarshal(b, &report))
+	require.Len(t, report.Results, 3)
+	require.Equal(t, providervalidation.ConformanceSkipped, report.Results[2].Status)
+
+	var report2 providervalidation.ConformanceReport
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "conformance-test", "filesystem", "--path", storageDir, "--test=clock-skew", "--json"), &report2)
+	require.Equal(t, 1, report2.Passed)
+
+	env.RunAndExpectFailure(t, "repo", "conformance-test", "filesystem", "--path", storageDir, "--test=no-such-test")
+}
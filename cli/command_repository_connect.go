@@ -55,6 +55,7 @@ type connectOptions struct {
 	connectPermissiveCacheLoading bool
 	connectDescription            string
 	connectEnableActions          bool
+	connectLocalReplica           string
 
 	formatBlobCacheDuration time.Duration
 	disableFormatBlobCache  bool
@@ -77,6 +78,7 @@ func (c *connectOptions) setup(svc appServices, cmd *kingpin.CmdClause) {
 	cmd.Flag("permissive-cache-loading", "Do not fail when loading bad cache index entries.  Repository must be opened in read-only mode").Hidden().BoolVar(&c.connectPermissiveCacheLoading)
 	cmd.Flag("description", "Human-readable description of the repository").StringVar(&c.connectDescription)
 	cmd.Flag("enable-actions", "Allow snapshot actions").BoolVar(&c.connectEnableActions)
+	cmd.Flag("local-replica", "Path to a local copy of the repository (e.g. maintained by 'repository sync-to filesystem') to read pack blobs from before using the repository storage").PlaceHolder("PATH").StringVar(&c.connectLocalReplica)
 	cmd.Flag("repository-format-cache-duration", "Duration of kopia.repository format blob cache").Hidden().DurationVar(&c.formatBlobCacheDuration)
 	cmd.Flag("disable-repository-format-cache", "Disable caching of kopia.repository format blob").Hidden().BoolVar(&c.disableFormatBlobCache)
 }
@@ -110,6 +112,7 @@ func (c *connectOptions) toRepoConnectOptions() *repo.ConnectOptions {
 			Description:             c.connectDescription,
 			EnableActions:           c.connectEnableActions,
 			FormatBlobCacheDuration: c.getFormatBlobCacheDuration(),
+			LocalReplica:            c.connectLocalReplica,
 		},
 	}
 }
@@ -0,0 +1,61 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/clitestutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func (s *formatSpecificTestSuite) TestRepositoryLocalReplica(t *testing.T) {
+	env := testenv.NewCLITest(t, s.formatFlags, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	fileData := bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), fileData, 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--pack-checksums=true")
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	replicaDir := testutil.TempDirectory(t)
+	env.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", replicaDir)
+
+	env.RunAndExpectSuccess(t, "repo", "disconnect")
+	env.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--local-replica", replicaDir)
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Local replica:       "+replicaDir)
+
+	// unavailable replica, such as an unmounted drive, falls back to the repository storage.
+	require.NoError(t, os.Rename(replicaDir, replicaDir+".moved"))
+	env.RunAndExpectSuccess(t, "snapshot", "verify", "--verify-files-percent=100")
+	require.NoError(t, os.Rename(replicaDir+".moved", replicaDir))
+
+	// remove data packs from the repository, they can only be read from the replica now.
+	packs, err := filepath.Glob(filepath.Join(env.RepoDir, "p*", "*", "*.f"))
+	require.NoError(t, err)
+	require.NotEmpty(t, packs)
+
+	for _, p := range packs {
+		require.NoError(t, os.Remove(p))
+	}
+
+	snapID := clitestutil.ListSnapshotsAndExpectSuccess(t, env, dir)[0].Snapshots[0].SnapshotID
+
+	restoreDir := testutil.TempDirectory(t)
+	env.RunAndExpectSuccess(t, "restore", snapID, restoreDir)
+
+	restored, err := os.ReadFile(filepath.Join(restoreDir, "file1.txt"))
+	require.NoError(t, err)
+	require.Equal(t, fileData, restored)
+
+	// corrupted replica packs fail verification and are not used.
+	env.TweakFile(t, replicaDir, "p*/*/*.f")
+	env.RunAndExpectSuccess(t, "cache", "clear")
+	env.RunAndExpectFailure(t, "restore", snapID, testutil.TempDirectory(t))
+}
@@ -20,6 +20,7 @@ type commandRepositorySetParameters struct {
 	indexFormatVersion int
 	retentionMode      string
 	retentionPeriod    time.Duration
+	packChecksums      string
 
 	epochRefreshFrequency    time.Duration
 	epochMinDuration         time.Duration
@@ -46,6 +47,8 @@ func (c *commandRepositorySetParameters) setup(svc appServices, parent commandPa
 	cmd.Flag("retention-mode", "Set the blob retention-mode for supported storage backends.").EnumVar(&c.retentionMode, "none", blob.Governance.String(), blob.Compliance.String())
 	cmd.Flag("retention-period", "Set the blob retention-period for supported storage backends.").DurationVar(&c.retentionPeriod)
 
+	cmd.Flag("pack-checksums", "Record checksums of pack blobs for use by 'blob verify'").EnumVar(&c.packChecksums, "true", "false")
+
 	cmd.Flag("upgrade", "Upgrade repository to the latest stable format").BoolVar(&c.upgradeRepositoryFormat)
 
 	cmd.Flag("epoch-refresh-frequency", "Epoch refresh frequency").DurationVar(&c.epochRefreshFrequency)
@@ -100,6 +103,13 @@ func setDurationParameter(ctx context.Context, v time.Duration, desc string, dst
 	log(ctx).Infof(" - setting %v to %v.\n", desc, v)
 }
 
+func setBoolParameter(ctx context.Context, v bool, desc string, dst *bool, anyChange *bool) {
+	*dst = v
+	*anyChange = true
+
+	log(ctx).Infof(" - setting %v to %v.\n", desc, v)
+}
+
 func setRetentionModeParameter(ctx context.Context, v blob.RetentionMode, desc string, dst *blob.RetentionMode, anyChange *bool) {
 	if !v.IsValid() {
 		return
@@ -206,6 +216,10 @@ func (c *commandRepositorySetParameters) run(ctx context.Context, rep repo.Direc
 		setDurationParameter(ctx, c.retentionPeriod, "storage backend blob retention period", &blobcfg.RetentionPeriod, &anyChange)
 	}
 
+	if c.packChecksums != "" {
+		setBoolParameter(ctx, c.packChecksums == "true", "pack checksums", &mp.PackChecksums, &anyChange)
+	}
+
 	setDurationParameter(ctx, c.epochMinDuration, "minimum epoch duration", &mp.EpochParameters.MinEpochDuration, &anyChange)
 	setDurationParameter(ctx, c.epochRefreshFrequency, "epoch refresh frequency", &mp.EpochParameters.EpochRefreshFrequency, &anyChange)
 	setDurationParameter(ctx, c.epochCleanupSafetyMargin, "epoch cleanup safety margin", &mp.EpochParameters.CleanupSafetyMargin, &anyChange)
@@ -141,6 +141,10 @@ func (c *commandRepositoryStatus) run(ctx context.Context, rep repo.Repository)
 	c.out.printStdout("Username:            %v\n", rep.ClientOptions().Username)
 	c.out.printStdout("Read-only:           %v\n", rep.ClientOptions().ReadOnly)
 
+	if lr := rep.ClientOptions().LocalReplica; lr != "" {
+		c.out.printStdout("Local replica:       %v\n", lr)
+	}
+
 	t := rep.ClientOptions().FormatBlobCacheDuration
 	if t > 0 {
 		c.out.printStdout("Format blob cache:   %v\n", t)
@@ -192,6 +196,7 @@ func (c *commandRepositoryStatus) run(ctx context.Context, rep repo.Repository)
 
 	c.out.printStdout("Max pack length:     %v\n", units.BytesString(mp.MaxPackSize))
 	c.out.printStdout("Index Format:        v%v\n", mp.IndexVersion)
+	c.out.printStdout("Pack checksums:      %v\n", mp.PackChecksums)
 
 	emgr, epochMgrEnabled, emerr := dr.ContentReader().EpochManager(ctx)
 	if emerr != nil {
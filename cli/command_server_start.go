@@ -24,6 +24,7 @@
 	"github.com/kopia/kopia/notification"
 	"github.com/kopia/kopia/notification/sender/jsonsender"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot/snapshotfreshness"
 )
 
 const (
@@ -72,6 +73,11 @@ type commandServerStart struct {
 
 	shutdownGracePeriod  time.Duration
 	kopiauiNotifications bool
+	trackChanges         bool
+
+	staleSourceCheckInterval time.Duration
+	staleSourceGracePeriod   time.Duration
+	healthMetricsInterval    time.Duration
 
 	logServerRequests bool
 
@@ -128,6 +134,13 @@ func (c *commandServerStart) setup(svc advancedAppServices, parent commandParent
 
 	cmd.Flag("kopiaui-notifications", "Enable notifications to be printed to stdout for KopiaUI").BoolVar(&c.kopiauiNotifications)
 
+	cmd.Flag("track-changes", "Track changes to local sources, so that snapshots skip unchanged directories (Linux only)").BoolVar(&c.trackChanges)
+
+	cmd.Flag("stale-source-check-interval", "How often to check for sources that are overdue for a snapshot (0 disables)").Default("1h").DurationVar(&c.staleSourceCheckInterval)
+	cmd.Flag("stale-source-grace-period", "Amount of time a snapshot may be late before the source is considered stale").Default(snapshotfreshness.DefaultGracePeriod.String()).DurationVar(&c.staleSourceGracePeriod)
+
+	cmd.Flag("health-metrics-interval", "How often to update Prometheus metrics describing snapshots and repository contents, should be used with --metrics-listen-addr (0 disables)").Default("0").DurationVar(&c.healthMetricsInterval)
+
 	c.sf.setup(svc, cmd)
 	c.co.setup(svc, cmd)
 	c.svc = svc
@@ -169,6 +182,10 @@ func (c *commandServerStart) serverStartOptions(ctx context.Context) (*server.Op
 
 		EnableErrorNotifications: c.svc.enableErrorNotifications(),
 		NotifyTemplateOptions:    c.svc.notificationTemplateOptions(),
+		TrackChanges:             c.trackChanges,
+		StaleSourceCheckInterval: c.staleSourceCheckInterval,
+		StaleSourceGracePeriod:   c.staleSourceGracePeriod,
+		HealthMetricsInterval:    c.healthMetricsInterval,
 	}, nil
 }
 
@@ -1,22 +1,27 @@
 package cli
 
 type commandSnapshot struct {
-	copyHistory commandSnapshotCopyMoveHistory
-	moveHistory commandSnapshotCopyMoveHistory
-	create      commandSnapshotCreate
-	delete      commandSnapshotDelete
-	estimate    commandSnapshotEstimate
-	expire      commandSnapshotExpire
-	fix         commandSnapshotFix
-	list        commandSnapshotList
-	migrate     commandSnapshotMigrate
-	pin         commandSnapshotPin
-	restore     commandSnapshotRestore
-	verify      commandSnapshotVerify
+	checkFreshness commandSnapshotCheckFreshness
+	copyHistory    commandSnapshotCopyMoveHistory
+	moveHistory    commandSnapshotCopyMoveHistory
+	create         commandSnapshotCreate
+	delete         commandSnapshotDelete
+	estimate       commandSnapshotEstimate
+	expire         commandSnapshotExpire
+	fix            commandSnapshotFix
+	hold           commandSnapshotHold
+	inventory      commandSnapshotInventory
+	list           commandSnapshotList
+	migrate        commandSnapshotMigrate
+	pin            commandSnapshotPin
+	restore        commandSnapshotRestore
+	trackChanges   commandSnapshotTrackChanges
+	verify         commandSnapshotVerify
 }
 
 func (c *commandSnapshot) setup(svc advancedAppServices, parent commandParent) {
 	cmd := parent.Command("snapshot", "Commands to manipulate snapshots.").Alias("snap")
+	c.checkFreshness.setup(svc, cmd)
 	c.copyHistory.setup(svc, cmd, false)
 	c.moveHistory.setup(svc, cmd, true)
 	c.create.setup(svc, cmd)
@@ -24,9 +29,12 @@ func (c *commandSnapshot) setup(svc advancedAppServices, parent commandParent) {
 	c.estimate.setup(svc, cmd)
 	c.expire.setup(svc, cmd)
 	c.fix.setup(svc, cmd)
+	c.hold.setup(svc, cmd)
+	c.inventory.setup(svc, cmd)
 	c.list.setup(svc, cmd)
 	c.migrate.setup(svc, cmd)
 	c.pin.setup(svc, cmd)
 	c.restore.setup(svc, cmd)
+	c.trackChanges.setup(svc, cmd)
 	c.verify.setup(svc, cmd)
 }
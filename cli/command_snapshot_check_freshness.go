@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfreshness"
+)
+
+type commandSnapshotCheckFreshness struct {
+	sources          []string
+	gracePeriod      time.Duration
+	sendNotification bool
+
+	jo  jsonOutput
+	out textOutput
+	svc appServices
+}
+
+func (c *commandSnapshotCheckFreshness) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("check-freshness", "Check that the latest snapshots of sources are not older than their scheduling policies permit.")
+	cmd.Arg("source", "Sources to check (defaults to all sources in the repository)").StringsVar(&c.sources)
+	cmd.Flag("grace-period", "Amount of time a snapshot may be late before the source is considered stale").Default(snapshotfreshness.DefaultGracePeriod.String()).DurationVar(&c.gracePeriod)
+	cmd.Flag("send-notification", "Send a notification listing stale sources using configured notification profiles").Default("true").BoolVar(&c.sendNotification)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	c.svc = svc
+
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandSnapshotCheckFreshness) run(ctx context.Context, rep repo.Repository) error {
+	var sources []snapshot.SourceInfo
+
+	for _, s := range c.sources {
+		si, err := snapshot.ParseSourceInfo(s, rep.ClientOptions().Hostname, rep.ClientOptions().Username)
+		if err != nil {
+			return errors.Wrapf(err, "invalid source: %q", s)
+		}
+
+		sources = append(sources, si)
+	}
+
+	st, err := snapshotfreshness.FindStaleSources(ctx, rep, sources, clock.Now(), c.gracePeriod)
+	if err != nil {
+		return errors.Wrap(err, "unable to check freshness of sources")
+	}
+
+	if len(st.Sources) > 0 && c.sendNotification {
+		notification.Send(ctx, rep, "stale-source", st, notification.SeverityWarning, c.svc.notificationTemplateOptions())
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(st))
+
+		return nil
+	}
+
+	if len(st.Sources) == 0 {
+		c.out.printStderr("All sources are up to date.\n")
+
+		return nil
+	}
+
+	for _, s := range st.Sources {
+		last := "never"
+		if !s.LastSnapshotTime.IsZero() {
+			last = formatTimestamp(s.LastSnapshotTime)
+		}
+
+		c.out.printStdout("%v\n  last snapshot: %v\n  due: %v (overdue by %v)\n", s.Source, last, formatTimestamp(s.DueTime), s.Overdue())
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotCheckFreshness(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "notification", "profile", "configure", "testsender", "--profile-name=mysender", "--min-severity=warning")
+
+	srcdir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcdir, "some-file"), []byte{1, 2, 3}, 0o755))
+
+	e.RunAndExpectSuccess(t, "policy", "set", srcdir, "--snapshot-interval=1h")
+	e.RunAndExpectSuccess(t, "snapshot", "create", srcdir, "--no-send-snapshot-report")
+
+	n := len(e.NotificationsSent())
+
+	var st notifydata.StaleSources
+
+	// the snapshot was just taken, so it's not due for another hour.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "check-freshness", "--json"), &st)
+	require.Empty(t, st.Sources)
+	require.Len(t, e.NotificationsSent(), n)
+
+	// negative grace period makes the source stale up to two hours before the snapshot is due.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "check-freshness", "--json", "--grace-period=-2h"), &st)
+	require.Len(t, st.Sources, 1)
+	require.Equal(t, srcdir, st.Sources[0].Source.Path)
+	require.Len(t, e.NotificationsSent(), n+1)
+	require.Contains(t, e.NotificationsSent()[n].Subject, "is overdue")
+
+	// sources may be listed explicitly.
+	e.RunAndExpectSuccess(t, "snapshot", "check-freshness", "--grace-period=-2h", "--no-send-notification", srcdir)
+	require.Len(t, e.NotificationsSent(), n+1)
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "check-freshness", "--json", "--grace-period=-2h", "/no/such/path"), &st)
+	require.Empty(t, st.Sources)
+}
@@ -7,8 +7,11 @@
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 )
 
 type commandSnapshotCopyMoveHistory struct {
@@ -93,6 +96,19 @@ func (c *commandSnapshotCopyMoveHistory) run(ctx context.Context, rep repo.Repos
 		return errors.Wrap(err, "error listing source snapshots")
 	}
 
+	if isMoveCommand {
+		var srcIDs []manifest.ID
+
+		for _, m := range srcSnapshots {
+			srcIDs = append(srcIDs, m.ID)
+		}
+
+		// moving deletes source snapshots, which is not permitted while they are held.
+		if err := legalhold.CheckNotHeld(ctx, rep, clock.Now(), srcIDs...); err != nil {
+			return errors.Wrap(err, "unable to move snapshots")
+		}
+	}
+
 	dstSnapshots, err := snapshot.ListSnapshots(ctx, rep, di)
 	if err != nil {
 		return errors.Wrap(err, "error listing destination snapshots")
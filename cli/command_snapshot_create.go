@@ -11,7 +11,10 @@
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/blockdev"
 	"github.com/kopia/kopia/fs/virtualfs"
+	"github.com/kopia/kopia/internal/changejournal"
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/notification"
 	"github.com/kopia/kopia/notification/notifydata"
 	"github.com/kopia/kopia/repo"
@@ -38,11 +41,13 @@ type commandSnapshotCreate struct {
 	snapshotCreateForceEnableActions      bool
 	snapshotCreateForceDisableActions     bool
 	snapshotCreateStdinFileName           string
+	snapshotCreateBlockDevice             bool
 	snapshotCreateCheckpointUploadLimitMB int64
 	snapshotCreateTags                    []string
 	flushPerSource                        bool
 	sourceOverride                        string
 	sendSnapshotReport                    bool
+	changeJournal                         string
 
 	pins []string
 
@@ -64,12 +69,14 @@ func (c *commandSnapshotCreate) setup(svc appServices, parent commandParent) {
 	cmd.Flag("description", "Free-form snapshot description.").StringVar(&c.snapshotCreateDescription)
 	cmd.Flag("fail-fast", "Fail fast when creating snapshot.").Envar(svc.EnvName("KOPIA_SNAPSHOT_FAIL_FAST")).BoolVar(&c.snapshotCreateFailFast)
 	cmd.Flag("force-hash", "Force hashing of source files for a given percentage of files [0.0 .. 100.0]").Default("0").Float64Var(&c.snapshotCreateForceHash)
+	cmd.Flag("change-journal", "Skip directories reported as unchanged since the previous snapshot by a journal written by 'snapshot track-changes'").StringVar(&c.changeJournal)
 	cmd.Flag("parallel", "Upload N files in parallel").PlaceHolder("N").Default("0").IntVar(&c.snapshotCreateParallelUploads)
 	cmd.Flag("start-time", "Override snapshot start timestamp.").StringVar(&c.snapshotCreateStartTime)
 	cmd.Flag("end-time", "Override snapshot end timestamp.").StringVar(&c.snapshotCreateEndTime)
 	cmd.Flag("force-enable-actions", "Enable snapshot actions even if globally disabled on this client").Hidden().BoolVar(&c.snapshotCreateForceEnableActions)
 	cmd.Flag("force-disable-actions", "Disable snapshot actions even if globally enabled on this client").Hidden().BoolVar(&c.snapshotCreateForceDisableActions)
 	cmd.Flag("stdin-file", "File path to be used for stdin data snapshot.").StringVar(&c.snapshotCreateStdinFileName)
+	cmd.Flag("block-device", "Snapshot the source block device or disk image as a sparse image, restore it with --write-sparse-files.").BoolVar(&c.snapshotCreateBlockDevice)
 	cmd.Flag("tags", "Tags applied on the snapshot. Must be provided in the <key>:<value> format.").StringsVar(&c.snapshotCreateTags)
 	cmd.Flag("pin", "Create a pinned snapshot that will not expire automatically").StringsVar(&c.pins)
 	cmd.Flag("flush-per-source", "Flush writes at the end of each source").Hidden().BoolVar(&c.flushPerSource)
@@ -122,7 +129,10 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 		return errors.New("description too long")
 	}
 
-	u := c.setupUploader(rep)
+	u, err := c.setupUploader(rep)
+	if err != nil {
+		return err
+	}
 
 	var finalErrors []string
 
@@ -150,7 +160,7 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 	}
 
 	if c.sendSnapshotReport {
-		notification.Send(ctx, rep, "snapshot-report", st, c.reportSeverity(st), c.svc.notificationTemplateOptions())
+		notification.Send(ctx, rep, "snapshot-report", st, notification.SnapshotReportSeverity(st), c.svc.notificationTemplateOptions())
 	}
 
 	// ensure we flush at least once in the session to properly close all pending buffers,
@@ -173,17 +183,6 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 	return errors.Errorf("encountered %v errors:\n%v", len(finalErrors), strings.Join(finalErrors, "\n"))
 }
 
-func (c *commandSnapshotCreate) reportSeverity(st notifydata.MultiSnapshotStatus) notification.Severity {
-	switch st.OverallStatusCode() {
-	case notifydata.StatusCodeFatal:
-		return notification.SeverityError
-	case notifydata.StatusCodeWarnings:
-		return notification.SeverityWarning
-	default:
-		return notification.SeverityReport
-	}
-}
-
 func getTags(tagStrings []string) (map[string]string, error) {
 	numberOfPartsInTagString := 2
 	// tagKeyPrefix is the prefix for user defined tag keys.
@@ -226,18 +225,21 @@ func validateStartEndTime(st, et string) error {
 	return nil
 }
 
-func (c *commandSnapshotCreate) setupUploader(rep repo.RepositoryWriter) *upload.Uploader {
-	u := upload.NewUploader(rep)
-	u.MaxUploadBytes = c.snapshotCreateCheckpointUploadLimitMB << 20 //nolint:mnd
-
-	if c.snapshotCreateForceEnableActions {
-		u.EnableActions = true
-	}
-
+// actionsEnabled determines whether actions and command sources can run, which is configured
+// when connecting to the repository and can be overridden using flags.
+func (c *commandSnapshotCreate) actionsEnabled(rep repo.Repository) bool {
 	if c.snapshotCreateForceDisableActions {
-		u.EnableActions = false
+		return false
 	}
 
+	return c.snapshotCreateForceEnableActions || rep.ClientOptions().EnableActions
+}
+
+func (c *commandSnapshotCreate) setupUploader(rep repo.RepositoryWriter) (*upload.Uploader, error) {
+	u := upload.NewUploader(rep)
+	u.MaxUploadBytes = c.snapshotCreateCheckpointUploadLimitMB << 20 //nolint:mnd
+	u.EnableActions = c.actionsEnabled(rep)
+
 	if l := c.logDirDetail; l != -1 {
 		ld := policy.LogDetail(l)
 
@@ -262,7 +264,16 @@ func (c *commandSnapshotCreate) setupUploader(rep repo.RepositoryWriter) *upload
 	u.FailFast = c.snapshotCreateFailFast
 	u.Progress = c.svc.getProgress()
 
-	return u
+	if c.changeJournal != "" {
+		tracker, err := changejournal.LoadJournal(c.changeJournal)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load change journal")
+		}
+
+		u.ChangeSource = tracker
+	}
+
+	return u, nil
 }
 
 func parseTimestamp(timestamp string) (time.Time, error) {
@@ -321,6 +332,10 @@ func (c *commandSnapshotCreate) snapshotSingleSource(
 		return errors.Wrap(finalErr, "unable to get policy tree")
 	}
 
+	if finalErr = c.quotaPreflight(ctx, rep, fsEntry, policyTree, sourceInfo, previous); finalErr != nil {
+		return finalErr
+	}
+
 	manifest, finalErr := u.Upload(ctx, fsEntry, policyTree, sourceInfo, previous...)
 	if finalErr != nil {
 		// fail-fast uploads will fail here without recording a manifest, other uploads will
@@ -389,6 +404,35 @@ func (c *commandSnapshotCreate) snapshotSingleSource(
 	return c.reportSnapshotStatus(ctx, manifest)
 }
 
+// quotaPreflight estimates the size of the source before uploading it and fails or warns when it exceeds quotas.
+func (c *commandSnapshotCreate) quotaPreflight(ctx context.Context, rep repo.Repository, fsEntry fs.Entry, policyTree *policy.Tree, sourceInfo snapshot.SourceInfo, previous []*snapshot.Manifest) error {
+	t0 := clock.Now()
+
+	res, err := upload.QuotaPreflight(ctx, fsEntry, policyTree, previous...)
+	if res == nil || len(res.Violations) == 0 {
+		return err //nolint:wrapcheck
+	}
+
+	// violations fail the snapshot in the "fail" mode and are only reported in the "warn" mode.
+	severity := notification.SeverityWarning
+	if err != nil {
+		severity = notification.SeverityError
+	}
+
+	log(ctx).Warnf("Pre-flight quota check for %v: %v", sourceInfo, res.Error())
+
+	notification.Send(ctx, rep, "generic-error", notifydata.NewErrorInfo(
+		"Snapshot Pre-flight Quota Check",
+		sourceInfo.String(),
+		t0,
+		clock.Now(),
+		res.Error()), severity,
+		c.svc.notificationTemplateOptions(),
+	)
+
+	return err //nolint:wrapcheck
+}
+
 func (c *commandSnapshotCreate) reportSnapshotStatus(ctx context.Context, manifest *snapshot.Manifest) error {
 	var maybePartial string
 	if manifest.IncompleteReason != "" {
@@ -487,7 +531,26 @@ func (c *commandSnapshotCreate) getContentToSnapshot(ctx context.Context, dir st
 			virtualfs.StreamingFileFromReader(c.snapshotCreateStdinFileName, io.NopCloser(c.svc.stdin())),
 		})
 		setManual = true
+	} else if c.snapshotCreateBlockDevice {
+		fsEntry, err = blockdev.NewEntry(absDir)
+		if err != nil {
+			return nil, info, false, errors.Wrap(err, "unable to get block device entry")
+		}
 	} else {
+		pol, _, _, err := policy.GetEffectivePolicy(ctx, rep, info)
+		if err != nil {
+			return nil, info, false, errors.Wrap(err, "unable to get effective policy")
+		}
+
+		if pol.CommandSource.IsCommandSource() {
+			if !c.actionsEnabled(rep) {
+				return nil, info, false, upload.ErrCommandSourceDisabled
+			}
+
+			// command source will be snapshotted using a virtual root directory with streaming files holding command output
+			return upload.NewCommandSource(filepath.Base(info.Path), pol.CommandSource.Streams), info, setManual, nil
+		}
+
 		fsEntry, err = getLocalFSEntry(ctx, absDir)
 		if err != nil {
 			return nil, info, false, errors.Wrap(err, "unable to get local filesystem entry")
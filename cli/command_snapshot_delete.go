@@ -6,10 +6,12 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 )
 
 type commandSnapshotDelete struct {
@@ -84,6 +86,10 @@ func (c *commandSnapshotDelete) snapshotDeleteSources(ctx context.Context, rep r
 func (c *commandSnapshotDelete) deleteSnapshot(ctx context.Context, rep repo.RepositoryWriter, m *snapshot.Manifest) error {
 	desc := fmt.Sprintf("snapshot %v of %v at %v", m.ID, m.Source, formatTimestamp(m.StartTime.ToTime()))
 
+	if err := legalhold.CheckNotHeld(ctx, rep, clock.Now(), m.ID); err != nil {
+		return errors.Wrapf(err, "unable to delete %v", desc)
+	}
+
 	if !c.snapshotDeleteConfirm {
 		log(ctx).Infof("Would delete %v (pass --delete to confirm)", desc)
 		return nil
@@ -6,10 +6,12 @@
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/policy"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
@@ -115,8 +117,14 @@ func (c *commonRewriteSnapshots) rewriteMatchingSnapshots(ctx context.Context, r
 			}
 
 			if c.commit {
-				if err := snapshot.UpdateSnapshot(ctx, rep, man); err != nil {
-					return errors.Wrap(err, "error updating snapshot")
+				if err := legalhold.UpdateSnapshot(ctx, rep, man, clock.Now()); err != nil {
+					if errors.Is(err, legalhold.ErrSnapshotHeld) {
+						log(ctx).Warnf("  %v not updated: %v", formatTimestamp(man.StartTime.ToTime()), err)
+
+						continue
+					}
+
+					return err
 				}
 			}
 
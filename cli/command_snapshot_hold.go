@@ -0,0 +1,14 @@
+package cli
+
+type commandSnapshotHold struct {
+	add     commandSnapshotHoldAdd
+	list    commandSnapshotHoldList
+	release commandSnapshotHoldRelease
+}
+
+func (c *commandSnapshotHold) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("hold", "Manage legal holds preventing deletion of snapshots and their contents")
+	c.add.setup(svc, cmd)
+	c.list.setup(svc, cmd)
+	c.release.setup(svc, cmd)
+}
@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+type commandSnapshotHoldAdd struct {
+	snapshotIDs      []string
+	reason           string
+	owner            string
+	expiresIn        string
+	extendObjectLock bool
+	objectLockMode   string
+}
+
+func (c *commandSnapshotHoldAdd) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("add", "Place a legal hold on snapshots")
+	cmd.Arg("id", "Snapshot ID").Required().StringsVar(&c.snapshotIDs)
+	cmd.Flag("reason", "Reason for the hold").Required().StringVar(&c.reason)
+	cmd.Flag("owner", "Owner of the hold (defaults to current user@hostname)").StringVar(&c.owner)
+	cmd.Flag("expires-in", "Duration after which the hold expires, such as 90d or 7y (defaults to never)").StringVar(&c.expiresIn)
+	cmd.Flag("extend-object-lock", "Extend object lock on existing pack blobs of the snapshot until the hold expires (contents later moved by maintenance are not locked again)").BoolVar(&c.extendObjectLock)
+	cmd.Flag("object-lock-mode", "Object lock mode (defaults to the repository retention mode or COMPLIANCE)").EnumVar(&c.objectLockMode, blob.Governance.String(), blob.Compliance.String())
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandSnapshotHoldAdd) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	now := clock.Now()
+
+	var expires time.Time
+
+	if c.expiresIn != "" {
+		d, err := policy.ParseDuration(c.expiresIn)
+		if err != nil {
+			return errors.Wrap(err, "invalid --expires-in")
+		}
+
+		expires = now.Add(d)
+	}
+
+	if c.extendObjectLock && expires.IsZero() {
+		return errors.New("--extend-object-lock requires --expires-in")
+	}
+
+	owner := c.owner
+	if owner == "" {
+		owner = rep.ClientOptions().UsernameAtHost()
+	}
+
+	for _, id := range c.snapshotIDs {
+		m, err := snapshot.LoadSnapshot(ctx, rep, manifest.ID(id))
+		if err != nil {
+			return errors.Wrapf(err, "error loading snapshot %v", id)
+		}
+
+		h := &legalhold.Hold{
+			Reason:      c.reason,
+			Owner:       owner,
+			CreatedTime: now,
+			ExpiresTime: expires,
+			Snapshot:    m,
+		}
+
+		if c.extendObjectLock {
+			if err := c.extendRetention(ctx, rep, h, now); err != nil {
+				return errors.Wrapf(err, "error extending object lock for snapshot %v", id)
+			}
+		}
+
+		holdID, err := legalhold.Add(ctx, rep, h)
+		if err != nil {
+			return errors.Wrapf(err, "error placing legal hold on snapshot %v", id)
+		}
+
+		if c.extendObjectLock {
+			if err := c.extendManifestRetention(ctx, rep, h, now); err != nil {
+				return errors.Wrapf(err, "error extending object lock for legal hold %v", holdID)
+			}
+		}
+
+		log(ctx).Infof("Placed legal hold %v on snapshot %v of %v at %v", holdID, m.ID, m.Source, formatTimestamp(m.StartTime.ToTime()))
+	}
+
+	return nil
+}
+
+// extendRetention extends object lock on all pack blobs holding contents of the held snapshot until the hold expires.
+// Pack blobs written later by maintenance when rewriting or compacting contents are not covered.
+func (c *commandSnapshotHoldAdd) extendRetention(ctx context.Context, rep repo.RepositoryWriter, h *legalhold.Hold, now time.Time) error {
+	dw, ok := rep.(repo.DirectRepositoryWriter)
+	if !ok {
+		return errors.New("extending object lock requires direct repository connection")
+	}
+
+	packs, err := findSnapshotPackBlobs(ctx, dw, h.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err := c.extendPackRetention(ctx, dw, packs, h, now); err != nil {
+		return err
+	}
+
+	h.RetainedUntil = h.ExpiresTime
+
+	return nil
+}
+
+// extendManifestRetention extends object lock on pack blobs holding manifests, which include the manifest
+// of the held snapshot and the hold itself, so that neither can be removed from storage until the hold expires.
+// Since manifests of many snapshots share pack blobs, this locks all current manifest pack blobs.
+// Manifests rewritten later by compaction are not locked, but their original pack blobs remain.
+func (c *commandSnapshotHoldAdd) extendManifestRetention(ctx context.Context, rep repo.RepositoryWriter, h *legalhold.Hold, now time.Time) error {
+	dw, ok := rep.(repo.DirectRepositoryWriter)
+	if !ok {
+		return errors.New("extending object lock requires direct repository connection")
+	}
+
+	// make sure the hold manifest is written to a pack blob.
+	if err := dw.Flush(ctx); err != nil {
+		return errors.Wrap(err, "unable to flush repository")
+	}
+
+	packs := map[blob.ID]bool{}
+
+	if err := dw.ContentReader().IterateContents(ctx, content.IterateOptions{
+		Range: index.PrefixRange(manifest.ContentPrefix),
+	}, func(ci content.Info) error {
+		packs[ci.PackBlobID] = true
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "unable to list manifest contents")
+	}
+
+	return c.extendPackRetention(ctx, dw, sortedBlobIDs(packs), h, now)
+}
+
+// extendPackRetention extends object lock on the provided pack blobs until the hold expires.
+func (c *commandSnapshotHoldAdd) extendPackRetention(ctx context.Context, dw repo.DirectRepositoryWriter, packs []blob.ID, h *legalhold.Hold, now time.Time) error {
+	mode := blob.RetentionMode(c.objectLockMode)
+
+	if mode == "" {
+		blobCfg, err := dw.FormatManager().BlobCfgBlob(ctx)
+		if err != nil {
+			return errors.Wrap(err, "blob configuration")
+		}
+
+		mode = blob.Compliance
+
+		if blobCfg.IsRetentionEnabled() {
+			mode = blobCfg.RetentionMode
+		}
+	}
+
+	opts := blob.ExtendOptions{
+		RetentionMode:   mode,
+		RetentionPeriod: h.ExpiresTime.Sub(now),
+	}
+
+	for _, packID := range packs {
+		if err := dw.BlobStorage().ExtendBlobRetention(ctx, packID, opts); err != nil {
+			return errors.Wrapf(err, "unable to extend retention of %v", packID)
+		}
+	}
+
+	log(ctx).Infof("Extended %v object lock on %v pack blobs until %v", mode, len(packs), formatTimestamp(h.ExpiresTime))
+
+	return nil
+}
+
+// findSnapshotPackBlobs returns sorted IDs of pack blobs holding contents reachable from the provided snapshot.
+func findSnapshotPackBlobs(ctx context.Context, rep repo.Repository, m *snapshot.Manifest) ([]blob.ID, error) {
+	var (
+		mu    sync.Mutex
+		packs = map[blob.ID]bool{}
+	)
+
+	w, err := snapshotfs.NewTreeWalker(ctx, snapshotfs.TreeWalkerOptions{
+		EntryCallback: func(ctx context.Context, _ fs.Entry, oid object.ID, _ string) error {
+			contentIDs, err := rep.VerifyObject(ctx, oid)
+			if err != nil {
+				return errors.Wrapf(err, "error verifying %v", oid)
+			}
+
+			for _, cid := range contentIDs {
+				ci, err := rep.ContentInfo(ctx, cid)
+				if err != nil {
+					return errors.Wrapf(err, "unable to get content info for %v", cid)
+				}
+
+				mu.Lock()
+				packs[ci.PackBlobID] = true
+				mu.Unlock()
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create tree walker")
+	}
+
+	defer w.Close(ctx)
+
+	root, err := snapshotfs.SnapshotRoot(rep, m)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get snapshot root")
+	}
+
+	if err := w.Process(ctx, root, ""); err != nil {
+		return nil, errors.Wrap(err, "error processing snapshot root")
+	}
+
+	return sortedBlobIDs(packs), nil
+}
+
+func sortedBlobIDs(ids map[blob.ID]bool) []blob.ID {
+	var result []blob.ID
+
+	for id := range ids {
+		result = append(result, id)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return result
+}
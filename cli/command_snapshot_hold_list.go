@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot/legalhold"
+)
+
+type commandSnapshotHoldList struct {
+	activeOnly bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandSnapshotHoldList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List legal holds").Alias("ls")
+	cmd.Flag("active", "Only list holds which did not expire").BoolVar(&c.activeOnly)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandSnapshotHoldList) run(ctx context.Context, rep repo.Repository) error {
+	now := clock.Now()
+
+	holds, err := legalhold.List(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to list legal holds")
+	}
+
+	var jl jsonList
+
+	if c.jo.jsonOutput {
+		jl.begin(&c.jo)
+		defer jl.end()
+	}
+
+	for _, h := range holds {
+		if c.activeOnly && !h.IsActive(now) {
+			continue
+		}
+
+		if c.jo.jsonOutput {
+			jl.emit(h)
+			continue
+		}
+
+		status := "active"
+		if !h.IsActive(now) {
+			status = "expired"
+		}
+
+		expires := "never"
+		if !h.ExpiresTime.IsZero() {
+			expires = formatTimestamp(h.ExpiresTime)
+		}
+
+		c.out.printStdout("%v %v snapshot %v of %v at %v\n", h.ID, status, h.Snapshot.ID, h.Snapshot.Source, formatTimestamp(h.Snapshot.StartTime.ToTime()))
+		c.out.printStdout("  Reason:  %v\n", h.Reason)
+		c.out.printStdout("  Owner:   %v\n", h.Owner)
+		c.out.printStdout("  Created: %v\n", formatTimestamp(h.CreatedTime))
+		c.out.printStdout("  Expires: %v\n", expires)
+
+		if !h.RetainedUntil.IsZero() {
+			c.out.printStdout("  Object lock until: %v\n", formatTimestamp(h.RetainedUntil))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot/legalhold"
+)
+
+type commandSnapshotHoldRelease struct {
+	holdIDs []string
+	force   bool
+}
+
+func (c *commandSnapshotHoldRelease) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("release", "Release legal holds")
+	cmd.Arg("id", "Legal hold ID").Required().StringsVar(&c.holdIDs)
+	cmd.Flag("force", "Release holds which did not expire yet, requires direct repository connection").BoolVar(&c.force)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandSnapshotHoldRelease) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	for _, id := range c.holdIDs {
+		if err := legalhold.Release(ctx, rep, manifest.ID(id), clock.Now(), c.force); err != nil {
+			return errors.Wrapf(err, "error releasing legal hold %v", id)
+		}
+
+		log(ctx).Infof("Released legal hold %v", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot/legalhold"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotHold(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	srcdir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcdir, "some-file"), []byte{1, 2, 3}, 0o755))
+
+	e.RunAndExpectSuccess(t, "policy", "set", srcdir, "--keep-latest=1", "--keep-hourly=0", "--keep-daily=0", "--keep-monthly=0", "--keep-weekly=0", "--keep-annual=0")
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", srcdir)
+
+	snapshots := mustListSnapshots(t, e)
+	require.Len(t, snapshots, 1)
+
+	heldID := string(snapshots[0].ID)
+
+	e.RunAndExpectFailure(t, "snapshot", "hold", "add", heldID)
+	e.RunAndExpectFailure(t, "snapshot", "hold", "add", heldID, "--reason=litigation", "--extend-object-lock")
+	e.RunAndExpectSuccess(t, "snapshot", "hold", "add", heldID, "--reason=litigation", "--owner=legal@example", "--expires-in=30d")
+
+	var holds []*legalhold.Hold
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "hold", "list", "--json"), &holds)
+	require.Len(t, holds, 1)
+	require.Equal(t, "litigation", holds[0].Reason)
+	require.Equal(t, "legal@example", holds[0].Owner)
+	require.Equal(t, heldID, string(holds[0].Snapshot.ID))
+	require.False(t, holds[0].ExpiresTime.IsZero())
+
+	holdID := string(holds[0].ID)
+
+	lines := e.RunAndExpectSuccess(t, "snapshot", "hold", "list")
+	require.Contains(t, lines[0], holdID+" active snapshot "+heldID+" of "+snapshots[0].Source.String())
+	require.Contains(t, lines, "  Reason:  litigation")
+
+	// held snapshot can't be deleted, neither can the hold be removed without releasing it.
+	e.RunAndExpectFailure(t, "snapshot", "delete", heldID, "--delete")
+	e.RunAndExpectFailure(t, "manifest", "rm", heldID)
+	e.RunAndExpectFailure(t, "manifest", "rm", holdID)
+
+	// held snapshot can't be updated either.
+	e.RunAndExpectFailure(t, "snapshot", "pin", heldID, "--add=keep")
+
+	// held snapshot is not expired, even though it's not retained by policy.
+	e.RunAndExpectSuccess(t, "snapshot", "create", srcdir)
+	e.RunAndExpectSuccess(t, "snapshot", "expire", srcdir, "--delete")
+	require.Len(t, mustListSnapshots(t, e), 2)
+
+	// active hold can only be released with --force.
+	e.RunAndExpectFailure(t, "snapshot", "hold", "release", holdID)
+	e.RunAndExpectSuccess(t, "snapshot", "hold", "release", holdID, "--force")
+	require.Empty(t, e.RunAndExpectSuccess(t, "snapshot", "hold", "list"))
+
+	e.RunAndExpectSuccess(t, "snapshot", "expire", srcdir, "--delete")
+	require.Len(t, mustListSnapshots(t, e), 1)
+}
+
+func TestSnapshotHoldExtendObjectLock(t *testing.T) {
+	// retention helper is a no-op command.
+	testutil.SkipTestUnlessLinux(t)
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--retention-helper=true")
+
+	srcdir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcdir, "some-file"), []byte{1, 2, 3}, 0o755))
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", srcdir)
+
+	snapshots := mustListSnapshots(t, e)
+	require.Len(t, snapshots, 1)
+
+	e.RunAndExpectSuccess(t, "snapshot", "hold", "add", string(snapshots[0].ID), "--reason=audit", "--expires-in=1d", "--extend-object-lock")
+
+	var holds []*legalhold.Hold
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "hold", "list", "--json"), &holds)
+	require.Len(t, holds, 1)
+	require.Equal(t, holds[0].ExpiresTime, holds[0].RetainedUntil)
+
+	// hold can't be released while the object lock is in effect, even with --force.
+	e.RunAndExpectFailure(t, "snapshot", "hold", "release", string(holds[0].ID), "--force")
+	e.RunAndExpectFailure(t, "manifest", "rm", string(holds[0].ID))
+}
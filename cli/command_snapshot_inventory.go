@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+const (
+	inventoryFormatCSV      = "csv"
+	inventoryFormatJSONL    = "jsonl"
+	inventoryFormatColumnar = "columnar"
+
+	// number of rows in each row group written in the columnar format.
+	inventoryRowGroupSize = 10000
+
+	// name of the file in the cache directory that holds hashes of previously-read objects.
+	inventoryCacheFile = "inventory-sha256"
+)
+
+//nolint:gochecknoglobals
+var inventoryColumns = []string{"snapshot", "path", "type", "size", "mtime", "uid", "gid", "mode", "sha256", "object"}
+
+type commandSnapshotInventory struct {
+	snapshotIDs []string
+	format      string
+	outputFile  string
+
+	svc appServices
+	out textOutput
+}
+
+func (c *commandSnapshotInventory) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("inventory", "List all files in snapshots along with SHA-256 hashes of their contents.")
+	cmd.Arg("snapshot-id", "Snapshot IDs to list").Required().StringsVar(&c.snapshotIDs)
+	cmd.Flag("format", "Output format: 'csv', 'jsonl' (one JSON object per file) or 'columnar' (one JSON object per group of rows, with one array per column)").Default(inventoryFormatCSV).EnumVar(&c.format, inventoryFormatCSV, inventoryFormatJSONL, inventoryFormatColumnar)
+	cmd.Flag("output", "Write inventory to the provided file instead of standard output").StringVar(&c.outputFile)
+
+	c.svc = svc
+	c.out.setup(svc)
+
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandSnapshotInventory) run(ctx context.Context, rep repo.Repository) (finalErr error) {
+	manifestIDs := toManifestIDs(c.snapshotIDs)
+
+	manifests, err := snapshot.LoadSnapshots(ctx, rep, manifestIDs)
+	if err != nil {
+		return errors.Wrap(err, "unable to load snapshot manifests")
+	}
+
+	if len(manifests) != len(manifestIDs) {
+		return errors.Errorf("found %d of the %d requested snapshot IDs", len(manifests), len(manifestIDs))
+	}
+
+	out := c.out.stdout()
+
+	if c.outputFile != "" {
+		f, err := os.Create(c.outputFile)
+		if err != nil {
+			return errors.Wrap(err, "unable to create output file")
+		}
+
+		defer func() {
+			if err := f.Close(); err != nil && finalErr == nil {
+				finalErr = errors.Wrap(err, "unable to close output file")
+			}
+		}()
+
+		out = f
+	}
+
+	bw := bufio.NewWriter(out)
+	w := newInventoryWriter(c.format, bw)
+
+	b, err := snapshotfs.NewInventoryBuilder(ctx, rep, c.cacheFile(ctx))
+	if err != nil {
+		return errors.Wrap(err, "unable to create inventory builder")
+	}
+
+	defer b.Close(ctx)
+
+	for _, man := range manifests {
+		if man.RootEntry == nil {
+			continue
+		}
+
+		root, err := snapshotfs.SnapshotRoot(rep, man)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get root of snapshot %v", man.ID)
+		}
+
+		if err := b.Walk(ctx, root, func(_ context.Context, e *snapshotfs.InventoryEntry) error {
+			return w.write(string(man.ID), e)
+		}); err != nil {
+			return errors.Wrapf(err, "error listing snapshot %v", man.ID)
+		}
+	}
+
+	if err := w.finish(); err != nil {
+		return errors.Wrap(err, "error writing inventory")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "error writing inventory")
+	}
+
+	st := b.Stats()
+
+	log(ctx).Infof("Listed %v files, read %v files (%v) to compute hashes.", st.Files, st.HashedFiles, units.BytesString(st.HashedBytes))
+
+	return nil
+}
+
+// cacheFile returns the path of the file in the cache directory where hashes are persisted across invocations,
+// or an empty string if caching is disabled.
+func (c *commandSnapshotInventory) cacheFile(ctx context.Context) string {
+	opts, err := repo.GetCachingOptions(ctx, c.svc.repositoryConfigFileName())
+	if err != nil {
+		log(ctx).Debugf("unable to get caching options: %v", err)
+		return ""
+	}
+
+	if opts.CacheDirectory == "" {
+		return ""
+	}
+
+	return filepath.Join(opts.CacheDirectory, inventoryCacheFile)
+}
+
+type inventoryWriter interface {
+	write(snapshotID string, e *snapshotfs.InventoryEntry) error
+	finish() error
+}
+
+func newInventoryWriter(format string, out io.Writer) inventoryWriter {
+	switch format {
+	case inventoryFormatJSONL:
+		return &jsonlInventoryWriter{json.NewEncoder(out)}
+	case inventoryFormatColumnar:
+		return &columnarInventoryWriter{enc: json.NewEncoder(out), rowGroupSize: inventoryRowGroupSize, columns: map[string][]any{}}
+	default:
+		return &csvInventoryWriter{w: csv.NewWriter(out)}
+	}
+}
+
+// inventoryRow returns the values of inventoryColumns for the provided entry.
+func inventoryRow(snapshotID string, e *snapshotfs.InventoryEntry) []any {
+	return []any{
+		snapshotID,
+		e.Path,
+		string(e.Type),
+		e.Size,
+		e.ModTime.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.GroupID,
+		"0" + strconv.FormatUint(uint64(unixMode(e.Mode)), 8),
+		e.SHA256,
+		e.ObjectID.String(),
+	}
+}
+
+// unixMode converts the permission bits of the provided mode, including setuid, setgid and sticky bits, to the Unix representation.
+func unixMode(m os.FileMode) uint32 {
+	result := uint32(m.Perm())
+
+	if m&os.ModeSetuid != 0 {
+		result |= 0o4000
+	}
+
+	if m&os.ModeSetgid != 0 {
+		result |= 0o2000
+	}
+
+	if m&os.ModeSticky != 0 {
+		result |= 0o1000
+	}
+
+	return result
+}
+
+type csvInventoryWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvInventoryWriter) writeHeaderIfNeeded() error {
+	if w.headerWritten {
+		return nil
+	}
+
+	w.headerWritten = true
+
+	return errors.Wrap(w.w.Write(inventoryColumns), "unable to write header")
+}
+
+func (w *csvInventoryWriter) write(snapshotID string, e *snapshotfs.InventoryEntry) error {
+	if err := w.writeHeaderIfNeeded(); err != nil {
+		return err
+	}
+
+	var rec []string
+
+	for _, v := range inventoryRow(snapshotID, e) {
+		switch v := v.(type) {
+		case string:
+			rec = append(rec, v)
+		case int64:
+			rec = append(rec, strconv.FormatInt(v, 10))
+		case uint32:
+			rec = append(rec, strconv.FormatUint(uint64(v), 10))
+		}
+	}
+
+	return errors.Wrap(w.w.Write(rec), "unable to write record")
+}
+
+func (w *csvInventoryWriter) finish() error {
+	if err := w.writeHeaderIfNeeded(); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+
+	return errors.Wrap(w.w.Error(), "unable to write CSV")
+}
+
+type jsonlInventoryWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonlInventoryWriter) write(snapshotID string, e *snapshotfs.InventoryEntry) error {
+	row := map[string]any{}
+
+	for i, v := range inventoryRow(snapshotID, e) {
+		row[inventoryColumns[i]] = v
+	}
+
+	return errors.Wrap(w.enc.Encode(row), "unable to write entry")
+}
+
+func (w *jsonlInventoryWriter) finish() error {
+	return nil
+}
+
+// columnarInventoryWriter buffers up to rowGroupSize entries and writes each group as a JSON object with
+// one array per column, so that memory usage doesn't depend on the number of files.
+type columnarInventoryWriter struct {
+	enc          *json.Encoder
+	rowGroupSize int
+	rows         int
+	columns      map[string][]any
+	groupWritten bool
+}
+
+func (w *columnarInventoryWriter) write(snapshotID string, e *snapshotfs.InventoryEntry) error {
+	for i, v := range inventoryRow(snapshotID, e) {
+		w.columns[inventoryColumns[i]] = append(w.columns[inventoryColumns[i]], v)
+	}
+
+	w.rows++
+
+	if w.rows >= w.rowGroupSize {
+		return w.writeRowGroup()
+	}
+
+	return nil
+}
+
+func (w *columnarInventoryWriter) writeRowGroup() error {
+	for _, c := range inventoryColumns {
+		if w.columns[c] == nil {
+			w.columns[c] = []any{}
+		}
+	}
+
+	if err := w.enc.Encode(struct {
+		Columns []string         `json:"columns"`
+		Rows    int              `json:"rows"`
+		Data    map[string][]any `json:"data"`
+	}{inventoryColumns, w.rows, w.columns}); err != nil {
+		return errors.Wrap(err, "unable to write inventory")
+	}
+
+	w.rows = 0
+	w.columns = map[string][]any{}
+	w.groupWritten = true
+
+	return nil
+}
+
+func (w *columnarInventoryWriter) finish() error {
+	// write the final group, which is always written when there are no entries at all.
+	if w.rows > 0 || !w.groupWritten {
+		return w.writeRowGroup()
+	}
+
+	return nil
+}
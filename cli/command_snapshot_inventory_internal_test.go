@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestColumnarInventoryWriter_RowGroups(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := newInventoryWriter(inventoryFormatColumnar, &buf).(*columnarInventoryWriter) //nolint:forcetypeassert
+	w.rowGroupSize = 2
+
+	for i := range 5 {
+		require.NoError(t, w.write("snap", &snapshotfs.InventoryEntry{Path: "f" + strconv.Itoa(i)}))
+	}
+
+	require.NoError(t, w.finish())
+
+	type rowGroup struct {
+		Rows int              `json:"rows"`
+		Data map[string][]any `json:"data"`
+	}
+
+	var groups []rowGroup
+
+	dec := json.NewDecoder(&buf)
+
+	for dec.More() {
+		var g rowGroup
+
+		require.NoError(t, dec.Decode(&g))
+
+		groups = append(groups, g)
+	}
+
+	require.Len(t, groups, 3)
+	require.Equal(t, 2, groups[0].Rows)
+	require.Equal(t, []any{"f0", "f1"}, groups[0].Data["path"])
+	require.Equal(t, 1, groups[2].Rows)
+	require.Equal(t, []any{"f4"}, groups[2].Data["path"])
+
+	// empty inventory produces a single empty group.
+	buf.Reset()
+
+	require.NoError(t, newInventoryWriter(inventoryFormatColumnar, &buf).finish())
+	require.JSONEq(t, `{"columns":["snapshot","path","type","size","mtime","uid","gid","mode","sha256","object"],"rows":0,"data":{"snapshot":[],"path":[],"type":[],"size":[],"mtime":[],"uid":[],"gid":[],"mode":[],"sha256":[],"object":[]}}`, buf.String())
+}
+
+func TestUnixMode(t *testing.T) {
+	require.Equal(t, uint32(0o644), unixMode(0o644))
+	require.Equal(t, uint32(0o4755), unixMode(0o755|os.ModeSetuid))
+	require.Equal(t, uint32(0o3770), unixMode(0o770|os.ModeSetgid|os.ModeSticky))
+}
@@ -0,0 +1,88 @@
+package cli_test
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotInventory(t *testing.T) {
+	srcDir := testutil.TempDirectory(t)
+
+	runner := testenv.NewInProcRunner(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("hello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "sub", "b.txt"), []byte("hello"), 0o600))
+
+	var man1, man2 snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--json"), &man1)
+
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "c.txt"), []byte("world"), 0o644))
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--json"), &man2)
+
+	helloHash := sha256.Sum256([]byte("hello"))
+
+	// CSV
+	records, err := csv.NewReader(strings.NewReader(strings.Join(env.RunAndExpectSuccess(t, "snapshot", "inventory", string(man1.ID)), "\n"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, []string{"snapshot", "path", "type", "size", "mtime", "uid", "gid", "mode", "sha256", "object"}, records[0])
+
+	rows := map[string][]string{}
+	for _, r := range records[1:] {
+		rows[r[1]] = r
+	}
+
+	require.Equal(t, string(man1.ID), rows["a.txt"][0])
+	require.Equal(t, "f", rows["a.txt"][2])
+	require.Equal(t, "5", rows["a.txt"][3])
+	require.Equal(t, hex.EncodeToString(helloHash[:]), rows["a.txt"][8])
+	require.Equal(t, hex.EncodeToString(helloHash[:]), rows["sub/b.txt"][8])
+
+	// JSONL with multiple snapshots written to a file.
+	outFile := filepath.Join(testutil.TempDirectory(t), "inventory.jsonl")
+	env.RunAndExpectSuccess(t, "snapshot", "inventory", string(man1.ID), string(man2.ID), "--format=jsonl", "--output", outFile)
+
+	data, err := os.ReadFile(outFile)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 5)
+
+	var entry map[string]any
+
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &entry))
+	require.Equal(t, string(man2.ID), entry["snapshot"])
+
+	// columnar
+	var columnar struct {
+		Columns []string         `json:"columns"`
+		Rows    int              `json:"rows"`
+		Data    map[string][]any `json:"data"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "inventory", string(man2.ID), "--format=columnar"), &columnar)
+	require.Equal(t, 3, columnar.Rows)
+	require.Len(t, columnar.Data["path"], 3)
+	require.ElementsMatch(t, []any{"a.txt", "c.txt", "sub/b.txt"}, columnar.Data["path"])
+
+	env.RunAndExpectFailure(t, "snapshot", "inventory", "no-such-snapshot")
+	env.RunAndExpectFailure(t, "snapshot", "inventory", string(man1.ID), "--format=xml")
+}
@@ -391,6 +391,12 @@ func (c *commandSnapshotList) entryBits(ctx context.Context, m *snapshot.Manifes
 		bits = append(bits, "incomplete:"+m.IncompleteReason)
 	}
 
+	for _, r := range m.CommandResults {
+		if r.ExitCode != 0 {
+			bits = append(bits, fmt.Sprintf("command-failed:%v(exit %v)", r.Name, r.ExitCode))
+		}
+	}
+
 	var summary *fs.DirectorySummary
 
 	if dws, ok := ent.(fs.DirectoryWithSummary); ok {
@@ -5,10 +5,12 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 )
 
 type commandSnapshotPin struct {
@@ -79,5 +81,5 @@ func (c *commandSnapshotPin) pinSnapshot(ctx context.Context, rep repo.Repositor
 
 	log(ctx).Infof("Updating snapshot at %v of %v", formatTimestamp(m.StartTime.ToTime()), m.Source)
 
-	return errors.Wrap(snapshot.UpdateSnapshot(ctx, rep, m), "error updating snapshot")
+	return legalhold.UpdateSnapshot(ctx, rep, m, clock.Now())
 }
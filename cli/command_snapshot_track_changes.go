@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/changejournal"
+)
+
+type commandSnapshotTrackChanges struct {
+	paths   []string
+	journal string
+
+	svc appServices
+}
+
+func (c *commandSnapshotTrackChanges) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("track-changes", "Track changes to local directories in a journal, which allows 'snapshot create --change-journal' to skip unchanged directories (Linux only).")
+	cmd.Arg("path", "Directories to track").Required().StringsVar(&c.paths)
+	cmd.Flag("journal", "Path to the change journal file").Required().StringVar(&c.journal)
+	cmd.Action(svc.noRepositoryAction(c.run))
+
+	c.svc = svc
+}
+
+func (c *commandSnapshotTrackChanges) run(ctx context.Context) error {
+	var roots []string
+
+	for _, p := range c.paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return errors.Wrapf(err, "invalid path %v", p)
+		}
+
+		roots = append(roots, abs)
+	}
+
+	tracker := changejournal.NewTracker()
+
+	if err := tracker.PersistTo(c.journal); err != nil {
+		return errors.Wrap(err, "unable to write change journal")
+	}
+
+	stop, err := changejournal.Watch(ctx, tracker, roots...)
+	if err != nil {
+		tracker.Close() //nolint:errcheck
+
+		return errors.Wrap(err, "unable to track changes")
+	}
+
+	log(ctx).Infof("Tracking changes to %v in %v, press Ctrl-C to stop.", roots, c.journal)
+
+	ctrlCPressed := make(chan struct{})
+
+	c.svc.onTerminate(func() {
+		close(ctrlCPressed)
+	})
+
+	<-ctrlCPressed
+
+	stop()
+
+	return errors.Wrap(tracker.Close(), "error closing change journal")
+}
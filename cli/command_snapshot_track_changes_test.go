@@ -0,0 +1,66 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotTrackChanges(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("tracking changes is only supported on Linux")
+	}
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "d1"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "d2"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "d1", "f1"), []byte{1, 2, 3}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "d2", "f2"), []byte{1, 2, 3}, 0o600))
+
+	journal := filepath.Join(testutil.TempDirectory(t), "journal")
+
+	wait, kill := env.RunAndProcessStderr(t, func(line string) bool {
+		return !strings.Contains(line, "Tracking changes")
+	}, "snapshot", "track-changes", "--journal", journal, srcDir)
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--change-journal", journal)
+
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "d2", "f2"), []byte{4, 5, 6, 7}, 0o600))
+
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(journal)
+		return err == nil && strings.Contains(string(b), filepath.Join(srcDir, "d2"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--change-journal", journal)
+
+	var manifests []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", srcDir, "--json"), &manifests)
+	require.Len(t, manifests, 2)
+
+	// only d1 was unchanged.
+	require.Equal(t, int32(0), manifests[0].Stats.ReusedDirCount)
+	require.Equal(t, int32(1), manifests[1].Stats.ReusedDirCount)
+	require.Equal(t, int64(7), manifests[1].RootEntry.DirSummary.TotalFileSize)
+
+	kill()
+	require.NoError(t, wait())
+
+	env.RunAndExpectFailure(t, "snapshot", "create", srcDir, "--change-journal", filepath.Join(srcDir, "no-such-journal"))
+}
@@ -66,6 +66,7 @@ func (c *App) openRepository(ctx context.Context, required bool) (repo.Repositor
 func (c *App) optionsFromFlags(ctx context.Context) *repo.Options {
 	return &repo.Options{
 		TraceStorage:         c.traceStorage,
+		StorageFaultProfile:  c.storageFaultProfile,
 		DisableRepositoryLog: c.disableRepositoryLog,
 		UpgradeOwnerID:       c.upgradeOwnerID,
 		DoNotWaitForUpgrade:  c.doNotWaitForUpgrade,
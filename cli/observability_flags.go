@@ -17,8 +17,11 @@
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/prometheus/common/expfmt"
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
@@ -56,6 +59,8 @@ type observabilityFlags struct {
 	metricsPushPassword string
 	metricsPushFormat   string
 	otlpTrace           bool
+	otlpMetrics         bool
+	otlpMetricsInterval time.Duration
 	saveMetrics         bool
 	pf                  profileFlags
 
@@ -65,6 +70,7 @@ type observabilityFlags struct {
 	pusherWG   sync.WaitGroup
 
 	traceProvider *trace.TracerProvider
+	meterProvider *metric.MeterProvider
 }
 
 func (c *observabilityFlags) setup(svc appServices, app *kingpin.Application) {
@@ -82,6 +88,8 @@ func (c *observabilityFlags) setup(svc appServices, app *kingpin.Application) {
 
 	// tracing (OTLP) parameters
 	app.Flag("otlp-trace", "Send OpenTelemetry traces to OTLP collector using gRPC").Hidden().Envar(svc.EnvName("KOPIA_ENABLE_OTLP_TRACE")).BoolVar(&c.otlpTrace)
+	app.Flag("otlp-metrics", "Send OpenTelemetry metrics to OTLP collector using gRPC").Hidden().Envar(svc.EnvName("KOPIA_ENABLE_OTLP_METRICS")).BoolVar(&c.otlpMetrics)
+	app.Flag("otlp-metrics-interval", "Frequency of OTLP metrics export").Hidden().Envar(svc.EnvName("KOPIA_OTLP_METRICS_INTERVAL")).Default("60s").DurationVar(&c.otlpMetricsInterval)
 
 	var formats []string
 
@@ -159,7 +167,11 @@ func (c *observabilityFlags) start(ctx context.Context) error {
 		}
 	}
 
-	return c.maybeStartTraceExporter(ctx)
+	if err := c.maybeStartTraceExporter(ctx); err != nil {
+		return err
+	}
+
+	return c.maybeStartMetricsExporter(ctx)
 }
 
 func mkSubdirectories(directoryNames ...string) (dirName string, err error) {
@@ -245,15 +257,9 @@ func (c *observabilityFlags) maybeStartTraceExporter(ctx context.Context) error
 	// Create the OTLP exporter.
 	se := otlptracegrpc.NewUnstarted()
 
-	r := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceNameKey.String("kopia"),
-		semconv.ServiceVersionKey.String(repo.BuildVersion),
-	)
-
 	tp := trace.NewTracerProvider(
 		trace.WithBatcher(se),
-		trace.WithResource(r),
+		trace.WithResource(otlpResource()),
 	)
 
 	if err := se.Start(ctx); err != nil {
@@ -267,6 +273,40 @@ func (c *observabilityFlags) maybeStartTraceExporter(ctx context.Context) error
 	return nil
 }
 
+// maybeStartMetricsExporter periodically exports all Prometheus metrics to the OTLP collector.
+func (c *observabilityFlags) maybeStartMetricsExporter(ctx context.Context) error {
+	if !c.otlpMetrics {
+		return nil
+	}
+
+	me, err := otlpmetricgrpc.New(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create OTLP metrics exporter")
+	}
+
+	mp := metric.NewMeterProvider(
+		metric.WithReader(metric.NewPeriodicReader(me,
+			metric.WithInterval(c.otlpMetricsInterval),
+			metric.WithProducer(otelprom.NewMetricProducer()),
+		)),
+		metric.WithResource(otlpResource()),
+	)
+
+	otel.SetMeterProvider(mp)
+
+	c.meterProvider = mp
+
+	return nil
+}
+
+func otlpResource() *resource.Resource {
+	return resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String("kopia"),
+		semconv.ServiceVersionKey.String(repo.BuildVersion),
+	)
+}
+
 func (c *observabilityFlags) stop(ctx context.Context) {
 	if c.dumpAllocatorStats {
 		gather.DumpStats(ctx)
@@ -284,6 +324,13 @@ func (c *observabilityFlags) stop(ctx context.Context) {
 		}
 	}
 
+	if c.meterProvider != nil {
+		// flushes the final values of all metrics.
+		if err := c.meterProvider.Shutdown(ctx); err != nil {
+			log(ctx).Warnf("unable to shutdown meter provider: %v", err)
+		}
+	}
+
 	if c.saveMetrics {
 		if metricsDir, err := mkSubdirectories(c.outputDirectory, c.outputSubdirectoryName); err != nil {
 			log(ctx).Warnf("unable to create metrics output directory '%s': %v", metricsDir, err)
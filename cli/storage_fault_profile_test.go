@@ -0,0 +1,45 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestStorageFaultProfile(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	profileDir := testutil.TempDirectory(t)
+
+	profile := filepath.Join(profileDir, "profile.json")
+	require.NoError(t, os.WriteFile(profile, []byte(`{"seed":1,"operations":{"*":{"latencyMillis":1}}}`), 0o600))
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t), "--storage-fault-profile", profile)
+	e.RunAndExpectSuccess(t, "snapshot", "verify", "--storage-fault-profile", profile)
+
+	// maintenance and garbage collection are refused while faults are injected.
+	e.RunAndExpectFailure(t, "maintenance", "run", "--full", "--storage-fault-profile", profile)
+	e.RunAndExpectFailure(t, "blob", "gc", "--delete=yes", "--storage-fault-profile", profile)
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full")
+
+	// injected errors are not retried and surface to the caller.
+	failingProfile := filepath.Join(profileDir, "failing.json")
+	require.NoError(t, os.WriteFile(failingProfile, []byte(`{"seed":1,"operations":{"PutBlob":{"errorRate":1}}}`), 0o600))
+
+	e.RunAndExpectFailure(t, "snapshot", "create", testutil.TempDirectory(t), "--storage-fault-profile", failingProfile)
+
+	invalidProfile := filepath.Join(profileDir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidProfile, []byte(`{"operations":{"GetBlob":{"errorRate":5}}}`), 0o600))
+
+	e.RunAndExpectFailure(t, "snapshot", "list", "--storage-fault-profile", invalidProfile)
+}
@@ -35,6 +35,7 @@ func (c *storageFilesystemFlags) Setup(_ StorageProviderServices, cmd *kingpin.C
 	cmd.Flag("file-mode", "File mode for newly created files (0600)").PlaceHolder("MODE").StringVar(&c.connectFileMode)
 	cmd.Flag("dir-mode", "Mode of newly directory files (0700)").PlaceHolder("MODE").StringVar(&c.connectDirMode)
 	cmd.Flag("flat", "Use flat directory structure").BoolVar(&c.connectFlat)
+	cmd.Flag("retention-helper", "Command used to make retained blobs immutable, such as 'sudo kopia blob filesystem-retention-helper --root=<path>'").StringVar(&c.options.RetentionHelper)
 	cmd.Flag("list-parallelism", "Set list parallelism").Hidden().IntVar(&c.options.ListParallelism)
 
 	commonThrottlingFlags(cmd, &c.options.Limits)
@@ -2,9 +2,11 @@
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
@@ -33,9 +35,56 @@ func (c *commonThrottleGet) output(limits *throttling.Limits) error {
 	c.printValueOrUnlimited("Max Concurrent Reads:", float64(limits.ConcurrentReads), c.floatToString)
 	c.printValueOrUnlimited("Max Concurrent Writes:", float64(limits.ConcurrentWrites), c.floatToString)
 
+	if len(limits.Schedule) == 0 {
+		return nil
+	}
+
+	c.out.printStdout("Schedule:\n")
+
+	active := limits.ActiveWindow(clock.Now())
+
+	for i := range limits.Schedule {
+		w := &limits.Schedule[i]
+
+		suffix := ""
+		if w == active {
+			suffix = " (active)"
+		}
+
+		c.out.printStdout("  %v%v\n", c.scheduleWindowString(w), suffix)
+	}
+
 	return nil
 }
 
+func (c *commonThrottleGet) scheduleWindowString(w *throttling.ScheduleWindow) string {
+	days := "every day"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+
+	var limits []string
+
+	add := func(label string, v float64, convert func(v float64) string) {
+		switch {
+		case v < 0:
+			limits = append(limits, label+" unlimited")
+		case v != 0:
+			limits = append(limits, label+" "+convert(v))
+		}
+	}
+
+	add("download", w.Limits.DownloadBytesPerSecond, units.BytesPerSecondsString)
+	add("upload", w.Limits.UploadBytesPerSecond, units.BytesPerSecondsString)
+	add("reads/s", w.Limits.ReadsPerSecond, c.floatToString)
+	add("writes/s", w.Limits.WritesPerSecond, c.floatToString)
+	add("lists/s", w.Limits.ListsPerSecond, c.floatToString)
+	add("concurrent reads", float64(w.Limits.ConcurrentReads), c.floatToString)
+	add("concurrent writes", float64(w.Limits.ConcurrentWrites), c.floatToString)
+
+	return days + " " + w.Start + "-" + w.End + ": " + strings.Join(limits, ", ")
+}
+
 func (c *commonThrottleGet) printValueOrUnlimited(label string, v float64, convert func(v float64) string) {
 	if v != 0 {
 		c.out.printStdout("%-30v %v\n", label, convert(v))
@@ -19,6 +19,9 @@ type commonThrottleSet struct {
 	setListsPerSecond         string
 	setConcurrentReads        string
 	setConcurrentWrites       string
+
+	addScheduleWindows []string
+	clearSchedule      bool
 }
 
 func (c *commonThrottleSet) setup(cmd *kingpin.CmdClause) {
@@ -29,6 +32,8 @@ func (c *commonThrottleSet) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("list-requests-per-second", "Set max lists per second").StringVar(&c.setListsPerSecond)
 	cmd.Flag("concurrent-reads", "Set max concurrent reads").StringVar(&c.setConcurrentReads)
 	cmd.Flag("concurrent-writes", "Set max concurrent writes").StringVar(&c.setConcurrentWrites)
+	cmd.Flag("add-schedule-window", "Add limits applied during a time window, e.g. 'mon-fri 08:00-18:00 upload=2000000' (keys: upload, download, reads, writes, lists, concurrent-reads, concurrent-writes, values can be 'unlimited')").PlaceHolder("DAYS HH:MM-HH:MM KEY=VALUE...").StringsVar(&c.addScheduleWindows)
+	cmd.Flag("clear-schedule", "Remove all schedule windows").BoolVar(&c.clearSchedule)
 }
 
 func (c *commonThrottleSet) apply(ctx context.Context, limits *throttling.Limits, changeCount *int) error {
@@ -56,7 +61,36 @@ func (c *commonThrottleSet) apply(ctx context.Context, limits *throttling.Limits
 		return err
 	}
 
-	return c.setThrottleInt(ctx, "concurrent writes", &limits.ConcurrentWrites, c.setConcurrentWrites, changeCount)
+	if err := c.setThrottleInt(ctx, "concurrent writes", &limits.ConcurrentWrites, c.setConcurrentWrites, changeCount); err != nil {
+		return err
+	}
+
+	return c.applySchedule(ctx, limits, changeCount)
+}
+
+func (c *commonThrottleSet) applySchedule(ctx context.Context, limits *throttling.Limits, changeCount *int) error {
+	if c.clearSchedule && len(limits.Schedule) > 0 {
+		*changeCount++
+
+		log(ctx).Info("Removing all schedule windows.")
+
+		limits.Schedule = nil
+	}
+
+	for _, spec := range c.addScheduleWindows {
+		w, err := throttling.ParseScheduleWindow(spec)
+		if err != nil {
+			return errors.Wrap(err, "invalid schedule window")
+		}
+
+		*changeCount++
+
+		log(ctx).Infof("Adding schedule window: %v", w)
+
+		limits.Schedule = append(limits.Schedule, w)
+	}
+
+	return nil
 }
 
 func (c *commonThrottleSet) setThrottleFloat64(ctx context.Context, desc string, bps bool, val *float64, str string, changeCount *int) error {
@@ -0,0 +1,145 @@
+// Package blockdev implements filesystem entries for raw block devices and disk images,
+// which are snapshotted as sparse images.
+package blockdev
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+)
+
+type blockDeviceEntry struct {
+	path     string
+	size     int64
+	mode     os.FileMode
+	mtime    time.Time
+	owner    fs.OwnerInfo
+	isDevice bool
+}
+
+func (e *blockDeviceEntry) Name() string {
+	return filepath.Base(e.path)
+}
+
+func (e *blockDeviceEntry) IsDir() bool {
+	return false
+}
+
+// Mode returns permission bits only, so that the entry is snapshotted and restored as a regular file.
+func (e *blockDeviceEntry) Mode() os.FileMode {
+	return e.mode
+}
+
+func (e *blockDeviceEntry) Size() int64 {
+	return e.size
+}
+
+func (e *blockDeviceEntry) ModTime() time.Time {
+	return e.mtime
+}
+
+func (e *blockDeviceEntry) Sys() any {
+	return nil
+}
+
+func (e *blockDeviceEntry) Owner() fs.OwnerInfo {
+	return e.owner
+}
+
+func (e *blockDeviceEntry) Device() fs.DeviceInfo {
+	return fs.DeviceInfo{}
+}
+
+func (e *blockDeviceEntry) LocalFilesystemPath() string {
+	return e.path
+}
+
+func (e *blockDeviceEntry) Close() {
+}
+
+func (e *blockDeviceEntry) Open(_ context.Context) (fs.Reader, error) {
+	f, err := os.Open(e.path) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open block device")
+	}
+
+	return &blockDeviceReader{f, e}, nil
+}
+
+// DataRegions returns regions that may contain data. Holes in disk images are determined using the filesystem,
+// block devices are reported as a single region and zero blocks are detected when reading them.
+func (e *blockDeviceEntry) DataRegions(_ context.Context) ([]fs.Region, error) {
+	if e.isDevice || e.size == 0 {
+		return []fs.Region{{Offset: 0, Length: e.size}}, nil
+	}
+
+	return fileDataRegions(e.path, e.size)
+}
+
+type blockDeviceReader struct {
+	*os.File
+	e *blockDeviceEntry
+}
+
+func (r *blockDeviceReader) Entry() (fs.Entry, error) {
+	return r.e, nil
+}
+
+// NewEntry returns a sparse file entry for the block device or disk image at the provided path.
+// Symbolic links such as /dev/vg/lv are followed.
+func NewEntry(path string) (fs.SparseFile, error) {
+	path = filepath.Clean(path)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to stat block device")
+	}
+
+	e := &blockDeviceEntry{
+		path:  path,
+		size:  fi.Size(),
+		mode:  fi.Mode().Perm(),
+		mtime: fi.ModTime(),
+		owner: platformSpecificOwnerInfo(fi),
+	}
+
+	switch m := fi.Mode(); {
+	case m.IsRegular():
+	case m&os.ModeDevice != 0 && m&os.ModeCharDevice == 0:
+		e.isDevice = true
+
+		// block devices report zero size, determine it by seeking to the end.
+		if e.size, err = deviceSize(path); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, errors.Errorf("%v is not a block device or disk image", path)
+	}
+
+	return e, nil
+}
+
+func deviceSize(path string) (int64, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to open block device")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to determine block device size")
+	}
+
+	return size, nil
+}
+
+var _ fs.SparseFile = (*blockDeviceEntry)(nil)
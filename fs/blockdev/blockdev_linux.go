@@ -0,0 +1,66 @@
+package blockdev
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/kopia/kopia/fs"
+)
+
+func platformSpecificOwnerInfo(fi os.FileInfo) fs.OwnerInfo {
+	var oi fs.OwnerInfo
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		oi.UserID = stat.Uid
+		oi.GroupID = stat.Gid
+	}
+
+	return oi
+}
+
+// fileDataRegions uses SEEK_DATA and SEEK_HOLE to find regions of the file that contain data.
+func fileDataRegions(path string, size int64) ([]fs.Region, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	fd := int(f.Fd())
+
+	var regions []fs.Region
+
+	for offset := int64(0); offset < size; {
+		start, err := unix.Seek(fd, offset, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			// no more data until the end of file.
+			break
+		}
+
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+			// filesystem does not support finding holes.
+			return []fs.Region{{Offset: 0, Length: size}}, nil
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find data")
+		}
+
+		end, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+		if err != nil || end > size {
+			end = size
+		}
+
+		if start >= end {
+			break
+		}
+
+		regions = append(regions, fs.Region{Offset: start, Length: end - start})
+		offset = end
+	}
+
+	return regions, nil
+}
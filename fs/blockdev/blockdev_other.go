@@ -0,0 +1,18 @@
+//go:build !linux
+
+package blockdev
+
+import (
+	"os"
+
+	"github.com/kopia/kopia/fs"
+)
+
+func platformSpecificOwnerInfo(_ os.FileInfo) fs.OwnerInfo {
+	return fs.OwnerInfo{}
+}
+
+// fileDataRegions reports the entire file as data, holes are detected when reading it.
+func fileDataRegions(_ string, size int64) ([]fs.Region, error) {
+	return []fs.Region{{Offset: 0, Length: size}}, nil
+}
@@ -0,0 +1,85 @@
+package blockdev_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/blockdev"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestDiskImage(t *testing.T) {
+	ctx := testlogging.Context(t)
+	td := t.TempDir()
+
+	const size = 10 << 20
+
+	fname := filepath.Join(td, "disk.img")
+
+	f, err := os.Create(fname)
+	require.NoError(t, err)
+
+	require.NoError(t, f.Truncate(size))
+
+	_, err = f.WriteAt([]byte("hello"), 5<<20)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// symbolic links are followed.
+	require.NoError(t, os.Symlink(fname, filepath.Join(td, "link")))
+
+	e, err := blockdev.NewEntry(filepath.Join(td, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "link", e.Name())
+	require.Equal(t, int64(size), e.Size())
+	require.False(t, e.IsDir())
+	require.Equal(t, os.FileMode(0), e.Mode()&os.ModeType)
+
+	regions, err := e.DataRegions(ctx)
+	require.NoError(t, err)
+
+	// written data must be covered by data regions, holes may or may not be detected depending on the filesystem.
+	require.True(t, isCovered(regions, 5<<20, 5), "regions: %v", regions)
+
+	if runtime.GOOS != "linux" {
+		require.Equal(t, []fs.Region{{Offset: 0, Length: size}}, regions)
+	}
+
+	r, err := e.Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	_, err = r.Seek(5<<20, io.SeekStart)
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+}
+
+func TestNotBlockDevice(t *testing.T) {
+	_, err := blockdev.NewEntry(t.TempDir())
+	require.ErrorContains(t, err, "is not a block device or disk image")
+
+	_, err = blockdev.NewEntry(filepath.Join(t.TempDir(), "no-such-file"))
+	require.Error(t, err)
+}
+
+func isCovered(regions []fs.Region, offset, length int64) bool {
+	for _, r := range regions {
+		if r.Offset <= offset && offset+length <= r.Offset+r.Length {
+			return true
+		}
+	}
+
+	return false
+}
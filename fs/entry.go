@@ -50,6 +50,21 @@ type File interface {
 	Open(ctx context.Context) (Reader, error)
 }
 
+// Region describes a contiguous range of bytes in a file.
+type Region struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// SparseFile is optionally implemented by File entries that can report regions which may contain data,
+// such as sparse disk images or block devices. Bytes outside of the returned regions are zero.
+type SparseFile interface {
+	File
+
+	// DataRegions returns non-overlapping regions that may contain non-zero data, ordered by offset.
+	DataRegions(ctx context.Context) ([]Region, error)
+}
+
 // StreamingFile represents an entry that is a stream.
 type StreamingFile interface {
 	Entry
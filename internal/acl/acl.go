@@ -11,6 +11,7 @@
 	"github.com/kopia/kopia/internal/user"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
@@ -112,6 +113,9 @@ func oneOf(allowed ...string) valueValidatorFunc {
 	user.ManifestType: {
 		user.UsernameAtHostnameLabel: nonEmptyString,
 	},
+	legalhold.ManifestType: {
+		legalhold.SnapshotIDLabel: nonEmptyString,
+	},
 	aclManifestType: {},
 }
 
@@ -220,7 +220,7 @@ func TestACLEntryValidation(t *testing.T) {
 				},
 				Access: acl.AccessLevelFull,
 			},
-			WantErr: "invalid 'type' label, must be one of: acl, content, policy, snapshot, user",
+			WantErr: "invalid 'type' label, must be one of: acl, content, legalhold, policy, snapshot, user",
 		},
 		{
 			Entry: &acl.Entry{
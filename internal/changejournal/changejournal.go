@@ -0,0 +1,174 @@
+// Package changejournal keeps track of local directories modified since a point in time,
+// which allows snapshots to reuse subtrees of the previous snapshot that are known to be
+// unchanged without reading them.
+package changejournal
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("changejournal")
+
+// Tracker records modified directories. A directory is considered modified when any entry
+// in it or in any of its subdirectories has changed.
+//
+// Changes are only known to be tracked completely after the coverage start time,
+// a subtree can never be reported as unchanged for an earlier point in time.
+type Tracker struct {
+	mu sync.Mutex
+
+	// +checklocks:mu
+	coverageStart time.Time
+
+	// +checklocks:mu
+	latestChange map[string]time.Time // latest change in each directory or its subdirectories
+
+	// +checklocks:mu
+	hardLinked map[string]bool // directories with files having multiple hard links in them or their subdirectories
+
+	// +checklocks:mu
+	journal *journalWriter
+
+	// +checklocks:mu
+	stopHeartbeat chan struct{}
+}
+
+// NewTracker returns a new Tracker, which does not consider any directory as unchanged
+// until Reset() is called.
+func NewTracker() *Tracker {
+	return &Tracker{
+		latestChange: map[string]time.Time{},
+		hardLinked:   map[string]bool{},
+	}
+}
+
+// Reset discards all recorded changes and starts coverage at the current time. It must be
+// called once the caller is certain that all subsequent changes will be reported,
+// and whenever changes may have been lost.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.resetLocked(clock.Now())
+}
+
+// +checklocks:t.mu
+func (t *Tracker) resetLocked(now time.Time) {
+	t.coverageStart = now
+	clear(t.latestChange)
+
+	if t.journal != nil {
+		t.journal.writeStart(now)
+	}
+}
+
+// invalidate stops coverage when changes are no longer tracked.
+func (t *Tracker) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.coverageStart = time.Time{}
+	clear(t.latestChange)
+
+	if t.journal != nil {
+		t.journal.writeStop(clock.Now())
+	}
+}
+
+// MarkDirty records a change to the provided local directory at the current time.
+func (t *Tracker) MarkDirty(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := clock.Now()
+
+	t.markDirtyLocked(filepath.Clean(dir), now)
+
+	if t.journal != nil {
+		t.journal.writeDirty(filepath.Clean(dir), now)
+		t.maybeCompactLocked()
+	}
+}
+
+// +checklocks:t.mu
+func (t *Tracker) markDirtyLocked(dir string, ts time.Time) {
+	for {
+		if ts.After(t.latestChange[dir]) {
+			t.latestChange[dir] = ts
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+
+		dir = parent
+	}
+}
+
+// MarkHardLinked records that the provided local directory contains a file with multiple hard links.
+// Such files can be modified through links in other directories without any change being reported,
+// so the directory and its ancestors are never considered unchanged.
+func (t *Tracker) MarkHardLinked(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dir = filepath.Clean(dir)
+	if t.hardLinked[dir] {
+		return
+	}
+
+	t.markHardLinkedLocked(dir)
+
+	if t.journal != nil {
+		t.journal.writeHardLinked(dir, clock.Now())
+		t.maybeCompactLocked()
+	}
+}
+
+// +checklocks:t.mu
+func (t *Tracker) markHardLinkedLocked(dir string) {
+	for !t.hardLinked[dir] {
+		t.hardLinked[dir] = true
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+
+		dir = parent
+	}
+}
+
+// CoverageStart returns the time since which changes have been tracked completely or zero time
+// if tracking has not started.
+func (t *Tracker) CoverageStart() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.coverageStart
+}
+
+// SubtreeUnchangedSince returns true if no entry in the provided local directory or any of its
+// subdirectories has changed since the given time.
+func (t *Tracker) SubtreeUnchangedSince(dir string, since time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.coverageStart.IsZero() || t.coverageStart.After(since) {
+		return false
+	}
+
+	if t.hardLinked[filepath.Clean(dir)] {
+		return false
+	}
+
+	latest, ok := t.latestChange[filepath.Clean(dir)]
+
+	return !ok || latest.Before(since)
+}
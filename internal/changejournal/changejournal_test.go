@@ -0,0 +1,162 @@
+package changejournal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tr := NewTracker()
+
+	before := clock.Now()
+
+	// not covered until reset.
+	require.False(t, tr.SubtreeUnchangedSince("/a/b", before))
+
+	tr.Reset()
+
+	afterReset := clock.Now()
+
+	// coverage started after the requested time.
+	require.False(t, tr.SubtreeUnchangedSince("/a/b", before))
+	require.True(t, tr.SubtreeUnchangedSince("/a/b", afterReset))
+
+	tr.MarkDirty("/a/b/c/")
+
+	// the change affects the directory and all its parents, but not siblings or children.
+	require.False(t, tr.SubtreeUnchangedSince("/a/b/c", afterReset))
+	require.False(t, tr.SubtreeUnchangedSince("/a/b", afterReset))
+	require.False(t, tr.SubtreeUnchangedSince("/a", afterReset))
+	require.False(t, tr.SubtreeUnchangedSince("/", afterReset))
+	require.True(t, tr.SubtreeUnchangedSince("/a/b/d", afterReset))
+	require.True(t, tr.SubtreeUnchangedSince("/a/b/c/d", afterReset))
+
+	// changes before the requested time don't matter.
+	require.True(t, tr.SubtreeUnchangedSince("/a/b", clock.Now().Add(time.Second)))
+
+	tr.invalidate()
+	require.False(t, tr.SubtreeUnchangedSince("/a/b/d", afterReset))
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	fname := filepath.Join(testutil.TempDirectory(t), "journal")
+
+	tr := NewTracker()
+	tr.MarkDirty("/a/x")
+	require.NoError(t, tr.PersistTo(fname))
+	require.Error(t, tr.PersistTo(fname))
+
+	tr.Reset()
+
+	t0 := tr.CoverageStart()
+
+	tr.MarkDirty("/a/b")
+	tr.MarkHardLinked("/c/d")
+
+	t1 := clock.Now()
+
+	loaded, err := LoadJournal(fname)
+	require.NoError(t, err)
+
+	require.Equal(t, tr.CoverageStart().UnixNano(), loaded.CoverageStart().UnixNano())
+	require.False(t, loaded.SubtreeUnchangedSince("/a/b", t0))
+	require.True(t, loaded.SubtreeUnchangedSince("/a/x", t0))
+	require.True(t, loaded.SubtreeUnchangedSince("/a", t1.Add(time.Second)))
+	require.False(t, loaded.SubtreeUnchangedSince("/c/d", t1.Add(time.Second)))
+	require.False(t, loaded.SubtreeUnchangedSince("/c", t1.Add(time.Second)))
+
+	// a partially-written trailing record is ignored.
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+
+	_, err = f.WriteString(`{"t":"2030-01-01T00:00:00Z","dir":"/a/`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	loaded, err = LoadJournal(fname)
+	require.NoError(t, err)
+	require.True(t, loaded.SubtreeUnchangedSince("/a/x", t0))
+
+	// once tracking stops, nothing is unchanged.
+	require.NoError(t, os.Truncate(fname, 0))
+	require.NoError(t, tr.Close())
+
+	tr2 := NewTracker()
+	tr2.Reset()
+	require.NoError(t, tr2.PersistTo(fname))
+	tr2.invalidate()
+	require.NoError(t, tr2.Close())
+
+	loaded, err = LoadJournal(fname)
+	require.NoError(t, err)
+	require.False(t, loaded.SubtreeUnchangedSince("/a/x", t0))
+
+	_, err = LoadJournal(filepath.Join(testutil.TempDirectory(t), "no-such-file"))
+	require.Error(t, err)
+}
+
+func TestJournal_StaleWithoutHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	fname := filepath.Join(testutil.TempDirectory(t), "journal")
+
+	// journal of a tracker that was killed without recording that it stopped.
+	start := clock.Now().Add(-journalStaleAfter - time.Minute)
+
+	require.NoError(t, os.WriteFile(fname, []byte(`{"t":"`+start.Format(time.RFC3339Nano)+`","start":true}`+"\n"), 0o600))
+
+	loaded, err := LoadJournal(fname)
+	require.NoError(t, err)
+	require.True(t, loaded.CoverageStart().IsZero())
+	require.False(t, loaded.SubtreeUnchangedSince("/a", start))
+
+	// recent heartbeat keeps the coverage.
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+
+	_, err = f.WriteString(`{"t":"` + clock.Now().Format(time.RFC3339Nano) + `","alive":true}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	loaded, err = LoadJournal(fname)
+	require.NoError(t, err)
+	require.True(t, loaded.SubtreeUnchangedSince("/a", start))
+}
+
+func TestJournal_Compaction(t *testing.T) {
+	t.Parallel()
+
+	fname := filepath.Join(testutil.TempDirectory(t), "journal")
+
+	tr := NewTracker()
+	tr.Reset()
+	require.NoError(t, tr.PersistTo(fname))
+
+	t0 := clock.Now()
+
+	for range compactMinRecords + 10 {
+		tr.MarkDirty("/a/b")
+	}
+
+	require.NoError(t, tr.Close())
+
+	b, err := os.ReadFile(fname)
+	require.NoError(t, err)
+	require.Less(t, len(b), 100*compactMinRecords/2)
+
+	loaded, err := LoadJournal(fname)
+	require.NoError(t, err)
+	require.False(t, loaded.SubtreeUnchangedSince("/a/b", t0))
+	require.True(t, loaded.SubtreeUnchangedSince("/a/c", t0))
+}
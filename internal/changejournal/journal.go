@@ -0,0 +1,315 @@
+package changejournal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/atomicfile"
+	"github.com/kopia/kopia/internal/clock"
+)
+
+const (
+	// the journal is rewritten once it has at least this many records
+	// and mostly contains records superseded by newer ones.
+	compactMinRecords = 10000
+	compactRatio      = 4
+
+	maxJournalLineLength = 1 << 20
+
+	// HeartbeatInterval is how often a tracker writing a journal records that it is still running.
+	HeartbeatInterval = time.Minute
+
+	// coverage of a journal which was not written to for this long is considered lost,
+	// since the tracker writing it may have been killed without recording that it stopped.
+	journalStaleAfter = 3 * HeartbeatInterval
+)
+
+// journalRecord is a single line of the journal file.
+type journalRecord struct {
+	Time  time.Time `json:"t"`
+	Start bool      `json:"start,omitempty"`
+	Stop  bool      `json:"stop,omitempty"`
+	Alive bool      `json:"alive,omitempty"`
+	Dir   string    `json:"dir,omitempty"`
+
+	// HardLinked marks Dir as containing files with multiple hard links, older readers treat
+	// such records as changes to Dir, which is safe.
+	HardLinked bool `json:"hardLinked,omitempty"`
+}
+
+// journalWriter appends records to a journal file, which allows changes observed by a long-running
+// process to be used by others.
+type journalWriter struct {
+	fname      string
+	f          *os.File
+	numRecords int
+	err        error
+}
+
+func (w *journalWriter) write(rec journalRecord) {
+	if w.err != nil {
+		return
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		w.fail(errors.Wrap(err, "unable to marshal journal record"))
+		return
+	}
+
+	// each record is written with a single call, so that readers never observe partial records
+	// other than the last one.
+	if _, err := w.f.Write(append(b, '\n')); err != nil {
+		w.fail(errors.Wrap(err, "unable to write journal record"))
+		return
+	}
+
+	w.numRecords++
+}
+
+func (w *journalWriter) writeStart(ts time.Time) {
+	w.write(journalRecord{Time: ts, Start: true})
+}
+
+func (w *journalWriter) writeStop(ts time.Time) {
+	w.write(journalRecord{Time: ts, Stop: true})
+}
+
+func (w *journalWriter) writeAlive(ts time.Time) {
+	w.write(journalRecord{Time: ts, Alive: true})
+}
+
+func (w *journalWriter) writeDirty(dir string, ts time.Time) {
+	w.write(journalRecord{Time: ts, Dir: dir})
+}
+
+func (w *journalWriter) writeHardLinked(dir string, ts time.Time) {
+	w.write(journalRecord{Time: ts, Dir: dir, HardLinked: true})
+}
+
+// fail removes the journal file since readers would otherwise miss changes, the error is
+// reported by JournalError().
+func (w *journalWriter) fail(err error) {
+	w.err = err
+	w.f.Close()        //nolint:errcheck,gosec
+	os.Remove(w.fname) //nolint:errcheck
+}
+
+// PersistTo starts writing the state of the tracker and all subsequent changes to the provided journal
+// file, which can be loaded using LoadJournal().
+func (t *Tracker) PersistTo(fname string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.journal != nil {
+		return errors.New("tracker is already persisted")
+	}
+
+	if err := t.rewriteJournalLocked(fname); err != nil {
+		return err
+	}
+
+	t.stopHeartbeat = make(chan struct{})
+
+	go t.heartbeatLoop(t.stopHeartbeat)
+
+	return nil
+}
+
+// heartbeatLoop periodically records in the journal that the tracker is still running.
+func (t *Tracker) heartbeatLoop(stop chan struct{}) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			t.heartbeat()
+		}
+	}
+}
+
+func (t *Tracker) heartbeat() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.journal != nil {
+		t.journal.writeAlive(clock.Now())
+		t.maybeCompactLocked()
+	}
+}
+
+// JournalError returns the error that caused the journal file to no longer be written, if any.
+func (t *Tracker) JournalError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.journal == nil {
+		return nil
+	}
+
+	return t.journal.err
+}
+
+// Close stops writing the journal file.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.journal == nil {
+		return nil
+	}
+
+	j := t.journal
+	t.journal = nil
+
+	close(t.stopHeartbeat)
+
+	if j.err != nil {
+		return j.err
+	}
+
+	return errors.Wrap(j.f.Close(), "error closing journal")
+}
+
+// +checklocks:t.mu
+func (t *Tracker) maybeCompactLocked() {
+	j := t.journal
+	if j.err != nil || j.numRecords < compactMinRecords || j.numRecords < compactRatio*len(t.latestChange) {
+		return
+	}
+
+	j.f.Close() //nolint:errcheck,gosec
+
+	if err := t.rewriteJournalLocked(j.fname); err != nil {
+		j.fail(err)
+	}
+}
+
+// rewriteJournalLocked atomically replaces the journal file with the current state of the tracker.
+//
+// +checklocks:t.mu
+func (t *Tracker) rewriteJournalLocked(fname string) error {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	records := []journalRecord{}
+
+	if !t.coverageStart.IsZero() {
+		records = append(records, journalRecord{Time: t.coverageStart, Start: true})
+	}
+
+	for dir, ts := range t.latestChange {
+		records = append(records, journalRecord{Time: ts, Dir: dir})
+	}
+
+	now := clock.Now()
+
+	for dir := range t.hardLinked {
+		records = append(records, journalRecord{Time: now, Dir: dir, HardLinked: true})
+	}
+
+	records = append(records, journalRecord{Time: now, Alive: true})
+
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return errors.Wrap(err, "unable to encode journal record")
+		}
+	}
+
+	if err := atomicfile.Write(fname, &buf); err != nil {
+		return errors.Wrap(err, "unable to write journal")
+	}
+
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_APPEND, 0) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to open journal")
+	}
+
+	t.journal = &journalWriter{fname: fname, f: f, numRecords: len(records)}
+
+	return nil
+}
+
+// LoadJournal returns a Tracker reflecting the contents of the journal file written by another tracker.
+// Coverage is only retained if the writer has recorded that it's running recently, so that changes
+// are never missed after the writer is killed without recording that it stopped.
+func LoadJournal(fname string) (*Tracker, error) {
+	f, err := os.Open(fname) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open journal")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	t := NewTracker()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, maxJournalLineLength)
+	s.Split(scanCompleteLines)
+
+	var lastRecordTime time.Time
+
+	for s.Scan() {
+		var rec journalRecord
+
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return nil, errors.Wrap(err, "invalid journal record")
+		}
+
+		if rec.Time.After(lastRecordTime) {
+			lastRecordTime = rec.Time
+		}
+
+		switch {
+		case rec.Start:
+			t.resetLocked(rec.Time)
+		case rec.Stop:
+			t.coverageStart = time.Time{}
+			clear(t.latestChange)
+		case rec.Alive:
+			// writer is still running, only the record time matters.
+		case rec.HardLinked:
+			t.markHardLinkedLocked(rec.Dir)
+		default:
+			t.markDirtyLocked(rec.Dir, rec.Time)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading journal")
+	}
+
+	if clock.Now().Sub(lastRecordTime) > journalStaleAfter {
+		t.coverageStart = time.Time{}
+		clear(t.latestChange)
+	}
+
+	return t, nil
+}
+
+// scanCompleteLines is like bufio.ScanLines but ignores a trailing line without newline, which
+// may be in the process of being written.
+func scanCompleteLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[0:i], nil
+	}
+
+	if atEOF {
+		return len(data), nil, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,229 @@
+package changejournal
+
+import (
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inotifyEventHeaderSize = 16 // wd, mask, cookie, len
+	inotifyReadBufferSize  = 64 << 10
+
+	watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB |
+		unix.IN_CLOSE_WRITE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+		unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR | unix.IN_DONT_FOLLOW
+)
+
+// Watch starts recording changes to the provided local directory trees in the tracker using inotify
+// and returns a function that stops watching. Coverage of the tracker starts once all directories are watched.
+//
+// Files with multiple hard links can be modified through links outside of the watched trees, so
+// directories containing them are never reported as unchanged. Hard links to watched files created
+// outside of the watched trees after watching has started can't be detected by inotify.
+func Watch(ctx context.Context, t *Tracker, roots ...string) (stop func(), err error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initialize inotify")
+	}
+
+	w := &watcher{
+		tracker: t,
+		fd:      fd,
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		roots:   roots,
+		paths:   map[int]string{},
+	}
+
+	if err := w.addAll(ctx); err != nil {
+		w.f.Close() //nolint:errcheck
+
+		return nil, err
+	}
+
+	t.Reset()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		// once events are no longer processed, nothing can be considered unchanged.
+		defer t.invalidate()
+
+		w.run(ctx)
+	}()
+
+	return func() {
+		w.f.Close() //nolint:errcheck
+		wg.Wait()
+	}, nil
+}
+
+type watcher struct {
+	tracker *Tracker
+	fd      int      // inotify descriptor, f.Fd() can't be used since it makes reads blocking
+	f       *os.File // used for reading, closing it interrupts pending reads
+	roots   []string
+
+	paths map[int]string // watch descriptor to directory path, only accessed by a single goroutine at a time
+}
+
+func (w *watcher) addAll(ctx context.Context) error {
+	for _, r := range w.roots {
+		if err := w.addRecursive(ctx, filepath.Clean(r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addRecursive watches the provided directory and all its subdirectories, marking them as dirty.
+func (w *watcher) addRecursive(ctx context.Context, root string) error {
+	//nolint:wrapcheck
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// directories that can't be read will fail the snapshot, there is nothing to track.
+			log(ctx).Debugf("unable to watch %v: %v", p, err)
+			return nil
+		}
+
+		if !d.IsDir() {
+			if d.Type().IsRegular() && hasMultipleLinks(p) {
+				w.tracker.MarkHardLinked(filepath.Dir(p))
+			}
+
+			return nil
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, p, watchMask)
+		if err != nil {
+			if errors.Is(err, unix.ENOSPC) {
+				return errors.Errorf("inotify watch limit reached while watching %v, consider increasing fs.inotify.max_user_watches", p)
+			}
+
+			log(ctx).Debugf("unable to watch %v: %v", p, err)
+
+			return nil
+		}
+
+		w.paths[wd] = p
+		w.tracker.MarkDirty(p)
+
+		return nil
+	})
+}
+
+func (w *watcher) run(ctx context.Context) {
+	buf := make([]byte, inotifyReadBufferSize)
+
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log(ctx).Errorf("error reading inotify events: %v", err)
+			}
+
+			return
+		}
+
+		if err := w.processEvents(ctx, buf[0:n]); err != nil {
+			log(ctx).Errorf("stopped tracking changes: %v", err)
+			return
+		}
+
+		if err := w.tracker.JournalError(); err != nil {
+			log(ctx).Errorf("stopped tracking changes: %v", err)
+			return
+		}
+	}
+}
+
+func (w *watcher) processEvents(ctx context.Context, buf []byte) error {
+	for len(buf) >= inotifyEventHeaderSize {
+		wd := int(int32(binary.NativeEndian.Uint32(buf[0:]))) //nolint:gosec
+		mask := binary.NativeEndian.Uint32(buf[4:])
+		nameLen := int(binary.NativeEndian.Uint32(buf[12:]))
+
+		if len(buf) < inotifyEventHeaderSize+nameLen {
+			return errors.New("truncated inotify event")
+		}
+
+		name := string(bytesUntilNul(buf[inotifyEventHeaderSize : inotifyEventHeaderSize+nameLen]))
+		buf = buf[inotifyEventHeaderSize+nameLen:]
+
+		if err := w.processEvent(ctx, wd, mask, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *watcher) processEvent(ctx context.Context, wd int, mask uint32, name string) error {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		// some events were lost, directories created in the meantime may not be watched yet.
+		log(ctx).Infof("inotify event queue overflow, restarting change tracking")
+
+		if err := w.addAll(ctx); err != nil {
+			return err
+		}
+
+		w.tracker.Reset()
+
+		return nil
+	}
+
+	dir, ok := w.paths[wd]
+	if !ok {
+		return nil
+	}
+
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+		return nil
+	}
+
+	w.tracker.MarkDirty(dir)
+
+	// hard links created in watched directories are reported as new entries.
+	if name != "" && mask&unix.IN_ISDIR == 0 && mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 && hasMultipleLinks(filepath.Join(dir, name)) {
+		w.tracker.MarkHardLinked(dir)
+	}
+
+	if name != "" && mask&unix.IN_ISDIR != 0 && mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		return w.addRecursive(ctx, filepath.Join(dir, name))
+	}
+
+	return nil
+}
+
+// hasMultipleLinks returns true if the provided path is a regular file with more than one hard link.
+func hasMultipleLinks(path string) bool {
+	var st unix.Stat_t
+
+	if err := unix.Lstat(path, &st); err != nil {
+		return false
+	}
+
+	return st.Mode&unix.S_IFMT == unix.S_IFREG && st.Nlink > 1
+}
+
+func bytesUntilNul(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[0:i]
+		}
+	}
+
+	return b
+}
@@ -0,0 +1,104 @@
+package changejournal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	td := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "a", "b"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "c"), 0o755))
+
+	tr := NewTracker()
+
+	stop, err := Watch(ctx, tr, td)
+	require.NoError(t, err)
+
+	t0 := clock.Now()
+
+	require.True(t, tr.SubtreeUnchangedSince(filepath.Join(td, "a"), t0))
+	require.True(t, tr.SubtreeUnchangedSince(filepath.Join(td, "c"), t0))
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "a", "b", "f"), []byte{1, 2, 3}, 0o600))
+
+	require.Eventually(t, func() bool {
+		return !tr.SubtreeUnchangedSince(filepath.Join(td, "a"), t0)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.True(t, tr.SubtreeUnchangedSince(filepath.Join(td, "c"), t0))
+
+	// changes in newly-created directories are tracked as well.
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "c", "new"), 0o755))
+
+	require.Eventually(t, func() bool {
+		return !tr.SubtreeUnchangedSince(filepath.Join(td, "c"), t0)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	t1 := clock.Now().Add(time.Millisecond)
+
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(td, "c", "new", "f"), []byte{1, 2, 3}, 0o600))
+
+	require.Eventually(t, func() bool {
+		return !tr.SubtreeUnchangedSince(filepath.Join(td, "c", "new"), t1)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stop()
+
+	require.False(t, tr.SubtreeUnchangedSince(filepath.Join(td, "a", "b"), t0))
+	require.Zero(t, tr.CoverageStart())
+}
+
+func TestWatch_HardLinks(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	td := testutil.TempDirectory(t)
+	outside := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "a", "b"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "c"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "d"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d", "g"), []byte{1, 2, 3}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "f"), []byte{1, 2, 3}, 0o600))
+	require.NoError(t, os.Link(filepath.Join(outside, "f"), filepath.Join(td, "a", "b", "f")))
+
+	tr := NewTracker()
+
+	stop, err := Watch(ctx, tr, td)
+	require.NoError(t, err)
+
+	defer stop()
+
+	t0 := clock.Now()
+
+	// writes through the link outside of the watched tree are not reported,
+	// so directories with hard-linked files are never unchanged.
+	require.False(t, tr.SubtreeUnchangedSince(filepath.Join(td, "a", "b"), t0))
+	require.False(t, tr.SubtreeUnchangedSince(filepath.Join(td, "a"), t0))
+	require.True(t, tr.SubtreeUnchangedSince(filepath.Join(td, "c"), t0))
+
+	// hard links created in the watched tree while watching are detected as well, unlike
+	// regular changes they prevent reuse for any later point in time.
+	require.NoError(t, os.Link(filepath.Join(outside, "f"), filepath.Join(td, "c", "f")))
+
+	require.Eventually(t, func() bool {
+		return !tr.SubtreeUnchangedSince(filepath.Join(td, "c"), clock.Now().Add(time.Hour))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.True(t, tr.SubtreeUnchangedSince(filepath.Join(td, "d"), clock.Now().Add(time.Hour)))
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package changejournal
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Watch starts recording changes to the provided local directory trees in the tracker.
+// Watching is only supported on Linux.
+func Watch(ctx context.Context, t *Tracker, roots ...string) (stop func(), err error) {
+	return nil, errors.New("tracking changes is only supported on Linux")
+}
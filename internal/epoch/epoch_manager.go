@@ -11,6 +11,10 @@
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/kopia/kopia/internal/blobparam"
@@ -25,6 +29,8 @@
 // LatestEpoch represents the current epoch number in GetCompleteIndexSet.
 const LatestEpoch = -1
 
+var tracer = otel.Tracer("kopia/epoch")
+
 const (
 	initialRefreshAttemptSleep     = 100 * time.Millisecond
 	maxRefreshAttemptSleep         = 15 * time.Second
@@ -1051,7 +1057,16 @@ func (e *Manager) MaybeCompactSingleEpoch(ctx context.Context) (*maintenancestat
 
 	contentlog.Log1(ctx, e.log, "starting single-epoch compaction for epoch", result)
 
+	ctx, span := tracer.Start(ctx, "CompactSingleEpoch", trace.WithAttributes(
+		attribute.Int("epoch", uncompacted),
+		attribute.Int("blobs", len(uncompactedBlobs)),
+	))
+	defer span.End()
+
 	if err := e.compact(ctx, blob.IDsFromMetadata(uncompactedBlobs), compactedEpochBlobPrefix(uncompacted)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unable to compact epoch")
+
 		return nil, errors.Wrapf(err, "unable to compact blobs for epoch %v: performance will be affected", uncompacted)
 	}
 
@@ -1080,7 +1095,21 @@ func (e *Manager) getIndexesFromEpochInternal(ctx context.Context, cs CurrentSna
 	return uncompactedBlobs, nil
 }
 
-func (e *Manager) generateRangeCheckpointFromCommittedState(ctx context.Context, cs CurrentSnapshot, minEpoch, maxEpoch int) error {
+func (e *Manager) generateRangeCheckpointFromCommittedState(ctx context.Context, cs CurrentSnapshot, minEpoch, maxEpoch int) (resultErr error) {
+	ctx, span := tracer.Start(ctx, "GenerateRangeCheckpoint", trace.WithAttributes(
+		attribute.Int("minEpoch", minEpoch),
+		attribute.Int("maxEpoch", maxEpoch),
+	))
+
+	defer func() {
+		if resultErr != nil {
+			span.RecordError(resultErr)
+			span.SetStatus(codes.Error, "unable to generate range checkpoint")
+		}
+
+		span.End()
+	}()
+
 	contentlog.Log2(ctx, e.log,
 		"generating range checkpoint",
 		logparam.Int("minEpoch", minEpoch),
@@ -0,0 +1,561 @@
+// Package fssnapshot creates consistent point-in-time snapshots of Linux filesystems
+// using btrfs, LVM thin volumes or ZFS, which can be read instead of the live filesystem.
+package fssnapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("fssnapshot")
+
+// Supported snapshot providers.
+const (
+	ProviderAuto  = "auto"
+	ProviderBtrfs = "btrfs"
+	ProviderLVM   = "lvm"
+	ProviderZFS   = "zfs"
+)
+
+// SupportedProviders returns the list of supported snapshot providers.
+func SupportedProviders() []string {
+	return []string{ProviderAuto, ProviderBtrfs, ProviderLVM, ProviderZFS}
+}
+
+// snapshotNamePrefix is the prefix of names of all snapshots, which are named
+// kopia-<date>-<time>-<host-id>-<boot-id>-<pid>-<random> so that snapshots left behind by processes
+// that crashed can be recognized and removed.
+const snapshotNamePrefix = "kopia-"
+
+const (
+	snapshotNameTimeFormat = "20060102-150405"
+
+	// length of host IDs, boot IDs and random parts of snapshot names, in hex characters.
+	snapshotNameIDLength = 8
+
+	// unknownID is used in snapshot names when the host or boot can't be identified.
+	unknownID = "00000000"
+)
+
+// Snapshot is a filesystem snapshot which exposes the contents of the source path at a local path.
+type Snapshot struct {
+	// Path is the location of the snapshotted source path.
+	Path string
+
+	// cleanup functions, executed in reverse order.
+	cleanup []func(ctx context.Context) error
+}
+
+// Release unmounts and destroys the snapshot.
+func (s *Snapshot) Release(ctx context.Context) error {
+	var errs []error
+
+	for i := len(s.cleanup) - 1; i >= 0; i-- {
+		if err := s.cleanup[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	s.cleanup = nil
+
+	return errors.Wrap(stderrors.Join(errs...), "error releasing snapshot")
+}
+
+func (s *Snapshot) addCleanup(f func(ctx context.Context) error) {
+	s.cleanup = append(s.cleanup, f)
+}
+
+// Creator creates filesystem snapshots.
+type Creator struct {
+	// Run runs external command and returns its standard output.
+	Run func(ctx context.Context, name string, args ...string) (string, error)
+
+	// Mounts returns the list of mounted filesystems.
+	Mounts func() ([]MountInfo, error)
+
+	// IsSubvolumeRoot determines whether the provided path is the root of a btrfs subvolume.
+	IsSubvolumeRoot func(path string) (bool, error)
+
+	// MkdirTemp creates a temporary directory used as a mount point.
+	MkdirTemp func() (string, error)
+
+	// ProcessExists determines whether a process with the provided ID is running.
+	ProcessExists func(pid int) bool
+
+	// HostID and BootID identify the host and its current boot. Snapshots are only removed as stale
+	// when they were created on the same host, by a process that is no longer running or during
+	// a previous boot. Stale snapshots are never removed when either is empty.
+	HostID string
+	BootID string
+}
+
+// NewCreator returns a Creator that operates on the local system.
+func NewCreator() *Creator {
+	return &Creator{
+		Run:             runCommand,
+		Mounts:          readMountInfo,
+		IsSubvolumeRoot: isBtrfsSubvolumeRoot,
+		MkdirTemp: func() (string, error) {
+			return os.MkdirTemp("", "kopia-snapshot-") //nolint:wrapcheck
+		},
+		ProcessExists: processExists,
+		HostID:        hostID(),
+		BootID:        bootID(),
+	}
+}
+
+// Create creates a snapshot of the filesystem containing the provided absolute path using the given provider.
+// The provider can be ProviderAuto, in which case it is determined based on the filesystem type.
+// Filesystems mounted below the path are not included in snapshots, so Create fails if there are any.
+func (c *Creator) Create(ctx context.Context, provider, path string) (*Snapshot, error) {
+	mounts, err := c.Mounts()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list mounts")
+	}
+
+	m, ok := mountForPath(mounts, path)
+	if !ok {
+		return nil, errors.Errorf("unable to find mount for %v", path)
+	}
+
+	if nested := nestedMountPoints(mounts, m, path); len(nested) > 0 {
+		return nil, errors.Errorf("unable to snapshot %v, it contains other mounted filesystems: %v", path, strings.Join(nested, ", "))
+	}
+
+	if provider == "" || provider == ProviderAuto {
+		provider = providerForMount(m)
+		if provider == "" {
+			return nil, errors.Errorf("%v is not on a btrfs, ZFS or LVM filesystem", path)
+		}
+	}
+
+	name := c.snapshotName()
+
+	log(ctx).Infof("creating %v snapshot %v of %v (mounted at %v)", provider, name, m.Source, m.MountPoint)
+
+	switch provider {
+	case ProviderBtrfs:
+		return c.createBtrfs(ctx, m, path, name)
+	case ProviderLVM:
+		return c.createLVM(ctx, mounts, m, path, name)
+	case ProviderZFS:
+		return c.createZFS(ctx, m, path, name)
+	default:
+		return nil, errors.Errorf("unsupported snapshot provider %q", provider)
+	}
+}
+
+func providerForMount(m MountInfo) string {
+	switch {
+	case m.FSType == "btrfs":
+		return ProviderBtrfs
+	case m.FSType == "zfs":
+		return ProviderZFS
+	case strings.HasPrefix(m.Source, "/dev/mapper/"):
+		return ProviderLVM
+	default:
+		return ""
+	}
+}
+
+// createBtrfs creates a read-only snapshot of the subvolume containing the path inside the subvolume itself.
+func (c *Creator) createBtrfs(ctx context.Context, m MountInfo, path, name string) (*Snapshot, error) {
+	if m.FSType != "btrfs" {
+		return nil, errors.Errorf("%v is not on a btrfs filesystem", path)
+	}
+
+	subvol := path
+
+	for {
+		isRoot, err := c.IsSubvolumeRoot(subvol)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to find btrfs subvolume")
+		}
+
+		if isRoot || subvol == m.MountPoint || subvol == filepath.Dir(subvol) {
+			break
+		}
+
+		subvol = filepath.Dir(subvol)
+	}
+
+	nested, err := c.cleanupStaleBtrfs(ctx, m, subvol)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range nested {
+		if isPathUnder(p, path) {
+			return nil, errors.Errorf("unable to snapshot %v, it contains nested btrfs subvolume %v", path, p)
+		}
+	}
+
+	snapPath := filepath.Join(subvol, "."+name)
+
+	if _, err := c.Run(ctx, "btrfs", "subvolume", "snapshot", "-r", subvol, snapPath); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{Path: rebase(path, subvol, snapPath)}
+	s.addCleanup(func(ctx context.Context) error {
+		_, err := c.Run(ctx, "btrfs", "subvolume", "delete", snapPath)
+		return err
+	})
+
+	return s, nil
+}
+
+// createLVM creates a thin snapshot of the logical volume backing the mount and mounts it read-only.
+func (c *Creator) createLVM(ctx context.Context, mounts []MountInfo, m MountInfo, path, name string) (_ *Snapshot, err error) {
+	out, err := c.Run(ctx, "lvs", "--noheadings", "--separator", ",", "-o", "vg_name,lv_name,lv_attr", m.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(strings.TrimSpace(out), ",")
+	if len(parts) != 3 { //nolint:mnd
+		return nil, errors.Errorf("unexpected output of lvs: %q", out)
+	}
+
+	vg, lv, attr := parts[0], parts[1], parts[2]
+	if !strings.HasPrefix(attr, "V") {
+		return nil, errors.Errorf("%v/%v is not a thin logical volume", vg, lv)
+	}
+
+	c.cleanupStaleLVM(ctx, mounts, vg)
+
+	s := &Snapshot{}
+
+	defer func() {
+		if err != nil {
+			s.Release(ctx) //nolint:errcheck
+		}
+	}()
+
+	if _, err = c.Run(ctx, "lvcreate", "--snapshot", "--setactivationskip", "n", "--permission", "r", "--name", name, vg+"/"+lv); err != nil {
+		return nil, err
+	}
+
+	s.addCleanup(func(ctx context.Context) error {
+		_, err := c.Run(ctx, "lvremove", "--yes", vg+"/"+name)
+		return err
+	})
+
+	mountDir, err := c.MkdirTemp()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create mount point")
+	}
+
+	s.addCleanup(func(context.Context) error {
+		return errors.Wrap(os.Remove(mountDir), "unable to remove mount point")
+	})
+
+	opts := "ro"
+	if m.FSType == "xfs" {
+		// XFS refuses to mount a filesystem with the same UUID as one that's already mounted.
+		opts += ",nouuid"
+	}
+
+	if _, err = c.Run(ctx, "mount", "-t", m.FSType, "-o", opts, "/dev/"+vg+"/"+name, mountDir); err != nil {
+		return nil, err
+	}
+
+	s.addCleanup(func(ctx context.Context) error {
+		_, err := c.Run(ctx, "umount", mountDir)
+		return err
+	})
+
+	s.Path = rebase(path, m.MountPoint, filepath.Join(mountDir, m.Root))
+
+	return s, nil
+}
+
+// createZFS creates a snapshot of the dataset backing the mount, which is accessed through the .zfs directory.
+func (c *Creator) createZFS(ctx context.Context, m MountInfo, path, name string) (*Snapshot, error) {
+	if m.FSType != "zfs" {
+		return nil, errors.Errorf("%v is not on a ZFS filesystem", path)
+	}
+
+	c.cleanupStaleZFS(ctx, m.Source)
+
+	snapName := m.Source + "@" + name
+
+	if _, err := c.Run(ctx, "zfs", "snapshot", snapName); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{Path: rebase(path, m.MountPoint, filepath.Join(m.MountPoint, ".zfs", "snapshot", name))}
+	s.addCleanup(func(ctx context.Context) error {
+		_, err := c.Run(ctx, "zfs", "destroy", snapName)
+		return err
+	})
+
+	return s, nil
+}
+
+// cleanupStaleBtrfs removes snapshots of the subvolume left behind by processes that crashed and
+// returns the paths of the remaining subvolumes nested in it.
+func (c *Creator) cleanupStaleBtrfs(ctx context.Context, m MountInfo, subvol string) ([]string, error) {
+	out, err := c.Run(ctx, "btrfs", "subvolume", "list", "-o", subvol)
+	if err != nil {
+		return nil, err
+	}
+
+	var nested []string
+
+	for line := range strings.SplitSeq(out, "\n") {
+		// ID 258 gen 9 top level 256 path @home/user/.kopia-20240101-120000-1234-abcdef01
+		_, fsPath, ok := strings.Cut(line, " path ")
+		if !ok {
+			continue
+		}
+
+		rel, ok := strings.CutPrefix("/"+strings.TrimPrefix(strings.TrimSpace(fsPath), "<FS_TREE>/"), m.Root)
+		if !ok {
+			continue
+		}
+
+		p := filepath.Join(m.MountPoint, rel)
+
+		if base := filepath.Base(p); filepath.Dir(p) == subvol && strings.HasPrefix(base, "."+snapshotNamePrefix) {
+			if c.isStaleSnapshotName(base[1:]) {
+				log(ctx).Infof("removing stale snapshot %v", p)
+
+				if _, err := c.Run(ctx, "btrfs", "subvolume", "delete", p); err != nil {
+					log(ctx).Warnf("unable to remove stale snapshot %v: %v", p, err)
+				}
+			}
+
+			continue
+		}
+
+		nested = append(nested, p)
+	}
+
+	return nested, nil
+}
+
+// cleanupStaleLVM unmounts and removes snapshots in the volume group left behind by processes that crashed.
+func (c *Creator) cleanupStaleLVM(ctx context.Context, mounts []MountInfo, vg string) {
+	out, err := c.Run(ctx, "lvs", "--noheadings", "-o", "lv_name", vg)
+	if err != nil {
+		log(ctx).Warnf("unable to list logical volumes in %v: %v", vg, err)
+		return
+	}
+
+	for _, lv := range strings.Fields(out) {
+		if !c.isStaleSnapshotName(lv) {
+			continue
+		}
+
+		log(ctx).Infof("removing stale snapshot %v/%v", vg, lv)
+
+		for _, m := range mounts {
+			if m.Source != "/dev/mapper/"+lvmMapperName(vg, lv) && m.Source != "/dev/"+vg+"/"+lv {
+				continue
+			}
+
+			if _, err := c.Run(ctx, "umount", m.MountPoint); err != nil {
+				log(ctx).Warnf("unable to unmount stale snapshot at %v: %v", m.MountPoint, err)
+				continue
+			}
+
+			if strings.HasPrefix(filepath.Base(m.MountPoint), "kopia-snapshot-") {
+				os.Remove(m.MountPoint) //nolint:errcheck
+			}
+		}
+
+		if _, err := c.Run(ctx, "lvremove", "--yes", vg+"/"+lv); err != nil {
+			log(ctx).Warnf("unable to remove stale snapshot %v/%v: %v", vg, lv, err)
+		}
+	}
+}
+
+// lvmMapperName returns the device mapper name of a logical volume, which has dashes in names doubled.
+func lvmMapperName(vg, lv string) string {
+	return strings.ReplaceAll(vg, "-", "--") + "-" + strings.ReplaceAll(lv, "-", "--")
+}
+
+// cleanupStaleZFS destroys snapshots of the dataset left behind by processes that crashed.
+func (c *Creator) cleanupStaleZFS(ctx context.Context, dataset string) {
+	out, err := c.Run(ctx, "zfs", "list", "-H", "-t", "snapshot", "-d", "1", "-o", "name", dataset)
+	if err != nil {
+		log(ctx).Warnf("unable to list snapshots of %v: %v", dataset, err)
+		return
+	}
+
+	for _, snapName := range strings.Fields(out) {
+		ds, name, ok := strings.Cut(snapName, "@")
+		if !ok || ds != dataset || !c.isStaleSnapshotName(name) {
+			continue
+		}
+
+		log(ctx).Infof("removing stale snapshot %v", snapName)
+
+		if _, err := c.Run(ctx, "zfs", "destroy", snapName); err != nil {
+			log(ctx).Warnf("unable to remove stale snapshot %v: %v", snapName, err)
+		}
+	}
+}
+
+// rebase returns the location of path, which is under oldBase, relative to newBase.
+func rebase(path, oldBase, newBase string) string {
+	rel, err := filepath.Rel(oldBase, path)
+	if err != nil {
+		return newBase
+	}
+
+	return filepath.Join(newBase, rel)
+}
+
+func (c *Creator) snapshotName() string {
+	var b [snapshotNameIDLength / 2]byte
+
+	rand.Read(b[:]) //nolint:errcheck
+
+	return snapshotNamePrefix + strings.Join([]string{
+		clock.Now().UTC().Format(snapshotNameTimeFormat),
+		orUnknownID(c.HostID),
+		orUnknownID(c.BootID),
+		strconv.Itoa(os.Getpid()),
+		hex.EncodeToString(b[:]),
+	}, "-")
+}
+
+// snapshotNameInfo describes the creator of a snapshot, as encoded in its name.
+type snapshotNameInfo struct {
+	hostID string
+	bootID string
+	pid    int
+}
+
+// parseSnapshotName strictly parses the name of a snapshot created by snapshotName().
+func parseSnapshotName(name string) (snapshotNameInfo, bool) {
+	rest, ok := strings.CutPrefix(name, snapshotNamePrefix)
+	if !ok {
+		return snapshotNameInfo{}, false
+	}
+
+	// <date>-<time>-<host-id>-<boot-id>-<pid>-<random>
+	parts := strings.Split(rest, "-")
+	if len(parts) != 6 { //nolint:mnd
+		return snapshotNameInfo{}, false
+	}
+
+	if _, err := time.Parse(snapshotNameTimeFormat, parts[0]+"-"+parts[1]); err != nil {
+		return snapshotNameInfo{}, false
+	}
+
+	if !isSnapshotNameID(parts[2]) || !isSnapshotNameID(parts[3]) || !isSnapshotNameID(parts[5]) {
+		return snapshotNameInfo{}, false
+	}
+
+	pid, err := strconv.Atoi(parts[4])
+	if err != nil || pid <= 0 || strconv.Itoa(pid) != parts[4] {
+		return snapshotNameInfo{}, false
+	}
+
+	return snapshotNameInfo{hostID: parts[2], bootID: parts[3], pid: pid}, true
+}
+
+func isSnapshotNameID(s string) bool {
+	if len(s) != snapshotNameIDLength {
+		return false
+	}
+
+	for _, ch := range s {
+		if !strings.ContainsRune("0123456789abcdef", ch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isStaleSnapshotName determines whether the provided name belongs to a snapshot created on this host
+// by a process that's no longer running.
+func (c *Creator) isStaleSnapshotName(name string) bool {
+	if c.HostID == "" || c.BootID == "" {
+		return false
+	}
+
+	info, ok := parseSnapshotName(name)
+	if !ok || info.hostID != c.HostID {
+		return false
+	}
+
+	if info.bootID != c.BootID {
+		// no process survives a reboot.
+		return true
+	}
+
+	return !c.ProcessExists(info.pid)
+}
+
+// hostID returns a short identifier of the host derived from its machine ID or host name.
+func hostID() string {
+	for _, fname := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if b, err := os.ReadFile(fname); err == nil && len(bytes.TrimSpace(b)) > 0 { //nolint:gosec
+			return shortID(b)
+		}
+	}
+
+	if h, err := os.Hostname(); err == nil && h != "" {
+		return shortID([]byte(h))
+	}
+
+	return ""
+}
+
+// bootID returns a short identifier of the current boot of the host.
+func bootID() string {
+	b, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil || len(bytes.TrimSpace(b)) == 0 {
+		return ""
+	}
+
+	return shortID(b)
+}
+
+func shortID(data []byte) string {
+	h := sha256.Sum256(bytes.TrimSpace(data))
+
+	return hex.EncodeToString(h[0 : snapshotNameIDLength/2])
+}
+
+func orUnknownID(id string) string {
+	if id == "" {
+		return unknownID
+	}
+
+	return id
+}
+
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "%v %v failed: %v", name, strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
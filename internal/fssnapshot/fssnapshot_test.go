@@ -0,0 +1,425 @@
+package fssnapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+type fakeSystem struct {
+	mounts       []MountInfo
+	subvolumes   map[string]bool
+	outputs      map[string]string
+	failCommands map[string]bool
+	commands     []string
+	tempDir      string
+}
+
+const (
+	// stalePID is the process ID of a crashed process that left snapshots behind.
+	stalePID = 999999
+
+	testHostID = "0123abcd"
+	testBootID = "4567ef89"
+)
+
+func testSnapshotName(hostID, bootID string, pid int) string {
+	return fmt.Sprintf("%v20240101-120000-%v-%v-%v-abcdef01", snapshotNamePrefix, hostID, bootID, pid)
+}
+
+func (f *fakeSystem) creator() *Creator {
+	return &Creator{
+		Run: func(_ context.Context, name string, args ...string) (string, error) {
+			f.commands = append(f.commands, name+" "+strings.Join(args, " "))
+
+			if f.failCommands[name] {
+				return "", errors.Errorf("%v failed", name)
+			}
+
+			if out, ok := f.outputs[name+" "+strings.Join(args, " ")]; ok {
+				return out, nil
+			}
+
+			return f.outputs[name], nil
+		},
+		Mounts: func() ([]MountInfo, error) {
+			return f.mounts, nil
+		},
+		IsSubvolumeRoot: func(path string) (bool, error) {
+			return f.subvolumes[path], nil
+		},
+		MkdirTemp: func() (string, error) {
+			return os.MkdirTemp(f.tempDir, "mnt-") //nolint:wrapcheck
+		},
+		ProcessExists: func(pid int) bool {
+			return pid != stalePID
+		},
+		HostID: testHostID,
+		BootID: testBootID,
+	}
+}
+
+func TestBtrfs(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/", FSType: "ext4", Source: "/dev/sda1"},
+			{Root: "/@home", MountPoint: "/home", FSType: "btrfs", Source: "/dev/sda2"},
+		},
+		subvolumes: map[string]bool{"/home/user": true},
+	}
+
+	s, err := f.creator().Create(ctx, ProviderAuto, "/home/user/docs")
+	require.NoError(t, err)
+
+	require.Len(t, f.commands, 2)
+	require.Equal(t, "btrfs subvolume list -o /home/user", f.commands[0])
+
+	snapDir := strings.TrimPrefix(f.commands[1], "btrfs subvolume snapshot -r /home/user ")
+	require.NotEqual(t, f.commands[1], snapDir)
+	require.True(t, strings.HasPrefix(snapDir, "/home/user/."+snapshotNamePrefix), snapDir)
+	require.Equal(t, filepath.Join(snapDir, "docs"), s.Path)
+
+	require.NoError(t, s.Release(ctx))
+	require.Equal(t, "btrfs subvolume delete "+snapDir, f.commands[2])
+}
+
+func TestBtrfsSubvolumeAtMountPoint(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/data", FSType: "btrfs", Source: "/dev/sdb"},
+		},
+	}
+
+	s, err := f.creator().Create(ctx, ProviderBtrfs, "/data/a/b")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(f.commands[1], "btrfs subvolume snapshot -r /data /data/."), f.commands[1])
+	require.True(t, strings.HasSuffix(s.Path, "/a/b"), s.Path)
+
+	_, err = f.creator().Create(ctx, ProviderZFS, "/data/a/b")
+	require.ErrorContains(t, err, "is not on a ZFS filesystem")
+}
+
+func TestLVM(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/", FSType: "ext4", Source: "/dev/sda1"},
+			{Root: "/", MountPoint: "/srv", FSType: "xfs", Source: "/dev/mapper/vg0-data"},
+		},
+		outputs: map[string]string{
+			"lvs": "  vg0,data,Vwi-aotz--\n",
+		},
+		tempDir: t.TempDir(),
+	}
+
+	s, err := f.creator().Create(ctx, ProviderAuto, "/srv/www")
+	require.NoError(t, err)
+	require.Len(t, f.commands, 4)
+
+	require.Equal(t, "lvs --noheadings --separator , -o vg_name,lv_name,lv_attr /dev/mapper/vg0-data", f.commands[0])
+	require.Equal(t, "lvs --noheadings -o lv_name vg0", f.commands[1])
+	require.True(t, strings.HasPrefix(f.commands[2], "lvcreate --snapshot --setactivationskip n --permission r --name "+snapshotNamePrefix), f.commands[2])
+	require.True(t, strings.HasSuffix(f.commands[2], " vg0/data"), f.commands[2])
+
+	name := strings.Fields(f.commands[2])[7]
+
+	mountDir := filepath.Dir(s.Path)
+	require.Equal(t, "mount -t xfs -o ro,nouuid /dev/vg0/"+name+" "+mountDir, f.commands[3])
+	require.Equal(t, "www", filepath.Base(s.Path))
+	require.DirExists(t, mountDir)
+
+	require.NoError(t, s.Release(ctx))
+	require.Equal(t, []string{
+		"umount " + mountDir,
+		"lvremove --yes vg0/" + name,
+	}, f.commands[4:])
+	require.NoDirExists(t, mountDir)
+}
+
+func TestLVMErrors(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/srv", FSType: "ext4", Source: "/dev/mapper/vg0-data"},
+		},
+		outputs: map[string]string{
+			"lvs": "  vg0,data,-wi-ao----\n",
+		},
+		tempDir: t.TempDir(),
+	}
+
+	_, err := f.creator().Create(ctx, ProviderLVM, "/srv")
+	require.ErrorContains(t, err, "vg0/data is not a thin logical volume")
+
+	// failure to mount removes the snapshot.
+	f.outputs["lvs"] = "vg0,data,Vwi-aotz--"
+	f.failCommands = map[string]bool{"mount": true}
+	f.commands = nil
+
+	_, err = f.creator().Create(ctx, ProviderLVM, "/srv")
+	require.ErrorContains(t, err, "mount failed")
+	require.Len(t, f.commands, 5)
+	require.True(t, strings.HasPrefix(f.commands[4], "lvremove --yes vg0/"+snapshotNamePrefix), f.commands[4])
+
+	entries, err := os.ReadDir(f.tempDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestZFS(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/tank/data", FSType: "zfs", Source: "tank/data"},
+		},
+	}
+
+	s, err := f.creator().Create(ctx, ProviderAuto, "/tank/data/photos")
+	require.NoError(t, err)
+	require.Len(t, f.commands, 2)
+	require.Equal(t, "zfs list -H -t snapshot -d 1 -o name tank/data", f.commands[0])
+
+	snapName := strings.TrimPrefix(f.commands[1], "zfs snapshot ")
+	name := strings.TrimPrefix(snapName, "tank/data@")
+	require.True(t, strings.HasPrefix(name, snapshotNamePrefix), name)
+	require.Equal(t, "/tank/data/.zfs/snapshot/"+name+"/photos", s.Path)
+
+	require.NoError(t, s.Release(ctx))
+	require.Equal(t, "zfs destroy "+snapName, f.commands[2])
+
+	// release is idempotent.
+	require.NoError(t, s.Release(ctx))
+	require.Len(t, f.commands, 3)
+}
+
+func TestNestedMounts(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/tank/data", FSType: "zfs", Source: "tank/data"},
+			{Root: "/", MountPoint: "/tank/data/photos/raw", FSType: "zfs", Source: "tank/data/raw"},
+			{Root: "/", MountPoint: "/tank/data/.zfs/snapshot/other", FSType: "zfs", Source: "tank/data@other"},
+		},
+	}
+
+	_, err := f.creator().Create(ctx, ProviderAuto, "/tank/data")
+	require.ErrorContains(t, err, "it contains other mounted filesystems: /tank/data/photos/raw")
+	require.Empty(t, f.commands)
+
+	// nested mounts outside of the path don't matter.
+	s, err := f.creator().Create(ctx, ProviderAuto, "/tank/data/videos")
+	require.NoError(t, err)
+	require.NoError(t, s.Release(ctx))
+}
+
+func TestBtrfsNestedSubvolumes(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/@home", MountPoint: "/home", FSType: "btrfs", Source: "/dev/sda2"},
+		},
+		subvolumes: map[string]bool{"/home/user": true},
+		outputs: map[string]string{
+			"btrfs subvolume list -o /home/user": "ID 258 gen 9 top level 257 path @home/user/.cache\n" +
+				"ID 259 gen 9 top level 257 path @home/user/docs/nested\n",
+		},
+	}
+
+	_, err := f.creator().Create(ctx, ProviderAuto, "/home/user/docs")
+	require.ErrorContains(t, err, "it contains nested btrfs subvolume /home/user/docs/nested")
+	require.Len(t, f.commands, 1)
+
+	// subvolumes outside of the path don't matter.
+	f.outputs["btrfs subvolume list -o /home/user"] = "ID 258 gen 9 top level 257 path @home/user/.cache\n"
+
+	s, err := f.creator().Create(ctx, ProviderAuto, "/home/user/docs")
+	require.NoError(t, err)
+	require.NoError(t, s.Release(ctx))
+}
+
+func TestStaleSnapshotsAreRemoved(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	stale := testSnapshotName(testHostID, testBootID, stalePID)
+	running := testSnapshotName(testHostID, testBootID, os.Getpid())
+	otherHost := testSnapshotName("ffffffff", testBootID, stalePID)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/@home", MountPoint: "/home", FSType: "btrfs", Source: "/dev/sda2"},
+			{Root: "/", MountPoint: "/tank/data", FSType: "zfs", Source: "tank/data"},
+			{Root: "/", MountPoint: "/srv", FSType: "ext4", Source: "/dev/mapper/vg0-data"},
+			{Root: "/", MountPoint: "/nonexistent/kopia-snapshot-1", FSType: "ext4", Source: "/dev/mapper/vg0-" + strings.ReplaceAll(stale, "-", "--")},
+		},
+		subvolumes: map[string]bool{"/home/user": true},
+		outputs: map[string]string{
+			"btrfs subvolume list -o /home/user": "ID 258 gen 9 top level 257 path @home/user/." + stale + "\n" +
+				"ID 259 gen 9 top level 257 path @home/user/." + running + "\n",
+			"zfs list -H -t snapshot -d 1 -o name tank/data": "tank/data@" + stale + "\ntank/data@" + running + "\ntank/data@" + otherHost + "\ntank/data@manual\n",
+			"lvs":                             "vg0,data,Vwi-aotz--",
+			"lvs --noheadings -o lv_name vg0": "  data\n  " + stale + "\n  " + running + "\n  " + otherHost + "\n",
+		},
+		tempDir: t.TempDir(),
+	}
+
+	s, err := f.creator().Create(ctx, ProviderBtrfs, "/home/user")
+	require.NoError(t, err)
+	require.Equal(t, "btrfs subvolume delete /home/user/."+stale, f.commands[1])
+	require.True(t, strings.HasPrefix(f.commands[2], "btrfs subvolume snapshot -r /home/user "), f.commands[2])
+	require.NoError(t, s.Release(ctx))
+
+	f.commands = nil
+
+	s, err = f.creator().Create(ctx, ProviderZFS, "/tank/data")
+	require.NoError(t, err)
+	require.Equal(t, "zfs destroy tank/data@"+stale, f.commands[1])
+	require.True(t, strings.HasPrefix(f.commands[2], "zfs snapshot tank/data@"), f.commands[2])
+	require.NoError(t, s.Release(ctx))
+
+	f.commands = nil
+
+	s, err = f.creator().Create(ctx, ProviderLVM, "/srv")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"umount /nonexistent/kopia-snapshot-1",
+		"lvremove --yes vg0/" + stale,
+	}, f.commands[2:4])
+	require.True(t, strings.HasPrefix(f.commands[4], "lvcreate "), f.commands[4])
+	require.NoError(t, s.Release(ctx))
+}
+
+func TestIsStaleSnapshotName(t *testing.T) {
+	c := (&fakeSystem{}).creator()
+
+	require.True(t, c.isStaleSnapshotName(testSnapshotName(testHostID, testBootID, stalePID)))
+	require.False(t, c.isStaleSnapshotName(testSnapshotName(testHostID, testBootID, os.Getpid())))
+	require.False(t, c.isStaleSnapshotName(c.snapshotName()))
+
+	// snapshots created during a previous boot are stale even if a process with the same ID is running.
+	require.True(t, c.isStaleSnapshotName(testSnapshotName(testHostID, "00000000", os.Getpid())))
+
+	for _, name := range []string{
+		"manual",
+		"",
+		snapshotNamePrefix,
+		// other hosts
+		testSnapshotName("ffffffff", testBootID, stalePID),
+		testSnapshotName("ffffffff", "00000000", stalePID),
+		testSnapshotName(unknownID, testBootID, stalePID),
+		// old format without host and boot IDs
+		fmt.Sprintf("%v20240101-120000-%v-abcdef01", snapshotNamePrefix, stalePID),
+		// wrong prefix
+		"Kopia-20240101-120000-0123abcd-4567ef89-999999-abcdef01",
+		"xkopia-20240101-120000-0123abcd-4567ef89-999999-abcdef01",
+		// malformed timestamps
+		"kopia-20241301-120000-0123abcd-4567ef89-999999-abcdef01",
+		"kopia-20240101-250000-0123abcd-4567ef89-999999-abcdef01",
+		"kopia-2024011-120000-0123abcd-4567ef89-999999-abcdef01",
+		"kopia-backup-120000-0123abcd-4567ef89-999999-abcdef01",
+		// malformed nonces
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-abcdefgh",
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-ABCDEF01",
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-abcdef0",
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-abcdef012",
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-",
+		// malformed process IDs
+		"kopia-20240101-120000-0123abcd-4567ef89-0999999-abcdef01",
+		"kopia-20240101-120000-0123abcd-4567ef89-0-abcdef01",
+		"kopia-20240101-120000-0123abcd-4567ef89-+999999-abcdef01",
+		"kopia-20240101-120000-0123abcd-4567ef89-pid-abcdef01",
+		// extra or missing parts
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-abcdef01-x",
+		"kopia-20240101-120000-0123abcd-4567ef89-999999-abcdef01-",
+		"kopia-20240101-120000-0123abcd-999999-abcdef01",
+	} {
+		require.False(t, c.isStaleSnapshotName(name), name)
+	}
+
+	// without knowing own host and boot, nothing is considered stale.
+	c.HostID = ""
+	require.False(t, c.isStaleSnapshotName(testSnapshotName(testHostID, testBootID, stalePID)))
+
+	c.HostID = testHostID
+	c.BootID = ""
+	require.False(t, c.isStaleSnapshotName(testSnapshotName(testHostID, testBootID, stalePID)))
+}
+
+func TestUnsupported(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	f := &fakeSystem{
+		mounts: []MountInfo{
+			{Root: "/", MountPoint: "/", FSType: "ext4", Source: "/dev/sda1"},
+		},
+	}
+
+	_, err := f.creator().Create(ctx, ProviderAuto, "/home")
+	require.ErrorContains(t, err, "is not on a btrfs, ZFS or LVM filesystem")
+
+	_, err = f.creator().Create(ctx, "no-such-provider", "/home")
+	require.ErrorContains(t, err, "unsupported snapshot provider")
+
+	f.mounts = nil
+
+	_, err = f.creator().Create(ctx, ProviderAuto, "/home")
+	require.ErrorContains(t, err, "unable to find mount")
+}
+
+func TestParseMountInfo(t *testing.T) {
+	mounts, err := parseMountInfo(strings.NewReader(`22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+36 22 0:32 /@home /home rw,noatime shared:2 - btrfs /dev/sda2 rw,subvol=/@home
+37 22 253:0 / /mnt/my\040disk rw master:1 - xfs /dev/mapper/vg0-data rw
+38 37 0:33 / /mnt/my\040disk/pool rw - zfs tank/pool rw
+`))
+	require.NoError(t, err)
+	require.Equal(t, []MountInfo{
+		{Root: "/", MountPoint: "/", FSType: "ext4", Source: "/dev/sda1"},
+		{Root: "/@home", MountPoint: "/home", FSType: "btrfs", Source: "/dev/sda2"},
+		{Root: "/", MountPoint: "/mnt/my disk", FSType: "xfs", Source: "/dev/mapper/vg0-data"},
+		{Root: "/", MountPoint: "/mnt/my disk/pool", FSType: "zfs", Source: "tank/pool"},
+	}, mounts)
+
+	cases := map[string]string{
+		"/":                    "/",
+		"/home":                "/home",
+		"/homework":            "/",
+		"/home/user":           "/home",
+		"/mnt/my disk/x":       "/mnt/my disk",
+		"/mnt/my disk/pool":    "/mnt/my disk/pool",
+		"/mnt/my disk/pool/a":  "/mnt/my disk/pool",
+		"/mnt/my disk/pool2/a": "/mnt/my disk",
+	}
+
+	for path, want := range cases {
+		m, ok := mountForPath(mounts, path)
+		require.True(t, ok, path)
+		require.Equal(t, want, m.MountPoint, path)
+	}
+
+	_, err = parseMountInfo(strings.NewReader("22 1 8:1 / /\n"))
+	require.Error(t, err)
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	require.Equal(t, "plain", unescapeMountInfo("plain"))
+	require.Equal(t, "a b\tc\\d", unescapeMountInfo(`a\040b\011c\134d`))
+	require.Equal(t, `trailing\04`, unescapeMountInfo(`trailing\04`))
+	require.Equal(t, `bad\089`, unescapeMountInfo(`bad\089`))
+}
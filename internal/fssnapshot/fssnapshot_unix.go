@@ -0,0 +1,33 @@
+//go:build !windows
+
+package fssnapshot
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// btrfsFirstFreeObjectID is the inode number of the root directory of every btrfs subvolume.
+const btrfsFirstFreeObjectID = 256
+
+func isBtrfsSubvolumeRoot(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to stat")
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, nil
+	}
+
+	return st.Ino == btrfsFirstFreeObjectID, nil
+}
+
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,13 @@
+package fssnapshot
+
+import (
+	"github.com/pkg/errors"
+)
+
+func isBtrfsSubvolumeRoot(string) (bool, error) {
+	return false, errors.New("btrfs is not supported on this platform")
+}
+
+func processExists(int) bool {
+	return true
+}
@@ -0,0 +1,141 @@
+package fssnapshot
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MountInfo describes a mounted filesystem.
+type MountInfo struct {
+	// Root is the directory within the filesystem which forms the root of the mount.
+	Root       string
+	MountPoint string
+	FSType     string
+	Source     string
+}
+
+func readMountInfo() ([]MountInfo, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open mountinfo")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	return parseMountInfo(f)
+}
+
+// parseMountInfo parses the contents of /proc/self/mountinfo, lines have the following format:
+//
+//	36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfo(r io.Reader) ([]MountInfo, error) {
+	var result []MountInfo
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+
+		sep := -1
+
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+
+		//nolint:mnd
+		if sep < 6 || len(fields) < sep+3 {
+			return nil, errors.Errorf("invalid mountinfo line: %q", s.Text())
+		}
+
+		result = append(result, MountInfo{
+			Root:       unescapeMountInfo(fields[3]),
+			MountPoint: unescapeMountInfo(fields[4]),
+			FSType:     fields[sep+1],
+			Source:     unescapeMountInfo(fields[sep+2]),
+		})
+	}
+
+	return result, errors.Wrap(s.Err(), "error reading mountinfo")
+}
+
+// unescapeMountInfo decodes octal escapes (such as \040 for space) used in mountinfo.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			sb.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+
+			continue
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+func isOctal(b byte) bool {
+	return b >= '0' && b <= '7'
+}
+
+// mountForPath returns the mount containing the provided path, later mounts hide earlier ones.
+func mountForPath(mounts []MountInfo, path string) (MountInfo, bool) {
+	var (
+		best  MountInfo
+		found bool
+	)
+
+	for _, m := range mounts {
+		if !isPathUnder(path, m.MountPoint) {
+			continue
+		}
+
+		if !found || len(m.MountPoint) >= len(best.MountPoint) {
+			best = m
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// nestedMountPoints returns mount points of filesystems other than m mounted below the provided path,
+// excluding automatically-mounted ZFS snapshots.
+func nestedMountPoints(mounts []MountInfo, m MountInfo, path string) []string {
+	var result []string
+
+	for _, o := range mounts {
+		if o.MountPoint == path || o.MountPoint == m.MountPoint || !isPathUnder(o.MountPoint, path) {
+			continue
+		}
+
+		if o.FSType == "zfs" && strings.Contains(o.MountPoint, "/.zfs/snapshot/") {
+			continue
+		}
+
+		result = append(result, o.MountPoint)
+	}
+
+	return result
+}
+
+func isPathUnder(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+
+	return strings.HasPrefix(path, dir+"/")
+}
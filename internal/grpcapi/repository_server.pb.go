@@ -24,10 +24,14 @@
 type NotificationEventArgType int32
 
 const (
-	NotificationEventArgType_ARG_TYPE_UNKNOWN               NotificationEventArgType = 0 // unknown, not provided by old clients
-	NotificationEventArgType_ARG_TYPE_EMPTY                 NotificationEventArgType = 1 //
-	NotificationEventArgType_ARG_TYPE_ERROR_INFO            NotificationEventArgType = 2
-	NotificationEventArgType_ARG_TYPE_MULTI_SNAPSHOT_STATUS NotificationEventArgType = 3
+	NotificationEventArgType_ARG_TYPE_UNKNOWN                  NotificationEventArgType = 0 // unknown, not provided by old clients
+	NotificationEventArgType_ARG_TYPE_EMPTY                    NotificationEventArgType = 1 //
+	NotificationEventArgType_ARG_TYPE_ERROR_INFO               NotificationEventArgType = 2
+	NotificationEventArgType_ARG_TYPE_MULTI_SNAPSHOT_STATUS    NotificationEventArgType = 3
+	NotificationEventArgType_ARG_TYPE_MAINTENANCE_REPORT       NotificationEventArgType = 4
+	NotificationEventArgType_ARG_TYPE_STALE_SOURCES            NotificationEventArgType = 5
+	NotificationEventArgType_ARG_TYPE_SUPPRESSED_NOTIFICATIONS NotificationEventArgType = 6
+	NotificationEventArgType_ARG_TYPE_DIGEST                   NotificationEventArgType = 7
 )
 
 // Enum value maps for NotificationEventArgType.
@@ -37,12 +41,20 @@
 		1: "ARG_TYPE_EMPTY",
 		2: "ARG_TYPE_ERROR_INFO",
 		3: "ARG_TYPE_MULTI_SNAPSHOT_STATUS",
+		4: "ARG_TYPE_MAINTENANCE_REPORT",
+		5: "ARG_TYPE_STALE_SOURCES",
+		6: "ARG_TYPE_SUPPRESSED_NOTIFICATIONS",
+		7: "ARG_TYPE_DIGEST",
 	}
 	NotificationEventArgType_value = map[string]int32{
-		"ARG_TYPE_UNKNOWN":               0,
-		"ARG_TYPE_EMPTY":                 1,
-		"ARG_TYPE_ERROR_INFO":            2,
-		"ARG_TYPE_MULTI_SNAPSHOT_STATUS": 3,
+		"ARG_TYPE_UNKNOWN":                  0,
+		"ARG_TYPE_EMPTY":                    1,
+		"ARG_TYPE_ERROR_INFO":               2,
+		"ARG_TYPE_MULTI_SNAPSHOT_STATUS":    3,
+		"ARG_TYPE_MAINTENANCE_REPORT":       4,
+		"ARG_TYPE_STALE_SOURCES":            5,
+		"ARG_TYPE_SUPPRESSED_NOTIFICATIONS": 6,
+		"ARG_TYPE_DIGEST":                   7,
 	}
 )
 
@@ -2233,12 +2245,16 @@ func (*SessionResponse_SendNotification) isSessionResponse_Response() {}
 	"\x16apply_retention_policy\x18\x14 \x01(\v2..kopia_repository.ApplyRetentionPolicyResponseH\x00R\x14applyRetentionPolicy\x12Y\n" +
 	"\x11send_notification\x18\x15 \x01(\v2*.kopia_repository.SendNotificationResponseH\x00R\x10sendNotificationB\n" +
 	"\n" +
-	"\bresponse*\x81\x01\n" +
+	"\bresponse*\xfa\x01\n" +
 	"\x18NotificationEventArgType\x12\x14\n" +
 	"\x10ARG_TYPE_UNKNOWN\x10\x00\x12\x12\n" +
 	"\x0eARG_TYPE_EMPTY\x10\x01\x12\x17\n" +
 	"\x13ARG_TYPE_ERROR_INFO\x10\x02\x12\"\n" +
-	"\x1eARG_TYPE_MULTI_SNAPSHOT_STATUS\x10\x032e\n" +
+	"\x1eARG_TYPE_MULTI_SNAPSHOT_STATUS\x10\x03\x12\x1f\n" +
+	"\x1bARG_TYPE_MAINTENANCE_REPORT\x10\x04\x12\x1a\n" +
+	"\x16ARG_TYPE_STALE_SOURCES\x10\x05\x12%\n" +
+	"!ARG_TYPE_SUPPRESSED_NOTIFICATIONS\x10\x06\x12\x13\n" +
+	"\x0fARG_TYPE_DIGEST\x10\a2e\n" +
 	"\x0fKopiaRepository\x12R\n" +
 	"\aSession\x12 .kopia_repository.SessionRequest\x1a!.kopia_repository.SessionResponse(\x010\x01B)Z'github.com/kopia/kopia/internal/grpcapib\x06proto3"
 
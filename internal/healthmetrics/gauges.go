@@ -0,0 +1,246 @@
+package healthmetrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/kopia/kopia/internal/clock"
+)
+
+const prometheusPrefix = "kopia_"
+
+//nolint:gochecknoglobals
+var (
+	sourceLabels      = []string{"host", "user", "path"}
+	errorLabels       = []string{"host", "user", "path", "kind"}
+	maintenanceLabels = []string{"kind"}
+)
+
+// gauges holds Prometheus gauges updated from Stats.
+type gauges struct {
+	lastCollectionTime prometheus.Gauge
+
+	snapshotCount    *prometheus.GaugeVec
+	snapshotLastTime *prometheus.GaugeVec
+	snapshotAge      *ageCollector
+	snapshotSize     *prometheus.GaugeVec
+	snapshotFiles    *prometheus.GaugeVec
+	snapshotDirs     *prometheus.GaugeVec
+	snapshotErrors   *prometheus.GaugeVec
+	sourceErrors     *prometheus.GaugeVec
+
+	repositorySize     prometheus.Gauge
+	repositoryContents prometheus.Gauge
+	repositoryPacks    prometheus.Gauge
+
+	maintenanceNextTime *prometheus.GaugeVec
+	maintenanceOverdue  *ageCollector
+
+	epochCurrent          prometheus.Gauge
+	epochCurrentAge       *ageCollector
+	epochUncompacted      prometheus.Gauge
+	epochUncompactedBlobs prometheus.Gauge
+}
+
+//nolint:gochecknoglobals
+var (
+	defaultGaugesMutex sync.Mutex
+	// +checklocks:defaultGaugesMutex
+	defaultGauges *gauges
+)
+
+// newGauges registers the gauges with the provided registerer, ages are computed using the provided clock
+// when the metrics are gathered, so they don't stay constant between collections.
+func newGauges(reg prometheus.Registerer, now func() time.Time) *gauges {
+	f := promauto.With(reg)
+
+	gauge := func(name, help string) prometheus.Gauge {
+		return f.NewGauge(prometheus.GaugeOpts{Name: prometheusPrefix + name, Help: help})
+	}
+
+	gaugeVec := func(name, help string, labels []string) *prometheus.GaugeVec {
+		return f.NewGaugeVec(prometheus.GaugeOpts{Name: prometheusPrefix + name, Help: help}, labels)
+	}
+
+	age := func(name, help string, labels []string) *ageCollector {
+		c := newAgeCollector(prometheusPrefix+name, help, labels, now)
+		reg.MustRegister(c)
+
+		return c
+	}
+
+	return &gauges{
+		lastCollectionTime: gauge("health_last_collection_time_seconds", "Time when the health metrics were last collected"),
+
+		snapshotCount:    gaugeVec("snapshot_count", "Number of snapshots of the source", sourceLabels),
+		snapshotLastTime: gaugeVec("snapshot_last_time_seconds", "Completion time of the latest complete snapshot of the source", sourceLabels),
+		snapshotAge:      age("snapshot_age_seconds", "Time since completion of the latest complete snapshot of the source", sourceLabels),
+		snapshotSize:     gaugeVec("snapshot_size_bytes", "Total size of files in the latest complete snapshot of the source", sourceLabels),
+		snapshotFiles:    gaugeVec("snapshot_files", "Number of files in the latest complete snapshot of the source", sourceLabels),
+		snapshotDirs:     gaugeVec("snapshot_dirs", "Number of directories in the latest complete snapshot of the source", sourceLabels),
+		snapshotErrors:   gaugeVec("snapshot_errors", "Number of errors in the latest complete snapshot of the source", errorLabels),
+		sourceErrors:     gaugeVec("health_source_errors", "One if the metrics of the source could not be collected, zero otherwise", sourceLabels),
+
+		repositorySize:     gauge("repository_size_bytes", "Total packed size of all contents in the repository index"),
+		repositoryContents: gauge("repository_contents", "Number of contents in the repository index"),
+		repositoryPacks:    gauge("repository_packs", "Number of pack blobs referenced by the repository index"),
+
+		maintenanceNextTime: gaugeVec("maintenance_next_time_seconds", "Time when the next maintenance is scheduled", maintenanceLabels),
+		maintenanceOverdue:  age("maintenance_overdue_seconds", "Time since the scheduled maintenance should have run, zero if not overdue", maintenanceLabels),
+
+		epochCurrent:          gauge("epoch_current", "Current write epoch of the index"),
+		epochCurrentAge:       age("epoch_current_age_seconds", "Time since the current write epoch started", nil),
+		epochUncompacted:      gauge("epoch_uncompacted_epochs", "Number of index epochs which were not compacted yet"),
+		epochUncompactedBlobs: gauge("epoch_uncompacted_index_blobs", "Number of index blobs in epochs which were not compacted yet"),
+	}
+}
+
+// Publish updates Prometheus gauges based on the provided stats, the gauges are registered on first use,
+// so that they are only exported by processes which collect health metrics.
+func Publish(st *Stats) {
+	defaultGaugesMutex.Lock()
+	defer defaultGaugesMutex.Unlock()
+
+	if defaultGauges == nil {
+		defaultGauges = newGauges(prometheus.DefaultRegisterer, clock.Now)
+	}
+
+	defaultGauges.publish(st)
+}
+
+func (g *gauges) publish(st *Stats) {
+	now := st.Time
+
+	g.lastCollectionTime.Set(unixSeconds(now))
+
+	// reset per-source gauges to remove sources which no longer exist.
+	for _, v := range []*prometheus.GaugeVec{g.snapshotCount, g.snapshotLastTime, g.snapshotSize, g.snapshotFiles, g.snapshotDirs, g.snapshotErrors, g.sourceErrors} {
+		v.Reset()
+	}
+
+	g.snapshotAge.reset()
+
+	for _, s := range st.Sources {
+		labels := []string{s.Source.Host, s.Source.UserName, s.Source.Path}
+
+		if s.Error != nil {
+			g.sourceErrors.WithLabelValues(labels...).Set(1)
+			continue
+		}
+
+		g.sourceErrors.WithLabelValues(labels...).Set(0)
+		g.snapshotCount.WithLabelValues(labels...).Set(float64(s.Snapshots))
+
+		if s.LastSnapshotTime.IsZero() {
+			continue
+		}
+
+		g.snapshotLastTime.WithLabelValues(labels...).Set(unixSeconds(s.LastSnapshotTime))
+		g.snapshotAge.set(s.LastSnapshotTime, labels...)
+		g.snapshotSize.WithLabelValues(labels...).Set(float64(s.TotalSize))
+		g.snapshotFiles.WithLabelValues(labels...).Set(float64(s.TotalFiles))
+		g.snapshotDirs.WithLabelValues(labels...).Set(float64(s.TotalDirs))
+		g.snapshotErrors.WithLabelValues(append(labels, "fatal")...).Set(float64(s.FatalErrors))
+		g.snapshotErrors.WithLabelValues(append(labels, "ignored")...).Set(float64(s.IgnoredErrors))
+	}
+
+	if !st.Direct {
+		return
+	}
+
+	g.repositorySize.Set(float64(st.RepositorySize))
+	g.repositoryContents.Set(float64(st.ContentCount))
+	g.repositoryPacks.Set(float64(st.PackCount))
+
+	g.maintenanceNextTime.Reset()
+	g.maintenanceOverdue.reset()
+
+	for kind, t := range map[string]time.Time{
+		"full":  st.Maintenance.NextFullMaintenanceTime,
+		"quick": st.Maintenance.NextQuickMaintenanceTime,
+	} {
+		if t.IsZero() {
+			continue
+		}
+
+		g.maintenanceNextTime.WithLabelValues(kind).Set(unixSeconds(t))
+		g.maintenanceOverdue.set(t, kind)
+	}
+
+	if !st.Epoch.Enabled {
+		return
+	}
+
+	g.epochCurrent.Set(float64(st.Epoch.WriteEpoch))
+	g.epochUncompacted.Set(float64(st.Epoch.UncompactedEpochs))
+	g.epochUncompactedBlobs.Set(float64(st.Epoch.UncompactedIndexBlobs))
+
+	g.epochCurrentAge.reset()
+
+	if !st.Epoch.CurrentEpochStartTime.IsZero() {
+		g.epochCurrentAge.set(st.Epoch.CurrentEpochStartTime)
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// ageCollector reports the time elapsed since stored timestamps, computed when metrics are gathered.
+// Negative ages, such as for maintenance which is not due yet, are reported as zero.
+type ageCollector struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	mu sync.Mutex
+	// +checklocks:mu
+	times map[string]ageEntry
+}
+
+type ageEntry struct {
+	labelValues []string
+	time        time.Time
+}
+
+func newAgeCollector(name, help string, labels []string, now func() time.Time) *ageCollector {
+	return &ageCollector{
+		desc:  prometheus.NewDesc(name, help, labels, nil),
+		now:   now,
+		times: map[string]ageEntry{},
+	}
+}
+
+func (c *ageCollector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.times)
+}
+
+func (c *ageCollector) set(t time.Time, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.times[strings.Join(labelValues, "\x00")] = ageEntry{labelValues, t}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *ageCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	for _, e := range c.times {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, max(0, now.Sub(e.time).Seconds()), e.labelValues...)
+	}
+}
@@ -0,0 +1,210 @@
+// Package healthmetrics derives Prometheus gauges describing the state of repository contents and
+// snapshots, such as the age of the latest snapshot of each source or pending maintenance.
+package healthmetrics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// SourceStats describes the snapshots of a single source.
+type SourceStats struct {
+	Source snapshot.SourceInfo
+
+	Snapshots int // number of snapshots, including incomplete ones
+
+	// properties of the latest complete snapshot, zero if there is none.
+	LastSnapshotTime time.Time
+	TotalSize        int64
+	TotalFiles       int64
+	TotalDirs        int64
+	FatalErrors      int64
+	IgnoredErrors    int64
+
+	// error encountered while collecting stats of the source, other stats are zero if set.
+	Error error
+}
+
+// MaintenanceStats describes the maintenance schedule of the repository.
+type MaintenanceStats struct {
+	NextFullMaintenanceTime  time.Time
+	NextQuickMaintenanceTime time.Time
+}
+
+// EpochStats describes the state of epoch-based index.
+type EpochStats struct {
+	WriteEpoch            int
+	UncompactedEpochs     int // number of epochs not covered by single-epoch or range compaction
+	UncompactedIndexBlobs int // number of index blobs in uncompacted epochs
+	LastCompactedEpoch    int // -1 if no epoch was compacted
+	CurrentEpochStartTime time.Time
+	Enabled               bool
+}
+
+// Stats describes the state of repository contents and snapshots at a point in time.
+type Stats struct {
+	Time    time.Time
+	Sources []*SourceStats
+
+	// the following are only available for direct repository connections.
+	Direct         bool
+	RepositorySize int64 // total packed size of contents in the index
+	ContentCount   int64
+	PackCount      int64 // number of pack blobs referenced by the index
+	Maintenance    MaintenanceStats
+	Epoch          EpochStats
+}
+
+// Collect derives the stats from the repository contents.
+func Collect(ctx context.Context, rep repo.Repository, now time.Time) (*Stats, error) {
+	st := &Stats{Time: now}
+
+	sources, err := snapshot.ListSources(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list sources")
+	}
+
+	for _, si := range sources {
+		ss, err := collectSource(ctx, rep, si)
+		if err != nil {
+			// don't let a single source prevent collection of the remaining ones.
+			ss = &SourceStats{Source: si, Error: err}
+		}
+
+		st.Sources = append(st.Sources, ss)
+	}
+
+	sort.Slice(st.Sources, func(i, j int) bool {
+		return st.Sources[i].Source.String() < st.Sources[j].Source.String()
+	})
+
+	dr, ok := rep.(repo.DirectRepository)
+	if !ok {
+		return st, nil
+	}
+
+	st.Direct = true
+
+	// the index is already loaded in memory, so unlike listing blobs this does not hit the storage.
+	packs := map[blob.ID]struct{}{}
+
+	if err := dr.ContentReader().IterateContents(ctx, content.IterateOptions{}, func(ci content.Info) error {
+		st.RepositorySize += int64(ci.PackedLength)
+		st.ContentCount++
+		packs[ci.PackBlobID] = struct{}{}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "unable to iterate contents")
+	}
+
+	st.PackCount = int64(len(packs))
+
+	sched, err := maintenance.GetSchedule(ctx, dr)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	st.Maintenance = MaintenanceStats{
+		NextFullMaintenanceTime:  sched.NextFullMaintenanceTime,
+		NextQuickMaintenanceTime: sched.NextQuickMaintenanceTime,
+	}
+
+	em, ok, err := dr.ContentReader().EpochManager(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get epoch manager")
+	}
+
+	if ok {
+		cs, err := em.Current(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to determine current epoch")
+		}
+
+		st.Epoch = epochStats(cs)
+	}
+
+	return st, nil
+}
+
+func collectSource(ctx context.Context, rep repo.Repository, si snapshot.SourceInfo) (*SourceStats, error) {
+	manifests, err := snapshot.ListSnapshots(ctx, rep, si)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list snapshots of %v", si)
+	}
+
+	ss := &SourceStats{
+		Source:    si,
+		Snapshots: len(manifests),
+	}
+
+	var latest *snapshot.Manifest
+
+	for _, m := range manifests {
+		if m.IncompleteReason != "" {
+			continue
+		}
+
+		if latest == nil || m.StartTime.After(latest.StartTime) {
+			latest = m
+		}
+	}
+
+	if latest == nil {
+		return ss, nil
+	}
+
+	ss.LastSnapshotTime = latest.EndTime.ToTime()
+
+	if latest.RootEntry != nil {
+		if s := latest.RootEntry.DirSummary; s != nil {
+			ss.TotalSize = s.TotalFileSize
+			ss.TotalFiles = s.TotalFileCount
+			ss.TotalDirs = s.TotalDirCount
+			ss.FatalErrors = int64(s.FatalErrorCount)
+			ss.IgnoredErrors = int64(s.IgnoredErrorCount)
+		} else {
+			ss.TotalSize = latest.RootEntry.FileSize
+			ss.TotalFiles = 1
+		}
+	}
+
+	return ss, nil
+}
+
+func epochStats(cs epoch.CurrentSnapshot) EpochStats {
+	es := EpochStats{
+		WriteEpoch:            cs.WriteEpoch,
+		LastCompactedEpoch:    -1,
+		CurrentEpochStartTime: cs.EpochStartTime[cs.WriteEpoch],
+		Enabled:               true,
+	}
+
+	for _, r := range cs.LongestRangeCheckpointSets {
+		es.LastCompactedEpoch = max(es.LastCompactedEpoch, r.MaxEpoch)
+	}
+
+	for e := range cs.SingleEpochCompactionSets {
+		es.LastCompactedEpoch = max(es.LastCompactedEpoch, e)
+	}
+
+	es.UncompactedEpochs = cs.WriteEpoch - es.LastCompactedEpoch
+
+	for e, blobs := range cs.UncompactedEpochSets {
+		if e > es.LastCompactedEpoch {
+			es.UncompactedIndexBlobs += len(blobs)
+		}
+	}
+
+	return es
+}
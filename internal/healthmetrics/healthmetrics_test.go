@@ -0,0 +1,257 @@
+package healthmetrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestCollect(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	src1 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path1"}
+	src2 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path2"}
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		mustSaveSnapshot(ctx, t, w, src1, now.Add(-5*time.Hour), "", &fs.DirectorySummary{TotalFileSize: 100, TotalFileCount: 1})
+		mustSaveSnapshot(ctx, t, w, src1, now.Add(-3*time.Hour), "", &fs.DirectorySummary{
+			TotalFileSize:     300,
+			TotalFileCount:    3,
+			TotalDirCount:     2,
+			FatalErrorCount:   1,
+			IgnoredErrorCount: 2,
+		})
+		mustSaveSnapshot(ctx, t, w, src1, now.Add(-1*time.Hour), "checkpoint", &fs.DirectorySummary{TotalFileSize: 500})
+
+		// only a checkpoint exists.
+		mustSaveSnapshot(ctx, t, w, src2, now.Add(-2*time.Hour), "checkpoint", nil)
+
+		return nil
+	}))
+
+	require.NoError(t, maintenance.SetSchedule(ctx, env.RepositoryWriter, &maintenance.Schedule{
+		NextFullMaintenanceTime:  now.Add(-time.Hour),
+		NextQuickMaintenanceTime: now.Add(time.Hour),
+	}))
+
+	st, err := Collect(ctx, env.Repository, now)
+	require.NoError(t, err)
+	require.Len(t, st.Sources, 2)
+
+	require.True(t, now.Add(-3*time.Hour+time.Minute).Equal(st.Sources[0].LastSnapshotTime))
+
+	st.Sources[0].LastSnapshotTime = time.Time{}
+
+	require.Equal(t, &SourceStats{
+		Source:        src1,
+		Snapshots:     3,
+		TotalSize:     300,
+		TotalFiles:    3,
+		TotalDirs:     2,
+		FatalErrors:   1,
+		IgnoredErrors: 2,
+	}, st.Sources[0])
+
+	require.Equal(t, &SourceStats{Source: src2, Snapshots: 1}, st.Sources[1])
+
+	require.True(t, st.Direct)
+	require.Positive(t, st.RepositorySize)
+	require.Positive(t, st.ContentCount)
+	require.Positive(t, st.PackCount)
+	require.True(t, now.Add(-time.Hour).Equal(st.Maintenance.NextFullMaintenanceTime))
+	require.True(t, now.Add(time.Hour).Equal(st.Maintenance.NextQuickMaintenanceTime))
+	require.True(t, st.Epoch.Enabled)
+	require.Equal(t, -1, st.Epoch.LastCompactedEpoch)
+	require.Equal(t, st.Epoch.WriteEpoch+1, st.Epoch.UncompactedEpochs)
+}
+
+func TestEpochStats(t *testing.T) {
+	cs := epoch.CurrentSnapshot{
+		WriteEpoch: 10,
+		LongestRangeCheckpointSets: []*epoch.RangeMetadata{
+			{MinEpoch: 0, MaxEpoch: 3},
+			{MinEpoch: 4, MaxEpoch: 5},
+		},
+		SingleEpochCompactionSets: map[int][]blob.Metadata{
+			6: {{BlobID: "s6"}},
+		},
+		UncompactedEpochSets: map[int][]blob.Metadata{
+			6:  {{BlobID: "a"}},
+			7:  {{BlobID: "b"}, {BlobID: "c"}},
+			10: {{BlobID: "d"}},
+		},
+		EpochStartTime: map[int]time.Time{
+			10: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	require.Equal(t, EpochStats{
+		WriteEpoch:            10,
+		UncompactedEpochs:     4,
+		UncompactedIndexBlobs: 3,
+		LastCompactedEpoch:    6,
+		CurrentEpochStartTime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Enabled:               true,
+	}, epochStats(cs))
+}
+
+func TestPublish(t *testing.T) {
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+	clockTime := now
+
+	reg := prometheus.NewRegistry()
+	g := newGauges(reg, func() time.Time { return clockTime })
+
+	st := &Stats{
+		Time: now,
+		Sources: []*SourceStats{
+			{
+				Source:           snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path1"},
+				Snapshots:        3,
+				LastSnapshotTime: now.Add(-time.Hour),
+				TotalSize:        300,
+				TotalFiles:       3,
+				TotalDirs:        2,
+				FatalErrors:      1,
+			},
+			{
+				Source:    snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path2"},
+				Snapshots: 1,
+			},
+			{
+				Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path3"},
+				Error:  errors.New("some error"),
+			},
+		},
+		Direct:         true,
+		RepositorySize: 1000,
+		ContentCount:   20,
+		PackCount:      10,
+		Maintenance: MaintenanceStats{
+			NextFullMaintenanceTime:  now.Add(-time.Hour),
+			NextQuickMaintenanceTime: now.Add(time.Hour),
+		},
+		Epoch: EpochStats{WriteEpoch: 5, UncompactedEpochs: 3, UncompactedIndexBlobs: 7, Enabled: true},
+	}
+
+	g.publish(st)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP kopia_snapshot_age_seconds Time since completion of the latest complete snapshot of the source
+# TYPE kopia_snapshot_age_seconds gauge
+kopia_snapshot_age_seconds{host="host",path="/path1",user="user"} 3600
+# HELP kopia_snapshot_count Number of snapshots of the source
+# TYPE kopia_snapshot_count gauge
+kopia_snapshot_count{host="host",path="/path1",user="user"} 3
+kopia_snapshot_count{host="host",path="/path2",user="user"} 1
+# HELP kopia_health_source_errors One if the metrics of the source could not be collected, zero otherwise
+# TYPE kopia_health_source_errors gauge
+kopia_health_source_errors{host="host",path="/path1",user="user"} 0
+kopia_health_source_errors{host="host",path="/path2",user="user"} 0
+kopia_health_source_errors{host="host",path="/path3",user="user"} 1
+# HELP kopia_snapshot_errors Number of errors in the latest complete snapshot of the source
+# TYPE kopia_snapshot_errors gauge
+kopia_snapshot_errors{host="host",kind="fatal",path="/path1",user="user"} 1
+kopia_snapshot_errors{host="host",kind="ignored",path="/path1",user="user"} 0
+# HELP kopia_snapshot_size_bytes Total size of files in the latest complete snapshot of the source
+# TYPE kopia_snapshot_size_bytes gauge
+kopia_snapshot_size_bytes{host="host",path="/path1",user="user"} 300
+# HELP kopia_repository_size_bytes Total packed size of all contents in the repository index
+# TYPE kopia_repository_size_bytes gauge
+kopia_repository_size_bytes 1000
+# HELP kopia_repository_packs Number of pack blobs referenced by the repository index
+# TYPE kopia_repository_packs gauge
+kopia_repository_packs 10
+# HELP kopia_maintenance_overdue_seconds Time since the scheduled maintenance should have run, zero if not overdue
+# TYPE kopia_maintenance_overdue_seconds gauge
+kopia_maintenance_overdue_seconds{kind="full"} 3600
+kopia_maintenance_overdue_seconds{kind="quick"} 0
+# HELP kopia_epoch_uncompacted_epochs Number of index epochs which were not compacted yet
+# TYPE kopia_epoch_uncompacted_epochs gauge
+kopia_epoch_uncompacted_epochs 3
+`),
+		"kopia_snapshot_age_seconds",
+		"kopia_snapshot_count",
+		"kopia_health_source_errors",
+		"kopia_snapshot_errors",
+		"kopia_snapshot_size_bytes",
+		"kopia_repository_size_bytes",
+		"kopia_repository_packs",
+		"kopia_maintenance_overdue_seconds",
+		"kopia_epoch_uncompacted_epochs",
+	))
+
+	// ages keep increasing between collections.
+	clockTime = now.Add(time.Minute)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP kopia_snapshot_age_seconds Time since completion of the latest complete snapshot of the source
+# TYPE kopia_snapshot_age_seconds gauge
+kopia_snapshot_age_seconds{host="host",path="/path1",user="user"} 3660
+# HELP kopia_maintenance_overdue_seconds Time since the scheduled maintenance should have run, zero if not overdue
+# TYPE kopia_maintenance_overdue_seconds gauge
+kopia_maintenance_overdue_seconds{kind="full"} 3660
+kopia_maintenance_overdue_seconds{kind="quick"} 0
+`),
+		"kopia_snapshot_age_seconds",
+		"kopia_maintenance_overdue_seconds",
+	))
+
+	// sources which no longer exist are removed.
+	st.Sources = st.Sources[1:2]
+	g.publish(st)
+
+	require.Equal(t, 1, testutil.CollectAndCount(g.snapshotCount))
+	require.Equal(t, 0, testutil.CollectAndCount(g.snapshotAge))
+}
+
+func mustSaveSnapshot(ctx context.Context, t *testing.T, w repo.RepositoryWriter, si snapshot.SourceInfo, startTime time.Time, incompleteReason string, summ *fs.DirectorySummary) {
+	t.Helper()
+
+	_, err := snapshot.SaveSnapshot(ctx, w, &snapshot.Manifest{
+		Source:           si,
+		StartTime:        fs.UTCTimestampFromTime(startTime),
+		EndTime:          fs.UTCTimestampFromTime(startTime.Add(time.Minute)),
+		IncompleteReason: incompleteReason,
+		RootEntry:        &snapshot.DirEntry{DirSummary: summ},
+	})
+	require.NoError(t, err)
+}
+
+func TestPublish_RegistersGaugesOnFirstUse(t *testing.T) {
+	defaultGaugesMutex.Lock()
+	registered := defaultGauges != nil
+	defaultGaugesMutex.Unlock()
+
+	require.False(t, registered)
+
+	Publish(&Stats{Time: clock.Now()})
+
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	var names []string
+
+	for _, mf := range mfs {
+		names = append(names, mf.GetName())
+	}
+
+	require.Contains(t, names, "kopia_health_last_collection_time_seconds")
+}
@@ -0,0 +1,635 @@
+package providervalidation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// ConformanceStatus is the outcome of a single conformance test.
+type ConformanceStatus string
+
+// Supported conformance test outcomes.
+const (
+	ConformancePassed  ConformanceStatus = "pass"
+	ConformanceFailed  ConformanceStatus = "fail"
+	ConformanceSkipped ConformanceStatus = "skip"
+)
+
+// ConformanceOptions provides options for the storage conformance suite.
+type ConformanceOptions struct {
+	Options
+
+	// Tests is the list of tests to run, all tests are run if empty.
+	Tests []string
+
+	// NumPaginationBlobs is the number of blobs written to exercise listing pagination.
+	// It should exceed the page size of the provider being tested.
+	NumPaginationBlobs int
+
+	// LargeBlobLength is the length of the blob used in the large blob test.
+	LargeBlobLength int
+
+	// ConsistencyTimeout is the maximum time to wait for writes and deletes to become
+	// visible in listings of other connections.
+	ConsistencyTimeout time.Duration
+
+	// RetentionMode and RetentionPeriod configure the retention test, which is skipped
+	// when no mode is given. Blobs written by the retention test can't be removed
+	// until retention expires.
+	RetentionMode   blob.RetentionMode
+	RetentionPeriod time.Duration
+}
+
+// DefaultConformanceOptions is the default set of conformance options.
+//
+//nolint:mnd,gochecknoglobals
+var DefaultConformanceOptions = ConformanceOptions{
+	Options:            DefaultOptions,
+	NumPaginationBlobs: 2500,
+	LargeBlobLength:    64 << 20,
+	ConsistencyTimeout: 30 * time.Second,
+	RetentionPeriod:    24 * time.Hour,
+}
+
+// ConformanceTestResult describes the result of a single conformance test.
+type ConformanceTestResult struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Status      ConformanceStatus `json:"status"`
+	Duration    time.Duration     `json:"duration"`
+	Error       string            `json:"error,omitempty"`
+	Details     map[string]any    `json:"details,omitempty"`
+}
+
+// ConformanceReport is the result of running the conformance suite.
+type ConformanceReport struct {
+	Storage   string                  `json:"storage"`
+	StartTime time.Time               `json:"startTime"`
+	EndTime   time.Time               `json:"endTime"`
+	Results   []ConformanceTestResult `json:"results"`
+	Passed    int                     `json:"passed"`
+	Failed    int                     `json:"failed"`
+	Skipped   int                     `json:"skipped"`
+}
+
+// Succeeded returns true if none of the conformance tests failed.
+func (r *ConformanceReport) Succeeded() bool {
+	return r.Failed == 0
+}
+
+var errConformanceTestSkipped = errors.New("skipped")
+
+type conformanceRun struct {
+	opt    ConformanceOptions
+	st     equivalentBlobStorageConnections
+	prefix blob.ID
+}
+
+type conformanceTest struct {
+	name        string
+	description string
+	run         func(ctx context.Context, r *conformanceRun) (map[string]any, error)
+}
+
+//nolint:gochecknoglobals
+var conformanceTests = []conformanceTest{
+	{"capacity", "Volume capacity is either unsupported or consistent", testCapacity},
+	{"missing-blobs", "Reads of non-existent blobs return not-found errors", testMissingBlobs},
+	{"conditional-create", "Conditional creates either are unsupported or never overwrite", testConditionalCreate},
+	{"range-reads", "Partial reads return exact byte ranges", testRangeReads},
+	{"large-blobs", "Large blobs round-trip intact", testLargeBlobs},
+	{"list-pagination", "Listing returns every blob exactly once across pages", testListPagination},
+	{"clock-skew", "Blob timestamps agree with the local clock", testClockSkew},
+	{"consistency", "Writes and deletes become visible to other connections", testConsistency},
+	{"concurrency", "Concurrent readers and writers observe consistent data", testConcurrency},
+	{"retention", "Blobs can be written with retention, retention can be extended and retained blobs can't be overwritten or deleted", testRetention},
+}
+
+// ConformanceTestNames returns the names of all conformance tests.
+func ConformanceTestNames() []string {
+	var result []string
+
+	for _, t := range conformanceTests {
+		result = append(result, t.name)
+	}
+
+	return result
+}
+
+// RunConformanceSuite runs the storage conformance suite against the provided storage and
+// returns a report of all test outcomes. The returned error is only non-nil if the suite
+// could not be run at all, failures of individual tests are recorded in the report.
+func RunConformanceSuite(ctx context.Context, st0 blob.Storage, opt ConformanceOptions) (*ConformanceReport, error) {
+	for _, n := range opt.Tests {
+		if !slices.Contains(ConformanceTestNames(), n) {
+			return nil, errors.Errorf("unknown conformance test %q, must be one of %v", n, ConformanceTestNames())
+		}
+	}
+
+	st, err := openEquivalentStorageConnections(ctx, st0, max(opt.NumEquivalentStorageConnections, 2)) //nolint:mnd
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open additional storage connections")
+	}
+
+	defer func() {
+		if cerr := st.closeAdditional(ctx); cerr != nil {
+			log(ctx).Warn("unable to close additional connections", "err", cerr)
+		}
+	}()
+
+	uberPrefix := blob.ID("z" + uuid.NewString())
+	defer cleanupAllBlobs(ctx, st[0], uberPrefix)
+
+	report := &ConformanceReport{
+		Storage:   st0.DisplayName(),
+		StartTime: clock.Now(),
+	}
+
+	for i, t := range conformanceTests {
+		if len(opt.Tests) > 0 && !slices.Contains(opt.Tests, t.name) {
+			continue
+		}
+
+		log(ctx).Infof("Running conformance test %q: %v...", t.name, t.description)
+
+		r := &conformanceRun{
+			opt:    opt,
+			st:     st,
+			prefix: uberPrefix + blob.ID(fmt.Sprintf("%02d", i)),
+		}
+
+		t0 := clock.Now()
+		details, err := t.run(ctx, r)
+
+		res := ConformanceTestResult{
+			Name:        t.name,
+			Description: t.description,
+			Status:      ConformancePassed,
+			Duration:    clock.Now().Sub(t0),
+			Details:     details,
+		}
+
+		switch {
+		case errors.Is(err, errConformanceTestSkipped):
+			res.Status = ConformanceSkipped
+			res.Error = err.Error()
+			report.Skipped++
+
+		case err != nil:
+			res.Status = ConformanceFailed
+			res.Error = err.Error()
+			report.Failed++
+
+			log(ctx).Errorf("Conformance test %q failed: %v", t.name, err)
+
+		default:
+			report.Passed++
+		}
+
+		report.Results = append(report.Results, res)
+	}
+
+	report.EndTime = clock.Now()
+
+	return report, nil
+}
+
+func testCapacity(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	c, err := r.st.pickOne().GetCapacity(ctx)
+
+	switch {
+	case errors.Is(err, blob.ErrNotAVolume):
+		return nil, errors.Wrap(errConformanceTestSkipped, "storage is not a volume")
+	case err != nil:
+		return nil, errors.Wrap(err, "unexpected error")
+	case c.FreeB > c.SizeB:
+		return nil, errors.Errorf("expected volume's free space (%dB) to be at most volume size (%dB)", c.FreeB, c.SizeB)
+	}
+
+	return map[string]any{"sizeBytes": c.SizeB, "freeBytes": c.FreeB}, nil
+}
+
+func testMissingBlobs(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	if err := verifyBlobCount(ctx, r.st.pickOne(), r.prefix, 0); err != nil {
+		return nil, errors.Wrap(err, "invalid blob count")
+	}
+
+	if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", 0, -1, &out); !errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, errors.Errorf("got unexpected error when reading non-existent blob: %v", err)
+	}
+
+	if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", 0, 5, &out); !errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, errors.Errorf("got unexpected error when reading non-existent partial blob: %v", err)
+	}
+
+	if _, err := r.st.pickOne().GetMetadata(ctx, r.prefix+"1"); !errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, errors.Errorf("got unexpected error when getting metadata for non-existent blob: %v", err)
+	}
+
+	if err := r.st.pickOne().DeleteBlob(ctx, r.prefix+"1"); err != nil {
+		return nil, errors.Wrap(err, "deleting non-existent blob must succeed")
+	}
+
+	return nil, nil
+}
+
+func testConditionalCreate(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	original := []byte{1, 2, 3}
+
+	if err := r.st.pickOne().PutBlob(ctx, r.prefix+"1", gather.FromSlice(original), blob.PutOptions{}); err != nil {
+		return nil, errors.Wrap(err, "error writing blob")
+	}
+
+	err := r.st.pickOne().PutBlob(ctx, r.prefix+"1", gather.FromSlice([]byte{99}), blob.PutOptions{DoNotRecreate: true})
+
+	switch {
+	case errors.Is(err, blob.ErrUnsupportedPutBlobOption):
+		return map[string]any{"supported": false}, nil
+	case errors.Is(err, blob.ErrBlobAlreadyExists):
+	default:
+		return nil, errors.Errorf("unexpected error returned from PutBlob with DoNotRecreate: %v", err)
+	}
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", 0, -1, &out); err != nil {
+		return nil, errors.Wrap(err, "error reading blob")
+	}
+
+	if !bytes.Equal(out.ToByteSlice(), original) {
+		return nil, errors.New("conditional create has overwritten existing blob")
+	}
+
+	return map[string]any{"supported": true}, nil
+}
+
+func testRangeReads(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	blobData := bytes.Repeat([]byte{1, 2, 3, 4, 5}, 1e5) //nolint:mnd
+
+	if err := r.st.pickOne().PutBlob(ctx, r.prefix+"1", gather.FromSlice(blobData), blob.PutOptions{}); err != nil {
+		return nil, errors.Wrap(err, "error writing blob")
+	}
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	cases := []struct {
+		offset int64
+		length int64
+	}{
+		{0, 10},
+		{1, 10},
+		{2, 1},
+		{5, 0},
+		{int64(len(blobData)) - 5, 5},
+		{int64(len(blobData)) / 2, int64(len(blobData)) / 2},
+		{0, -1},
+	}
+
+	for _, tc := range cases {
+		if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", tc.offset, tc.length, &out); err != nil {
+			return nil, errors.Wrapf(err, "got unexpected error when reading partial blob @%v+%v", tc.offset, tc.length)
+		}
+
+		want := blobData[tc.offset:]
+		if tc.length >= 0 {
+			want = want[:tc.length]
+		}
+
+		if !bytes.Equal(out.ToByteSlice(), want) {
+			return nil, errors.Errorf("got unexpected data after reading partial blob @%v+%v", tc.offset, tc.length)
+		}
+	}
+
+	// reads past the end of the blob must fail.
+	if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", int64(len(blobData))-5, 10, &out); err == nil { //nolint:mnd
+		return nil, errors.New("reading past the end of the blob did not fail")
+	}
+
+	return map[string]any{"cases": len(cases) + 1}, nil
+}
+
+func testLargeBlobs(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	blobData := make([]byte, r.opt.LargeBlobLength)
+	rand.New(rand.NewSource(clock.Now().UnixNano())).Read(blobData) //nolint:gosec
+
+	t0 := clock.Now()
+
+	if err := r.st.pickOne().PutBlob(ctx, r.prefix+"1", gather.FromSlice(blobData), blob.PutOptions{}); err != nil {
+		return nil, errors.Wrap(err, "error writing large blob")
+	}
+
+	writeTime := clock.Now().Sub(t0)
+
+	bm, err := r.st.pickOne().GetMetadata(ctx, r.prefix+"1")
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting metadata")
+	}
+
+	if bm.Length != int64(len(blobData)) {
+		return nil, errors.Errorf("invalid length returned by GetMetadata(): %v, wanted %v", bm.Length, len(blobData))
+	}
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	t0 = clock.Now()
+
+	if err := r.st.pickOne().GetBlob(ctx, r.prefix+"1", 0, -1, &out); err != nil {
+		return nil, errors.Wrap(err, "error reading large blob")
+	}
+
+	readTime := clock.Now().Sub(t0)
+
+	if sha256.Sum256(out.ToByteSlice()) != sha256.Sum256(blobData) {
+		return nil, errors.New("large blob contents differ after reading back")
+	}
+
+	return map[string]any{
+		"length":    len(blobData),
+		"writeTime": writeTime.String(),
+		"readTime":  readTime.String(),
+	}, nil
+}
+
+func testListPagination(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	// write blobs into two sub-prefixes to also verify prefix filtering.
+	want := map[blob.ID]bool{}
+
+	eg, egctx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(r.opt.NumPutBlobWorkers, 1))
+
+	for i := range r.opt.NumPaginationBlobs {
+		id := r.prefix + blob.ID(fmt.Sprintf("%v%08x", "ab"[i%2:i%2+1], i))
+		want[id] = true
+
+		eg.Go(func() error {
+			return errors.Wrapf(r.st.pickOne().PutBlob(egctx, id, gather.FromSlice([]byte(id)), blob.PutOptions{}), "error writing %v", id)
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, errors.Wrap(err, "error writing blobs")
+	}
+
+	seen := map[blob.ID]int{}
+
+	if err := r.st.pickOne().ListBlobs(ctx, r.prefix, func(bm blob.Metadata) error {
+		seen[bm.BlobID]++
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error listing blobs")
+	}
+
+	var missing, duplicated, unexpected int
+
+	for id := range want {
+		if seen[id] == 0 {
+			missing++
+		}
+	}
+
+	for id, n := range seen {
+		if !want[id] {
+			unexpected++
+		}
+
+		if n > 1 {
+			duplicated++
+		}
+	}
+
+	details := map[string]any{
+		"written":    len(want),
+		"listed":     len(seen),
+		"missing":    missing,
+		"duplicated": duplicated,
+		"unexpected": unexpected,
+	}
+
+	if missing+duplicated+unexpected > 0 {
+		return details, errors.Errorf("listing returned inconsistent results: %v missing, %v duplicated, %v unexpected", missing, duplicated, unexpected)
+	}
+
+	if err := verifyBlobCount(ctx, r.st.pickOne(), r.prefix+"a", (r.opt.NumPaginationBlobs+1)/2); err != nil { //nolint:mnd
+		return details, errors.Wrap(err, "invalid blob count with prefix")
+	}
+
+	return details, nil
+}
+
+func testClockSkew(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	t0 := clock.Now()
+
+	if err := r.st.pickOne().PutBlob(ctx, r.prefix+"1", gather.FromSlice([]byte{1}), blob.PutOptions{}); err != nil {
+		return nil, errors.Wrap(err, "error writing blob")
+	}
+
+	t1 := clock.Now()
+
+	bm, err := r.st.pickOne().GetMetadata(ctx, r.prefix+"1")
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting metadata")
+	}
+
+	// the storage timestamp should be between the start and end of the write.
+	var skew time.Duration
+
+	switch {
+	case bm.Timestamp.Before(t0):
+		skew = bm.Timestamp.Sub(t0)
+	case bm.Timestamp.After(t1):
+		skew = bm.Timestamp.Sub(t1)
+	}
+
+	details := map[string]any{"skew": skew.String()}
+
+	if skew.Abs() > r.opt.MaxClockDrift {
+		return details, errors.Errorf("newly-written blob has a timestamp %v away from local clock, max difference allowed is %v", skew, r.opt.MaxClockDrift)
+	}
+
+	return details, nil
+}
+
+// waitUntil polls the provided condition until it's true or the consistency timeout expires and
+// returns the time it took.
+func (r *conformanceRun) waitUntil(ctx context.Context, desc string, cond func() (bool, error)) (time.Duration, error) {
+	const pollInterval = 100 * time.Millisecond
+
+	t0 := clock.Now()
+	deadline := t0.Add(r.opt.ConsistencyTimeout)
+
+	for {
+		ok, err := cond()
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			return clock.Now().Sub(t0), nil
+		}
+
+		if clock.Now().After(deadline) {
+			return 0, errors.Errorf("%v not observed within %v", desc, r.opt.ConsistencyTimeout)
+		}
+
+		if !clock.SleepInterruptibly(ctx, pollInterval) {
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func blobIsListed(ctx context.Context, st blob.Storage, id blob.ID) (bool, error) {
+	found := false
+
+	err := st.ListBlobs(ctx, id, func(bm blob.Metadata) error {
+		if bm.BlobID == id {
+			found = true
+		}
+
+		return nil
+	})
+
+	return found, errors.Wrap(err, "error listing blobs")
+}
+
+func testConsistency(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	const numBlobs = 5
+
+	var maxListLag, maxDeleteLag time.Duration
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	for i := range numBlobs {
+		id := r.prefix + blob.ID(fmt.Sprintf("%v", i))
+		writer, reader := r.st[i%len(r.st)], r.st[(i+1)%len(r.st)]
+
+		if err := writer.PutBlob(ctx, id, gather.FromSlice([]byte(id)), blob.PutOptions{}); err != nil {
+			return nil, errors.Wrap(err, "error writing blob")
+		}
+
+		// reads after write must succeed right away.
+		if err := reader.GetBlob(ctx, id, 0, -1, &out); err != nil {
+			return nil, errors.Wrapf(err, "read-after-write of %v failed", id)
+		}
+
+		lag, err := r.waitUntil(ctx, "listing of new blob", func() (bool, error) {
+			return blobIsListed(ctx, reader, id)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		maxListLag = max(maxListLag, lag)
+
+		if err := writer.DeleteBlob(ctx, id); err != nil {
+			return nil, errors.Wrap(err, "error deleting blob")
+		}
+
+		lag, err = r.waitUntil(ctx, "deletion of blob", func() (bool, error) {
+			listed, err := blobIsListed(ctx, reader, id)
+			if err != nil || listed {
+				return false, err
+			}
+
+			_, err = reader.GetMetadata(ctx, id)
+			if errors.Is(err, blob.ErrBlobNotFound) {
+				return true, nil
+			}
+
+			return false, errors.Wrap(err, "unexpected error getting metadata")
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		maxDeleteLag = max(maxDeleteLag, lag)
+	}
+
+	return map[string]any{
+		"maxListLag":   maxListLag.String(),
+		"maxDeleteLag": maxDeleteLag.String(),
+	}, nil
+}
+
+func testConcurrency(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	ct := newConcurrencyTest(r.st, r.prefix, r.opt.Options)
+
+	if err := ct.run(ctx); err != nil {
+		return nil, errors.Wrap(err, "error validating concurrency")
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	return map[string]any{"blobsWritten": len(ct.blobIDs)}, nil
+}
+
+func testRetention(ctx context.Context, r *conformanceRun) (map[string]any, error) {
+	if r.opt.RetentionMode == "" {
+		return nil, errors.Wrap(errConformanceTestSkipped, "retention mode not specified")
+	}
+
+	id := r.prefix + "1"
+	data := []byte{1, 2, 3}
+
+	if err := r.st.pickOne().PutBlob(ctx, id, gather.FromSlice(data), blob.PutOptions{
+		RetentionMode:   r.opt.RetentionMode,
+		RetentionPeriod: r.opt.RetentionPeriod,
+	}); err != nil {
+		return nil, errors.Wrap(err, "error writing blob with retention")
+	}
+
+	if err := r.st.pickOne().ExtendBlobRetention(ctx, id, blob.ExtendOptions{
+		RetentionMode:   r.opt.RetentionMode,
+		RetentionPeriod: r.opt.RetentionPeriod,
+	}); err != nil {
+		return nil, errors.Wrap(err, "error extending retention")
+	}
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	if err := r.st.pickOne().GetBlob(ctx, id, 0, -1, &out); err != nil {
+		return nil, errors.Wrap(err, "error reading retained blob")
+	}
+
+	if !bytes.Equal(out.ToByteSlice(), data) {
+		return nil, errors.New("retained blob has unexpected contents")
+	}
+
+	if err := r.st.pickOne().PutBlob(ctx, id, gather.FromSlice([]byte{4, 5, 6}), blob.PutOptions{}); err == nil {
+		return nil, errors.New("overwriting retained blob with different contents succeeded")
+	}
+
+	// storage may accept the deletion as long as it keeps the blob protected.
+	deleteRejected := r.st.pickOne().DeleteBlob(ctx, id) != nil
+
+	if err := r.st.pickOne().PutBlob(ctx, id, gather.FromSlice([]byte{7, 8, 9}), blob.PutOptions{}); err == nil {
+		return nil, errors.New("retained blob was replaced after deletion")
+	}
+
+	return map[string]any{
+		"mode":           r.opt.RetentionMode,
+		"period":         r.opt.RetentionPeriod.String(),
+		"deleteRejected": deleteRejected,
+	}, nil
+}
@@ -1,6 +1,8 @@
 package providervalidation_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -9,6 +11,8 @@
 	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/providervalidation"
 	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/filesystem"
 )
 
@@ -23,3 +27,90 @@ func TestProviderValidation(t *testing.T) {
 	opt.ConcurrencyTestDuration = 3 * time.Second
 	require.NoError(t, providervalidation.ValidateProvider(ctx, st, opt))
 }
+
+func TestConformanceSuite(t *testing.T) {
+	ctx := testlogging.Context(t)
+	st, err := filesystem.New(ctx, &filesystem.Options{
+		Path: t.TempDir(),
+	}, false)
+	require.NoError(t, err)
+
+	opt := providervalidation.DefaultConformanceOptions
+	opt.Options = blobtesting.TestValidationOptions
+	opt.ConcurrencyTestDuration = 3 * time.Second
+	opt.NumPaginationBlobs = 50
+	opt.LargeBlobLength = 5 << 20
+	opt.ConsistencyTimeout = 5 * time.Second
+
+	report, err := providervalidation.RunConformanceSuite(ctx, st, opt)
+	require.NoError(t, err)
+	require.True(t, report.Succeeded(), "report: %+v", report)
+	require.Len(t, report.Results, len(providervalidation.ConformanceTestNames()))
+
+	for _, r := range report.Results {
+		switch r.Name {
+		case "capacity":
+			// depends on the platform
+		case "retention":
+			require.Equal(t, providervalidation.ConformanceSkipped, r.Status)
+		default:
+			require.Equal(t, providervalidation.ConformancePassed, r.Status, r.Name)
+		}
+	}
+
+	opt.Tests = []string{"range-reads", "clock-skew"}
+
+	report, err = providervalidation.RunConformanceSuite(ctx, st, opt)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 2)
+	require.Equal(t, 2, report.Passed)
+
+	opt.Tests = []string{"no-such-test"}
+
+	_, err = providervalidation.RunConformanceSuite(ctx, st, opt)
+	require.ErrorContains(t, err, "unknown conformance test")
+}
+
+func TestConformanceSuite_Retention(t *testing.T) {
+	// retention helper is a shell script.
+	testutil.SkipTestUnlessLinux(t)
+
+	ctx := testlogging.Context(t)
+
+	// the helper records retention the same way kopia does, without making files immutable,
+	// so that test directories can be removed.
+	helper := filepath.Join(t.TempDir(), "retention-helper")
+	require.NoError(t, os.WriteFile(helper, []byte(`#!/bin/sh
+case "$1" in
+lock) printf '{"mode":"%s","retainUntil":"%s"}' "$3" "$4" > "$2.retention" ;;
+unlock) rm -f "$2.retention" ;;
+esac
+`), 0o700))
+
+	opt := providervalidation.DefaultConformanceOptions
+	opt.Options = blobtesting.TestValidationOptions
+	opt.Tests = []string{"retention"}
+	opt.RetentionMode = blob.Governance
+
+	st, err := filesystem.New(ctx, &filesystem.Options{
+		Path:            t.TempDir(),
+		RetentionHelper: helper,
+	}, false)
+	require.NoError(t, err)
+
+	report, err := providervalidation.RunConformanceSuite(ctx, st, opt)
+	require.NoError(t, err)
+	require.Equal(t, providervalidation.ConformancePassed, report.Results[0].Status, report.Results[0].Error)
+
+	// storage which accepts retention options without enforcing them fails the test.
+	st, err = filesystem.New(ctx, &filesystem.Options{
+		Path:            t.TempDir(),
+		RetentionHelper: "true",
+	}, false)
+	require.NoError(t, err)
+
+	report, err = providervalidation.RunConformanceSuite(ctx, st, opt)
+	require.NoError(t, err)
+	require.Equal(t, providervalidation.ConformanceFailed, report.Results[0].Status)
+	require.Contains(t, report.Results[0].Error, "overwriting retained blob")
+}
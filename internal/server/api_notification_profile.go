@@ -4,6 +4,8 @@
 	"context"
 	"encoding/json"
 
+	"github.com/pkg/errors"
+
 	"github.com/kopia/kopia/internal/serverapi"
 	"github.com/kopia/kopia/notification"
 	"github.com/kopia/kopia/notification/notifyprofile"
@@ -18,6 +20,10 @@ func handleNotificationProfileCreate(ctx context.Context, rc requestContext) (an
 		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body: "+string(rc.body))
 	}
 
+	if err := restoreSensitiveData(ctx, rc.rep, &cfg); err != nil {
+		return nil, internalServerError(err)
+	}
+
 	if err := repo.WriteSession(ctx, rc.rep, repo.WriteSessionOptions{
 		Purpose: "NotificationProfileCreate",
 	}, func(ctx context.Context, w repo.RepositoryWriter) error {
@@ -36,6 +42,10 @@ func handleNotificationProfileTest(ctx context.Context, rc requestContext) (any,
 		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body: "+string(rc.body))
 	}
 
+	if err := restoreSensitiveData(ctx, rc.rep, &cfg); err != nil {
+		return nil, internalServerError(err)
+	}
+
 	s, err := sender.GetSender(ctx, cfg.ProfileName, cfg.MethodConfig.Type, cfg.MethodConfig.Config)
 	if err != nil {
 		return nil, requestError(serverapi.ErrorMalformedRequest, "unable to construct sender: "+err.Error())
@@ -55,7 +65,12 @@ func handleNotificationProfileGet(ctx context.Context, rc requestContext) (any,
 		return nil, internalServerError(err)
 	}
 
-	return cfg, nil
+	scrubbed, err := cfg.Scrubbed()
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return scrubbed, nil
 }
 
 func handleNotificationProfileDelete(ctx context.Context, rc requestContext) (any, *apiError) {
@@ -76,5 +91,33 @@ func handleNotificationProfileList(ctx context.Context, rc requestContext) (any,
 		return nil, internalServerError(err)
 	}
 
+	for i, p := range profiles {
+		if profiles[i], err = p.Scrubbed(); err != nil {
+			return nil, internalServerError(err)
+		}
+	}
+
 	return profiles, nil
 }
+
+// restoreSensitiveData restores sensitive options of the existing profile which are submitted as previously
+// returned by the API, with their values scrubbed.
+func restoreSensitiveData(ctx context.Context, rep repo.Repository, cfg *notifyprofile.Config) error {
+	existing, err := notifyprofile.GetProfile(ctx, rep, cfg.ProfileName)
+	if errors.Is(err, notifyprofile.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to get existing notification profile")
+	}
+
+	mc, err := cfg.MethodConfig.RestoreSensitiveData(existing.MethodConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to restore sensitive options")
+	}
+
+	cfg.MethodConfig = mc
+
+	return nil
+}
@@ -13,6 +13,7 @@
 	"github.com/kopia/kopia/internal/servertesting"
 	"github.com/kopia/kopia/notification/notifyprofile"
 	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/ntfy"
 	"github.com/kopia/kopia/notification/sender/testsender"
 )
 
@@ -160,3 +161,71 @@ func TestNotificationProfile(t *testing.T) {
 	require.NoError(t, cli.Get(ctx, "notificationProfiles", nil, &profiles))
 	require.Empty(t, profiles)
 }
+
+func TestNotificationProfile_SensitiveOptions(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	srvInfo := servertesting.StartServerContext(ctx, t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	require.NoError(t, cli.Post(ctx, "notificationProfiles", &notifyprofile.Config{
+		ProfileName: "profile1",
+		MethodConfig: sender.MethodConfig{
+			Type: ntfy.ProviderType,
+			Config: ntfy.Options{
+				ServerURL:   "https://ntfy.example.com",
+				Topic:       "topic1",
+				AccessToken: "secret",
+			},
+		},
+	}, &serverapi.Empty{}))
+
+	var (
+		cfg      notifyprofile.Config
+		profiles []notifyprofile.Config
+		opt      ntfy.Options
+	)
+
+	// access token is scrubbed when getting and listing profiles.
+	require.NoError(t, cli.Get(ctx, "notificationProfiles/profile1", nil, &cfg))
+	require.NoError(t, cfg.MethodConfig.Options(&opt))
+	require.Equal(t, "topic1", opt.Topic)
+	require.Equal(t, "******", opt.AccessToken)
+
+	require.NoError(t, cli.Get(ctx, "notificationProfiles", nil, &profiles))
+	require.Len(t, profiles, 1)
+	require.NoError(t, profiles[0].MethodConfig.Options(&opt))
+	require.Equal(t, "******", opt.AccessToken)
+
+	// saving the scrubbed profile with other changes keeps the original access token.
+	opt.Topic = "topic2"
+	cfg.MethodConfig.Config = opt
+
+	require.NoError(t, cli.Post(ctx, "notificationProfiles", &cfg, &serverapi.Empty{}))
+
+	stored, err := notifyprofile.GetProfile(ctx, env.MustConnectOpenAnother(t), "profile1")
+	require.NoError(t, err)
+	require.NoError(t, stored.MethodConfig.Options(&opt))
+	require.Equal(t, "topic2", opt.Topic)
+	require.Equal(t, "secret", opt.AccessToken)
+
+	// the access token can be changed.
+	opt.AccessToken = "secret2"
+	cfg.MethodConfig.Config = opt
+
+	require.NoError(t, cli.Post(ctx, "notificationProfiles", &cfg, &serverapi.Empty{}))
+
+	stored, err = notifyprofile.GetProfile(ctx, env.MustConnectOpenAnother(t), "profile1")
+	require.NoError(t, err)
+	require.NoError(t, stored.MethodConfig.Options(&opt))
+	require.Equal(t, "secret2", opt.AccessToken)
+}
@@ -7,10 +7,12 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/serverapi"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
@@ -100,6 +102,10 @@ func handleDeleteSnapshots(ctx context.Context, rc requestContext) (any, *apiErr
 			manifestIDs = req.SnapshotManifestIDs
 		}
 
+		if err := legalhold.CheckNotHeld(ctx, w, clock.Now(), manifestIDs...); err != nil {
+			return errors.Wrap(err, "unable to delete snapshots")
+		}
+
 		for _, m := range manifestIDs {
 			if err := w.DeleteManifest(ctx, m); err != nil {
 				return errors.Wrap(err, "unable to delete snapshot")
@@ -117,6 +123,10 @@ func handleDeleteSnapshots(ctx context.Context, rc requestContext) (any, *apiErr
 		// if source deletion failed, refresh the repository to rediscover the source
 		rc.srv.Refresh()
 
+		if errors.Is(err, legalhold.ErrSnapshotHeld) {
+			return nil, requestError(serverapi.ErrorAccessDenied, err.Error())
+		}
+
 		return nil, internalServerError(err)
 	}
 
@@ -153,8 +163,8 @@ func handleEditSnapshots(ctx context.Context, rc requestContext) (any, *apiError
 			}
 
 			if changed {
-				if err := snapshot.UpdateSnapshot(ctx, w, snap); err != nil {
-					return errors.Wrap(err, "error updating snapshot")
+				if err := legalhold.UpdateSnapshot(ctx, w, snap, clock.Now()); err != nil {
+					return err
 				}
 			}
 
@@ -163,6 +173,10 @@ func handleEditSnapshots(ctx context.Context, rc requestContext) (any, *apiError
 
 		return nil
 	}); err != nil {
+		if errors.Is(err, legalhold.ErrSnapshotHeld) {
+			return nil, requestError(serverapi.ErrorAccessDenied, err.Error())
+		}
+
 		return nil, internalServerError(err)
 	}
 
@@ -31,6 +31,7 @@
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
@@ -428,6 +429,10 @@ func handleDeleteManifestRequest(ctx context.Context, dw repo.DirectRepositoryWr
 		return accessDeniedResponse()
 	}
 
+	if err := legalhold.CheckDeleteManifest(ctx, dw, dw.Time(), em); err != nil {
+		return errorResponse(err)
+	}
+
 	if err := dw.DeleteManifest(ctx, manifest.ID(req.GetManifestId())); err != nil {
 		return errorResponse(err)
 	}
@@ -13,6 +13,7 @@
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -114,6 +115,13 @@ type Server struct {
 	// +checklocks:nextRefreshTimeLock
 	nextRefreshTime time.Time
 
+	staleSources  staleSourceWatchdog
+	digests       digestSchedule
+	healthMetrics healthMetricsCollector
+
+	// set while summaries of rate-limited notifications are being sent.
+	sendingSuppressedSummaries atomic.Bool
+
 	grpcServerState
 }
 
@@ -469,6 +477,8 @@ func (s *Server) refreshLocked(ctx context.Context) error {
 		}
 	}
 
+	s.refreshDigestScheduleLocked(ctx)
+
 	if err := s.syncSourcesLocked(ctx); err != nil {
 		return errors.Wrap(err, "unable to sync sources")
 	}
@@ -573,19 +583,7 @@ func (s *Server) sendSnapshotReport(st notifydata.MultiSnapshotStatus) {
 	// send the notification without blocking if we still have the repository
 	// it's possible that repository was closed in the meantime.
 	if rep != nil {
-		notification.Send(s.rootctx, rep, "snapshot-report", st, s.reportSeverity(st), s.notificationTemplateOptions())
-	}
-}
-
-func (*Server) reportSeverity(st notifydata.MultiSnapshotStatus) notification.Severity {
-	// TODO - this is a duplicate of the code in command_snapshot_create.go - we should unify this.
-	switch st.OverallStatusCode() {
-	case notifydata.StatusCodeFatal:
-		return notification.SeverityError
-	case notifydata.StatusCodeWarnings:
-		return notification.SeverityWarning
-	default:
-		return notification.SeverityReport
+		notification.Send(s.rootctx, rep, "snapshot-report", st, notification.SnapshotReportSeverity(st), s.notificationTemplateOptions())
 	}
 }
 
@@ -600,6 +598,8 @@ func (s *Server) notificationTemplateOptions() notifytemplate.Options {
 // SetRepository sets the repository (nil is allowed and indicates server that is not
 // connected to the repository).
 func (s *Server) SetRepository(ctx context.Context, rep repo.Repository) error {
+	s.flushSuppressedSummaries(ctx, rep)
+
 	s.serverMutex.Lock()
 	defer s.serverMutex.Unlock()
 
@@ -646,6 +646,11 @@ func (s *Server) SetRepository(ctx context.Context, rep repo.Repository) error {
 	}
 
 	s.maint = maybeStartMaintenanceManager(ctx, s.rep, s, s.options.MinMaintenanceInterval)
+	s.staleSources.reset(clock.Now().Add(s.options.StaleSourceCheckInterval))
+	s.refreshDigestScheduleLocked(ctx)
+
+	// collect health metrics as soon as the repository is connected.
+	s.healthMetrics.setNextCollection(clock.Now())
 
 	s.sched = scheduler.Start(context.WithoutCancel(ctx), s.getSchedulerItems, scheduler.Options{
 		TimeNow:        clock.Now,
@@ -859,6 +864,10 @@ type Options struct {
 	MinMaintenanceInterval   time.Duration
 	EnableErrorNotifications bool
 	NotifyTemplateOptions    notifytemplate.Options
+	TrackChanges             bool          // track changes to local sources to skip unchanged directories
+	StaleSourceCheckInterval time.Duration // how often to check for sources overdue for a snapshot, zero disables
+	StaleSourceGracePeriod   time.Duration // how long a snapshot may be late before the source is considered stale
+	HealthMetricsInterval    time.Duration // how often to collect repository health metrics, zero disables
 }
 
 // InitRepositoryFunc is a function that attempts to connect to/open repository.
@@ -1000,7 +1009,12 @@ func (s *Server) runMaintenanceTask(ctx context.Context, dr repo.DirectRepositor
 		return repo.DirectWriteSession(ctx, dr, repo.WriteSessionOptions{
 			Purpose: "periodicMaintenance",
 		}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
-			return snapshotmaintenance.Run(ctx, w, maintenance.ModeAuto, false, maintenance.SafetyFull)
+			report, err := snapshotmaintenance.RunWithReport(ctx, w, maintenance.ModeAuto, false, maintenance.SafetyFull)
+			if report != nil {
+				notification.Send(ctx, w, "maintenance-report", report, snapshotmaintenance.ReportSeverity(report), s.notificationTemplateOptions())
+			}
+
+			return err //nolint:wrapcheck
 		})
 	}), "unable to run maintenance")
 }
@@ -1077,6 +1091,38 @@ func (s *Server) getSchedulerItems(ctx context.Context, now time.Time) []schedul
 		}
 	}
 
+	if s.options.StaleSourceCheckInterval > 0 && s.rep != nil {
+		result = append(result, scheduler.Item{
+			Description: "stale source check",
+			Trigger:     s.staleSourceCheckAsync,
+			NextTime:    s.staleSources.nextCheck(),
+		})
+	}
+
+	if s.options.HealthMetricsInterval > 0 && s.rep != nil {
+		result = append(result, scheduler.Item{
+			Description: "health metrics",
+			Trigger:     s.collectHealthMetricsAsync,
+			NextTime:    s.healthMetrics.nextCollection(),
+		})
+	}
+
+	for profileName, nt := range s.digests.nextTimes() {
+		result = append(result, scheduler.Item{
+			Description: fmt.Sprintf("notification digest %q", profileName),
+			Trigger:     func() { s.sendDigestAsync(profileName) },
+			NextTime:    nt,
+		})
+	}
+
+	if nt, ok := notification.NextSuppressedSummaryTime(); ok && s.rep != nil && !s.sendingSuppressedSummaries.Load() {
+		result = append(result, scheduler.Item{
+			Description: "suppressed notifications summary",
+			Trigger:     s.sendSuppressedSummariesAsync,
+			NextTime:    nt,
+		})
+	}
+
 	// add next snapshot time for all local sources
 	for _, sm := range s.sourceManagers {
 		if !s.isLocal(sm.src) {
@@ -1137,5 +1183,11 @@ func New(ctx context.Context, options *Options) (*Server, error) {
 
 	s.parallelSnapshotsChanged = sync.NewCond(&s.parallelSnapshotsMutex)
 
+	// the server delivers reports to profiles in digest mode on their schedule.
+	notification.EnableDigests(true)
+
+	// the server delivers summaries of rate-limited notifications when the limits expire.
+	notification.SetSuppressedHandler(func() { s.refreshScheduler("notification suppressed") })
+
 	return s, nil
 }
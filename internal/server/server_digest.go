@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifyprofile"
+)
+
+// digestSchedule keeps track of the next delivery times of notification profiles in digest mode.
+type digestSchedule struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	profiles map[string]digestProfileSchedule
+}
+
+type digestProfileSchedule struct {
+	digest   *notifyprofile.Digest
+	cron     string // cron expressions the next time was computed for
+	nextTime time.Time
+}
+
+// update updates the schedule based on the provided profiles, preserving next delivery times of
+// profiles whose schedule did not change.
+func (d *digestSchedule) update(profiles []notifyprofile.Config, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := map[string]digestProfileSchedule{}
+
+	for _, p := range profiles {
+		if p.Digest == nil {
+			continue
+		}
+
+		cron := strings.Join(p.Digest.Cron, "\n")
+
+		if prev, ok := d.profiles[p.ProfileName]; ok && prev.cron == cron {
+			result[p.ProfileName] = prev
+			continue
+		}
+
+		nt, ok := p.Digest.NextTime(now)
+		if !ok {
+			continue
+		}
+
+		result[p.ProfileName] = digestProfileSchedule{p.Digest, cron, nt}
+	}
+
+	d.profiles = result
+}
+
+// nextTimes returns the next delivery times of all profiles in digest mode.
+func (d *digestSchedule) nextTimes() map[string]time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := map[string]time.Time{}
+
+	for name, p := range d.profiles {
+		result[name] = p.nextTime
+	}
+
+	return result
+}
+
+// advance computes the next delivery time of the profile after the provided time.
+func (d *digestSchedule) advance(profileName string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.profiles[profileName]
+	if !ok {
+		return
+	}
+
+	nt, ok := p.digest.NextTime(now)
+	if !ok {
+		delete(d.profiles, profileName)
+		return
+	}
+
+	p.nextTime = nt
+	d.profiles[profileName] = p
+}
+
+// +checklocksread:s.serverMutex
+func (s *Server) refreshDigestScheduleLocked(ctx context.Context) {
+	if s.rep == nil {
+		s.digests.update(nil, clock.Now())
+		return
+	}
+
+	profiles, err := notifyprofile.ListProfiles(ctx, s.rep)
+	if err != nil {
+		userLog(ctx).Errorf("unable to list notification profiles: %v", err)
+		return
+	}
+
+	s.digests.update(profiles, clock.Now())
+}
+
+func (s *Server) sendDigestAsync(profileName string) {
+	// prevent the digest from being runnable until it's due again.
+	s.digests.advance(profileName, clock.Now())
+
+	go s.sendDigest(s.rootctx, profileName)
+}
+
+// sendDigest sends the notifications accumulated for the profile as a single digest.
+func (s *Server) sendDigest(ctx context.Context, profileName string) {
+	s.serverMutex.RLock()
+	rep := s.rep
+	s.serverMutex.RUnlock()
+
+	if rep == nil {
+		return
+	}
+
+	if err := notification.SendDigest(ctx, rep, profileName, s.notificationTemplateOptions()); err != nil {
+		userLog(ctx).Errorf("unable to send notification digest for profile %q: %v", profileName, err)
+	}
+}
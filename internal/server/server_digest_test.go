@@ -0,0 +1,48 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifyprofile"
+)
+
+func TestDigestSchedule(t *testing.T) {
+	var d digestSchedule
+
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	profiles := []notifyprofile.Config{
+		{ProfileName: "daily", Digest: &notifyprofile.Digest{Cron: []string{"0 8 * * *"}}},
+		{ProfileName: "weekly", Digest: &notifyprofile.Digest{Cron: []string{"0 8 * * 1"}}},
+		{ProfileName: "immediate"},
+	}
+
+	d.update(profiles, t0)
+	require.Equal(t, map[string]time.Time{
+		"daily":  time.Date(2020, 1, 2, 8, 0, 0, 0, time.UTC),
+		"weekly": time.Date(2020, 1, 6, 8, 0, 0, 0, time.UTC),
+	}, d.nextTimes())
+
+	// after the daily digest is sent, it's scheduled for the next day
+	d.advance("daily", time.Date(2020, 1, 2, 8, 0, 1, 0, time.UTC))
+	require.Equal(t, time.Date(2020, 1, 3, 8, 0, 0, 0, time.UTC), d.nextTimes()["daily"])
+
+	// unchanged schedules are preserved on refresh, changed ones are recomputed
+	profiles[1].Digest = &notifyprofile.Digest{Cron: []string{"0 9 * * *"}}
+
+	d.update(profiles, time.Date(2020, 1, 2, 9, 30, 0, 0, time.UTC))
+	require.Equal(t, map[string]time.Time{
+		"daily":  time.Date(2020, 1, 3, 8, 0, 0, 0, time.UTC),
+		"weekly": time.Date(2020, 1, 3, 9, 0, 0, 0, time.UTC),
+	}, d.nextTimes())
+
+	// removed profiles are no longer scheduled
+	d.update(profiles[:1], t0)
+	require.Len(t, d.nextTimes(), 1)
+
+	d.advance("no-such-profile", t0)
+	require.Len(t, d.nextTimes(), 1)
+}
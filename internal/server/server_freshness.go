@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfreshness"
+)
+
+// staleSourceWatchdog keeps track of periodic checks for sources whose snapshots are overdue.
+type staleSourceWatchdog struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	nextCheckTime time.Time
+	// +checklocks:mu
+	notified map[snapshot.SourceInfo]time.Time // due times of stale sources that were already notified about
+}
+
+func (w *staleSourceWatchdog) reset(nextCheckTime time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.nextCheckTime = nextCheckTime
+	w.notified = nil
+}
+
+func (w *staleSourceWatchdog) nextCheck() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.nextCheckTime
+}
+
+func (w *staleSourceWatchdog) setNextCheck(t time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.nextCheckTime = t
+}
+
+// shouldNotify records the provided stale sources and returns true if any of them was not notified about before.
+func (w *staleSourceWatchdog) shouldNotify(st *notifydata.StaleSources) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	notified := map[snapshot.SourceInfo]time.Time{}
+	hasNew := false
+
+	for _, s := range st.Sources {
+		if due, ok := w.notified[s.Source]; !ok || !due.Equal(s.DueTime) {
+			hasNew = true
+		}
+
+		notified[s.Source] = s.DueTime
+	}
+
+	w.notified = notified
+
+	return hasNew
+}
+
+func (s *Server) staleSourceCheckAsync() {
+	// prevent the check from being runnable until it's due again.
+	s.staleSources.setNextCheck(clock.Now().Add(s.options.StaleSourceCheckInterval))
+
+	go s.checkStaleSources(s.rootctx)
+}
+
+// checkStaleSources checks whether any sources are overdue for a snapshot and sends a single
+// notification listing all of them when a source becomes stale.
+func (s *Server) checkStaleSources(ctx context.Context) {
+	// the scan reads manifests of all sources, so don't block the server while it runs.
+	s.serverMutex.RLock()
+	rep := s.rep
+	sources := slices.Collect(maps.Keys(s.sourceManagers))
+	s.serverMutex.RUnlock()
+
+	if rep == nil || len(sources) == 0 {
+		return
+	}
+
+	st, err := snapshotfreshness.FindStaleSources(ctx, rep, sources, clock.Now(), s.options.StaleSourceGracePeriod)
+	if err != nil {
+		userLog(ctx).Errorf("unable to check for stale sources: %v", err)
+		return
+	}
+
+	if !s.staleSources.shouldNotify(st) {
+		return
+	}
+
+	userLog(ctx).Infof("%v source(s) are overdue for a snapshot", len(st.Sources))
+
+	notification.Send(ctx, rep, "stale-source", st, notification.SeverityWarning, s.notificationTemplateOptions())
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestStaleSourceWatchdog_ShouldNotify(t *testing.T) {
+	var w staleSourceWatchdog
+
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	src1 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path1"}
+	src2 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path2"}
+
+	stale := func(sources ...*notifydata.StaleSource) *notifydata.StaleSources {
+		return &notifydata.StaleSources{Sources: sources}
+	}
+
+	// nothing is stale
+	require.False(t, w.shouldNotify(stale()))
+
+	// src1 became stale
+	require.True(t, w.shouldNotify(stale(&notifydata.StaleSource{Source: src1, DueTime: t0})))
+
+	// src1 is still stale, already notified
+	require.False(t, w.shouldNotify(stale(&notifydata.StaleSource{Source: src1, DueTime: t0})))
+
+	// src2 became stale as well
+	require.True(t, w.shouldNotify(stale(
+		&notifydata.StaleSource{Source: src1, DueTime: t0},
+		&notifydata.StaleSource{Source: src2, DueTime: t0},
+	)))
+
+	// src1 was snapshotted and is now stale again with a different due time.
+	require.True(t, w.shouldNotify(stale(
+		&notifydata.StaleSource{Source: src1, DueTime: t0.Add(time.Hour)},
+		&notifydata.StaleSource{Source: src2, DueTime: t0},
+	)))
+
+	// src2 was snapshotted, src1 is still stale.
+	require.False(t, w.shouldNotify(stale(&notifydata.StaleSource{Source: src1, DueTime: t0.Add(time.Hour)})))
+
+	// after reset all stale sources are notified about again.
+	w.reset(t0)
+	require.Equal(t, t0, w.nextCheck())
+	require.True(t, w.shouldNotify(stale(&notifydata.StaleSource{Source: src1, DueTime: t0.Add(time.Hour)})))
+}
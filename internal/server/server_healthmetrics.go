@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/healthmetrics"
+)
+
+// healthMetricsCollector keeps track of periodic collection of repository health metrics.
+type healthMetricsCollector struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	nextCollectionTime time.Time
+}
+
+func (c *healthMetricsCollector) nextCollection() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.nextCollectionTime
+}
+
+func (c *healthMetricsCollector) setNextCollection(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextCollectionTime = t
+}
+
+func (s *Server) collectHealthMetricsAsync() {
+	// prevent the collection from being runnable until it's due again.
+	s.healthMetrics.setNextCollection(clock.Now().Add(s.options.HealthMetricsInterval))
+
+	go s.collectHealthMetrics(s.rootctx)
+}
+
+// collectHealthMetrics derives Prometheus gauges describing snapshots and repository contents.
+func (s *Server) collectHealthMetrics(ctx context.Context) {
+	// collection reads all snapshot manifests, so don't block the server while it runs.
+	s.serverMutex.RLock()
+	rep := s.rep
+	s.serverMutex.RUnlock()
+
+	if rep == nil {
+		return
+	}
+
+	st, err := healthmetrics.Collect(ctx, rep, clock.Now())
+	if err != nil {
+		userLog(ctx).Errorf("unable to collect health metrics: %v", err)
+		return
+	}
+
+	for _, ss := range st.Sources {
+		if ss.Error != nil {
+			userLog(ctx).Warnf("unable to collect health metrics of %v: %v", ss.Source, ss.Error)
+		}
+	}
+
+	healthmetrics.Publish(st)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/repo"
+)
+
+func (s *Server) sendSuppressedSummariesAsync() {
+	// prevent the summaries from being runnable until they have been sent.
+	s.sendingSuppressedSummaries.Store(true)
+
+	go func() {
+		defer s.refreshScheduler("suppressed notifications summary sent")
+		defer s.sendingSuppressedSummaries.Store(false)
+
+		s.sendSuppressedSummaries(s.rootctx, false)
+	}()
+}
+
+// sendSuppressedSummaries sends summaries of notifications suppressed by rate limits which are due
+// or all pending summaries if all is true.
+func (s *Server) sendSuppressedSummaries(ctx context.Context, all bool) {
+	s.serverMutex.RLock()
+	rep := s.rep
+	s.serverMutex.RUnlock()
+
+	if rep == nil {
+		return
+	}
+
+	if err := notification.SendSuppressedSummaries(ctx, rep, s.notificationTemplateOptions(), all); err != nil {
+		userLog(ctx).Errorf("unable to send summary of suppressed notifications: %v", err)
+	}
+}
+
+// flushSuppressedSummaries sends all pending summaries of rate-limited notifications before the server
+// disconnects from the current repository, since suppressed notifications are only kept in memory.
+func (s *Server) flushSuppressedSummaries(ctx context.Context, newRep repo.Repository) {
+	s.serverMutex.RLock()
+	changed := s.rep != nil && s.rep != newRep
+	s.serverMutex.RUnlock()
+
+	if changed {
+		s.sendSuppressedSummaries(ctx, true)
+	}
+}
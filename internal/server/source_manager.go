@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,10 +11,13 @@
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/changejournal"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/serverapi"
 	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/notification"
 	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifytemplate"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -28,6 +32,7 @@
 type sourceManagerServerInterface interface {
 	runSnapshotTask(ctx context.Context, src snapshot.SourceInfo, inner func(ctx context.Context, ctrl uitask.Controller, result *notifydata.ManifestWithError) error) error
 	refreshScheduler(reason string)
+	notificationTemplateOptions() notifytemplate.Options
 }
 
 // sourceManager manages the state machine of each source
@@ -74,6 +79,9 @@ type sourceManager struct {
 	isReadOnly bool
 
 	progress *upload.CountingUploadProgress
+
+	trackChanges  bool
+	changeTracker *changejournal.Tracker // only accessed by the goroutine running the source manager
 }
 
 func (s *sourceManager) Status() *serverapi.SourceStatus {
@@ -176,6 +184,12 @@ func (s *sourceManager) run(ctx context.Context, isLocal bool) {
 }
 
 func (s *sourceManager) runLocal(ctx context.Context) {
+	if s.trackChanges {
+		if stop := s.startTrackingChanges(ctx); stop != nil {
+			defer stop()
+		}
+	}
+
 	if s.isPaused() {
 		s.setStatus("PAUSED")
 	} else {
@@ -213,6 +227,22 @@ func (s *sourceManager) runLocal(ctx context.Context) {
 	}
 }
 
+// startTrackingChanges starts watching the source directory, so that uploads can skip directories
+// that have not changed since the previous snapshot.
+func (s *sourceManager) startTrackingChanges(ctx context.Context) (stop func()) {
+	tracker := changejournal.NewTracker()
+
+	stop, err := changejournal.Watch(ctx, tracker, s.src.Path)
+	if err != nil {
+		userLog(ctx).Warnf("unable to track changes to %v, all directories will be scanned: %v", s.src, err)
+		return nil
+	}
+
+	s.changeTracker = tracker
+
+	return stop
+}
+
 func (s *sourceManager) backoffBeforeNextSnapshot() {
 	if _, ok := s.getNextSnapshotTime(); !ok {
 		return
@@ -326,9 +356,9 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 	default:
 	}
 
-	localEntry, err := localfs.NewEntry(s.src.Path)
+	localEntry, err := s.sourceEntry(ctx)
 	if err != nil {
-		return errors.Wrap(err, "unable to create local filesystem")
+		return err
 	}
 
 	onUpload := func(int64) {}
@@ -355,6 +385,10 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 
 		u := upload.NewUploader(w)
 
+		if s.changeTracker != nil {
+			u.ChangeSource = s.changeTracker
+		}
+
 		ctrl.OnCancel(u.Cancel)
 
 		policyTree, err := policy.TreeForSource(ctx, w, s.src)
@@ -362,6 +396,10 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 			return errors.Wrap(err, "unable to create policy getter")
 		}
 
+		if err := s.quotaPreflight(ctx, localEntry, policyTree, manifestsSinceLastCompleteSnapshot); err != nil {
+			return err
+		}
+
 		// set up progress that will keep counters and report to the uitask.
 		prog := &uitaskProgress{
 			p:    s.progress,
@@ -409,6 +447,56 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 	})
 }
 
+// quotaPreflight estimates the size of the source before uploading it and fails or warns when it exceeds quotas.
+func (s *sourceManager) quotaPreflight(ctx context.Context, localEntry fs.Entry, policyTree *policy.Tree, previous []*snapshot.Manifest) error {
+	t0 := clock.Now()
+
+	res, err := upload.QuotaPreflight(ctx, localEntry, policyTree, previous...)
+	if res == nil || len(res.Violations) == 0 {
+		return err //nolint:wrapcheck
+	}
+
+	// violations fail the snapshot in the "fail" mode and are only reported in the "warn" mode.
+	severity := notification.SeverityWarning
+	if err != nil {
+		severity = notification.SeverityError
+	}
+
+	userLog(ctx).Warnf("pre-flight quota check for %v: %v", s.src, res.Error())
+
+	notification.Send(ctx, s.rep, "generic-error",
+		notifydata.NewErrorInfo("Snapshot Pre-flight Quota Check", s.src.String(), t0, clock.Now(), res.Error()),
+		severity,
+		s.server.notificationTemplateOptions(),
+	)
+
+	return err //nolint:wrapcheck
+}
+
+// sourceEntry returns the filesystem entry to be snapshotted, which is either the local directory
+// or the output of commands configured in the source policy.
+func (s *sourceManager) sourceEntry(ctx context.Context) (fs.Entry, error) {
+	pol, _, _, err := policy.GetEffectivePolicy(ctx, s.rep, s.src)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get effective policy")
+	}
+
+	if pol.CommandSource.IsCommandSource() {
+		if !s.rep.ClientOptions().EnableActions {
+			return nil, upload.ErrCommandSourceDisabled
+		}
+
+		return upload.NewCommandSource(filepath.Base(s.src.Path), pol.CommandSource.Streams), nil
+	}
+
+	localEntry, err := localfs.NewEntry(s.src.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create local filesystem")
+	}
+
+	return localEntry, nil
+}
+
 // +checklocksread:s.sourceMutex
 func (s *sourceManager) findClosestNextSnapshotTimeReadLocked() *time.Time {
 	var previousSnapshotTime fs.UTCTimestamp
@@ -601,6 +689,7 @@ func newSourceManager(src snapshot.SourceInfo, server *Server, rep repo.Reposito
 		closed:           make(chan struct{}),
 		snapshotRequests: make(chan struct{}, 1),
 		progress:         &upload.CountingUploadProgress{},
+		trackChanges:     server.options.TrackChanges,
 	}
 
 	return m
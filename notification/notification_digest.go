@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/repo"
+)
+
+// digestAccumulator accumulates snapshot and maintenance reports for profiles in digest mode.
+// The state is kept in memory, so digests are only accumulated within a single process, such as the server.
+type digestAccumulator struct {
+	mu      sync.Mutex
+	enabled bool
+	digests map[string]*notifydata.Digest
+}
+
+//nolint:gochecknoglobals
+var profileDigests = &digestAccumulator{
+	digests: map[string]*notifydata.Digest{},
+}
+
+// EnableDigests enables accumulation of reports for profiles in digest mode.
+// It should only be enabled by long-running processes, such as the server, which deliver
+// the accumulated digests on schedule using SendDigest(). When disabled, reports are sent immediately.
+func EnableDigests(enabled bool) {
+	profileDigests.mu.Lock()
+	defer profileDigests.mu.Unlock()
+
+	profileDigests.enabled = enabled
+}
+
+func (a *digestAccumulator) isEnabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.enabled
+}
+
+// isDigestEvent returns true if the event is summarized in digests instead of being sent immediately.
+func isDigestEvent(eventArgs notifydata.TypedEventArgs) bool {
+	switch eventArgs.(type) {
+	case notifydata.MultiSnapshotStatus, *notifydata.MultiSnapshotStatus, *notifydata.MaintenanceReport:
+		return true
+	default:
+		return false
+	}
+}
+
+// add adds the event to the digest of the profile and returns true if it was accumulated.
+func (a *digestAccumulator) add(profileName string, eventArgs notifydata.TypedEventArgs, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.enabled || !isDigestEvent(eventArgs) {
+		return false
+	}
+
+	d := a.digests[profileName]
+	if d == nil {
+		d = &notifydata.Digest{ProfileName: profileName, StartTime: now}
+		a.digests[profileName] = d
+	}
+
+	switch ea := eventArgs.(type) {
+	case notifydata.MultiSnapshotStatus:
+		d.AddSnapshots(ea)
+	case *notifydata.MultiSnapshotStatus:
+		d.AddSnapshots(*ea)
+	case *notifydata.MaintenanceReport:
+		d.AddMaintenance(ea)
+	}
+
+	return true
+}
+
+// take returns the digest accumulated for the profile and starts a new digest period.
+// The digest must be restored if it can't be delivered.
+func (a *digestAccumulator) take(profileName string, now time.Time) *notifydata.Digest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	d := a.digests[profileName]
+	if d == nil {
+		d = &notifydata.Digest{ProfileName: profileName, StartTime: now}
+	}
+
+	d.EndTime = now
+	a.digests[profileName] = &notifydata.Digest{ProfileName: profileName, StartTime: now}
+
+	return d
+}
+
+// restore puts back the digest taken for the profile, which could not be delivered,
+// merging it with reports accumulated since.
+func (a *digestAccumulator) restore(profileName string, d *notifydata.Digest) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cur := a.digests[profileName]; cur != nil {
+		d.Merge(cur)
+	}
+
+	d.EndTime = time.Time{}
+	a.digests[profileName] = d
+}
+
+// SendDigest sends the digest of snapshot and maintenance reports accumulated for the given profile
+// since the previous digest. Nothing is sent if no reports were accumulated.
+func SendDigest(ctx context.Context, rep repo.Repository, profileName string, opt notifytemplate.Options) error {
+	p, err := notifyprofile.GetProfile(ctx, rep, profileName)
+	if err != nil {
+		return errors.Wrap(err, "unable to get notification profile")
+	}
+
+	s, err := sender.GetSender(ctx, p.ProfileName, p.MethodConfig.Type, p.MethodConfig.Config)
+	if err != nil {
+		return errors.Wrap(err, "unable to create sender for notification profile")
+	}
+
+	d := profileDigests.take(profileName, clock.Now())
+	if d.IsEmpty() {
+		log(ctx).Debugw("no reports accumulated for digest", "profile", profileName)
+
+		return nil
+	}
+
+	sev := SeverityReport
+	if d.OverallStatusCode() == notifydata.StatusCodeFatal {
+		sev = SeverityError
+	}
+
+	if err := SendTo(ctx, rep, s, notifytemplate.Digest, d, sev, opt); err != nil {
+		// deliver the reports with the next digest.
+		profileDigests.restore(profileName, d)
+
+		return err
+	}
+
+	return nil
+}
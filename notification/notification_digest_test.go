@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/testsender"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestDigestAccumulator(t *testing.T) {
+	a := &digestAccumulator{digests: map[string]*notifydata.Digest{}}
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	st := notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}}},
+		},
+	}
+
+	// not accumulated unless enabled
+	require.False(t, a.add("p1", st, t0))
+
+	a.enabled = true
+
+	require.True(t, a.add("p1", st, t0))
+	require.True(t, a.add("p1", &st, t0.Add(time.Hour)))
+	require.True(t, a.add("p1", &notifydata.MaintenanceReport{Mode: "full"}, t0.Add(2*time.Hour)))
+	require.True(t, a.add("p2", st, t0.Add(3*time.Hour)))
+
+	// other events are sent immediately
+	require.False(t, a.add("p1", notifydata.NewErrorInfo("Snapshot", "details", t0, t0, errors.New("some error")), t0))
+	require.False(t, a.add("p1", &notifydata.StaleSources{}, t0))
+
+	d := a.take("p1", t0.Add(4*time.Hour))
+	require.Equal(t, "p1", d.ProfileName)
+	require.Equal(t, t0, d.StartTime)
+	require.Equal(t, t0.Add(4*time.Hour), d.EndTime)
+	require.Equal(t, 2, d.TotalSnapshots())
+	require.Equal(t, 1, d.MaintenanceRuns)
+
+	// next digest period starts when the previous one was taken
+	d = a.take("p1", t0.Add(5*time.Hour))
+	require.True(t, d.IsEmpty())
+	require.Equal(t, t0.Add(4*time.Hour), d.StartTime)
+
+	d = a.take("p2", t0.Add(5*time.Hour))
+	require.Equal(t, 1, d.TotalSnapshots())
+	require.Equal(t, t0.Add(3*time.Hour), d.StartTime)
+}
+
+func TestSendDigest(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	ctx = testsender.CaptureMessages(ctx)
+
+	EnableDigests(true)
+	defer EnableDigests(false)
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		for _, p := range []notifyprofile.Config{
+			{ProfileName: "digest", Digest: &notifyprofile.Digest{Cron: []string{"0 8 * * *"}}},
+			{ProfileName: "immediate"},
+		} {
+			p.MethodConfig = sender.MethodConfig{Type: "testsender", Config: &testsender.Options{Format: "txt"}}
+
+			if err := notifyprofile.SaveProfile(ctx, w, p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	st := notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}}, Error: "some error"},
+		},
+	}
+
+	// reports are only sent to the profile without digest, errors are sent to both.
+	require.NoError(t, SendInternal(ctx, env.Repository, "snapshot-report", st, SeverityError, notifytemplate.DefaultOptions))
+	require.NoError(t, SendInternal(ctx, env.Repository, "maintenance-report", &notifydata.MaintenanceReport{Mode: "full"}, SeverityVerbose, notifytemplate.DefaultOptions))
+	require.Len(t, testsender.MessagesInContext(ctx), 1)
+
+	require.NoError(t, SendInternal(ctx, env.Repository, "generic-error", notifydata.NewErrorInfo("Snapshot", "details", time.Now(), time.Now(), errors.New("some error")), SeverityError, notifytemplate.DefaultOptions))
+	require.Len(t, testsender.MessagesInContext(ctx), 3)
+
+	require.NoError(t, SendDigest(ctx, env.Repository, "digest", notifytemplate.DefaultOptions))
+
+	msgs := testsender.MessagesInContext(ctx)
+	require.Len(t, msgs, 4)
+	require.Contains(t, msgs[3].Subject, "Digest: 1 of 1 snapshots failed")
+	require.Equal(t, SeverityError, msgs[3].Severity)
+	require.Contains(t, msgs[3].Body, "Maintenance Runs: 1")
+
+	// nothing accumulated since the previous digest.
+	require.NoError(t, SendDigest(ctx, env.Repository, "digest", notifytemplate.DefaultOptions))
+	require.Len(t, testsender.MessagesInContext(ctx), 4)
+
+	require.ErrorContains(t, SendDigest(ctx, env.Repository, "no-such-profile", notifytemplate.DefaultOptions), "unable to get notification profile")
+}
+
+func TestSendDigest_RestoredOnFailure(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	var (
+		sendErr error
+		msgs    []*sender.Message
+	)
+
+	ctx = testsender.CaptureMessagesWithHandler(ctx, func(msg *sender.Message) error {
+		if sendErr != nil {
+			return sendErr
+		}
+
+		msgs = append(msgs, msg)
+
+		return nil
+	})
+
+	EnableDigests(true)
+	defer EnableDigests(false)
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		return notifyprofile.SaveProfile(ctx, w, notifyprofile.Config{
+			ProfileName:  "digest-restore",
+			MethodConfig: sender.MethodConfig{Type: "testsender", Config: &testsender.Options{Format: "txt"}},
+			Digest:       &notifyprofile.Digest{Cron: []string{"0 8 * * *"}},
+		})
+	}))
+
+	st := notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}}, Error: "some error"},
+		},
+	}
+
+	require.NoError(t, SendInternal(ctx, env.Repository, "snapshot-report", st, SeverityError, notifytemplate.DefaultOptions))
+
+	sendErr = errors.New("send failed")
+
+	require.ErrorContains(t, SendDigest(ctx, env.Repository, "digest-restore", notifytemplate.DefaultOptions), "send failed")
+
+	// reports accumulated after the failure are delivered together with the ones that failed to send.
+	require.NoError(t, SendInternal(ctx, env.Repository, "snapshot-report", st, SeverityError, notifytemplate.DefaultOptions))
+
+	sendErr = nil
+
+	require.NoError(t, SendDigest(ctx, env.Repository, "digest-restore", notifytemplate.DefaultOptions))
+
+	require.Len(t, msgs, 1)
+	require.Contains(t, msgs[0].Subject, "Digest: 2 of 2 snapshots failed")
+
+	// nothing left after successful delivery.
+	require.NoError(t, SendDigest(ctx, env.Repository, "digest-restore", notifytemplate.DefaultOptions))
+	require.Len(t, msgs, 1)
+}
@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// routingEvent returns the event used for routing notifications to profiles.
+func routingEvent(templateName string, eventArgs notifydata.TypedEventArgs, sev Severity) notifyprofile.Event {
+	ev := notifyprofile.Event{
+		Type:     templateName,
+		Severity: sev,
+	}
+
+	switch ea := eventArgs.(type) {
+	case notifydata.MultiSnapshotStatus:
+		ev.Sources = snapshotSources(ea.Snapshots)
+	case *notifydata.MultiSnapshotStatus:
+		ev.Sources = snapshotSources(ea.Snapshots)
+	case *notifydata.StaleSources:
+		for _, s := range ea.Sources {
+			ev.Sources = append(ev.Sources, s.Source)
+		}
+	}
+
+	return ev
+}
+
+// profileEventArgs returns the event arguments and severity of the notification delivered to the profile
+// and whether the profile should receive it at all.
+// Snapshot reports sent to profiles with rules only include snapshots matching the rules on their own,
+// with the severity derived from the included snapshots, so that the profile does not receive
+// snapshots of unrelated sources because another source in the same report matched.
+func profileEventArgs(p *notifyprofile.Config, templateName string, eventArgs notifydata.TypedEventArgs, sev Severity, digest bool) (notifydata.TypedEventArgs, Severity, bool) {
+	var st notifydata.MultiSnapshotStatus
+
+	switch ea := eventArgs.(type) {
+	case notifydata.MultiSnapshotStatus:
+		st = ea
+	case *notifydata.MultiSnapshotStatus:
+		st = *ea
+	default:
+		return eventArgs, sev, profileMatches(p, routingEvent(templateName, eventArgs, sev), digest)
+	}
+
+	if len(p.Rules) == 0 {
+		return eventArgs, sev, profileMatches(p, routingEvent(templateName, eventArgs, sev), digest)
+	}
+
+	var filtered notifydata.MultiSnapshotStatus
+
+	for _, s := range st.Snapshots {
+		single := notifydata.MultiSnapshotStatus{Snapshots: []*notifydata.ManifestWithError{s}}
+
+		if profileMatches(p, routingEvent(templateName, single, min(sev, SnapshotReportSeverity(single))), digest) {
+			filtered.Snapshots = append(filtered.Snapshots, s)
+		}
+	}
+
+	switch len(filtered.Snapshots) {
+	case 0:
+		return nil, sev, false
+	case len(st.Snapshots):
+		return eventArgs, sev, true
+	default:
+		return &filtered, min(sev, SnapshotReportSeverity(filtered)), true
+	}
+}
+
+// profileMatches returns true if the event should be delivered to the profile.
+// Profiles in digest mode summarize all matching reports, regardless of their severity.
+func profileMatches(p *notifyprofile.Config, ev notifyprofile.Event, digest bool) bool {
+	return p.Matches(ev) || (digest && p.MatchesRules(ev))
+}
+
+func snapshotSources(snapshots []*notifydata.ManifestWithError) []snapshot.SourceInfo {
+	var result []snapshot.SourceInfo
+
+	for _, s := range snapshots {
+		result = append(result, s.Manifest.Source)
+	}
+
+	return result
+}
+
+// eventKey returns a key which is identical for repeated notifications about the same outcome,
+// ignoring details such as timestamps and statistics.
+func eventKey(templateName string, eventArgs notifydata.TypedEventArgs) string {
+	var sb strings.Builder
+
+	sb.WriteString(templateName)
+
+	switch ea := eventArgs.(type) {
+	case notifydata.MultiSnapshotStatus:
+		writeSnapshotsKey(&sb, ea.Snapshots)
+	case *notifydata.MultiSnapshotStatus:
+		writeSnapshotsKey(&sb, ea.Snapshots)
+	case *notifydata.ErrorInfo:
+		fmt.Fprintf(&sb, "|%v|%v|%v", ea.Operation, ea.OperationDetails, ea.ErrorMessage)
+	case *notifydata.MaintenanceReport:
+		fmt.Fprintf(&sb, "|%v|%v|%v", ea.Mode, ea.OverallStatusCode(), ea.Error)
+
+		for _, t := range ea.Tasks {
+			fmt.Fprintf(&sb, "|%v:%v", t.Task, t.Error)
+		}
+	case *notifydata.StaleSources:
+		for _, s := range ea.Sources {
+			fmt.Fprintf(&sb, "|%v", s.Source)
+		}
+	}
+
+	return sb.String()
+}
+
+func writeSnapshotsKey(sb *strings.Builder, snapshots []*notifydata.ManifestWithError) {
+	for _, s := range snapshots {
+		fmt.Fprintf(sb, "|%v:%v:%v", s.Manifest.Source, s.StatusCode(), s.Error)
+	}
+}
+
+// SnapshotReportSeverity returns the severity of the snapshot report notification based on the overall status of the snapshots.
+func SnapshotReportSeverity(st notifydata.MultiSnapshotStatus) Severity {
+	switch st.OverallStatusCode() {
+	case notifydata.StatusCodeFatal:
+		return SeverityError
+	case notifydata.StatusCodeWarnings:
+		return SeverityWarning
+	default:
+		return SeverityReport
+	}
+}
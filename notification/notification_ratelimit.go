@@ -0,0 +1,277 @@
+package notification
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/repo"
+)
+
+// rateLimiter keeps track of notifications delivered to profiles with rate limits.
+// The state is kept in memory and is not persisted, so limits are only enforced within a single process,
+// such as the server, and notifications suppressed by a short-lived process are summarized when it
+// delivers its next notification to the profile, or lost when it exits before doing so.
+// Long-running processes should deliver the summaries using SendSuppressedSummaries() when they are due
+// and before exiting.
+type rateLimiter struct {
+	mu       sync.Mutex
+	profiles map[string]*profileRateLimitState
+
+	onSuppressed func() // invoked after a notification is suppressed, without holding mu
+}
+
+type profileRateLimitState struct {
+	windowStart  time.Time
+	sentInWindow int
+	lastSent     map[string]time.Time
+
+	// suppressed notifications in the order they were first suppressed.
+	suppressed      []*notifydata.SuppressedNotification
+	suppressedByKey map[string]*notifydata.SuppressedNotification
+
+	// highest severity of the suppressed notifications.
+	suppressedSeverity Severity
+
+	// time when the limits which suppressed the notifications expire and their summary is due.
+	summaryDue time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		profiles: map[string]*profileRateLimitState{},
+	}
+}
+
+func (l *rateLimiter) stateLocked(profileName string) *profileRateLimitState {
+	st := l.profiles[profileName]
+	if st == nil {
+		st = &profileRateLimitState{
+			lastSent:        map[string]time.Time{},
+			suppressedByKey: map[string]*notifydata.SuppressedNotification{},
+		}
+
+		l.profiles[profileName] = st
+	}
+
+	return st
+}
+
+// allow determines whether a notification identified by the provided key can be delivered to the profile.
+// If it can't, the notification is recorded as suppressed.
+func (l *rateLimiter) allow(profileName string, rl *notifyprofile.RateLimit, key, subject string, sev Severity, now time.Time) bool {
+	if l.allowOrSuppress(profileName, rl, key, subject, sev, now) {
+		return true
+	}
+
+	if f := l.suppressedHandler(); f != nil {
+		f()
+	}
+
+	return false
+}
+
+func (l *rateLimiter) allowOrSuppress(profileName string, rl *notifyprofile.RateLimit, key, subject string, sev Severity, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st := l.stateLocked(profileName)
+
+	if rl.DedupInterval > 0 {
+		for k, t := range st.lastSent {
+			if now.Sub(t) >= rl.DedupInterval {
+				delete(st.lastSent, k)
+			}
+		}
+
+		if t, ok := st.lastSent[key]; ok {
+			st.suppress(key, subject, sev, now, t.Add(rl.DedupInterval))
+			return false
+		}
+	}
+
+	if rl.MaxNotifications > 0 && rl.Interval > 0 {
+		if now.Sub(st.windowStart) >= rl.Interval {
+			st.windowStart = now
+			st.sentInWindow = 0
+		}
+
+		if st.sentInWindow >= rl.MaxNotifications {
+			st.suppress(key, subject, sev, now, st.windowStart.Add(rl.Interval))
+			return false
+		}
+
+		st.sentInWindow++
+	}
+
+	if rl.DedupInterval > 0 {
+		st.lastSent[key] = now
+	}
+
+	return true
+}
+
+func (l *rateLimiter) suppressedHandler() func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.onSuppressed
+}
+
+// takeSuppressed returns and clears the list of notifications suppressed for the profile
+// together with their highest severity.
+func (l *rateLimiter) takeSuppressed(profileName string) ([]*notifydata.SuppressedNotification, Severity) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st := l.profiles[profileName]
+	if st == nil || len(st.suppressed) == 0 {
+		return nil, 0
+	}
+
+	result, sev := st.suppressed, st.suppressedSeverity
+
+	st.suppressed = nil
+	st.suppressedByKey = map[string]*notifydata.SuppressedNotification{}
+	st.suppressedSeverity = 0
+	st.summaryDue = time.Time{}
+
+	return result, sev
+}
+
+// nextSummaryTime returns the earliest time when a summary of suppressed notifications is due.
+func (l *rateLimiter) nextSummaryTime() (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var (
+		next time.Time
+		ok   bool
+	)
+
+	for _, st := range l.profiles {
+		if len(st.suppressed) > 0 && (!ok || st.summaryDue.Before(next)) {
+			next = st.summaryDue
+			ok = true
+		}
+	}
+
+	return next, ok
+}
+
+// profilesWithSummaries returns the names of profiles with suppressed notifications whose summary is due
+// at the provided time or with any suppressed notifications if all is true.
+func (l *rateLimiter) profilesWithSummaries(now time.Time, all bool) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result []string
+
+	for profileName, st := range l.profiles {
+		if len(st.suppressed) > 0 && (all || !st.summaryDue.After(now)) {
+			result = append(result, profileName)
+		}
+	}
+
+	return result
+}
+
+func (st *profileRateLimitState) suppress(key, subject string, sev Severity, now, due time.Time) {
+	if len(st.suppressed) == 0 || sev > st.suppressedSeverity {
+		st.suppressedSeverity = sev
+	}
+
+	if st.summaryDue.IsZero() || due.Before(st.summaryDue) {
+		st.summaryDue = due
+	}
+
+	if n := st.suppressedByKey[key]; n != nil {
+		n.Count++
+		n.LastTime = now
+
+		return
+	}
+
+	n := &notifydata.SuppressedNotification{
+		Subject:   subject,
+		Count:     1,
+		FirstTime: now,
+		LastTime:  now,
+	}
+
+	st.suppressed = append(st.suppressed, n)
+	st.suppressedByKey[key] = n
+}
+
+// SetSuppressedHandler sets the function invoked after a notification is suppressed by the rate limits
+// of a profile, which allows long-running processes to schedule delivery of the summary.
+func SetSuppressedHandler(f func()) {
+	profileRateLimiter.mu.Lock()
+	defer profileRateLimiter.mu.Unlock()
+
+	profileRateLimiter.onSuppressed = f
+}
+
+// NextSuppressedSummaryTime returns the earliest time when a summary of notifications
+// suppressed by rate limits is due, or false if no notifications were suppressed.
+func NextSuppressedSummaryTime() (time.Time, bool) {
+	return profileRateLimiter.nextSummaryTime()
+}
+
+// SendSuppressedSummaries sends summaries of notifications suppressed by rate limits to profiles
+// whose limits have expired since. If all is true, summaries are sent to all profiles
+// with suppressed notifications, which should be done before the process exits or disconnects
+// from the repository, since suppressed notifications are only kept in memory.
+func SendSuppressedSummaries(ctx context.Context, rep repo.Repository, opt notifytemplate.Options, all bool) error {
+	var resultErr error
+
+	for _, profileName := range profileRateLimiter.profilesWithSummaries(clock.Now(), all) {
+		if err := sendSuppressedSummaryToProfile(ctx, rep, profileName, opt); err != nil {
+			resultErr = stderrors.Join(resultErr, errors.Wrapf(err, "profile %q", profileName))
+		}
+	}
+
+	return resultErr
+}
+
+func sendSuppressedSummaryToProfile(ctx context.Context, rep repo.Repository, profileName string, opt notifytemplate.Options) error {
+	p, err := notifyprofile.GetProfile(ctx, rep, profileName)
+	if errors.Is(err, notifyprofile.ErrNotFound) {
+		// profile was deleted, drop its suppressed notifications.
+		profileRateLimiter.takeSuppressed(profileName)
+
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to get notification profile")
+	}
+
+	s, err := sender.GetSender(ctx, p.ProfileName, p.MethodConfig.Type, p.MethodConfig.Config)
+	if err != nil {
+		return errors.Wrap(err, "unable to create sender for notification profile")
+	}
+
+	return sendSuppressedSummary(ctx, rep, s, opt)
+}
+
+// sendSuppressedSummary sends the summary of notifications suppressed by the rate limits of the profile, if any.
+func sendSuppressedSummary(ctx context.Context, rep repo.Repository, s sender.Sender, opt notifytemplate.Options) error {
+	suppressed, sev := profileRateLimiter.takeSuppressed(s.ProfileName())
+	if len(suppressed) == 0 {
+		return nil
+	}
+
+	return SendTo(ctx, rep, s, notifytemplate.SuppressedNotifications, &notifydata.SuppressedNotifications{
+		ProfileName:   s.ProfileName(),
+		Notifications: suppressed,
+	}, sev, opt)
+}
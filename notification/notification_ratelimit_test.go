@@ -0,0 +1,192 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/testsender"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestRateLimiter_Dedup(t *testing.T) {
+	l := newRateLimiter()
+	rl := &notifyprofile.RateLimit{DedupInterval: time.Hour}
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.True(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0))
+	require.False(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0.Add(10*time.Minute)))
+	require.False(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0.Add(20*time.Minute)))
+
+	// different key and different profile are not affected
+	require.True(t, l.allow("p1", rl, "key2", "subject2", SeverityWarning, t0.Add(30*time.Minute)))
+	require.True(t, l.allow("p2", rl, "key1", "subject1", SeverityWarning, t0.Add(30*time.Minute)))
+
+	// summary is due when the dedup interval of the first suppressed notification expires.
+	next, ok := l.nextSummaryTime()
+	require.True(t, ok)
+	require.Equal(t, t0.Add(time.Hour), next)
+	require.Empty(t, l.profilesWithSummaries(t0.Add(59*time.Minute), false))
+	require.Equal(t, []string{"p1"}, l.profilesWithSummaries(t0.Add(59*time.Minute), true))
+	require.Equal(t, []string{"p1"}, l.profilesWithSummaries(t0.Add(time.Hour), false))
+
+	suppressed, sev := l.takeSuppressed("p1")
+	require.Equal(t, []*notifydata.SuppressedNotification{
+		{Subject: "subject1", Count: 2, FirstTime: t0.Add(10 * time.Minute), LastTime: t0.Add(20 * time.Minute)},
+	}, suppressed)
+	require.Equal(t, SeverityWarning, sev)
+
+	suppressed, _ = l.takeSuppressed("p1")
+	require.Nil(t, suppressed)
+
+	suppressed, _ = l.takeSuppressed("p2")
+	require.Nil(t, suppressed)
+
+	_, ok = l.nextSummaryTime()
+	require.False(t, ok)
+
+	// dedup interval expired
+	require.True(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0.Add(time.Hour)))
+}
+
+func TestRateLimiter_MaxNotifications(t *testing.T) {
+	l := newRateLimiter()
+	rl := &notifyprofile.RateLimit{MaxNotifications: 2, Interval: time.Hour}
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.True(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0))
+	require.True(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0.Add(time.Minute)))
+	require.False(t, l.allow("p1", rl, "key2", "subject2", SeverityWarning, t0.Add(2*time.Minute)))
+	require.False(t, l.allow("p1", rl, "key1", "subject1", SeverityWarning, t0.Add(3*time.Minute)))
+
+	// new window
+	require.True(t, l.allow("p1", rl, "key2", "subject2", SeverityWarning, t0.Add(time.Hour)))
+
+	// summary is due at the end of the window in which notifications were suppressed.
+	next, ok := l.nextSummaryTime()
+	require.True(t, ok)
+	require.Equal(t, t0.Add(time.Hour), next)
+
+	s, _ := l.takeSuppressed("p1")
+	require.Len(t, s, 2)
+	require.Equal(t, "subject2", s[0].Subject)
+	require.Equal(t, "subject1", s[1].Subject)
+}
+
+func TestEventKeyAndRouting(t *testing.T) {
+	src := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}
+
+	st := func(errMsg string, startTime int64) notifydata.MultiSnapshotStatus {
+		return notifydata.MultiSnapshotStatus{
+			Snapshots: []*notifydata.ManifestWithError{
+				{Manifest: snapshot.Manifest{Source: src, StartTime: fs.UTCTimestamp(startTime)}, Error: errMsg},
+			},
+		}
+	}
+
+	// identical outcome produces the same key regardless of details
+	require.Equal(t, eventKey("snapshot-report", st("some error", 1)), eventKey("snapshot-report", st("some error", 2)))
+	require.NotEqual(t, eventKey("snapshot-report", st("some error", 1)), eventKey("snapshot-report", st("other error", 1)))
+	require.NotEqual(t, eventKey("snapshot-report", st("", 1)), eventKey("snapshot-report", st("some error", 1)))
+
+	errSome := errors.New("some error")
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	e1 := notifydata.NewErrorInfo("Snapshot", "Snapshotting /path", t0, t0, errSome)
+	e2 := notifydata.NewErrorInfo("Snapshot", "Snapshotting /path", t0.Add(time.Hour), t0.Add(time.Hour), errSome)
+	require.Equal(t, eventKey("generic-error", e1), eventKey("generic-error", e2))
+
+	ev := routingEvent("snapshot-report", st("", 1), SeverityError)
+	require.Equal(t, notifyprofile.Event{Type: "snapshot-report", Severity: SeverityError, Sources: []snapshot.SourceInfo{src}}, ev)
+
+	sst := st("", 1)
+	require.Equal(t, ev, routingEvent("snapshot-report", &sst, SeverityError))
+	require.Empty(t, routingEvent("generic-error", e1, SeverityError).Sources)
+}
+
+func TestProfileEventArgs(t *testing.T) {
+	sev := func(s Severity) *Severity { return &s }
+
+	ok := &notifydata.ManifestWithError{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host1", UserName: "user", Path: "/path"}}}
+	failed := &notifydata.ManifestWithError{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host2", UserName: "user", Path: "/path"}}, Error: "some error"}
+	st := notifydata.MultiSnapshotStatus{Snapshots: []*notifydata.ManifestWithError{ok, failed}}
+
+	// profile without rules receives the entire report.
+	args, s, matched := profileEventArgs(&notifyprofile.Config{}, "snapshot-report", st, SeverityError, false)
+	require.True(t, matched)
+	require.Equal(t, st, args)
+	require.Equal(t, SeverityError, s)
+
+	// profile with host rule only receives snapshots of matching sources with their own severity.
+	args, s, matched = profileEventArgs(&notifyprofile.Config{Rules: []notifyprofile.Rule{{Hosts: []string{"host1"}}}}, "snapshot-report", &st, SeverityError, false)
+	require.True(t, matched)
+	require.Equal(t, &notifydata.MultiSnapshotStatus{Snapshots: []*notifydata.ManifestWithError{ok}}, args)
+	require.Equal(t, SeverityReport, s)
+
+	// rule severity applies to individual snapshots.
+	args, s, matched = profileEventArgs(&notifyprofile.Config{Rules: []notifyprofile.Rule{{Hosts: []string{"*"}, MinSeverity: sev(SeverityWarning)}}}, "snapshot-report", st, SeverityError, false)
+	require.True(t, matched)
+	require.Equal(t, &notifydata.MultiSnapshotStatus{Snapshots: []*notifydata.ManifestWithError{failed}}, args)
+	require.Equal(t, SeverityError, s)
+
+	_, _, matched = profileEventArgs(&notifyprofile.Config{Rules: []notifyprofile.Rule{{Hosts: []string{"host1"}, MinSeverity: sev(SeverityWarning)}}}, "snapshot-report", st, SeverityError, false)
+	require.False(t, matched)
+
+	// profiles in digest mode receive matching snapshots regardless of severity.
+	args, _, matched = profileEventArgs(&notifyprofile.Config{MinSeverity: SeverityError, Rules: []notifyprofile.Rule{{Hosts: []string{"host1"}}}}, "snapshot-report", st, SeverityError, true)
+	require.True(t, matched)
+	require.Equal(t, &notifydata.MultiSnapshotStatus{Snapshots: []*notifydata.ManifestWithError{ok}}, args)
+}
+
+func TestSendSuppressedSummaries(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	ctx = testsender.CaptureMessages(ctx)
+
+	oldLimiter := profileRateLimiter
+	profileRateLimiter = newRateLimiter()
+
+	defer func() { profileRateLimiter = oldLimiter }()
+
+	suppressedCount := 0
+
+	SetSuppressedHandler(func() { suppressedCount++ })
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		return notifyprofile.SaveProfile(ctx, w, notifyprofile.Config{
+			ProfileName:  "limited",
+			MethodConfig: sender.MethodConfig{Type: "testsender", Config: &testsender.Options{Format: "txt"}},
+			RateLimit:    &notifyprofile.RateLimit{DedupInterval: time.Hour},
+		})
+	}))
+
+	errInfo := notifydata.NewErrorInfo("Snapshot", "details", time.Now(), time.Now(), errors.New("some error"))
+
+	for range 3 {
+		require.NoError(t, SendInternal(ctx, env.Repository, "generic-error", errInfo, SeverityError, notifytemplate.DefaultOptions))
+	}
+
+	require.Len(t, testsender.MessagesInContext(ctx), 1)
+	require.Equal(t, 2, suppressedCount)
+
+	// summary is not due until the dedup interval expires.
+	require.NoError(t, SendSuppressedSummaries(ctx, env.Repository, notifytemplate.DefaultOptions, false))
+	require.Len(t, testsender.MessagesInContext(ctx), 1)
+
+	require.NoError(t, SendSuppressedSummaries(ctx, env.Repository, notifytemplate.DefaultOptions, true))
+
+	msgs := testsender.MessagesInContext(ctx)
+	require.Len(t, msgs, 2)
+	require.Equal(t, SeverityError, msgs[1].Severity)
+
+	_, ok := NextSuppressedSummaryTime()
+	require.False(t, ok)
+}
@@ -44,22 +44,22 @@ type TemplateArgs struct {
 
 const (
 	// SeverityVerbose includes all notification messages, including frequent and verbose ones.
-	SeverityVerbose Severity = -100
+	SeverityVerbose = sender.SeverityVerbose
 
 	// SeveritySuccess is used for successful operations.
-	SeveritySuccess Severity = -10
+	SeveritySuccess = sender.SeveritySuccess
 
 	// SeverityDefault includes notification messages enabled by default.
-	SeverityDefault Severity = 0
+	SeverityDefault = sender.SeverityDefault
 
 	// SeverityReport is used for periodic reports.
-	SeverityReport Severity = 0
+	SeverityReport = sender.SeverityReport
 
 	// SeverityWarning is used for warnings about potential issues.
-	SeverityWarning Severity = 10
+	SeverityWarning = sender.SeverityWarning
 
 	// SeverityError is used for errors that require attention.
-	SeverityError Severity = 20
+	SeverityError = sender.SeverityError
 )
 
 // SeverityToNumber maps severity names to numbers.
@@ -86,16 +86,34 @@ func init() {
 	}
 }
 
-func notificationSendersFromRepo(ctx context.Context, rep repo.Repository, severity Severity) ([]sender.Sender, error) {
+// profileSender is a sender for a notification profile together with its rate limits
+// and the notification to be delivered to the profile.
+type profileSender struct {
+	sender.Sender
+	rateLimit *notifyprofile.RateLimit
+	digest    bool // profile accumulates reports into digests
+	eventArgs notifydata.TypedEventArgs
+	sev       Severity
+}
+
+//nolint:gochecknoglobals
+var profileRateLimiter = newRateLimiter()
+
+// notificationSendersFromRepo returns senders for profiles matching the event.
+// If digestEvent is true, the event is accumulated by profiles in digest mode.
+func notificationSendersFromRepo(ctx context.Context, rep repo.Repository, templateName string, eventArgs notifydata.TypedEventArgs, sev Severity, digestEvent bool) ([]profileSender, error) {
 	profiles, err := notifyprofile.ListProfiles(ctx, rep)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to list notification profiles")
 	}
 
-	var result []sender.Sender
+	var result []profileSender
 
 	for _, p := range profiles {
-		if severity < p.MinSeverity {
+		digest := digestEvent && p.Digest != nil
+
+		pargs, psev, ok := profileEventArgs(&p, templateName, eventArgs, sev, digest)
+		if !ok {
 			continue
 		}
 
@@ -105,7 +123,7 @@ func notificationSendersFromRepo(ctx context.Context, rep repo.Repository, sever
 			continue
 		}
 
-		result = append(result, s)
+		result = append(result, profileSender{s, p.RateLimit, digest, pargs, psev})
 	}
 
 	return result, nil
@@ -137,17 +155,21 @@ func Send(ctx context.Context, rep repo.Repository, templateName string, eventAr
 
 // SendInternal sends a notification for the given event and returns an error.
 func SendInternal(ctx context.Context, rep repo.Repository, templateName string, eventArgs notifydata.TypedEventArgs, sev Severity, opt notifytemplate.Options) error {
-	senders, err := notificationSendersFromRepo(ctx, rep, sev)
+	digestEvent := profileDigests.isEnabled() && isDigestEvent(eventArgs)
+
+	senders, err := notificationSendersFromRepo(ctx, rep, templateName, eventArgs, sev, digestEvent)
 	if err != nil {
 		return errors.Wrap(err, "unable to get notification senders")
 	}
 
-	senders = append(senders, AdditionalSenders...)
+	for _, s := range AdditionalSenders {
+		senders = append(senders, profileSender{Sender: s, eventArgs: eventArgs, sev: sev})
+	}
 
 	var resultErr error
 
 	for _, s := range senders {
-		if err := SendTo(ctx, rep, s, templateName, eventArgs, sev, opt); err != nil {
+		if err := sendToProfile(ctx, rep, s, templateName, opt); err != nil {
 			resultErr = stderrors.Join(resultErr, err)
 		}
 	}
@@ -155,6 +177,38 @@ func SendInternal(ctx context.Context, rep repo.Repository, templateName string,
 	return resultErr
 }
 
+// sendToProfile sends a notification to the given profile sender, applying its rate limits.
+// Notifications suppressed by rate limits are summarized after the next notification which passes the limits
+// or by SendSuppressedSummaries() once the limits expire.
+func sendToProfile(ctx context.Context, rep repo.Repository, s profileSender, templateName string, opt notifytemplate.Options) error {
+	if s.digest && profileDigests.add(s.ProfileName(), s.eventArgs, clock.Now()) {
+		log(ctx).Debugw("notification added to digest", "profile", s.ProfileName(), "template", templateName)
+
+		return nil
+	}
+
+	if s.rateLimit.IsEmpty() {
+		return SendTo(ctx, rep, s, templateName, s.eventArgs, s.sev, opt)
+	}
+
+	msg, err := renderMessage(ctx, rep, s, templateName, s.eventArgs, s.sev, opt)
+	if err != nil {
+		return err
+	}
+
+	if !profileRateLimiter.allow(s.ProfileName(), s.rateLimit, eventKey(templateName, s.eventArgs), msg.Subject, s.sev, clock.Now()) {
+		log(ctx).Debugw("notification suppressed by rate limit", "profile", s.ProfileName(), "subject", msg.Subject)
+
+		return nil
+	}
+
+	if err := s.Send(ctx, msg); err != nil {
+		return errors.Wrap(err, "unable to send notification message")
+	}
+
+	return sendSuppressedSummary(ctx, rep, s, opt)
+}
+
 // MakeTemplateArgs wraps event-specific arguments into TemplateArgs object.
 func MakeTemplateArgs(eventArgs notifydata.TypedEventArgs) TemplateArgs {
 	now := clock.Now()
@@ -177,38 +231,47 @@ func MakeTemplateArgs(eventArgs notifydata.TypedEventArgs) TemplateArgs {
 
 // SendTo sends a notification to the given sender.
 func SendTo(ctx context.Context, rep repo.Repository, s sender.Sender, templateName string, eventArgs notifydata.TypedEventArgs, sev Severity, opt notifytemplate.Options) error {
+	msg, err := renderMessage(ctx, rep, s, templateName, eventArgs, sev, opt)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Send(ctx, msg); err != nil {
+		return errors.Wrap(err, "unable to send notification message")
+	}
+
+	return nil
+}
+
+// renderMessage renders the notification message for the given sender using the specified template.
+func renderMessage(ctx context.Context, rep repo.Repository, s sender.Sender, templateName string, eventArgs notifydata.TypedEventArgs, sev Severity, opt notifytemplate.Options) (*sender.Message, error) {
 	// execute template
 	var bodyBuf bytes.Buffer
 
 	tmpl, err := notifytemplate.ResolveTemplate(ctx, rep, s.ProfileName(), templateName, s.Format())
 	if err != nil {
-		return errors.Wrap(err, "unable to resolve notification template")
+		return nil, errors.Wrap(err, "unable to resolve notification template")
 	}
 
 	t, err := notifytemplate.ParseTemplate(tmpl, opt)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse notification template")
+		return nil, errors.Wrap(err, "unable to parse notification template")
 	}
 
 	if err := t.Execute(&bodyBuf, MakeTemplateArgs(eventArgs)); err != nil {
-		return errors.Wrap(err, "unable to execute notification template")
+		return nil, errors.Wrap(err, "unable to execute notification template")
 	}
 
 	// extract headers from the template
 	msg, err := sender.ParseMessage(ctx, &bodyBuf)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse message from notification template")
+		return nil, errors.Wrap(err, "unable to parse message from notification template")
 	}
 
 	msg.Severity = sev
+	msg.EventArgs = eventArgs
 
-	var resultErr error
-
-	if err := s.Send(ctx, msg); err != nil {
-		resultErr = stderrors.Join(resultErr, errors.Wrap(err, "unable to send notification message"))
-	}
-
-	return resultErr
+	return msg, nil
 }
 
 // SendTestNotification sends a test notification to the given sender.
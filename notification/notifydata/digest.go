@@ -0,0 +1,236 @@
+package notifydata
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kopia/kopia/internal/grpcapi"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// maxDigestFailures is the maximum number of failure messages retained for each source or for maintenance.
+const maxDigestFailures = 10
+
+// DigestFailure represents a failed snapshot or maintenance run included in a digest.
+type DigestFailure struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Timestamp returns the time of the failure.
+func (f *DigestFailure) Timestamp() time.Time {
+	return f.Time.UTC().Truncate(time.Second)
+}
+
+// DigestSource summarizes snapshots of a single source included in a digest.
+type DigestSource struct {
+	Source     snapshot.SourceInfo `json:"source"`
+	Snapshots  int                 `json:"snapshots"`
+	Successful int                 `json:"successful"`
+	LastTime   time.Time           `json:"last"`
+	TotalSize  int64               `json:"totalSize"` // total size of the latest successful snapshot
+	SizeDelta  int64               `json:"sizeDelta"` // change of the total size over the digest period
+	Failures   []*DigestFailure    `json:"failures,omitempty"`
+}
+
+// SuccessRate returns the percentage of successful snapshots.
+func (s *DigestSource) SuccessRate() int {
+	if s.Snapshots == 0 {
+		return 0
+	}
+
+	return s.Successful * 100 / s.Snapshots //nolint:mnd
+}
+
+// Failed returns the number of failed snapshots.
+func (s *DigestSource) Failed() int {
+	return s.Snapshots - s.Successful
+}
+
+// LastTimestamp returns the time of the latest snapshot of the source.
+func (s *DigestSource) LastTimestamp() time.Time {
+	return s.LastTime.UTC().Truncate(time.Second)
+}
+
+// Digest represents the summary of snapshots and maintenance runs over a period of time.
+type Digest struct {
+	ProfileName string          `json:"profile"`
+	StartTime   time.Time       `json:"start"`
+	EndTime     time.Time       `json:"end"`
+	Sources     []*DigestSource `json:"sources"`
+
+	MaintenanceRuns     int              `json:"maintenanceRuns"`
+	BytesReclaimed      int64            `json:"bytesReclaimed"`
+	MaintenanceFailures []*DigestFailure `json:"maintenanceFailures,omitempty"`
+}
+
+// EventArgsType returns the type of event arguments for Digest.
+func (d *Digest) EventArgsType() grpcapi.NotificationEventArgType {
+	return grpcapi.NotificationEventArgType_ARG_TYPE_DIGEST
+}
+
+// StartTimestamp returns the start time of the digest period.
+func (d *Digest) StartTimestamp() time.Time {
+	return d.StartTime.UTC().Truncate(time.Second)
+}
+
+// EndTimestamp returns the end time of the digest period.
+func (d *Digest) EndTimestamp() time.Time {
+	return d.EndTime.UTC().Truncate(time.Second)
+}
+
+// IsEmpty returns true if the digest does not contain any snapshots or maintenance runs.
+func (d *Digest) IsEmpty() bool {
+	return len(d.Sources) == 0 && d.MaintenanceRuns == 0
+}
+
+// AddSnapshots adds the provided snapshot results to the digest.
+func (d *Digest) AddSnapshots(st MultiSnapshotStatus) {
+	for _, m := range st.Snapshots {
+		d.addSnapshot(m)
+	}
+}
+
+func (d *Digest) addSnapshot(m *ManifestWithError) {
+	ds := d.source(m.Manifest.Source)
+	ds.Snapshots++
+
+	if t := m.Manifest.EndTime.ToTime().UTC(); t.After(ds.LastTime) {
+		ds.LastTime = t
+	}
+
+	switch m.StatusCode() {
+	case StatusCodeFatal:
+		msg := m.Error
+		if msg == "" {
+			msg = fmt.Sprintf("%v fatal errors", m.Manifest.RootEntry.DirSummary.FatalErrorCount)
+		}
+
+		ds.Failures = appendFailure(ds.Failures, m.Manifest.StartTime.ToTime().UTC(), msg)
+
+	default:
+		ds.Successful++
+		ds.TotalSize = m.TotalSize()
+		ds.SizeDelta += m.TotalSizeDelta()
+	}
+}
+
+// AddMaintenance adds the provided maintenance report to the digest.
+func (d *Digest) AddMaintenance(r *MaintenanceReport) {
+	d.MaintenanceRuns++
+	d.BytesReclaimed += r.TotalBytesReclaimed()
+
+	if r.OverallStatusCode() != StatusCodeFatal {
+		return
+	}
+
+	msg := r.Error
+
+	for _, t := range r.Tasks {
+		if msg == "" && t.Error != "" {
+			msg = fmt.Sprintf("%v: %v", t.Task, t.Error)
+		}
+	}
+
+	d.MaintenanceFailures = appendFailure(d.MaintenanceFailures, r.StartTime, fmt.Sprintf("%v maintenance: %v", r.Mode, msg))
+}
+
+// Merge adds snapshots and maintenance runs summarized by the other digest, which covers a later period, to the digest.
+func (d *Digest) Merge(later *Digest) {
+	for _, ls := range later.Sources {
+		ds := d.source(ls.Source)
+		ds.Snapshots += ls.Snapshots
+		ds.Successful += ls.Successful
+
+		if ls.LastTime.After(ds.LastTime) {
+			ds.LastTime = ls.LastTime
+		}
+
+		if ls.Successful > 0 {
+			ds.TotalSize = ls.TotalSize
+		}
+
+		ds.SizeDelta += ls.SizeDelta
+
+		for _, f := range ls.Failures {
+			ds.Failures = appendFailure(ds.Failures, f.Time, f.Message)
+		}
+	}
+
+	d.MaintenanceRuns += later.MaintenanceRuns
+	d.BytesReclaimed += later.BytesReclaimed
+
+	for _, f := range later.MaintenanceFailures {
+		d.MaintenanceFailures = appendFailure(d.MaintenanceFailures, f.Time, f.Message)
+	}
+}
+
+func (d *Digest) source(si snapshot.SourceInfo) *DigestSource {
+	for _, s := range d.Sources {
+		if s.Source == si {
+			return s
+		}
+	}
+
+	ds := &DigestSource{Source: si}
+
+	d.Sources = append(d.Sources, ds)
+	sort.Slice(d.Sources, func(i, j int) bool {
+		return d.Sources[i].Source.String() < d.Sources[j].Source.String()
+	})
+
+	return ds
+}
+
+func appendFailure(failures []*DigestFailure, t time.Time, msg string) []*DigestFailure {
+	if len(failures) >= maxDigestFailures {
+		return failures
+	}
+
+	return append(failures, &DigestFailure{Time: t, Message: msg})
+}
+
+// TotalSnapshots returns the total number of snapshots in the digest.
+func (d *Digest) TotalSnapshots() int {
+	var total int
+
+	for _, s := range d.Sources {
+		total += s.Snapshots
+	}
+
+	return total
+}
+
+// TotalFailed returns the total number of failed snapshots in the digest.
+func (d *Digest) TotalFailed() int {
+	var total int
+
+	for _, s := range d.Sources {
+		total += s.Failed()
+	}
+
+	return total
+}
+
+// OverallStatusCode returns the overall status of the digest (StatusCodeSuccess or StatusCodeFatal).
+func (d *Digest) OverallStatusCode() string {
+	if d.TotalFailed() > 0 || len(d.MaintenanceFailures) > 0 {
+		return StatusCodeFatal
+	}
+
+	return StatusCodeSuccess
+}
+
+// OverallStatus returns the summary of the digest.
+func (d *Digest) OverallStatus() string {
+	if d.TotalFailed() > 0 {
+		return fmt.Sprintf("Digest: %v of %v snapshots failed", d.TotalFailed(), d.TotalSnapshots())
+	}
+
+	if len(d.MaintenanceFailures) > 0 {
+		return fmt.Sprintf("Digest: %v snapshots succeeded, maintenance failed", d.TotalSnapshots())
+	}
+
+	return fmt.Sprintf("Digest: %v snapshots of %v sources succeeded", d.TotalSnapshots(), len(d.Sources))
+}
@@ -0,0 +1,176 @@
+package notifydata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestDigest(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	src1 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/some/path"}
+	src2 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/other/path"}
+
+	manifest := func(src snapshot.SourceInfo, tm time.Time, size int64) snapshot.Manifest {
+		return snapshot.Manifest{
+			Source:    src,
+			StartTime: fs.UTCTimestampFromTime(tm),
+			EndTime:   fs.UTCTimestampFromTime(tm.Add(time.Minute)),
+			RootEntry: &snapshot.DirEntry{DirSummary: &fs.DirectorySummary{TotalFileSize: size}},
+		}
+	}
+
+	d := &notifydata.Digest{
+		ProfileName: "my-profile",
+		StartTime:   t0,
+	}
+
+	require.True(t, d.IsEmpty())
+
+	prev := manifest(src1, t0, 1000)
+
+	d.AddSnapshots(notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: manifest(src1, t0.Add(time.Hour), 1500), Previous: &prev},
+			{Manifest: manifest(src2, t0.Add(time.Hour), 200)},
+		},
+	})
+
+	prev = manifest(src1, t0.Add(time.Hour), 1500)
+
+	d.AddSnapshots(notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: manifest(src1, t0.Add(2*time.Hour), 1700), Previous: &prev},
+			{Manifest: manifest(src2, t0.Add(2*time.Hour), 0), Error: "some error"},
+		},
+	})
+
+	require.False(t, d.IsEmpty())
+	require.Len(t, d.Sources, 2)
+
+	// sources are sorted by name.
+	s2, s1 := d.Sources[0], d.Sources[1]
+
+	require.Equal(t, src1, s1.Source)
+	require.Equal(t, 2, s1.Snapshots)
+	require.Equal(t, 100, s1.SuccessRate())
+	require.Equal(t, int64(1700), s1.TotalSize)
+	require.Equal(t, int64(700), s1.SizeDelta)
+	require.Equal(t, t0.Add(2*time.Hour+time.Minute).Truncate(time.Second), s1.LastTimestamp())
+	require.Empty(t, s1.Failures)
+
+	require.Equal(t, src2, s2.Source)
+	require.Equal(t, 2, s2.Snapshots)
+	require.Equal(t, 1, s2.Failed())
+	require.Equal(t, 50, s2.SuccessRate())
+	require.Equal(t, int64(200), s2.TotalSize)
+	require.Len(t, s2.Failures, 1)
+	require.Equal(t, "some error", s2.Failures[0].Message)
+	require.Equal(t, t0.Add(2*time.Hour).Truncate(time.Second), s2.Failures[0].Timestamp())
+
+	require.Equal(t, 4, d.TotalSnapshots())
+	require.Equal(t, 1, d.TotalFailed())
+	require.Equal(t, notifydata.StatusCodeFatal, d.OverallStatusCode())
+	require.Equal(t, "Digest: 1 of 4 snapshots failed", d.OverallStatus())
+
+	d.AddMaintenance(&notifydata.MaintenanceReport{
+		Mode:      "quick",
+		StartTime: t0,
+		Tasks: []*notifydata.MaintenanceTaskReport{
+			{Task: "full-delete-blobs", BytesReclaimed: 3000},
+		},
+	})
+	d.AddMaintenance(&notifydata.MaintenanceReport{
+		Mode:      "full",
+		StartTime: t0.Add(time.Hour),
+		Tasks: []*notifydata.MaintenanceTaskReport{
+			{Task: "snapshot-gc", Error: "gc failed"},
+		},
+	})
+
+	require.Equal(t, 2, d.MaintenanceRuns)
+	require.Equal(t, int64(3000), d.BytesReclaimed)
+	require.Len(t, d.MaintenanceFailures, 1)
+	require.Equal(t, "full maintenance: snapshot-gc: gc failed", d.MaintenanceFailures[0].Message)
+
+	testRoundTrip(t, d)
+}
+
+func TestDigest_OverallStatus(t *testing.T) {
+	d := &notifydata.Digest{}
+
+	d.AddSnapshots(notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Path: "/a"}}},
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Path: "/b"}}},
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Path: "/a"}}},
+		},
+	})
+
+	require.Equal(t, notifydata.StatusCodeSuccess, d.OverallStatusCode())
+	require.Equal(t, "Digest: 3 snapshots of 2 sources succeeded", d.OverallStatus())
+
+	d.AddMaintenance(&notifydata.MaintenanceReport{Mode: "full", Error: "some error"})
+
+	require.Equal(t, notifydata.StatusCodeFatal, d.OverallStatusCode())
+	require.Equal(t, "Digest: 3 snapshots succeeded, maintenance failed", d.OverallStatus())
+	require.Equal(t, "full maintenance: some error", d.MaintenanceFailures[0].Message)
+}
+
+func TestDigest_MaxFailures(t *testing.T) {
+	d := &notifydata.Digest{}
+
+	for range 20 {
+		d.AddSnapshots(notifydata.MultiSnapshotStatus{
+			Snapshots: []*notifydata.ManifestWithError{
+				{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Path: "/a"}}, Error: "some error"},
+			},
+		})
+	}
+
+	require.Equal(t, 20, d.Sources[0].Failed())
+	require.Len(t, d.Sources[0].Failures, 10)
+}
+
+func TestDigest_Merge(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	src := snapshot.SourceInfo{Path: "/a"}
+
+	d := &notifydata.Digest{StartTime: t0}
+	d.AddSnapshots(notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{Source: src, EndTime: fs.UTCTimestampFromTime(t0)}, Error: "some error"},
+		},
+	})
+
+	later := &notifydata.Digest{StartTime: t0.Add(time.Hour)}
+	later.AddSnapshots(notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{Manifest: snapshot.Manifest{
+				Source:    src,
+				EndTime:   fs.UTCTimestampFromTime(t0.Add(time.Hour)),
+				RootEntry: &snapshot.DirEntry{DirSummary: &fs.DirectorySummary{TotalFileSize: 100}},
+			}},
+			{Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Path: "/b"}}},
+		},
+	})
+	later.AddMaintenance(&notifydata.MaintenanceReport{Mode: "full", Error: "some error"})
+
+	d.Merge(later)
+
+	require.Equal(t, t0, d.StartTime)
+	require.Len(t, d.Sources, 2)
+	require.Equal(t, 2, d.Sources[0].Snapshots)
+	require.Equal(t, 1, d.Sources[0].Failed())
+	require.Equal(t, int64(100), d.Sources[0].TotalSize)
+	require.Equal(t, t0.Add(time.Hour), d.Sources[0].LastTime)
+	require.Len(t, d.Sources[0].Failures, 1)
+	require.Equal(t, 1, d.MaintenanceRuns)
+	require.Len(t, d.MaintenanceFailures, 1)
+}
@@ -0,0 +1,131 @@
+package notifydata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kopia/kopia/internal/grpcapi"
+)
+
+// MaintenanceTaskReport represents the outcome of a single maintenance task.
+type MaintenanceTaskReport struct {
+	Task      string    `json:"task"`
+	StartTime time.Time `json:"start"`
+	EndTime   time.Time `json:"end"`
+	Error     string    `json:"error,omitempty"`   // will be present if the task failed
+	Summary   string    `json:"summary,omitempty"` // human-readable summary of task statistics
+
+	ContentsDropped int64 `json:"contentsDropped,omitempty"`
+	PacksDeleted    int64 `json:"packsDeleted,omitempty"`
+	BytesReclaimed  int64 `json:"bytesReclaimed,omitempty"`
+}
+
+// StartTimestamp returns the start time of the task.
+func (t *MaintenanceTaskReport) StartTimestamp() time.Time {
+	return t.StartTime.UTC().Truncate(time.Second)
+}
+
+// EndTimestamp returns the end time of the task.
+func (t *MaintenanceTaskReport) EndTimestamp() time.Time {
+	return t.EndTime.UTC().Truncate(time.Second)
+}
+
+// Duration returns the duration of the task.
+func (t *MaintenanceTaskReport) Duration() time.Duration {
+	return t.EndTime.Sub(t.StartTime).Round(durationPrecision)
+}
+
+// StatusCode returns the status code of the task (StatusCodeSuccess or StatusCodeFatal).
+func (t *MaintenanceTaskReport) StatusCode() string {
+	if t.Error != "" {
+		return StatusCodeFatal
+	}
+
+	return StatusCodeSuccess
+}
+
+// MaintenanceReport represents the outcome of a maintenance run.
+type MaintenanceReport struct {
+	Mode      string                   `json:"mode"`
+	StartTime time.Time                `json:"start"`
+	EndTime   time.Time                `json:"end"`
+	Error     string                   `json:"error,omitempty"` // will be present if maintenance failed
+	Tasks     []*MaintenanceTaskReport `json:"tasks"`
+}
+
+// EventArgsType returns the type of event arguments for MaintenanceReport.
+func (r *MaintenanceReport) EventArgsType() grpcapi.NotificationEventArgType {
+	return grpcapi.NotificationEventArgType_ARG_TYPE_MAINTENANCE_REPORT
+}
+
+// StartTimestamp returns the start time of maintenance.
+func (r *MaintenanceReport) StartTimestamp() time.Time {
+	return r.StartTime.UTC().Truncate(time.Second)
+}
+
+// EndTimestamp returns the end time of maintenance.
+func (r *MaintenanceReport) EndTimestamp() time.Time {
+	return r.EndTime.UTC().Truncate(time.Second)
+}
+
+// Duration returns the duration of maintenance.
+func (r *MaintenanceReport) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime).Round(durationPrecision)
+}
+
+// TotalContentsDropped returns the total number of contents dropped by all tasks.
+func (r *MaintenanceReport) TotalContentsDropped() int64 {
+	var total int64
+
+	for _, t := range r.Tasks {
+		total += t.ContentsDropped
+	}
+
+	return total
+}
+
+// TotalPacksDeleted returns the total number of pack blobs deleted by all tasks.
+func (r *MaintenanceReport) TotalPacksDeleted() int64 {
+	var total int64
+
+	for _, t := range r.Tasks {
+		total += t.PacksDeleted
+	}
+
+	return total
+}
+
+// TotalBytesReclaimed returns the total number of bytes reclaimed by all tasks.
+func (r *MaintenanceReport) TotalBytesReclaimed() int64 {
+	var total int64
+
+	for _, t := range r.Tasks {
+		total += t.BytesReclaimed
+	}
+
+	return total
+}
+
+// OverallStatusCode returns the overall status of maintenance (StatusCodeSuccess or StatusCodeFatal).
+func (r *MaintenanceReport) OverallStatusCode() string {
+	if r.Error != "" {
+		return StatusCodeFatal
+	}
+
+	for _, t := range r.Tasks {
+		if t.StatusCode() == StatusCodeFatal {
+			return StatusCodeFatal
+		}
+	}
+
+	return StatusCodeSuccess
+}
+
+// OverallStatus returns the overall status of maintenance.
+func (r *MaintenanceReport) OverallStatus() string {
+	if r.OverallStatusCode() == StatusCodeFatal {
+		return fmt.Sprintf("Failed to run %v maintenance", r.Mode)
+	}
+
+	return fmt.Sprintf("Successfully ran %v maintenance", r.Mode)
+}
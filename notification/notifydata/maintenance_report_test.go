@@ -0,0 +1,61 @@
+package notifydata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifydata"
+)
+
+func TestMaintenanceReport(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	r := &notifydata.MaintenanceReport{
+		Mode:      "full",
+		StartTime: t0,
+		EndTime:   t0.Add(5 * time.Second),
+		Tasks: []*notifydata.MaintenanceTaskReport{
+			{
+				Task:            "snapshot-gc",
+				StartTime:       t0,
+				EndTime:         t0.Add(2 * time.Second),
+				ContentsDropped: 10,
+			},
+			{
+				Task:           "full-delete-blobs",
+				StartTime:      t0.Add(2 * time.Second),
+				EndTime:        t0.Add(3 * time.Second),
+				PacksDeleted:   3,
+				BytesReclaimed: 3000,
+			},
+			{
+				Task:           "cleanup-logs",
+				StartTime:      t0.Add(3 * time.Second),
+				EndTime:        t0.Add(4 * time.Second),
+				BytesReclaimed: 500,
+			},
+		},
+	}
+
+	require.Equal(t, int64(10), r.TotalContentsDropped())
+	require.Equal(t, int64(3), r.TotalPacksDeleted())
+	require.Equal(t, int64(3500), r.TotalBytesReclaimed())
+	require.Equal(t, 5*time.Second, r.Duration())
+	require.Equal(t, t0.Truncate(time.Second), r.StartTimestamp())
+	require.Equal(t, 2*time.Second, r.Tasks[0].Duration())
+	require.Equal(t, notifydata.StatusCodeSuccess, r.OverallStatusCode())
+	require.Equal(t, "Successfully ran full maintenance", r.OverallStatus())
+
+	testRoundTrip(t, r)
+
+	r.Tasks[1].Error = "some error"
+	require.Equal(t, notifydata.StatusCodeFatal, r.Tasks[1].StatusCode())
+	require.Equal(t, notifydata.StatusCodeFatal, r.OverallStatusCode())
+	require.Equal(t, "Failed to run full maintenance", r.OverallStatus())
+
+	r.Tasks[1].Error = ""
+	r.Error = "maintenance error"
+	require.Equal(t, notifydata.StatusCodeFatal, r.OverallStatusCode())
+}
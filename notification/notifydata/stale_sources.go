@@ -0,0 +1,58 @@
+package notifydata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kopia/kopia/internal/grpcapi"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// StaleSource represents a source whose latest snapshot is older than its scheduling policy permits.
+type StaleSource struct {
+	Source           snapshot.SourceInfo `json:"source"`
+	LastSnapshotTime time.Time           `json:"lastSnapshot"`
+	DueTime          time.Time           `json:"due"` // time when the next snapshot was scheduled
+	OverdueBy        time.Duration       `json:"overdueBy"`
+}
+
+// LastSnapshotTimestamp returns the time of the latest snapshot of the source.
+func (s *StaleSource) LastSnapshotTimestamp() time.Time {
+	return s.LastSnapshotTime.UTC().Truncate(time.Second)
+}
+
+// DueTimestamp returns the time when the next snapshot of the source was scheduled.
+func (s *StaleSource) DueTimestamp() time.Time {
+	return s.DueTime.UTC().Truncate(time.Second)
+}
+
+// Overdue returns the amount of time by which the snapshot is overdue.
+func (s *StaleSource) Overdue() time.Duration {
+	return s.OverdueBy.Truncate(time.Minute)
+}
+
+// StaleSources represents the list of sources that are overdue for a snapshot.
+type StaleSources struct {
+	CheckTime   time.Time      `json:"checkTime"`
+	GracePeriod time.Duration  `json:"gracePeriod"`
+	Sources     []*StaleSource `json:"sources"`
+}
+
+// EventArgsType returns the type of event arguments for StaleSources.
+func (s *StaleSources) EventArgsType() grpcapi.NotificationEventArgType {
+	return grpcapi.NotificationEventArgType_ARG_TYPE_STALE_SOURCES
+}
+
+// CheckTimestamp returns the time of the check.
+func (s *StaleSources) CheckTimestamp() time.Time {
+	return s.CheckTime.UTC().Truncate(time.Second)
+}
+
+// OverallStatus returns the summary of stale sources.
+func (s *StaleSources) OverallStatus() string {
+	if len(s.Sources) == 1 {
+		return fmt.Sprintf("Snapshot of %v is overdue", s.Sources[0].Source.Path)
+	}
+
+	return fmt.Sprintf("Snapshots of %v sources are overdue", len(s.Sources))
+}
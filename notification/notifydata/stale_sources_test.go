@@ -0,0 +1,40 @@
+package notifydata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestStaleSources(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	s := &notifydata.StaleSources{
+		CheckTime:   t0,
+		GracePeriod: time.Hour,
+		Sources: []*notifydata.StaleSource{
+			{
+				Source:           snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/some/path"},
+				LastSnapshotTime: t0.Add(-50 * time.Hour),
+				DueTime:          t0.Add(-26 * time.Hour),
+				OverdueBy:        26*time.Hour + 5*time.Second,
+			},
+		},
+	}
+
+	require.Equal(t, "Snapshot of /some/path is overdue", s.OverallStatus())
+	require.Equal(t, 26*time.Hour, s.Sources[0].Overdue())
+	require.Equal(t, t0.Add(-26*time.Hour).Truncate(time.Second), s.Sources[0].DueTimestamp())
+
+	testRoundTrip(t, s)
+
+	s.Sources = append(s.Sources, &notifydata.StaleSource{
+		Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/other/path"},
+	})
+
+	require.Equal(t, "Snapshots of 2 sources are overdue", s.OverallStatus())
+}
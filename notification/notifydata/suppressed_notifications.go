@@ -0,0 +1,57 @@
+package notifydata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kopia/kopia/internal/grpcapi"
+)
+
+// SuppressedNotification represents a group of identical notifications that were not delivered because of rate limits.
+type SuppressedNotification struct {
+	Subject   string    `json:"subject"`
+	Count     int       `json:"count"`
+	FirstTime time.Time `json:"first"`
+	LastTime  time.Time `json:"last"`
+}
+
+// FirstTimestamp returns the time of the first suppressed notification.
+func (n *SuppressedNotification) FirstTimestamp() time.Time {
+	return n.FirstTime.UTC().Truncate(time.Second)
+}
+
+// LastTimestamp returns the time of the last suppressed notification.
+func (n *SuppressedNotification) LastTimestamp() time.Time {
+	return n.LastTime.UTC().Truncate(time.Second)
+}
+
+// SuppressedNotifications represents the list of notifications suppressed by rate limits of a notification profile.
+type SuppressedNotifications struct {
+	ProfileName   string                    `json:"profile"`
+	Notifications []*SuppressedNotification `json:"notifications"`
+}
+
+// EventArgsType returns the type of event arguments for SuppressedNotifications.
+func (s *SuppressedNotifications) EventArgsType() grpcapi.NotificationEventArgType {
+	return grpcapi.NotificationEventArgType_ARG_TYPE_SUPPRESSED_NOTIFICATIONS
+}
+
+// TotalCount returns the total number of suppressed notifications.
+func (s *SuppressedNotifications) TotalCount() int {
+	var total int
+
+	for _, n := range s.Notifications {
+		total += n.Count
+	}
+
+	return total
+}
+
+// OverallStatus returns the summary of suppressed notifications.
+func (s *SuppressedNotifications) OverallStatus() string {
+	if s.TotalCount() == 1 {
+		return "1 notification was suppressed"
+	}
+
+	return fmt.Sprintf("%v notifications were suppressed", s.TotalCount())
+}
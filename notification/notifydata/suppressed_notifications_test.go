@@ -0,0 +1,41 @@
+package notifydata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifydata"
+)
+
+func TestSuppressedNotifications(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	s := &notifydata.SuppressedNotifications{
+		ProfileName: "my-profile",
+		Notifications: []*notifydata.SuppressedNotification{
+			{
+				Subject:   "Some subject",
+				Count:     1,
+				FirstTime: t0,
+				LastTime:  t0,
+			},
+		},
+	}
+
+	require.Equal(t, "1 notification was suppressed", s.OverallStatus())
+	require.Equal(t, t0.Truncate(time.Second), s.Notifications[0].FirstTimestamp())
+
+	testRoundTrip(t, s)
+
+	s.Notifications = append(s.Notifications, &notifydata.SuppressedNotification{
+		Subject:   "Other subject",
+		Count:     3,
+		FirstTime: t0,
+		LastTime:  t0.Add(time.Hour),
+	})
+
+	require.Equal(t, 4, s.TotalCount())
+	require.Equal(t, "4 notifications were suppressed", s.OverallStatus())
+}
@@ -28,6 +28,18 @@ func UnmarshalEventArgs(data []byte, notificationEventArgType grpcapi.Notificati
 	case grpcapi.NotificationEventArgType_ARG_TYPE_ERROR_INFO:
 		payload = &ErrorInfo{}
 
+	case grpcapi.NotificationEventArgType_ARG_TYPE_MAINTENANCE_REPORT:
+		payload = &MaintenanceReport{}
+
+	case grpcapi.NotificationEventArgType_ARG_TYPE_STALE_SOURCES:
+		payload = &StaleSources{}
+
+	case grpcapi.NotificationEventArgType_ARG_TYPE_SUPPRESSED_NOTIFICATIONS:
+		payload = &SuppressedNotifications{}
+
+	case grpcapi.NotificationEventArgType_ARG_TYPE_DIGEST:
+		payload = &Digest{}
+
 	default:
 		return nil, errors.Errorf("unsupported notification event arg type: %v", notificationEventArgType)
 	}
@@ -23,6 +23,11 @@ type Config struct {
 	ProfileName  string              `json:"profile"`
 	MethodConfig sender.MethodConfig `json:"method"`
 	MinSeverity  sender.Severity     `json:"minSeverity"`
+
+	// Rules restrict notifications delivered to the profile, when empty all notifications are delivered.
+	Rules     []Rule     `json:"rules,omitempty"`
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+	Digest    *Digest    `json:"digest,omitempty"`
 }
 
 // Summary contains JSON-serializable summary of a notification profile.
@@ -57,6 +62,18 @@ func ListProfiles(ctx context.Context, rep repo.Repository) ([]Config, error) {
 	return profiles, nil
 }
 
+// Scrubbed returns a copy of the profile configuration with sensitive options of the method scrubbed.
+func (c Config) Scrubbed() (Config, error) {
+	mc, err := c.MethodConfig.ScrubbedConfig()
+	if err != nil {
+		return Config{}, errors.Wrap(err, "unable to scrub notification profile")
+	}
+
+	c.MethodConfig = mc
+
+	return c, nil
+}
+
 // ErrNotFound is returned when a profile is not found.
 var ErrNotFound = errors.New("profile not found")
 
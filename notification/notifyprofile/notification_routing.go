@@ -0,0 +1,201 @@
+package notifyprofile
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// Event describes properties of a notification event used for routing it to profiles.
+type Event struct {
+	Type     string // name of the notification template, such as "snapshot-report"
+	Severity sender.Severity
+	Sources  []snapshot.SourceInfo // snapshot sources the event refers to, if any
+}
+
+// Rule describes conditions which must all be met for an event to be delivered to a profile.
+// Empty conditions match any event. Patterns use the syntax of path.Match().
+type Rule struct {
+	EventTypes  []string         `json:"eventTypes,omitempty"`
+	Hosts       []string         `json:"hosts,omitempty"`
+	Users       []string         `json:"users,omitempty"`
+	Paths       []string         `json:"paths,omitempty"` // also matches all paths below the matching directory
+	MinSeverity *sender.Severity `json:"minSeverity,omitempty"`
+}
+
+// RateLimit describes limits on the number of notifications delivered to a profile.
+// Notifications which exceed the limits are suppressed and summarized in a digest message
+// delivered with the next notification that passes the limits or by the server once the limits expire.
+// The state of rate limits is kept in memory of the process sending the notifications.
+type RateLimit struct {
+	// identical notifications delivered within this interval are suppressed.
+	DedupInterval time.Duration `json:"dedupInterval,omitempty"`
+
+	// maximum number of notifications delivered within each Interval.
+	MaxNotifications int           `json:"maxNotifications,omitempty"`
+	Interval         time.Duration `json:"interval,omitempty"`
+}
+
+// IsEmpty returns true if the rate limit does not limit any notifications.
+func (r *RateLimit) IsEmpty() bool {
+	return r == nil || (r.DedupInterval <= 0 && (r.MaxNotifications <= 0 || r.Interval <= 0))
+}
+
+// Matches returns true if the event should be delivered to the profile.
+// The event must have at least the minimum severity of the profile and if the profile
+// has any rules, it must match at least one of them.
+func (c *Config) Matches(ev Event) bool {
+	if ev.Severity < c.MinSeverity {
+		return false
+	}
+
+	return c.MatchesRules(ev)
+}
+
+// MatchesRules returns true if the profile has no rules or the event matches at least one of them,
+// regardless of the minimum severity of the profile.
+func (c *Config) MatchesRules(ev Event) bool {
+	if len(c.Rules) == 0 {
+		return true
+	}
+
+	for _, r := range c.Rules {
+		if r.Matches(ev) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches returns true if the event matches all conditions of the rule.
+func (r *Rule) Matches(ev Event) bool {
+	if r.MinSeverity != nil && ev.Severity < *r.MinSeverity {
+		return false
+	}
+
+	if len(r.EventTypes) > 0 && !matchesAny(r.EventTypes, ev.Type) {
+		return false
+	}
+
+	if len(r.Hosts) == 0 && len(r.Users) == 0 && len(r.Paths) == 0 {
+		return true
+	}
+
+	for _, src := range ev.Sources {
+		if r.matchesSource(src) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Rule) matchesSource(src snapshot.SourceInfo) bool {
+	if len(r.Hosts) > 0 && !matchesAny(r.Hosts, src.Host) {
+		return false
+	}
+
+	if len(r.Users) > 0 && !matchesAny(r.Users, src.UserName) {
+		return false
+	}
+
+	if len(r.Paths) > 0 && !matchesAnyPath(r.Paths, src.Path) {
+		return false
+	}
+
+	return true
+}
+
+// Validate returns an error if the rule has invalid patterns.
+func (r *Rule) Validate() error {
+	for _, patterns := range [][]string{r.EventTypes, r.Hosts, r.Users, r.Paths} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return errors.Errorf("invalid pattern %q", p)
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesAnyPath returns true if the provided path or any of its parent directories matches any of the patterns.
+func matchesAnyPath(patterns []string, p string) bool {
+	p = strings.ReplaceAll(p, "\\", "/")
+
+	for {
+		if matchesAny(patterns, p) {
+			return true
+		}
+
+		parent := path.Dir(p)
+		if parent == p || parent == "." {
+			return false
+		}
+
+		p = parent
+	}
+}
+
+// Digest describes the digest mode of a profile, in which snapshot and maintenance reports
+// are accumulated by the server and delivered as a single summary on a schedule.
+// Accumulated reports are kept in memory, so they are lost when the server restarts.
+type Digest struct {
+	// cron expressions, such as "0 8 * * *" for daily or "0 8 * * 1" for weekly digests.
+	Cron []string `json:"cron"`
+}
+
+// Validate returns an error if the digest schedule is invalid.
+func (d *Digest) Validate() error {
+	if len(d.Cron) == 0 {
+		return errors.New("digest schedule must be provided")
+	}
+
+	for _, e := range d.Cron {
+		if _, err := cronexpr.Parse(e); err != nil {
+			return errors.Errorf("invalid cron expression %q", e)
+		}
+	}
+
+	return nil
+}
+
+// NextTime returns the next time after the provided time when the digest should be delivered.
+func (d *Digest) NextTime(after time.Time) (time.Time, bool) {
+	var (
+		next time.Time
+		ok   bool
+	)
+
+	for _, e := range d.Cron {
+		ce, err := cronexpr.Parse(e)
+		if err != nil {
+			// ignore invalid entries, they are validated when the profile is configured.
+			continue
+		}
+
+		if nt := ce.Next(after); !nt.IsZero() && (!ok || nt.Before(next)) {
+			next = nt
+			ok = true
+		}
+	}
+
+	return next, ok
+}
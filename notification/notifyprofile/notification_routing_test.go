@@ -0,0 +1,146 @@
+package notifyprofile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestConfigMatches(t *testing.T) {
+	errorSeverity := sender.Severity(20)
+
+	dbSnapshotFailure := notifyprofile.Event{
+		Type:     "snapshot-report",
+		Severity: 20,
+		Sources:  []snapshot.SourceInfo{{Host: "db-1", UserName: "postgres", Path: "/var/lib/postgresql/data"}},
+	}
+
+	webSnapshotFailure := notifyprofile.Event{
+		Type:     "snapshot-report",
+		Severity: 20,
+		Sources:  []snapshot.SourceInfo{{Host: "web-1", UserName: "www", Path: "/srv/www"}},
+	}
+
+	dbSnapshotSuccess := notifyprofile.Event{
+		Type:     "snapshot-report",
+		Severity: -10,
+		Sources:  dbSnapshotFailure.Sources,
+	}
+
+	genericError := notifyprofile.Event{
+		Type:     "generic-error",
+		Severity: 20,
+	}
+
+	cases := []struct {
+		desc    string
+		config  notifyprofile.Config
+		matches []bool // dbSnapshotFailure, webSnapshotFailure, dbSnapshotSuccess, genericError
+	}{
+		{
+			desc:    "no rules",
+			config:  notifyprofile.Config{MinSeverity: -100},
+			matches: []bool{true, true, true, true},
+		},
+		{
+			desc:    "min severity",
+			config:  notifyprofile.Config{MinSeverity: 0},
+			matches: []bool{true, true, false, true},
+		},
+		{
+			desc: "host pattern",
+			config: notifyprofile.Config{MinSeverity: -100, Rules: []notifyprofile.Rule{
+				{Hosts: []string{"db-*"}},
+			}},
+			matches: []bool{true, false, true, false},
+		},
+		{
+			desc: "snapshot failures for db hosts",
+			config: notifyprofile.Config{MinSeverity: -100, Rules: []notifyprofile.Rule{
+				{EventTypes: []string{"snapshot-*"}, Hosts: []string{"db-*"}, MinSeverity: &errorSeverity},
+			}},
+			matches: []bool{true, false, false, false},
+		},
+		{
+			desc: "path prefix and user",
+			config: notifyprofile.Config{MinSeverity: -100, Rules: []notifyprofile.Rule{
+				{Paths: []string{"/var/lib"}, Users: []string{"postgres"}},
+			}},
+			matches: []bool{true, false, true, false},
+		},
+		{
+			desc: "path pattern does not match",
+			config: notifyprofile.Config{MinSeverity: -100, Rules: []notifyprofile.Rule{
+				{Paths: []string{"/srv/*"}, Users: []string{"postgres"}},
+			}},
+			matches: []bool{false, false, false, false},
+		},
+		{
+			desc: "any rule",
+			config: notifyprofile.Config{MinSeverity: -100, Rules: []notifyprofile.Rule{
+				{Paths: []string{"/srv/*"}},
+				{EventTypes: []string{"generic-error"}},
+			}},
+			matches: []bool{false, true, false, true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			for i, ev := range []notifyprofile.Event{dbSnapshotFailure, webSnapshotFailure, dbSnapshotSuccess, genericError} {
+				require.Equal(t, tc.matches[i], tc.config.Matches(ev), "event %v", i)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	require.NoError(t, (&notifyprofile.Rule{Hosts: []string{"db-*"}, Paths: []string{"/data/[a-z]*"}}).Validate())
+	require.ErrorContains(t, (&notifyprofile.Rule{Hosts: []string{"db-["}}).Validate(), "invalid pattern")
+}
+
+func TestRateLimitIsEmpty(t *testing.T) {
+	var rl *notifyprofile.RateLimit
+
+	require.True(t, rl.IsEmpty())
+	require.True(t, (&notifyprofile.RateLimit{MaxNotifications: 3}).IsEmpty())
+	require.False(t, (&notifyprofile.RateLimit{MaxNotifications: 3, Interval: 1}).IsEmpty())
+	require.False(t, (&notifyprofile.RateLimit{DedupInterval: 1}).IsEmpty())
+}
+
+func TestConfigMatchesRules(t *testing.T) {
+	c := notifyprofile.Config{MinSeverity: 10, Rules: []notifyprofile.Rule{{EventTypes: []string{"snapshot-report"}}}}
+
+	ev := notifyprofile.Event{Type: "snapshot-report", Severity: -10}
+
+	require.False(t, c.Matches(ev))
+	require.True(t, c.MatchesRules(ev))
+	require.False(t, c.MatchesRules(notifyprofile.Event{Type: "generic-error", Severity: 20}))
+}
+
+func TestDigest(t *testing.T) {
+	require.ErrorContains(t, (&notifyprofile.Digest{}).Validate(), "digest schedule must be provided")
+	require.ErrorContains(t, (&notifyprofile.Digest{Cron: []string{"not a cron"}}).Validate(), "invalid cron expression")
+
+	d := &notifyprofile.Digest{Cron: []string{"0 8 * * 1", "0 8 * * *"}}
+	require.NoError(t, d.Validate())
+
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	next, ok := d.NextTime(t0)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2020, 1, 2, 8, 0, 0, 0, time.UTC), next)
+
+	// weekly digest on Mondays
+	next, ok = (&notifyprofile.Digest{Cron: []string{"0 8 * * 1"}}).NextTime(t0)
+	require.True(t, ok)
+	require.Equal(t, time.Date(2020, 1, 6, 8, 0, 0, 0, time.UTC), next)
+
+	_, ok = (&notifyprofile.Digest{}).NextTime(t0)
+	require.False(t, ok)
+}
@@ -22,7 +22,9 @@
 
 // Template names.
 const (
-	TestNotification = "test-notification"
+	TestNotification        = "test-notification"
+	SuppressedNotifications = "suppressed-notifications"
+	Digest                  = "digest"
 )
 
 // Options provides options for template rendering.
@@ -215,6 +215,141 @@ func TestNotifyTemplate_snapshot_report_single_success(t *testing.T) {
 	verifyTemplate(t, "snapshot-report.html", ".success", args, defaultTestOptions)
 }
 
+func TestNotifyTemplate_maintenance_report(t *testing.T) {
+	args := notification.MakeTemplateArgs(&notifydata.MaintenanceReport{
+		Mode:      "full",
+		StartTime: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		EndTime:   time.Date(2020, 1, 2, 3, 4, 9, 7, time.UTC),
+		Error:     "error deleting unreferenced blobs",
+		Tasks: []*notifydata.MaintenanceTaskReport{
+			{
+				Task:            "snapshot-gc",
+				StartTime:       time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+				EndTime:         time.Date(2020, 1, 2, 3, 4, 7, 6, time.UTC),
+				Summary:         "Found 3 unreferenced contents and marked 3 for deletion.",
+				ContentsDropped: 3,
+			},
+			{
+				Task:           "full-delete-blobs",
+				StartTime:      time.Date(2020, 1, 2, 3, 4, 7, 6, time.UTC),
+				EndTime:        time.Date(2020, 1, 2, 3, 4, 9, 6, time.UTC),
+				Error:          "some error",
+				PacksDeleted:   2,
+				BytesReclaimed: 2048,
+			},
+		},
+	})
+
+	args.EventTime = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	args.Hostname = "some-host"
+
+	verifyTemplate(t, "maintenance-report.txt", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "maintenance-report.html", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "maintenance-report.txt", ".alt", args, altTestOptions)
+	verifyTemplate(t, "maintenance-report.html", ".alt", args, altTestOptions)
+}
+
+func TestNotifyTemplate_stale_source(t *testing.T) {
+	args := notification.MakeTemplateArgs(&notifydata.StaleSources{
+		CheckTime:   time.Date(2020, 1, 4, 3, 4, 5, 6, time.UTC),
+		GracePeriod: time.Hour,
+		Sources: []*notifydata.StaleSource{
+			{
+				Source:           snapshot.SourceInfo{Host: "some-host", UserName: "some-user", Path: "/some/path"},
+				LastSnapshotTime: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+				DueTime:          time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+				OverdueBy:        27*time.Hour + 4*time.Minute + 5*time.Second,
+			},
+			{
+				Source:           snapshot.SourceInfo{Host: "other-host", UserName: "some-user", Path: "/other/path"},
+				LastSnapshotTime: time.Date(2020, 1, 3, 3, 4, 5, 6, time.UTC),
+				DueTime:          time.Date(2020, 1, 3, 4, 0, 0, 0, time.UTC),
+				OverdueBy:        23*time.Hour + 4*time.Minute + 5*time.Second,
+			},
+		},
+	})
+
+	args.EventTime = time.Date(2020, 1, 4, 3, 4, 5, 6, time.UTC)
+	args.Hostname = "some-host"
+
+	verifyTemplate(t, "stale-source.txt", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "stale-source.html", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "stale-source.txt", ".alt", args, altTestOptions)
+	verifyTemplate(t, "stale-source.html", ".alt", args, altTestOptions)
+}
+
+func TestNotifyTemplate_suppressed_notifications(t *testing.T) {
+	args := notification.MakeTemplateArgs(&notifydata.SuppressedNotifications{
+		ProfileName: "my-profile",
+		Notifications: []*notifydata.SuppressedNotification{
+			{
+				Subject:   "Kopia has encountered an error during Snapshot on some-host",
+				Count:     5,
+				FirstTime: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+				LastTime:  time.Date(2020, 1, 2, 7, 4, 5, 6, time.UTC),
+			},
+			{
+				Subject:   "Snapshot of /some/path is overdue on some-host",
+				Count:     1,
+				FirstTime: time.Date(2020, 1, 2, 4, 0, 0, 0, time.UTC),
+				LastTime:  time.Date(2020, 1, 2, 4, 0, 0, 0, time.UTC),
+			},
+		},
+	})
+
+	args.EventTime = time.Date(2020, 1, 4, 3, 4, 5, 6, time.UTC)
+	args.Hostname = "some-host"
+
+	verifyTemplate(t, "suppressed-notifications.txt", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "suppressed-notifications.html", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "suppressed-notifications.txt", ".alt", args, altTestOptions)
+	verifyTemplate(t, "suppressed-notifications.html", ".alt", args, altTestOptions)
+}
+
+func TestNotifyTemplate_digest(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	args := notification.MakeTemplateArgs(&notifydata.Digest{
+		ProfileName: "my-profile",
+		StartTime:   t0,
+		EndTime:     t0.Add(24 * time.Hour),
+		Sources: []*notifydata.DigestSource{
+			{
+				Source:     snapshot.SourceInfo{Host: "some-host", UserName: "some-user", Path: "/some/path"},
+				Snapshots:  24,
+				Successful: 24,
+				LastTime:   t0.Add(23 * time.Hour),
+				TotalSize:  1500000,
+				SizeDelta:  500000,
+			},
+			{
+				Source:     snapshot.SourceInfo{Host: "some-host", UserName: "some-user", Path: "/some/other/path"},
+				Snapshots:  4,
+				Successful: 3,
+				LastTime:   t0.Add(20 * time.Hour),
+				TotalSize:  200000,
+				SizeDelta:  -1000,
+				Failures: []*notifydata.DigestFailure{
+					{Time: t0.Add(10 * time.Hour), Message: "some error"},
+				},
+			},
+		},
+		MaintenanceRuns: 2,
+		BytesReclaimed:  30000,
+		MaintenanceFailures: []*notifydata.DigestFailure{
+			{Time: t0.Add(12 * time.Hour), Message: "full maintenance: snapshot-gc: some error"},
+		},
+	})
+
+	args.EventTime = t0.Add(24 * time.Hour)
+	args.Hostname = "some-host"
+
+	verifyTemplate(t, "digest.txt", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "digest.html", ".default", args, defaultTestOptions)
+	verifyTemplate(t, "digest.txt", ".alt", args, altTestOptions)
+	verifyTemplate(t, "digest.html", ".alt", args, altTestOptions)
+}
+
 func verifyTemplate(t *testing.T, embeddedTemplateName, expectedSuffix string, args any, opt notifytemplate.Options) {
 	t.Helper()
 
@@ -0,0 +1,193 @@
+// Package chatcard converts notification messages into a structured representation
+// used by senders which render rich messages for chat applications.
+package chatcard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// maxListedErrors is the maximum number of failed entries listed for each snapshot.
+const maxListedErrors = 10
+
+// Fact is a name-value pair displayed in a card.
+type Fact struct {
+	Name  string
+	Value string
+}
+
+// Section is a group of facts and details, typically describing a single snapshot or operation.
+type Section struct {
+	Title   string
+	Status  string // one of notifydata.StatusCode* values
+	Facts   []Fact
+	Details []string // additional lines of text, such as error messages
+}
+
+// Card is a structured representation of a notification message.
+type Card struct {
+	Title    string
+	Status   string // one of notifydata.StatusCode* values
+	Sections []Section
+
+	// Text is the plain-text body of the message, used when the event arguments can't be represented as sections.
+	Text string
+}
+
+// FromMessage converts the provided message into a card.
+func FromMessage(msg *sender.Message) *Card {
+	c := &Card{
+		Title:  msg.Subject,
+		Status: statusFromSeverity(msg.Severity),
+	}
+
+	switch ea := msg.EventArgs.(type) {
+	case notifydata.MultiSnapshotStatus:
+		c.addMultiSnapshotStatus(&ea)
+	case *notifydata.MultiSnapshotStatus:
+		c.addMultiSnapshotStatus(ea)
+	case *notifydata.ErrorInfo:
+		c.addErrorInfo(ea)
+	default:
+		c.Text = strings.TrimSpace(msg.Body)
+	}
+
+	return c
+}
+
+// PlainText returns the plain-text rendering of the card, used as a fallback by chat applications.
+func (c *Card) PlainText() string {
+	var sb strings.Builder
+
+	sb.WriteString(c.Title)
+
+	if c.Text != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(c.Text)
+	}
+
+	for _, s := range c.Sections {
+		sb.WriteString("\n\n")
+		sb.WriteString(s.Title)
+
+		for _, f := range s.Facts {
+			fmt.Fprintf(&sb, "\n%v: %v", f.Name, f.Value)
+		}
+
+		for _, d := range s.Details {
+			sb.WriteString("\n")
+			sb.WriteString(d)
+		}
+	}
+
+	return sb.String()
+}
+
+func (c *Card) addMultiSnapshotStatus(st *notifydata.MultiSnapshotStatus) {
+	c.Status = st.OverallStatusCode()
+
+	snaps := append([]*notifydata.ManifestWithError(nil), st.Snapshots...)
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return snaps[i].Manifest.Source.String() < snaps[j].Manifest.Source.String()
+	})
+
+	for _, s := range snaps {
+		sec := Section{
+			Title:  s.Manifest.Source.String(),
+			Status: s.StatusCode(),
+			Facts: []Fact{
+				{"Status", s.StatusCode()},
+			},
+		}
+
+		if s.Error != "" {
+			sec.Details = append(sec.Details, "Error: "+s.Error)
+			c.Sections = append(c.Sections, sec)
+
+			continue
+		}
+
+		sec.Facts = append(sec.Facts,
+			Fact{"Start", formatTime(s.StartTimestamp())},
+			Fact{"Duration", s.Duration().String()},
+			Fact{"Size", units.BytesString(s.TotalSize()) + bytesDelta(s.TotalSizeDelta())},
+			Fact{"Files", fmt.Sprintf("%v%v", s.TotalFiles(), countDelta(s.TotalFilesDelta()))},
+			Fact{"Directories", fmt.Sprintf("%v%v", s.TotalDirs(), countDelta(s.TotalDirsDelta()))},
+		)
+
+		if s.Manifest.IncompleteReason != "" {
+			sec.Details = append(sec.Details, "Incomplete: "+s.Manifest.IncompleteReason)
+		}
+
+		if re := s.Manifest.RootEntry; re != nil && re.DirSummary != nil {
+			for i, fe := range re.DirSummary.FailedEntries {
+				if i == maxListedErrors {
+					sec.Details = append(sec.Details, fmt.Sprintf("...and %v more errors", len(re.DirSummary.FailedEntries)-maxListedErrors))
+					break
+				}
+
+				sec.Details = append(sec.Details, fmt.Sprintf("%v: %v", fe.EntryPath, fe.Error))
+			}
+		}
+
+		c.Sections = append(c.Sections, sec)
+	}
+}
+
+// addErrorInfo adds the details of the error, the status is determined by the severity of the message.
+func (c *Card) addErrorInfo(e *notifydata.ErrorInfo) {
+	c.Sections = append(c.Sections, Section{
+		Title:  e.OperationDetails,
+		Status: c.Status,
+		Facts: []Fact{
+			{"Operation", e.Operation},
+			{"Started", formatTime(e.StartTimestamp())},
+			{"Finished", formatTime(e.EndTimestamp())},
+			{"Duration", e.Duration().String()},
+		},
+		Details: []string{"Error: " + e.ErrorMessage},
+	})
+}
+
+func statusFromSeverity(sev sender.Severity) string {
+	switch {
+	case sev >= sender.SeverityError:
+		return notifydata.StatusCodeFatal
+	case sev >= sender.SeverityWarning:
+		return notifydata.StatusCodeWarnings
+	default:
+		return notifydata.StatusCodeSuccess
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC1123Z)
+}
+
+func bytesDelta(v int64) string {
+	switch {
+	case v > 0:
+		return " (+" + units.BytesString(v) + ")"
+	case v < 0:
+		return " (-" + units.BytesString(-v) + ")"
+	default:
+		return ""
+	}
+}
+
+func countDelta(v int64) string {
+	switch {
+	case v > 0:
+		return fmt.Sprintf(" (+%v)", v)
+	case v < 0:
+		return fmt.Sprintf(" (%v)", v)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,119 @@
+package chatcard_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/chatcard"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestFromMessage_MultiSnapshotStatus(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	st := notifydata.MultiSnapshotStatus{
+		Snapshots: []*notifydata.ManifestWithError{
+			{
+				Manifest: snapshot.Manifest{
+					Source:    snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path2"},
+					StartTime: fs.UTCTimestampFromTime(t0),
+					EndTime:   fs.UTCTimestampFromTime(t0.Add(5 * time.Second)),
+					RootEntry: &snapshot.DirEntry{
+						DirSummary: &fs.DirectorySummary{
+							TotalFileSize:   2000,
+							TotalFileCount:  20,
+							TotalDirCount:   3,
+							FatalErrorCount: 1,
+							FailedEntries: []*fs.EntryWithError{
+								{EntryPath: "some/file", Error: "permission denied"},
+							},
+						},
+					},
+				},
+				Previous: &snapshot.Manifest{
+					RootEntry: &snapshot.DirEntry{
+						DirSummary: &fs.DirectorySummary{
+							TotalFileSize:  1000,
+							TotalFileCount: 10,
+							TotalDirCount:  3,
+						},
+					},
+				},
+			},
+			{
+				Manifest: snapshot.Manifest{
+					Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path1"},
+				},
+				Error: "some error",
+			},
+		},
+	}
+
+	c := chatcard.FromMessage(&sender.Message{Subject: "Subject", Body: "Body", EventArgs: st})
+
+	require.Equal(t, "Subject", c.Title)
+	require.Equal(t, notifydata.StatusCodeFatal, c.Status)
+	require.Empty(t, c.Text)
+	require.Len(t, c.Sections, 2)
+
+	require.Equal(t, "user@host:/path1", c.Sections[0].Title)
+	require.Equal(t, []string{"Error: some error"}, c.Sections[0].Details)
+
+	require.Equal(t, "user@host:/path2", c.Sections[1].Title)
+	require.Equal(t, notifydata.StatusCodeFatal, c.Sections[1].Status)
+	require.Contains(t, c.Sections[1].Facts, chatcard.Fact{Name: "Size", Value: "2 KB (+1 KB)"})
+	require.Contains(t, c.Sections[1].Facts, chatcard.Fact{Name: "Files", Value: "20 (+10)"})
+	require.Contains(t, c.Sections[1].Facts, chatcard.Fact{Name: "Directories", Value: "3"})
+	require.Contains(t, c.Sections[1].Facts, chatcard.Fact{Name: "Duration", Value: "5s"})
+	require.Equal(t, []string{"some/file: permission denied"}, c.Sections[1].Details)
+
+	// pointer is handled the same way
+	require.Equal(t, c, chatcard.FromMessage(&sender.Message{Subject: "Subject", Body: "Body", EventArgs: &st}))
+
+	require.Contains(t, c.PlainText(), "Subject\n\nuser@host:/path1\nStatus: fatal\nError: some error")
+}
+
+func TestFromMessage_ErrorInfo(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	c := chatcard.FromMessage(&sender.Message{
+		Subject:   "Subject",
+		Severity:  sender.SeverityError,
+		EventArgs: notifydata.NewErrorInfo("Maintenance", "Scheduled maintenance", t0, t0.Add(3*time.Second), errors.New("some error")),
+	})
+
+	require.Equal(t, notifydata.StatusCodeFatal, c.Status)
+	require.Len(t, c.Sections, 1)
+	require.Equal(t, "Scheduled maintenance", c.Sections[0].Title)
+	require.Contains(t, c.Sections[0].Facts, chatcard.Fact{Name: "Operation", Value: "Maintenance"})
+	require.Contains(t, c.Sections[0].Facts, chatcard.Fact{Name: "Duration", Value: "3s"})
+	require.Equal(t, []string{"Error: some error"}, c.Sections[0].Details)
+
+	// errors reported with lower severity are not fatal.
+	c = chatcard.FromMessage(&sender.Message{
+		Subject:   "Subject",
+		Severity:  sender.SeverityWarning,
+		EventArgs: notifydata.NewErrorInfo("Snapshot", "Snapshot of /some/path", t0, t0.Add(time.Second), errors.New("some error")),
+	})
+
+	require.Equal(t, notifydata.StatusCodeWarnings, c.Status)
+	require.Equal(t, notifydata.StatusCodeWarnings, c.Sections[0].Status)
+}
+
+func TestFromMessage_Fallback(t *testing.T) {
+	c := chatcard.FromMessage(&sender.Message{Subject: "Subject", Body: "Some body\n", Severity: sender.SeverityWarning})
+
+	require.Equal(t, notifydata.StatusCodeWarnings, c.Status)
+	require.Equal(t, "Some body", c.Text)
+	require.Empty(t, c.Sections)
+	require.Equal(t, "Subject\n\nSome body", c.PlainText())
+
+	require.Equal(t, notifydata.StatusCodeSuccess, chatcard.FromMessage(&sender.Message{}).Status)
+	require.Equal(t, notifydata.StatusCodeFatal, chatcard.FromMessage(&sender.Message{Severity: sender.SeverityError}).Status)
+}
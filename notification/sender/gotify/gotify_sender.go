@@ -0,0 +1,116 @@
+// Package gotify provides Gotify notification support.
+package gotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// ProviderType defines the type of the Gotify notification provider.
+const ProviderType = "gotify"
+
+// Gotify message priorities, clients typically only show pop-up notifications for priority 4 and above.
+const (
+	priorityVerbose = 1
+	prioritySuccess = 2
+	priorityReport  = 4
+	priorityWarning = 6
+	priorityError   = 8
+)
+
+type gotifyProvider struct {
+	opt Options
+}
+
+type messageRequest struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+func (p *gotifyProvider) Send(ctx context.Context, msg *sender.Message) error {
+	mr := &messageRequest{
+		Title:    msg.Subject,
+		Message:  msg.Body,
+		Priority: priorityFromSeverity(msg.Severity),
+	}
+
+	if p.opt.ClickURL != "" {
+		mr.Extras = map[string]any{
+			"client::notification": map[string]any{
+				"click": map[string]string{"url": p.opt.ClickURL},
+			},
+		}
+	}
+
+	body, err := json.Marshal(mr)
+	if err != nil {
+		return errors.Wrap(err, "error preparing gotify notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.opt.ServerURL, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error preparing gotify notification")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", p.opt.AppToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending gotify notification")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("error sending gotify notification: %v", resp.Status)
+	}
+
+	return nil
+}
+
+func priorityFromSeverity(sev notification.Severity) int {
+	switch {
+	case sev >= notification.SeverityError:
+		return priorityError
+	case sev >= notification.SeverityWarning:
+		return priorityWarning
+	case sev >= notification.SeverityReport:
+		return priorityReport
+	case sev >= notification.SeveritySuccess:
+		return prioritySuccess
+	default:
+		return priorityVerbose
+	}
+}
+
+func (p *gotifyProvider) Summary() string {
+	return fmt.Sprintf("Gotify server %v", p.opt.ServerURL)
+}
+
+func (p *gotifyProvider) Format() string {
+	return sender.FormatPlainText
+}
+
+func init() {
+	sender.Register(ProviderType, func(ctx context.Context, options *Options) (sender.Provider, error) {
+		if err := options.ApplyDefaultsAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "invalid notification configuration")
+		}
+
+		return &gotifyProvider{
+			opt: *options,
+		}, nil
+	})
+}
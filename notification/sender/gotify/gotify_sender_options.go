@@ -0,0 +1,60 @@
+package gotify
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Options defines Gotify notification sender options.
+type Options struct {
+	ServerURL string `json:"serverURL"`
+	AppToken  string `json:"appToken" kopia:"sensitive"`
+	ClickURL  string `json:"clickURL,omitempty"` // URL opened when the notification is clicked
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the configuration.
+func (o *Options) ApplyDefaultsAndValidate(_ context.Context) error {
+	if o.ServerURL == "" {
+		return errors.Errorf("Server URL must be provided")
+	}
+
+	u, err := url.ParseRequestURI(o.ServerURL)
+	if err != nil {
+		return errors.Errorf("invalid server URL")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid server URL scheme, must be http:// or https://")
+	}
+
+	if o.AppToken == "" {
+		return errors.Errorf("App Token must be provided")
+	}
+
+	if o.ClickURL != "" {
+		if _, err := url.ParseRequestURI(o.ClickURL); err != nil {
+			return errors.Errorf("invalid click URL")
+		}
+	}
+
+	return nil
+}
+
+// MergeOptions updates the destination options with the source options.
+func MergeOptions(ctx context.Context, src Options, dst *Options, isUpdate bool) error {
+	copyOrMerge(&dst.ServerURL, src.ServerURL, isUpdate)
+	copyOrMerge(&dst.AppToken, src.AppToken, isUpdate)
+	copyOrMerge(&dst.ClickURL, src.ClickURL, isUpdate)
+
+	return dst.ApplyDefaultsAndValidate(ctx)
+}
+
+func copyOrMerge[T comparable](dst *T, src T, isUpdate bool) {
+	var defaultT T
+
+	if !isUpdate || src != defaultT {
+		*dst = src
+	}
+}
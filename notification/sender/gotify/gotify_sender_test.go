@@ -0,0 +1,120 @@
+package gotify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/gotify"
+)
+
+type messageRequest struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras"`
+}
+
+func TestGotify(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	mux := http.NewServeMux()
+
+	var (
+		keys     []string
+		requests []messageRequest
+	)
+
+	mux.HandleFunc("POST /gotify/message", func(w http.ResponseWriter, r *http.Request) {
+		var req messageRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		keys = append(keys, r.Header.Get("X-Gotify-Key"))
+		requests = append(requests, req)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "gotify", &gotify.Options{
+		ServerURL: server.URL + "/gotify/",
+		AppToken:  "app-token1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Gotify server "+server.URL+"/gotify/", p.Summary())
+
+	pc, err := sender.GetSender(ctx, "my-profile", "gotify", &gotify.Options{
+		ServerURL: server.URL + "/gotify",
+		AppToken:  "app-token2",
+		ClickURL:  "https://kopia.example.com/",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Body: "Body", Severity: notification.SeveritySuccess}))
+	require.NoError(t, pc.Send(ctx, &sender.Message{Subject: "Test2", Body: "Body2", Severity: notification.SeverityWarning}))
+
+	require.Equal(t, []string{"app-token1", "app-token2"}, keys)
+	require.Equal(t, []messageRequest{
+		{
+			Title:    "Test",
+			Message:  "Body",
+			Priority: 2,
+		},
+		{
+			Title:    "Test2",
+			Message:  "Body2",
+			Priority: 6,
+			Extras: map[string]any{
+				"client::notification": map[string]any{
+					"click": map[string]any{"url": "https://kopia.example.com/"},
+				},
+			},
+		},
+	}, requests)
+
+	p2, err := sender.GetSender(ctx, "my-profile", "gotify", &gotify.Options{
+		ServerURL: server.URL + "/not-found",
+		AppToken:  "app-token1",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p2.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending gotify notification")
+}
+
+func TestGotify_Invalid(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := sender.GetSender(ctx, "my-profile", "gotify", &gotify.Options{})
+	require.ErrorContains(t, err, "Server URL must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "gotify", &gotify.Options{ServerURL: "https://gotify.example.com"})
+	require.ErrorContains(t, err, "App Token must be provided")
+}
+
+func TestMergeOptions(t *testing.T) {
+	var dst gotify.Options
+
+	require.NoError(t, gotify.MergeOptions(context.Background(), gotify.Options{
+		ServerURL: "https://gotify.example.com",
+		AppToken:  "token1",
+	}, &dst, false))
+
+	require.NoError(t, gotify.MergeOptions(context.Background(), gotify.Options{
+		AppToken: "token2",
+	}, &dst, true))
+
+	require.Equal(t, gotify.Options{
+		ServerURL: "https://gotify.example.com",
+		AppToken:  "token2",
+	}, dst)
+}
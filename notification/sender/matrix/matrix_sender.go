@@ -0,0 +1,137 @@
+// Package matrix provides Matrix notification support.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/chatcard"
+)
+
+// ProviderType defines the type of the Matrix notification provider.
+const ProviderType = "matrix"
+
+type matrixProvider struct {
+	opt Options
+}
+
+type roomMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func (p *matrixProvider) Send(ctx context.Context, msg *sender.Message) error {
+	c := chatcard.FromMessage(msg)
+
+	body, err := json.Marshal(&roomMessage{
+		MsgType:       "m.text",
+		Body:          c.PlainText(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatHTML(c),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error preparing matrix notification")
+	}
+
+	// transaction ID makes the request idempotent, so each message gets a unique one.
+	targetURL := fmt.Sprintf("%v/_matrix/client/v3/rooms/%v/send/m.room.message/%v",
+		strings.TrimSuffix(p.opt.HomeserverURL, "/"),
+		url.PathEscape(p.opt.RoomID),
+		uuid.NewString())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error preparing matrix notification")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.opt.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending matrix notification")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("error sending matrix notification: %v", resp.Status)
+	}
+
+	return nil
+}
+
+// formatHTML renders the card using the subset of HTML supported by Matrix clients.
+func formatHTML(c *chatcard.Card) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "<h3>%v %v</h3>", statusIcon(c.Status), html.EscapeString(c.Title))
+
+	if c.Text != "" {
+		fmt.Fprintf(&sb, "<pre>%v</pre>", html.EscapeString(c.Text))
+	}
+
+	for _, s := range c.Sections {
+		fmt.Fprintf(&sb, "<h4>%v %v</h4>", statusIcon(s.Status), html.EscapeString(s.Title))
+
+		if len(s.Facts) > 0 {
+			sb.WriteString("<table>")
+
+			for _, f := range s.Facts {
+				fmt.Fprintf(&sb, "<tr><td><b>%v</b></td><td>%v</td></tr>", html.EscapeString(f.Name), html.EscapeString(f.Value))
+			}
+
+			sb.WriteString("</table>")
+		}
+
+		if len(s.Details) > 0 {
+			fmt.Fprintf(&sb, "<pre><code>%v</code></pre>", html.EscapeString(strings.Join(s.Details, "\n")))
+		}
+	}
+
+	return sb.String()
+}
+
+func statusIcon(status string) string {
+	switch status {
+	case notifydata.StatusCodeFatal:
+		return "❌"
+	case notifydata.StatusCodeWarnings, notifydata.StatusCodeIncomplete:
+		return "⚠️"
+	default:
+		return "✅"
+	}
+}
+
+func (p *matrixProvider) Summary() string {
+	return fmt.Sprintf("Matrix room %q on %v", p.opt.RoomID, p.opt.HomeserverURL)
+}
+
+func (p *matrixProvider) Format() string {
+	return sender.FormatPlainText
+}
+
+func init() {
+	sender.Register(ProviderType, func(ctx context.Context, options *Options) (sender.Provider, error) {
+		if err := options.ApplyDefaultsAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "invalid notification configuration")
+		}
+
+		return &matrixProvider{
+			opt: *options,
+		}, nil
+	})
+}
@@ -0,0 +1,58 @@
+package matrix
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Options defines Matrix notification sender options.
+type Options struct {
+	HomeserverURL string `json:"homeserverURL"`
+	AccessToken   string `json:"accessToken" kopia:"sensitive"`
+	RoomID        string `json:"roomID"`
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the configuration.
+func (o *Options) ApplyDefaultsAndValidate(_ context.Context) error {
+	if o.HomeserverURL == "" {
+		return errors.Errorf("Homeserver URL must be provided")
+	}
+
+	u, err := url.ParseRequestURI(o.HomeserverURL)
+	if err != nil {
+		return errors.Errorf("invalid homeserver URL")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid homeserver URL scheme, must be http:// or https://")
+	}
+
+	if o.AccessToken == "" {
+		return errors.Errorf("Access Token must be provided")
+	}
+
+	if o.RoomID == "" {
+		return errors.Errorf("Room ID must be provided")
+	}
+
+	return nil
+}
+
+// MergeOptions updates the destination options with the source options.
+func MergeOptions(ctx context.Context, src Options, dst *Options, isUpdate bool) error {
+	copyOrMerge(&dst.HomeserverURL, src.HomeserverURL, isUpdate)
+	copyOrMerge(&dst.AccessToken, src.AccessToken, isUpdate)
+	copyOrMerge(&dst.RoomID, src.RoomID, isUpdate)
+
+	return dst.ApplyDefaultsAndValidate(ctx)
+}
+
+func copyOrMerge[T comparable](dst *T, src T, isUpdate bool) {
+	var defaultT T
+
+	if !isUpdate || src != defaultT {
+		*dst = src
+	}
+}
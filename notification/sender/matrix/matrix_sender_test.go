@@ -0,0 +1,132 @@
+package matrix_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/matrix"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestMatrix(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	mux := http.NewServeMux()
+
+	var requests []*http.Request
+
+	var requestBodies []bytes.Buffer
+
+	mux.HandleFunc("/_matrix/client/v3/rooms/{room}/send/m.room.message/{txn}", func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+
+		io.Copy(&b, r.Body)
+
+		requestBodies = append(requestBodies, b)
+		requests = append(requests, r)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{
+		HomeserverURL: server.URL + "/",
+		AccessToken:   "token1",
+		RoomID:        "!room1:example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Matrix room \"!room1:example.com\" on "+server.URL+"/", p.Summary())
+
+	require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Body: "This is a <test>."}))
+	require.NoError(t, p.Send(ctx, &sender.Message{
+		Subject: "Snapshot report",
+		EventArgs: notifydata.MultiSnapshotStatus{
+			Snapshots: []*notifydata.ManifestWithError{
+				{
+					Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}},
+					Error:    "some error",
+				},
+			},
+		},
+	}))
+
+	require.Len(t, requests, 2)
+	require.Equal(t, http.MethodPut, requests[0].Method)
+	require.Equal(t, "Bearer token1", requests[0].Header.Get("Authorization"))
+	require.Equal(t, "!room1:example.com", requests[0].PathValue("room"))
+	require.NotEqual(t, requests[0].PathValue("txn"), requests[1].PathValue("txn"))
+
+	var body map[string]string
+
+	require.NoError(t, json.NewDecoder(&requestBodies[0]).Decode(&body))
+	require.Equal(t, "m.text", body["msgtype"])
+	require.Equal(t, "org.matrix.custom.html", body["format"])
+	require.Equal(t, "Test\n\nThis is a <test>.", body["body"])
+	require.Equal(t, "<h3>✅ Test</h3><pre>This is a &lt;test&gt;.</pre>", body["formatted_body"])
+
+	require.NoError(t, json.NewDecoder(&requestBodies[1]).Decode(&body))
+	require.Equal(t, "Snapshot report\n\nuser@host:/path\nStatus: fatal\nError: some error", body["body"])
+	require.True(t, strings.HasPrefix(body["formatted_body"], "<h3>❌ Snapshot report</h3><h4>❌ user@host:/path</h4>"), body["formatted_body"])
+	require.Contains(t, body["formatted_body"], "<tr><td><b>Status</b></td><td>fatal</td></tr>")
+	require.Contains(t, body["formatted_body"], "<pre><code>Error: some error</code></pre>")
+
+	p2, err := sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{
+		HomeserverURL: server.URL + "/not-found",
+		AccessToken:   "token1",
+		RoomID:        "!room1:example.com",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p2.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending matrix notification")
+
+	p3, err := sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{
+		HomeserverURL: "http://localhost:59123",
+		AccessToken:   "token1",
+		RoomID:        "!room1:example.com",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p3.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending matrix notification")
+}
+
+func TestMatrix_Invalid(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{})
+	require.ErrorContains(t, err, "Homeserver URL must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{HomeserverURL: "https://matrix.org"})
+	require.ErrorContains(t, err, "Access Token must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "matrix", &matrix.Options{HomeserverURL: "https://matrix.org", AccessToken: "token"})
+	require.ErrorContains(t, err, "Room ID must be provided")
+}
+
+func TestMergeOptions(t *testing.T) {
+	var dst matrix.Options
+
+	require.NoError(t, matrix.MergeOptions(context.Background(), matrix.Options{
+		HomeserverURL: "https://matrix.org",
+		AccessToken:   "token1",
+		RoomID:        "!room1:matrix.org",
+	}, &dst, false))
+
+	require.NoError(t, matrix.MergeOptions(context.Background(), matrix.Options{
+		RoomID: "!room2:matrix.org",
+	}, &dst, true))
+
+	require.Equal(t, matrix.Options{
+		HomeserverURL: "https://matrix.org",
+		AccessToken:   "token1",
+		RoomID:        "!room2:matrix.org",
+	}, dst)
+}
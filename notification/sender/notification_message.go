@@ -12,17 +12,43 @@
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifydata"
 )
 
 // Severity represents the severity of a notification message.
 type Severity int32
 
+const (
+	// SeverityVerbose includes all notification messages, including frequent and verbose ones.
+	SeverityVerbose Severity = -100
+
+	// SeveritySuccess is used for successful operations.
+	SeveritySuccess Severity = -10
+
+	// SeverityDefault includes notification messages enabled by default.
+	SeverityDefault Severity = 0
+
+	// SeverityReport is used for periodic reports.
+	SeverityReport Severity = 0
+
+	// SeverityWarning is used for warnings about potential issues.
+	SeverityWarning Severity = 10
+
+	// SeverityError is used for errors that require attention.
+	SeverityError Severity = 20
+)
+
 // Message represents a notification message.
 type Message struct {
 	Subject  string            `json:"subject"`
 	Headers  map[string]string `json:"headers,omitempty"`
 	Severity Severity          `json:"severity"`
 	Body     string            `json:"body"`
+
+	// EventArgs contains structured event data used to render the message, senders that produce
+	// rich messages (such as chat cards) can use it instead of the rendered body.
+	EventArgs notifydata.TypedEventArgs `json:"-"`
 }
 
 // ParseMessage parses a notification message string into a Message structure.
@@ -0,0 +1,127 @@
+// Package ntfy provides ntfy notification support.
+package ntfy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// ProviderType defines the type of the ntfy notification provider.
+const ProviderType = "ntfy"
+
+// ntfy message priorities.
+const (
+	priorityMin     = 1
+	priorityLow     = 2
+	priorityDefault = 3
+	priorityHigh    = 4
+	priorityMax     = 5
+)
+
+type ntfyProvider struct {
+	opt Options
+}
+
+type publishRequest struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+}
+
+func (p *ntfyProvider) Send(ctx context.Context, msg *sender.Message) error {
+	body, err := json.Marshal(&publishRequest{
+		Topic:    p.opt.Topic,
+		Title:    msg.Subject,
+		Message:  msg.Body,
+		Priority: priorityFromSeverity(msg.Severity),
+		Tags:     splitTags(p.opt.Tags),
+		Click:    p.opt.ClickURL,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error preparing ntfy notification")
+	}
+
+	// publishing JSON messages is done by posting to the root URL of the server.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.opt.ServerURL, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error preparing ntfy notification")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if p.opt.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.opt.AccessToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending ntfy notification")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("error sending ntfy notification: %v", resp.Status)
+	}
+
+	return nil
+}
+
+func priorityFromSeverity(sev notification.Severity) int {
+	switch {
+	case sev >= notification.SeverityError:
+		return priorityMax
+	case sev >= notification.SeverityWarning:
+		return priorityHigh
+	case sev >= notification.SeverityReport:
+		return priorityDefault
+	case sev >= notification.SeveritySuccess:
+		return priorityLow
+	default:
+		return priorityMin
+	}
+}
+
+func splitTags(tags string) []string {
+	var result []string
+
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+func (p *ntfyProvider) Summary() string {
+	return fmt.Sprintf("ntfy topic %q on %v", p.opt.Topic, p.opt.ServerURL)
+}
+
+func (p *ntfyProvider) Format() string {
+	return sender.FormatPlainText
+}
+
+func init() {
+	sender.Register(ProviderType, func(ctx context.Context, options *Options) (sender.Provider, error) {
+		if err := options.ApplyDefaultsAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "invalid notification configuration")
+		}
+
+		return &ntfyProvider{
+			opt: *options,
+		}, nil
+	})
+}
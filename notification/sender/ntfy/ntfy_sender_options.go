@@ -0,0 +1,67 @@
+package ntfy
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// defaultServerURL is the URL of the public ntfy server.
+const defaultServerURL = "https://ntfy.sh"
+
+// Options defines ntfy notification sender options.
+type Options struct {
+	ServerURL   string `json:"serverURL"`
+	Topic       string `json:"topic"`
+	AccessToken string `json:"accessToken,omitempty" kopia:"sensitive"`
+	Tags        string `json:"tags,omitempty"`     // comma-separated list of tags or emoji short codes
+	ClickURL    string `json:"clickURL,omitempty"` // URL opened when the notification is clicked
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the configuration.
+func (o *Options) ApplyDefaultsAndValidate(_ context.Context) error {
+	if o.ServerURL == "" {
+		o.ServerURL = defaultServerURL
+	}
+
+	u, err := url.ParseRequestURI(o.ServerURL)
+	if err != nil {
+		return errors.Errorf("invalid server URL")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid server URL scheme, must be http:// or https://")
+	}
+
+	if o.Topic == "" {
+		return errors.Errorf("Topic must be provided")
+	}
+
+	if o.ClickURL != "" {
+		if _, err := url.ParseRequestURI(o.ClickURL); err != nil {
+			return errors.Errorf("invalid click URL")
+		}
+	}
+
+	return nil
+}
+
+// MergeOptions updates the destination options with the source options.
+func MergeOptions(ctx context.Context, src Options, dst *Options, isUpdate bool) error {
+	copyOrMerge(&dst.ServerURL, src.ServerURL, isUpdate)
+	copyOrMerge(&dst.Topic, src.Topic, isUpdate)
+	copyOrMerge(&dst.AccessToken, src.AccessToken, isUpdate)
+	copyOrMerge(&dst.Tags, src.Tags, isUpdate)
+	copyOrMerge(&dst.ClickURL, src.ClickURL, isUpdate)
+
+	return dst.ApplyDefaultsAndValidate(ctx)
+}
+
+func copyOrMerge[T comparable](dst *T, src T, isUpdate bool) {
+	var defaultT T
+
+	if !isUpdate || src != defaultT {
+		*dst = src
+	}
+}
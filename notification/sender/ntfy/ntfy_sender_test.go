@@ -0,0 +1,159 @@
+package ntfy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/ntfy"
+)
+
+type publishRequest struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags"`
+	Click    string   `json:"click"`
+}
+
+func TestNtfy(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	mux := http.NewServeMux()
+
+	var (
+		authHeaders []string
+		requests    []publishRequest
+	)
+
+	mux.HandleFunc("POST /{$}", func(w http.ResponseWriter, r *http.Request) {
+		var req publishRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		requests = append(requests, req)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{
+		ServerURL: server.URL,
+		Topic:     "backups",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ntfy topic \"backups\" on "+server.URL, p.Summary())
+
+	pa, err := sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{
+		ServerURL:   server.URL + "/",
+		Topic:       "backups",
+		AccessToken: "tk_secret",
+		Tags:        "floppy_disk, kopia,",
+		ClickURL:    "https://kopia.example.com/",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Body: "Body", Severity: notification.SeverityReport}))
+	require.NoError(t, pa.Send(ctx, &sender.Message{Subject: "Test2", Body: "Body2", Severity: notification.SeverityError}))
+
+	require.Equal(t, []string{"", "Bearer tk_secret"}, authHeaders)
+	require.Equal(t, []publishRequest{
+		{
+			Topic:    "backups",
+			Title:    "Test",
+			Message:  "Body",
+			Priority: 3,
+		},
+		{
+			Topic:    "backups",
+			Title:    "Test2",
+			Message:  "Body2",
+			Priority: 5,
+			Tags:     []string{"floppy_disk", "kopia"},
+			Click:    "https://kopia.example.com/",
+		},
+	}, requests)
+
+	p2, err := sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{
+		ServerURL: server.URL + "/not-found/",
+		Topic:     "backups",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p2.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending ntfy notification")
+}
+
+func TestNtfy_Priority(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var priorities []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req publishRequest
+
+		json.NewDecoder(r.Body).Decode(&req)
+
+		priorities = append(priorities, req.Priority)
+	}))
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{ServerURL: server.URL, Topic: "t"})
+	require.NoError(t, err)
+
+	for _, sev := range []notification.Severity{
+		notification.SeverityVerbose,
+		notification.SeveritySuccess,
+		notification.SeverityReport,
+		notification.SeverityWarning,
+		notification.SeverityError,
+	} {
+		require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Severity: sev}))
+	}
+
+	require.Equal(t, []int{1, 2, 3, 4, 5}, priorities)
+}
+
+func TestNtfy_Invalid(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{})
+	require.ErrorContains(t, err, "Topic must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{ServerURL: "ftp://host", Topic: "t"})
+	require.ErrorContains(t, err, "invalid server URL scheme")
+
+	_, err = sender.GetSender(ctx, "my-profile", "ntfy", &ntfy.Options{Topic: "t", ClickURL: "not a url"})
+	require.ErrorContains(t, err, "invalid click URL")
+}
+
+func TestMergeOptions(t *testing.T) {
+	var dst ntfy.Options
+
+	require.NoError(t, ntfy.MergeOptions(context.Background(), ntfy.Options{
+		Topic:       "topic1",
+		AccessToken: "token1",
+	}, &dst, false))
+	require.Equal(t, "https://ntfy.sh", dst.ServerURL)
+
+	require.NoError(t, ntfy.MergeOptions(context.Background(), ntfy.Options{
+		Tags: "tag1",
+	}, &dst, true))
+
+	require.Equal(t, ntfy.Options{
+		ServerURL:   "https://ntfy.sh",
+		Topic:       "topic1",
+		AccessToken: "token1",
+		Tags:        "tag1",
+	}, dst)
+}
@@ -4,6 +4,7 @@
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 
 	"github.com/pkg/errors"
 
@@ -38,6 +39,7 @@ type Sender interface {
 var (
 	allSenders     = map[Method]Factory[any]{}
 	defaultOptions = map[Method]any{}
+	optionsTypes   = map[Method]reflect.Type{}
 )
 
 type senderWrapper struct {
@@ -69,6 +71,7 @@ func Register[T any](method Method, p Factory[*T]) {
 	var defT T
 
 	defaultOptions[method] = defT
+	optionsTypes[method] = reflect.TypeFor[T]()
 
 	allSenders[method] = func(ctx context.Context, jsonOptions any) (Provider, error) {
 		typedOptions := defT
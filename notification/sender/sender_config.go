@@ -2,8 +2,12 @@
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/scrubber"
 )
 
 // Method represents the configuration of a Sender.
@@ -67,3 +71,64 @@ func (c MethodConfig) MarshalJSON() ([]byte, error) {
 		Data: c.Config,
 	})
 }
+
+// ScrubbedConfig returns a copy of the configuration with sensitive options scrubbed, suitable for presenting to users.
+func (c MethodConfig) ScrubbedConfig() (MethodConfig, error) {
+	opt, err := c.typedOptions()
+	if err != nil {
+		return MethodConfig{}, err
+	}
+
+	return MethodConfig{
+		Type:   c.Type,
+		Config: scrubber.ScrubSensitiveData(opt).Interface(),
+	}, nil
+}
+
+// RestoreSensitiveData returns a copy of the configuration in which sensitive options that are still scrubbed,
+// because the configuration was previously returned by ScrubbedConfig(), are restored from the original configuration.
+func (c MethodConfig) RestoreSensitiveData(original MethodConfig) (MethodConfig, error) {
+	if c.Type != original.Type {
+		return c, nil
+	}
+
+	opt, err := c.typedOptions()
+	if err != nil {
+		return MethodConfig{}, err
+	}
+
+	orig, err := original.typedOptions()
+	if err != nil {
+		return MethodConfig{}, err
+	}
+
+	for i := range opt.NumField() {
+		if opt.Type().Field(i).Tag.Get("kopia") != "sensitive" || opt.Field(i).Kind() != reflect.String {
+			continue
+		}
+
+		if ov := orig.Field(i).String(); ov != "" && opt.Field(i).String() == strings.Repeat("*", len(ov)) {
+			opt.Field(i).SetString(ov)
+		}
+	}
+
+	return MethodConfig{
+		Type:   c.Type,
+		Config: opt.Interface(),
+	}, nil
+}
+
+// typedOptions returns the options of the method as a settable value of the type registered for the method.
+func (c MethodConfig) typedOptions() (reflect.Value, error) {
+	t := optionsTypes[c.Type]
+	if t == nil {
+		return reflect.Value{}, errors.Errorf("sender type '%v' not registered", c.Type)
+	}
+
+	opt := reflect.New(t)
+	if err := c.Options(opt.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return opt.Elem(), nil
+}
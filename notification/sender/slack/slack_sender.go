@@ -0,0 +1,194 @@
+// Package slack provides Slack notification support using Block Kit messages.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/chatcard"
+)
+
+// ProviderType defines the type of the Slack notification provider.
+const ProviderType = "slack"
+
+// Limits imposed by Slack on Block Kit elements.
+const (
+	maxHeaderLength  = 150
+	maxTextLength    = 3000
+	maxFieldsPerItem = 10
+	maxBlocks        = 50
+)
+
+type slackProvider struct {
+	opt Options
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type block struct {
+	Type   string        `json:"type"`
+	Text   *textObject   `json:"text,omitempty"`
+	Fields []*textObject `json:"fields,omitempty"`
+}
+
+type attachment struct {
+	Color  string   `json:"color"`
+	Blocks []*block `json:"blocks"`
+}
+
+type payload struct {
+	Text        string        `json:"text"`
+	Blocks      []*block      `json:"blocks"`
+	Attachments []*attachment `json:"attachments,omitempty"`
+}
+
+func (p *slackProvider) Send(ctx context.Context, msg *sender.Message) error {
+	body, err := json.Marshal(buildPayload(chatcard.FromMessage(msg)))
+	if err != nil {
+		return errors.Wrap(err, "error preparing slack notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opt.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error preparing slack notification")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending slack notification")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("error sending slack notification: %v", resp.Status)
+	}
+
+	return nil
+}
+
+func buildPayload(c *chatcard.Card) *payload {
+	att := &attachment{
+		Color: statusColor(c.Status),
+	}
+
+	if c.Text != "" {
+		att.Blocks = append(att.Blocks, markdownSection(escape(c.Text)))
+	}
+
+	for i, s := range c.Sections {
+		if i > 0 {
+			att.Blocks = append(att.Blocks, &block{Type: "divider"})
+		}
+
+		att.Blocks = append(att.Blocks, markdownSection(fmt.Sprintf("%v *%v*", statusEmoji(s.Status), escape(s.Title))))
+
+		for start := 0; start < len(s.Facts); start += maxFieldsPerItem {
+			b := &block{Type: "section"}
+
+			for _, f := range s.Facts[start:min(start+maxFieldsPerItem, len(s.Facts))] {
+				b.Fields = append(b.Fields, &textObject{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("*%v*\n%v", escape(f.Name), escape(f.Value)),
+				})
+			}
+
+			att.Blocks = append(att.Blocks, b)
+		}
+
+		if len(s.Details) > 0 {
+			att.Blocks = append(att.Blocks, markdownSection("```"+truncate(escape(strings.Join(s.Details, "\n")), maxTextLength-len("``````"))+"```"))
+		}
+	}
+
+	if len(att.Blocks) > maxBlocks-1 {
+		att.Blocks = append(att.Blocks[0:maxBlocks-2], markdownSection("_Message truncated._"))
+	}
+
+	return &payload{
+		Text: c.Title,
+		Blocks: []*block{
+			{
+				Type: "header",
+				Text: &textObject{Type: "plain_text", Text: truncate(c.Title, maxHeaderLength)},
+			},
+		},
+		Attachments: []*attachment{att},
+	}
+}
+
+func markdownSection(text string) *block {
+	return &block{
+		Type: "section",
+		Text: &textObject{Type: "mrkdwn", Text: truncate(text, maxTextLength)},
+	}
+}
+
+func statusColor(status string) string {
+	switch status {
+	case notifydata.StatusCodeFatal:
+		return "#d00000"
+	case notifydata.StatusCodeWarnings, notifydata.StatusCodeIncomplete:
+		return "#daa038"
+	default:
+		return "#2eb886"
+	}
+}
+
+func statusEmoji(status string) string {
+	switch status {
+	case notifydata.StatusCodeFatal:
+		return ":x:"
+	case notifydata.StatusCodeWarnings, notifydata.StatusCodeIncomplete:
+		return ":warning:"
+	default:
+		return ":white_check_mark:"
+	}
+}
+
+// escape escapes control characters in mrkdwn text.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func truncate(s string, maxLength int) string {
+	r := []rune(s)
+	if len(r) <= maxLength {
+		return s
+	}
+
+	return string(r[0:maxLength-1]) + "…"
+}
+
+func (p *slackProvider) Summary() string {
+	return "Slack webhook"
+}
+
+func (p *slackProvider) Format() string {
+	return sender.FormatPlainText
+}
+
+func init() {
+	sender.Register(ProviderType, func(ctx context.Context, options *Options) (sender.Provider, error) {
+		if err := options.ApplyDefaultsAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "invalid notification configuration")
+		}
+
+		return &slackProvider{
+			opt: *options,
+		}, nil
+	})
+}
@@ -0,0 +1,46 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Options defines Slack notification sender options.
+type Options struct {
+	WebhookURL string `json:"webhookURL" kopia:"sensitive"` // incoming webhook URL
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the configuration.
+func (o *Options) ApplyDefaultsAndValidate(_ context.Context) error {
+	if o.WebhookURL == "" {
+		return errors.Errorf("Webhook URL must be provided")
+	}
+
+	u, err := url.ParseRequestURI(o.WebhookURL)
+	if err != nil {
+		return errors.Errorf("invalid webhook URL")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid webhook URL scheme, must be http:// or https://")
+	}
+
+	return nil
+}
+
+// MergeOptions updates the destination options with the source options.
+func MergeOptions(ctx context.Context, src Options, dst *Options, isUpdate bool) error {
+	copyOrMerge(&dst.WebhookURL, src.WebhookURL, isUpdate)
+
+	return dst.ApplyDefaultsAndValidate(ctx)
+}
+
+func copyOrMerge[T comparable](dst *T, src T, isUpdate bool) {
+	var defaultT T
+
+	if !isUpdate || src != defaultT {
+		*dst = src
+	}
+}
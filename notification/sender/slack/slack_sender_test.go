@@ -0,0 +1,147 @@
+package slack_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/slack"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestSlack(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	mux := http.NewServeMux()
+
+	var requests []*http.Request
+
+	var requestBodies []bytes.Buffer
+
+	mux.HandleFunc("/some-path", func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+
+		io.Copy(&b, r.Body)
+
+		requestBodies = append(requestBodies, b)
+		requests = append(requests, r)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "slack", &slack.Options{
+		WebhookURL: server.URL + "/some-path",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Slack webhook", p.Summary())
+	require.Equal(t, sender.FormatPlainText, p.Format())
+
+	require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Body: "This is a <test>."}))
+	require.NoError(t, p.Send(ctx, &sender.Message{
+		Subject: "Snapshot report",
+		Body:    "ignored",
+		EventArgs: notifydata.MultiSnapshotStatus{
+			Snapshots: []*notifydata.ManifestWithError{
+				{
+					Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}},
+					Error:    "some error",
+				},
+			},
+		},
+	}))
+	require.NoError(t, p.Send(ctx, &sender.Message{
+		Subject:   "Error",
+		Severity:  sender.SeverityError,
+		EventArgs: notifydata.NewErrorInfo("Snapshot", "Snapshotting /path", time.Now(), time.Now(), context.Canceled),
+	}))
+
+	require.Len(t, requests, 3)
+	require.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	require.Equal(t, http.MethodPost, requests[0].Method)
+
+	var body map[string]any
+
+	// fallback to plain text body
+	require.NoError(t, json.NewDecoder(&requestBodies[0]).Decode(&body))
+	require.Equal(t, "Test", body["text"])
+	require.Equal(t, map[string]any{
+		"type": "header",
+		"text": map[string]any{"type": "plain_text", "text": "Test"},
+	}, body["blocks"].([]any)[0])
+
+	att := body["attachments"].([]any)[0].(map[string]any)
+	require.Equal(t, "#2eb886", att["color"])
+	require.Equal(t, "This is a &lt;test&gt;.", att["blocks"].([]any)[0].(map[string]any)["text"].(map[string]any)["text"])
+
+	// snapshot status
+	body = nil
+	require.NoError(t, json.NewDecoder(&requestBodies[1]).Decode(&body))
+	require.Equal(t, "Snapshot report", body["text"])
+
+	att = body["attachments"].([]any)[0].(map[string]any)
+	require.Equal(t, "#d00000", att["color"])
+
+	blocks := att["blocks"].([]any)
+	require.Len(t, blocks, 3)
+	require.Equal(t, ":x: *user@host:/path*", blocks[0].(map[string]any)["text"].(map[string]any)["text"])
+	require.Equal(t, "*Status*\nfatal", blocks[1].(map[string]any)["fields"].([]any)[0].(map[string]any)["text"])
+	require.Equal(t, "```Error: some error```", blocks[2].(map[string]any)["text"].(map[string]any)["text"])
+
+	// error info
+	body = nil
+	require.NoError(t, json.NewDecoder(&requestBodies[2]).Decode(&body))
+
+	att = body["attachments"].([]any)[0].(map[string]any)
+	require.Equal(t, "#d00000", att["color"])
+	require.Equal(t, ":x: *Snapshotting /path*", att["blocks"].([]any)[0].(map[string]any)["text"].(map[string]any)["text"])
+
+	p2, err := sender.GetSender(ctx, "my-profile", "slack", &slack.Options{
+		WebhookURL: server.URL + "/not-found-path",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p2.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending slack notification")
+
+	p3, err := sender.GetSender(ctx, "my-profile", "slack", &slack.Options{
+		WebhookURL: "http://localhost:59123/not-found-path",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p3.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending slack notification")
+}
+
+func TestSlack_Invalid(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := sender.GetSender(ctx, "my-profile", "slack", &slack.Options{})
+	require.ErrorContains(t, err, "Webhook URL must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "slack", &slack.Options{WebhookURL: "ftp://some-host/path"})
+	require.ErrorContains(t, err, "invalid webhook URL scheme")
+}
+
+func TestMergeOptions(t *testing.T) {
+	var dst slack.Options
+
+	require.NoError(t, slack.MergeOptions(context.Background(), slack.Options{
+		WebhookURL: "https://hooks.slack.com/services/1",
+	}, &dst, false))
+	require.Equal(t, "https://hooks.slack.com/services/1", dst.WebhookURL)
+
+	require.NoError(t, slack.MergeOptions(context.Background(), slack.Options{}, &dst, true))
+	require.Equal(t, "https://hooks.slack.com/services/1", dst.WebhookURL)
+
+	require.NoError(t, slack.MergeOptions(context.Background(), slack.Options{
+		WebhookURL: "https://hooks.slack.com/services/2",
+	}, &dst, true))
+	require.Equal(t, "https://hooks.slack.com/services/2", dst.WebhookURL)
+}
@@ -0,0 +1,188 @@
+// Package teams provides Microsoft Teams notification support using Adaptive Cards.
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/chatcard"
+)
+
+// ProviderType defines the type of the Microsoft Teams notification provider.
+const ProviderType = "teams"
+
+const (
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.4"
+)
+
+type teamsProvider struct {
+	opt Options
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type element struct {
+	Type      string  `json:"type"`
+	Text      string  `json:"text,omitempty"`
+	Size      string  `json:"size,omitempty"`
+	Weight    string  `json:"weight,omitempty"`
+	Color     string  `json:"color,omitempty"`
+	FontType  string  `json:"fontType,omitempty"`
+	Wrap      bool    `json:"wrap,omitempty"`
+	Separator bool    `json:"separator,omitempty"`
+	Facts     []*fact `json:"facts,omitempty"`
+}
+
+type adaptiveCard struct {
+	Schema  string     `json:"$schema"`
+	Type    string     `json:"type"`
+	Version string     `json:"version"`
+	Body    []*element `json:"body"`
+}
+
+type attachment struct {
+	ContentType string        `json:"contentType"`
+	Content     *adaptiveCard `json:"content"`
+}
+
+type payload struct {
+	Type        string        `json:"type"`
+	Attachments []*attachment `json:"attachments"`
+}
+
+func (p *teamsProvider) Send(ctx context.Context, msg *sender.Message) error {
+	body, err := json.Marshal(buildPayload(chatcard.FromMessage(msg)))
+	if err != nil {
+		return errors.Wrap(err, "error preparing teams notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opt.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error preparing teams notification")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending teams notification")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	// incoming webhooks return 200 OK, workflows return 202 Accepted.
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("error sending teams notification: %v", resp.Status)
+	}
+
+	return nil
+}
+
+func buildPayload(c *chatcard.Card) *payload {
+	card := &adaptiveCard{
+		Schema:  adaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: adaptiveCardVersion,
+		Body: []*element{
+			{
+				Type:   "TextBlock",
+				Text:   c.Title,
+				Size:   "Large",
+				Weight: "Bolder",
+				Color:  statusColor(c.Status),
+				Wrap:   true,
+			},
+		},
+	}
+
+	if c.Text != "" {
+		card.Body = append(card.Body, &element{
+			Type: "TextBlock",
+			Text: c.Text,
+			Wrap: true,
+		})
+	}
+
+	for _, s := range c.Sections {
+		card.Body = append(card.Body, &element{
+			Type:      "TextBlock",
+			Text:      s.Title,
+			Weight:    "Bolder",
+			Color:     statusColor(s.Status),
+			Wrap:      true,
+			Separator: true,
+		})
+
+		if len(s.Facts) > 0 {
+			fs := &element{Type: "FactSet"}
+
+			for _, f := range s.Facts {
+				fs.Facts = append(fs.Facts, &fact{Title: f.Name, Value: f.Value})
+			}
+
+			card.Body = append(card.Body, fs)
+		}
+
+		if len(s.Details) > 0 {
+			card.Body = append(card.Body, &element{
+				Type:     "TextBlock",
+				Text:     strings.Join(s.Details, "\n\n"),
+				FontType: "Monospace",
+				Wrap:     true,
+			})
+		}
+	}
+
+	return &payload{
+		Type: "message",
+		Attachments: []*attachment{
+			{
+				ContentType: adaptiveCardContentType,
+				Content:     card,
+			},
+		},
+	}
+}
+
+func statusColor(status string) string {
+	switch status {
+	case notifydata.StatusCodeFatal:
+		return "Attention"
+	case notifydata.StatusCodeWarnings, notifydata.StatusCodeIncomplete:
+		return "Warning"
+	default:
+		return "Good"
+	}
+}
+
+func (p *teamsProvider) Summary() string {
+	return "Microsoft Teams webhook"
+}
+
+func (p *teamsProvider) Format() string {
+	return sender.FormatPlainText
+}
+
+func init() {
+	sender.Register(ProviderType, func(ctx context.Context, options *Options) (sender.Provider, error) {
+		if err := options.ApplyDefaultsAndValidate(ctx); err != nil {
+			return nil, errors.Wrap(err, "invalid notification configuration")
+		}
+
+		return &teamsProvider{
+			opt: *options,
+		}, nil
+	})
+}
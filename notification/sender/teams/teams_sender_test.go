@@ -0,0 +1,151 @@
+package teams_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/teams"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestTeams(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	mux := http.NewServeMux()
+
+	var requests []*http.Request
+
+	var requestBodies []bytes.Buffer
+
+	mux.HandleFunc("/some-path", func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+
+		io.Copy(&b, r.Body)
+
+		requestBodies = append(requestBodies, b)
+		requests = append(requests, r)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := sender.GetSender(ctx, "my-profile", "teams", &teams.Options{
+		WebhookURL: server.URL + "/some-path",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Microsoft Teams webhook", p.Summary())
+	require.Equal(t, sender.FormatPlainText, p.Format())
+
+	require.NoError(t, p.Send(ctx, &sender.Message{Subject: "Test", Body: "This is a <test>."}))
+	require.NoError(t, p.Send(ctx, &sender.Message{
+		Subject: "Snapshot report",
+		Body:    "ignored",
+		EventArgs: notifydata.MultiSnapshotStatus{
+			Snapshots: []*notifydata.ManifestWithError{
+				{
+					Manifest: snapshot.Manifest{Source: snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"}},
+					Error:    "some error",
+				},
+			},
+		},
+	}))
+	require.NoError(t, p.Send(ctx, &sender.Message{
+		Subject:   "Error",
+		Severity:  sender.SeverityError,
+		EventArgs: notifydata.NewErrorInfo("Snapshot", "Snapshotting /path", time.Now(), time.Now(), context.Canceled),
+	}))
+
+	require.Len(t, requests, 3)
+	require.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	require.Equal(t, http.MethodPost, requests[0].Method)
+
+	var body map[string]any
+
+	// fallback to plain text body
+	require.NoError(t, json.NewDecoder(&requestBodies[0]).Decode(&body))
+	require.Equal(t, "message", body["type"])
+
+	att := body["attachments"].([]any)[0].(map[string]any)
+	require.Equal(t, "application/vnd.microsoft.card.adaptive", att["contentType"])
+
+	card := att["content"].(map[string]any)
+	require.Equal(t, "AdaptiveCard", card["type"])
+	require.Equal(t, "1.4", card["version"])
+
+	elements := card["body"].([]any)
+	require.Len(t, elements, 2)
+	require.Equal(t, "Test", elements[0].(map[string]any)["text"])
+	require.Equal(t, "Good", elements[0].(map[string]any)["color"])
+	require.Equal(t, "This is a <test>.", elements[1].(map[string]any)["text"])
+
+	// snapshot status
+	body = nil
+	require.NoError(t, json.NewDecoder(&requestBodies[1]).Decode(&body))
+
+	elements = body["attachments"].([]any)[0].(map[string]any)["content"].(map[string]any)["body"].([]any)
+	require.Len(t, elements, 4)
+	require.Equal(t, "Snapshot report", elements[0].(map[string]any)["text"])
+	require.Equal(t, "Attention", elements[0].(map[string]any)["color"])
+	require.Equal(t, "user@host:/path", elements[1].(map[string]any)["text"])
+	require.Equal(t, "FactSet", elements[2].(map[string]any)["type"])
+	require.Equal(t, map[string]any{"title": "Status", "value": "fatal"}, elements[2].(map[string]any)["facts"].([]any)[0])
+	require.Equal(t, "Error: some error", elements[3].(map[string]any)["text"])
+
+	// error info
+	body = nil
+	require.NoError(t, json.NewDecoder(&requestBodies[2]).Decode(&body))
+
+	elements = body["attachments"].([]any)[0].(map[string]any)["content"].(map[string]any)["body"].([]any)
+	require.Equal(t, "Snapshotting /path", elements[1].(map[string]any)["text"])
+	require.Equal(t, "Attention", elements[1].(map[string]any)["color"])
+
+	p2, err := sender.GetSender(ctx, "my-profile", "teams", &teams.Options{
+		WebhookURL: server.URL + "/not-found-path",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p2.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending teams notification")
+
+	p3, err := sender.GetSender(ctx, "my-profile", "teams", &teams.Options{
+		WebhookURL: "http://localhost:59123/not-found-path",
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, p3.Send(ctx, &sender.Message{Subject: "Test", Body: "test"}), "error sending teams notification")
+}
+
+func TestTeams_Invalid(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := sender.GetSender(ctx, "my-profile", "teams", &teams.Options{})
+	require.ErrorContains(t, err, "Webhook URL must be provided")
+
+	_, err = sender.GetSender(ctx, "my-profile", "teams", &teams.Options{WebhookURL: "ftp://some-host/path"})
+	require.ErrorContains(t, err, "invalid webhook URL scheme")
+}
+
+func TestMergeOptions(t *testing.T) {
+	var dst teams.Options
+
+	require.NoError(t, teams.MergeOptions(context.Background(), teams.Options{
+		WebhookURL: "https://hooks.teams.com/services/1",
+	}, &dst, false))
+	require.Equal(t, "https://hooks.teams.com/services/1", dst.WebhookURL)
+
+	require.NoError(t, teams.MergeOptions(context.Background(), teams.Options{}, &dst, true))
+	require.Equal(t, "https://hooks.teams.com/services/1", dst.WebhookURL)
+
+	require.NoError(t, teams.MergeOptions(context.Background(), teams.Options{
+		WebhookURL: "https://hooks.teams.com/services/2",
+	}, &dst, true))
+	require.Equal(t, "https://hooks.teams.com/services/2", dst.WebhookURL)
+}
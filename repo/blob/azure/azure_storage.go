@@ -111,6 +111,10 @@ func (az *azStorage) GetMetadata(ctx context.Context, b blob.ID) (blob.Metadata,
 		}
 	}
 
+	if c, ok := blob.MD5ChecksumFromBytes(fi.ContentMD5); ok {
+		bm.Checksums = append(bm.Checksums, c)
+	}
+
 	return bm, nil
 }
 
@@ -261,6 +265,10 @@ func (az *azStorage) getBlobMeta(it *azblobmodels.BlobItem) blob.Metadata {
 		bm.Timestamp = *it.Properties.LastModified
 	}
 
+	if c, ok := blob.MD5ChecksumFromBytes(it.Properties.ContentMD5); ok {
+		bm.Checksums = append(bm.Checksums, c)
+	}
+
 	return bm
 }
 
@@ -0,0 +1,201 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+const (
+	defaultB2APIHost = "https://api.backblazeb2.com"
+
+	b2APIVersionPath = "/b2api/v2/"
+
+	// retentionRequestTimeout bounds the duration of a single native API request.
+	retentionRequestTimeout = 30 * time.Second
+)
+
+// retentionClient implements the subset of the native B2 API needed for Object Lock,
+// which is not provided by the B2 client library.
+type retentionClient struct {
+	httpClient *http.Client
+	apiHost    string
+	keyID      string
+	key        string
+
+	mu sync.Mutex
+	// +checklocks:mu
+	apiURL string
+	// +checklocks:mu
+	authToken string
+}
+
+type b2AuthorizeAccountResponse struct {
+	APIURL             string `json:"apiUrl"`
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+type b2FileRetention struct {
+	Mode                 string `json:"mode"`
+	RetainUntilTimestamp int64  `json:"retainUntilTimestamp"`
+}
+
+type b2UpdateFileRetentionRequest struct {
+	FileName      string          `json:"fileName"`
+	FileID        string          `json:"fileId"`
+	FileRetention b2FileRetention `json:"fileRetention"`
+}
+
+type b2ErrorResponse struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *b2ErrorResponse) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func newRetentionClient(opt *Options) *retentionClient {
+	return &retentionClient{
+		httpClient: &http.Client{Timeout: retentionRequestTimeout},
+		apiHost:    defaultB2APIHost,
+		keyID:      opt.KeyID,
+		key:        opt.Key,
+	}
+}
+
+func (c *retentionClient) authorize(ctx context.Context) (apiURL, authToken string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.authToken != "" {
+		return c.apiURL, c.authToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiHost+b2APIVersionPath+"b2_authorize_account", http.NoBody)
+	if err != nil {
+		return "", "", errors.Wrap(err, "unable to create request")
+	}
+
+	req.SetBasicAuth(c.keyID, c.key)
+
+	var resp b2AuthorizeAccountResponse
+
+	if err := c.do(req, &resp); err != nil {
+		return "", "", errors.Wrap(err, "unable to authorize account")
+	}
+
+	c.apiURL = resp.APIURL
+	c.authToken = resp.AuthorizationToken
+
+	return c.apiURL, c.authToken, nil
+}
+
+func (c *retentionClient) invalidateAuthorization() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.authToken = ""
+}
+
+func (c *retentionClient) do(req *http.Request, result any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "unable to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		er := &b2ErrorResponse{Status: resp.StatusCode}
+
+		if err := json.Unmarshal(body, er); err != nil {
+			er.Message = string(body)
+		}
+
+		return er
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return errors.Wrap(json.Unmarshal(body, result), "invalid response")
+}
+
+// updateFileRetention sets the retention of a single version of a file.
+func (c *retentionClient) updateFileRetention(ctx context.Context, fileName, fileID string, mode blob.RetentionMode, retainUntil time.Time) error {
+	payload, err := json.Marshal(b2UpdateFileRetentionRequest{
+		FileName: fileName,
+		FileID:   fileID,
+		FileRetention: b2FileRetention{
+			Mode:                 strings.ToLower(string(mode)),
+			RetainUntilTimestamp: retainUntil.UnixMilli(),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize request")
+	}
+
+	const maxAttempts = 2
+
+	for attempt := 1; ; attempt++ {
+		apiURL, authToken, err := c.authorize(ctx)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+b2APIVersionPath+"b2_update_file_retention", bytes.NewReader(payload))
+		if err != nil {
+			return errors.Wrap(err, "unable to create request")
+		}
+
+		req.Header.Set("Authorization", authToken)
+
+		err = c.do(req, nil)
+
+		var er *b2ErrorResponse
+		if errors.As(err, &er) && er.Status == http.StatusUnauthorized && attempt < maxAttempts {
+			// token expired, re-authorize and try again.
+			c.invalidateAuthorization()
+			continue
+		}
+
+		return errors.Wrapf(err, "unable to update retention of %v", fileName)
+	}
+}
+
+func (s *b2Storage) ExtendBlobRetention(ctx context.Context, id blob.ID, opts blob.ExtendOptions) error {
+	if !opts.RetentionMode.IsValid() {
+		return errors.Errorf("invalid retention mode: %q", opts.RetentionMode)
+	}
+
+	fileName := s.getObjectNameString(id)
+
+	fileID, err := s.resolveFileID(fileName)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if fileID == "" {
+		return blob.ErrBlobNotFound
+	}
+
+	return s.retention.updateFileRetention(ctx, fileName, fileID, opts.RetentionMode, clock.Now().Add(opts.RetentionPeriod))
+}
@@ -0,0 +1,98 @@
+package b2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestRetentionClient_UpdateFileRetention(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	var (
+		authorizations int
+		updates        []b2UpdateFileRetentionRequest
+		srv            *httptest.Server
+	)
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/b2api/v2/b2_authorize_account", func(w http.ResponseWriter, r *http.Request) {
+		if id, key, ok := r.BasicAuth(); !ok || id != "some-key-id" || key != "some-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		authorizations++
+
+		json.NewEncoder(w).Encode(b2AuthorizeAccountResponse{ //nolint:errcheck
+			APIURL:             srv.URL,
+			AuthorizationToken: "token" + string(rune('0'+authorizations)),
+		})
+	})
+
+	mux.HandleFunc("/b2api/v2/b2_update_file_retention", func(w http.ResponseWriter, r *http.Request) {
+		// the first token expires right away.
+		if r.Header.Get("Authorization") != "token2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(b2ErrorResponse{Status: http.StatusUnauthorized, Code: "expired_auth_token"}) //nolint:errcheck
+
+			return
+		}
+
+		var req b2UpdateFileRetentionRequest
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		updates = append(updates, req)
+
+		if req.FileID == "bad-file-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(b2ErrorResponse{Status: http.StatusBadRequest, Code: "bad_request", Message: "bad file id"}) //nolint:errcheck
+
+			return
+		}
+
+		w.Write([]byte("{}")) //nolint:errcheck
+	})
+
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := newRetentionClient(&Options{KeyID: "some-key-id", Key: "some-key"})
+	c.apiHost = srv.URL
+
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.NoError(t, c.updateFileRetention(ctx, "prefix/blob1", "file-id-1", blob.Compliance, retainUntil))
+	require.Equal(t, 2, authorizations)
+	require.Equal(t, []b2UpdateFileRetentionRequest{
+		{
+			FileName: "prefix/blob1",
+			FileID:   "file-id-1",
+			FileRetention: b2FileRetention{
+				Mode:                 "compliance",
+				RetainUntilTimestamp: retainUntil.UnixMilli(),
+			},
+		},
+	}, updates)
+
+	err := c.updateFileRetention(ctx, "prefix/blob2", "bad-file-id", blob.Governance, retainUntil)
+	require.ErrorContains(t, err, "bad file id")
+	require.Equal(t, 2, authorizations)
+
+	// invalid credentials
+	c2 := newRetentionClient(&Options{KeyID: "some-key-id", Key: "wrong-key"})
+	c2.apiHost = srv.URL
+
+	require.ErrorContains(t, c2.updateFileRetention(ctx, "prefix/blob1", "file-id-1", blob.Governance, retainUntil), "unable to authorize account")
+}
@@ -12,6 +12,7 @@
 	"github.com/pkg/errors"
 	"gopkg.in/kothar/go-backblaze.v0"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/iocopy"
 	"github.com/kopia/kopia/internal/timestampmeta"
 	"github.com/kopia/kopia/repo/blob"
@@ -31,8 +32,9 @@ type b2Storage struct {
 	Options
 	blob.DefaultProviderImplementation
 
-	cli    *backblaze.B2
-	bucket *backblaze.Bucket
+	cli       *backblaze.B2
+	bucket    *backblaze.Bucket
+	retention *retentionClient
 }
 
 func (s *b2Storage) GetBlob(_ context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
@@ -148,14 +150,15 @@ func translateError(err error) error {
 	return err
 }
 
-func (s *b2Storage) PutBlob(_ context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
-	switch {
-	case opts.HasRetentionOptions():
-		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "blob-retention")
-	case opts.DoNotRecreate:
+func (s *b2Storage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if opts.DoNotRecreate {
 		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "do-not-recreate")
 	}
 
+	if opts.HasRetentionOptions() && !opts.RetentionMode.IsValid() {
+		return errors.Errorf("invalid retention mode: %q", opts.RetentionMode)
+	}
+
 	fileName := s.getObjectNameString(id)
 
 	// Backblaze always expects Content-Length to be set, even in http.Request ContentLength==0
@@ -171,6 +174,19 @@ func (s *b2Storage) PutBlob(_ context.Context, id blob.ID, data blob.Bytes, opts
 		return translateError(err)
 	}
 
+	if opts.HasRetentionOptions() {
+		// the B2 client library does not support passing retention on upload, so it is applied
+		// to the uploaded file version right after the upload.
+		if err := s.retention.updateFileRetention(ctx, fileName, fi.ID, opts.RetentionMode, clock.Now().Add(opts.RetentionPeriod)); err != nil {
+			// don't leave behind a blob which is not protected by retention.
+			if _, delErr := s.bucket.DeleteFileVersion(fileName, fi.ID); delErr != nil {
+				log(ctx).Errorf("unable to delete %v after failing to apply retention: %v", fileName, delErr)
+			}
+
+			return err
+		}
+	}
+
 	if opts.GetModTime != nil {
 		*opts.GetModTime = time.Unix(0, fi.UploadTimestamp*1e6)
 	}
@@ -274,9 +290,10 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 	}
 
 	return retrying.NewWrapper(&b2Storage{
-		Options: *opt,
-		cli:     cli,
-		bucket:  bucket,
+		Options:   *opt,
+		cli:       cli,
+		bucket:    bucket,
+		retention: newRetentionClient(opt),
 	}), nil
 }
 
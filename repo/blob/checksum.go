@@ -0,0 +1,150 @@
+package blob
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ChecksumAlgorithm identifies the algorithm used to compute a Checksum.
+type ChecksumAlgorithm string
+
+// Supported checksum algorithms.
+const (
+	// ChecksumMD5 is reported by S3 (as ETag of single-part uploads) and Azure (as Content-MD5).
+	ChecksumMD5 ChecksumAlgorithm = "md5"
+
+	// ChecksumCRC32C is reported by GCS.
+	ChecksumCRC32C ChecksumAlgorithm = "crc32c"
+)
+
+// AllChecksumAlgorithms lists all supported checksum algorithms.
+//
+//nolint:gochecknoglobals
+var AllChecksumAlgorithms = []ChecksumAlgorithm{
+	ChecksumMD5,
+	ChecksumCRC32C,
+}
+
+//nolint:gochecknoglobals
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum represents a checksum of the full contents of a blob, either computed locally
+// or reported by the storage provider. The value is always lowercase hex-encoded.
+type Checksum struct {
+	Algorithm ChecksumAlgorithm `json:"algorithm"`
+	Value     string            `json:"value"`
+}
+
+func (c Checksum) String() string {
+	return string(c.Algorithm) + ":" + c.Value
+}
+
+func newChecksumHash(alg ChecksumAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case ChecksumMD5:
+		return md5.New(), nil //nolint:gosec
+	case ChecksumCRC32C:
+		return crc32.New(crc32cTable), nil
+	default:
+		return nil, errors.Errorf("unsupported checksum algorithm: %q", alg)
+	}
+}
+
+// ComputeChecksums computes checksums of the provided data using all provided algorithms.
+func ComputeChecksums(data Bytes, algorithms ...ChecksumAlgorithm) ([]Checksum, error) {
+	var result []Checksum
+
+	for _, alg := range algorithms {
+		h, err := newChecksumHash(alg)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := data.WriteTo(h); err != nil {
+			return nil, errors.Wrapf(err, "error computing %v checksum", alg)
+		}
+
+		result = append(result, Checksum{alg, hex.EncodeToString(h.Sum(nil))})
+	}
+
+	return result, nil
+}
+
+// MD5ChecksumFromETag returns MD5 checksum based on the provided ETag value.
+// ETags of multi-part uploads (which contain a dash) and other non-MD5 ETags are ignored.
+func MD5ChecksumFromETag(etag string) (Checksum, bool) {
+	const md5HexLength = 2 * md5.Size
+
+	v := strings.ToLower(strings.Trim(etag, `"`))
+	if len(v) != md5HexLength {
+		return Checksum{}, false
+	}
+
+	if _, err := hex.DecodeString(v); err != nil {
+		return Checksum{}, false
+	}
+
+	return Checksum{ChecksumMD5, v}, true
+}
+
+// MD5ChecksumFromBytes returns MD5 checksum based on raw digest bytes, such as Azure Content-MD5.
+func MD5ChecksumFromBytes(b []byte) (Checksum, bool) {
+	if len(b) != md5.Size {
+		return Checksum{}, false
+	}
+
+	return Checksum{ChecksumMD5, hex.EncodeToString(b)}, true
+}
+
+// CRC32CChecksumFromUint32 returns CRC32C checksum based on the provided numeric value, such as reported by GCS.
+func CRC32CChecksumFromUint32(v uint32) Checksum {
+	var b [crc32.Size]byte
+
+	binary.BigEndian.PutUint32(b[:], v)
+
+	return Checksum{ChecksumCRC32C, hex.EncodeToString(b[:])}
+}
+
+// CRC32CChecksumFromBase64 returns CRC32C checksum based on base64-encoded big-endian value, such as reported by S3.
+func CRC32CChecksumFromBase64(s string) (Checksum, bool) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != crc32.Size {
+		return Checksum{}, false
+	}
+
+	return Checksum{ChecksumCRC32C, hex.EncodeToString(b)}, true
+}
+
+// ChecksumMismatch describes the result of comparing expected and actual checksums.
+type ChecksumMismatch struct {
+	Expected Checksum `json:"expected"`
+	Actual   Checksum `json:"actual"`
+}
+
+// CompareChecksums compares expected checksums with checksums reported by the provider.
+// It returns the number of algorithms that were compared and a list of mismatches.
+// Algorithms not present on both sides are ignored.
+func CompareChecksums(expected, actual []Checksum) (compared int, mismatches []ChecksumMismatch) {
+	for _, e := range expected {
+		for _, a := range actual {
+			if a.Algorithm != e.Algorithm {
+				continue
+			}
+
+			compared++
+
+			if !strings.EqualFold(a.Value, e.Value) {
+				mismatches = append(mismatches, ChecksumMismatch{e, a})
+			}
+		}
+	}
+
+	return compared, mismatches
+}
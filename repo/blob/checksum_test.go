@@ -0,0 +1,63 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestComputeChecksums(t *testing.T) {
+	cs, err := blob.ComputeChecksums(gather.FromSlice([]byte("hello world")), blob.AllChecksumAlgorithms...)
+	require.NoError(t, err)
+	require.Equal(t, []blob.Checksum{
+		{blob.ChecksumMD5, "5eb63bbbe01eeed093cb22bb8f5acdc3"},
+		{blob.ChecksumCRC32C, "c99465aa"},
+	}, cs)
+
+	_, err = blob.ComputeChecksums(gather.FromSlice(nil), "no-such-algorithm")
+	require.Error(t, err)
+}
+
+func TestChecksumParsing(t *testing.T) {
+	c, ok := blob.MD5ChecksumFromETag(`"5EB63BBBE01EEED093CB22BB8F5ACDC3"`)
+	require.True(t, ok)
+	require.Equal(t, blob.Checksum{blob.ChecksumMD5, "5eb63bbbe01eeed093cb22bb8f5acdc3"}, c)
+
+	// multi-part upload
+	_, ok = blob.MD5ChecksumFromETag(`"5eb63bbbe01eeed093cb22bb8f5acdc3-2"`)
+	require.False(t, ok)
+
+	_, ok = blob.MD5ChecksumFromETag(`"zzb63bbbe01eeed093cb22bb8f5acdc3"`)
+	require.False(t, ok)
+
+	_, ok = blob.MD5ChecksumFromBytes(nil)
+	require.False(t, ok)
+
+	require.Equal(t, blob.Checksum{blob.ChecksumCRC32C, "c99465aa"}, blob.CRC32CChecksumFromUint32(0xc99465aa))
+
+	c, ok = blob.CRC32CChecksumFromBase64("yZRlqg==")
+	require.True(t, ok)
+	require.Equal(t, blob.Checksum{blob.ChecksumCRC32C, "c99465aa"}, c)
+}
+
+func TestCompareChecksums(t *testing.T) {
+	expected := []blob.Checksum{
+		{blob.ChecksumMD5, "5eb63bbbe01eeed093cb22bb8f5acdc3"},
+		{blob.ChecksumCRC32C, "c99465aa"},
+	}
+
+	compared, mismatches := blob.CompareChecksums(expected, []blob.Checksum{{blob.ChecksumCRC32C, "C99465AA"}})
+	require.Equal(t, 1, compared)
+	require.Empty(t, mismatches)
+
+	compared, mismatches = blob.CompareChecksums(expected, nil)
+	require.Equal(t, 0, compared)
+	require.Empty(t, mismatches)
+
+	compared, mismatches = blob.CompareChecksums(expected, []blob.Checksum{{blob.ChecksumMD5, "00000000000000000000000000000000"}})
+	require.Equal(t, 1, compared)
+	require.Len(t, mismatches, 1)
+}
@@ -0,0 +1,172 @@
+// Package faultinjection implements wrapper around blob.Storage that injects latency and failures
+// into storage operations according to a profile, which is useful for rehearsing how backups
+// behave with an unreliable storage backend.
+package faultinjection
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("faultinjection")
+
+// ErrInjected is returned by operations that failed due to fault injection.
+var ErrInjected = errors.New("injected storage fault")
+
+type faultyStorage struct {
+	blob.Storage
+
+	profile *Profile
+
+	mu sync.Mutex
+	// +checklocks:mu
+	rnd *rand.Rand
+}
+
+// roll returns true with the provided probability.
+func (s *faultyStorage) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rnd.Float64() < rate
+}
+
+func (s *faultyStorage) randomDuration(maxMillis int) time.Duration {
+	if maxMillis <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return time.Duration(s.rnd.Intn(maxMillis+1)) * time.Millisecond
+}
+
+// before applies latency and error faults of the given operation.
+func (s *faultyStorage) before(ctx context.Context, op string, id blob.ID) (OperationProfile, error) {
+	o := s.profile.forOperation(op)
+
+	if d := time.Duration(o.LatencyMillis)*time.Millisecond + s.randomDuration(o.LatencyJitterMillis); d > 0 {
+		if !clock.SleepInterruptibly(ctx, d) {
+			return o, ctx.Err()
+		}
+	}
+
+	if s.roll(o.ErrorRate) {
+		log(ctx).Debugf("injecting error in %v(%v)", op, id)
+
+		return o, errors.Wrapf(ErrInjected, "%v(%v)", op, id)
+	}
+
+	return o, nil
+}
+
+func (s *faultyStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	o, err := s.before(ctx, OpGetBlob, id)
+	if err != nil {
+		return err
+	}
+
+	if s.roll(o.MissingRate) {
+		log(ctx).Debugf("injecting missing blob in GetBlob(%v)", id)
+
+		return blob.ErrBlobNotFound
+	}
+
+	return s.Storage.GetBlob(ctx, id, offset, length, output) //nolint:wrapcheck
+}
+
+func (s *faultyStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	o, err := s.before(ctx, OpGetMetadata, id)
+	if err != nil {
+		return blob.Metadata{}, err
+	}
+
+	if s.roll(o.MissingRate) {
+		log(ctx).Debugf("injecting missing blob in GetMetadata(%v)", id)
+
+		return blob.Metadata{}, blob.ErrBlobNotFound
+	}
+
+	return s.Storage.GetMetadata(ctx, id) //nolint:wrapcheck
+}
+
+func (s *faultyStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	o, err := s.before(ctx, OpPutBlob, id)
+	if err != nil {
+		return err
+	}
+
+	if data.Length() > 1 && s.roll(o.PartialWriteRate) {
+		log(ctx).Debugf("injecting partial write in PutBlob(%v)", id)
+
+		var tmp gather.WriteBuffer
+		defer tmp.Close()
+
+		if _, err := data.WriteTo(&tmp); err != nil {
+			return errors.Wrap(err, "unable to buffer data")
+		}
+
+		partial := tmp.ToByteSlice()[:data.Length()/2] //nolint:mnd
+
+		if err := s.Storage.PutBlob(ctx, id, gather.FromSlice(partial), opts); err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		return errors.Wrapf(ErrInjected, "partial write of %v", id)
+	}
+
+	return s.Storage.PutBlob(ctx, id, data, opts) //nolint:wrapcheck
+}
+
+func (s *faultyStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if _, err := s.before(ctx, OpDeleteBlob, id); err != nil {
+		return err
+	}
+
+	return s.Storage.DeleteBlob(ctx, id) //nolint:wrapcheck
+}
+
+func (s *faultyStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	o, err := s.before(ctx, OpListBlobs, prefix)
+	if err != nil {
+		return err
+	}
+
+	//nolint:wrapcheck
+	return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		if s.roll(o.MissingRate) {
+			log(ctx).Debugf("injecting missing blob in ListBlobs(%v): %v", prefix, bm.BlobID)
+			return nil
+		}
+
+		return callback(bm)
+	})
+}
+
+// NewWrapper returns a Storage wrapper that injects faults described by the provided profile.
+func NewWrapper(wrapped blob.Storage, p *Profile) blob.Storage {
+	seed := p.Seed
+	if seed == 0 {
+		seed = clock.Now().UnixNano()
+	}
+
+	return &faultyStorage{
+		Storage: wrapped,
+		profile: p,
+		rnd:     rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
@@ -0,0 +1,130 @@
+package faultinjection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrying"
+)
+
+func TestFaultInjection_NoFaults(t *testing.T) {
+	ctx := testlogging.Context(t)
+	st := NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, clock.Now), &Profile{})
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+}
+
+func TestFaultInjection_Errors(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	base := blobtesting.NewMapStorage(data, nil, clock.Now)
+
+	require.NoError(t, base.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1, 2, 3, 4}), blob.PutOptions{}))
+
+	st := NewWrapper(base, &Profile{
+		Seed: 1,
+		Operations: map[string]OperationProfile{
+			OpDefault:   {ErrorRate: 1},
+			OpGetBlob:   {MissingRate: 1},
+			OpListBlobs: {MissingRate: 1},
+			OpPutBlob:   {PartialWriteRate: 1},
+		},
+	})
+
+	var out gather.WriteBuffer
+	defer out.Close()
+
+	require.ErrorIs(t, st.GetBlob(ctx, "blob1", 0, -1, &out), blob.ErrBlobNotFound)
+
+	_, err := st.GetMetadata(ctx, "blob1")
+	require.ErrorIs(t, err, ErrInjected)
+	require.ErrorIs(t, st.DeleteBlob(ctx, "blob1"), ErrInjected)
+
+	blobs, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Empty(t, blobs)
+
+	require.ErrorIs(t, st.PutBlob(ctx, "blob2", gather.FromSlice([]byte{1, 2, 3, 4}), blob.PutOptions{}), ErrInjected)
+	require.Equal(t, []byte{1, 2}, data["blob2"])
+}
+
+func TestFaultInjection_Latency(t *testing.T) {
+	ctx := testlogging.Context(t)
+	st := NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, clock.Now), &Profile{
+		Operations: map[string]OperationProfile{
+			OpGetMetadata: {LatencyMillis: 50, LatencyJitterMillis: 10},
+		},
+	})
+
+	t0 := clock.Now()
+
+	_, err := st.GetMetadata(ctx, "no-such-blob")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+	require.GreaterOrEqual(t, clock.Now().Sub(t0), 50*time.Millisecond)
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := map[string]string{
+		"valid":        `{"seed":5,"operations":{"*":{"errorRate":0.1},"PutBlob":{"latencyMillis":10,"partialWriteRate":0.5}}}`,
+		"bad-json":     `{`,
+		"bad-op":       `{"operations":{"Foo":{}}}`,
+		"bad-rate":     `{"operations":{"GetBlob":{"errorRate":2}}}`,
+		"bad-latency":  `{"operations":{"GetBlob":{"latencyMillis":-1}}}`,
+		"missing-file": "",
+	}
+
+	for name, contents := range cases {
+		fname := filepath.Join(dir, name+".json")
+
+		if contents != "" {
+			require.NoError(t, os.WriteFile(fname, []byte(contents), 0o600))
+		}
+
+		p, err := LoadProfile(fname)
+		if name == "valid" {
+			require.NoError(t, err)
+			require.Equal(t, int64(5), p.Seed)
+			require.InDelta(t, 0.1, p.forOperation(OpGetBlob).ErrorRate, 1e-9)
+			require.InDelta(t, 0.5, p.forOperation(OpPutBlob).PartialWriteRate, 1e-9)
+		} else {
+			require.Error(t, err, name)
+		}
+	}
+}
+
+func TestFaultInjection_RetryingWrapper(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+
+	st := retrying.NewWrapper(NewWrapper(blobtesting.NewMapStorage(data, nil, clock.Now), &Profile{
+		Seed: 3,
+		Operations: map[string]OperationProfile{
+			OpDefault: {ErrorRate: 0.3},
+			OpPutBlob: {PartialWriteRate: 0.3},
+		},
+	}))
+
+	for _, id := range []blob.ID{"blob01", "blob02", "blob03", "blob04"} {
+		require.NoError(t, st.PutBlob(ctx, id, gather.FromSlice([]byte(id)), blob.PutOptions{}))
+		require.Equal(t, []byte(id), data[id])
+
+		blobtesting.AssertGetBlob(ctx, t, st, id, []byte(id))
+	}
+
+	for range 5 {
+		blobs, err := blob.ListAllBlobs(ctx, st, "")
+		require.NoError(t, err)
+		require.Len(t, blobs, 4)
+	}
+}
@@ -0,0 +1,108 @@
+package faultinjection
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Names of operations that can be configured in a Profile.
+const (
+	OpGetBlob     = "GetBlob"
+	OpGetMetadata = "GetMetadata"
+	OpPutBlob     = "PutBlob"
+	OpDeleteBlob  = "DeleteBlob"
+	OpListBlobs   = "ListBlobs"
+
+	// OpDefault applies to all operations without their own entry.
+	OpDefault = "*"
+)
+
+//nolint:gochecknoglobals
+var allOperations = []string{OpGetBlob, OpGetMetadata, OpPutBlob, OpDeleteBlob, OpListBlobs, OpDefault}
+
+// OperationProfile describes faults injected into a single kind of storage operation.
+// Rates are probabilities between 0 and 1 evaluated independently for each call.
+type OperationProfile struct {
+	// LatencyMillis is added before every call, plus a random amount up to LatencyJitterMillis.
+	LatencyMillis       int `json:"latencyMillis,omitempty"`
+	LatencyJitterMillis int `json:"latencyJitterMillis,omitempty"`
+
+	// ErrorRate is the probability of failing the call with ErrInjected.
+	ErrorRate float64 `json:"errorRate,omitempty"`
+
+	// MissingRate is the probability of reporting the blob as not found (GetBlob, GetMetadata)
+	// or omitting it from the results (ListBlobs).
+	MissingRate float64 `json:"missingRate,omitempty"`
+
+	// PartialWriteRate is the probability of PutBlob writing only a prefix of the data and failing.
+	PartialWriteRate float64 `json:"partialWriteRate,omitempty"`
+}
+
+// Profile describes faults to inject into storage operations.
+type Profile struct {
+	// Seed initializes the random number generator, zero picks a random seed.
+	Seed int64 `json:"seed,omitempty"`
+
+	Operations map[string]OperationProfile `json:"operations"`
+}
+
+// Validate ensures that the profile is valid.
+func (p *Profile) Validate() error {
+	for op, o := range p.Operations {
+		if !isValidOperation(op) {
+			return errors.Errorf("unknown operation %q, must be one of %v", op, allOperations)
+		}
+
+		if o.LatencyMillis < 0 || o.LatencyJitterMillis < 0 {
+			return errors.Errorf("invalid latency for %v", op)
+		}
+
+		for _, r := range []float64{o.ErrorRate, o.MissingRate, o.PartialWriteRate} {
+			if r < 0 || r > 1 {
+				return errors.Errorf("invalid rate for %v: %v, must be between 0 and 1", op, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *Profile) forOperation(op string) OperationProfile {
+	if o, ok := p.Operations[op]; ok {
+		return o
+	}
+
+	return p.Operations[OpDefault]
+}
+
+func isValidOperation(op string) bool {
+	for _, o := range allOperations {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadProfile loads and validates the fault profile from the provided JSON file.
+func LoadProfile(fname string) (*Profile, error) {
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read fault profile")
+	}
+
+	p := &Profile{}
+
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, errors.Wrap(err, "unable to parse fault profile")
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid fault profile")
+	}
+
+	return p, nil
+}
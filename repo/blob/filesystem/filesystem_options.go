@@ -17,6 +17,13 @@ type Options struct {
 	FileUID *int `json:"uid,omitempty"`
 	FileGID *int `json:"gid,omitempty"`
 
+	// RetentionHelper is an optional command used to apply retention to blobs by setting their
+	// immutable attribute, invoked as '<helper> lock <file> <mode> <retain-until>' and
+	// '<helper> unlock <file>'. The helper should be pinned to the repository root, such as
+	// 'sudo kopia blob filesystem-retention-helper --root=<path>'. Without it, retention is only enforced by kopia itself,
+	// unless running as root.
+	RetentionHelper string `json:"retentionHelper,omitempty"`
+
 	sharded.Options
 	throttling.Limits
 
@@ -0,0 +1,559 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/iocopy"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/sharded"
+)
+
+const (
+	// retentionSidecarSuffix is appended to the blob file name to form the name of the file
+	// holding retention information for the blob.
+	retentionSidecarSuffix = ".retention"
+
+	// deletedMarkerSuffix is appended to the blob file name to mark a retained blob as deleted.
+	// The blob itself is removed once its retention period expires.
+	deletedMarkerSuffix = ".deleted"
+
+	retentionHelperLock   = "lock"
+	retentionHelperUnlock = "unlock"
+)
+
+var (
+	errImmutableUnsupported = errors.New("immutable files are not supported on this platform")
+	errBlobUnderRetention   = errors.New("blob is under retention")
+)
+
+// retentionInfo is persisted in the retention sidecar file.
+type retentionInfo struct {
+	Mode        blob.RetentionMode `json:"mode"`
+	RetainUntil time.Time          `json:"retainUntil"`
+}
+
+func (ri *retentionInfo) isActive(now time.Time) bool {
+	return now.Before(ri.RetainUntil)
+}
+
+// retentionLocker applies and removes retention of blob files.
+type retentionLocker interface {
+	lock(ctx context.Context, path string, ri retentionInfo) error
+	unlock(ctx context.Context, path string) error
+}
+
+// localRetentionLocker manages retention sidecars and immutable attributes directly.
+// When strict is false, failures to change the immutable attribute are ignored and retention
+// is only enforced by kopia itself.
+type localRetentionLocker struct {
+	osi    osInterface
+	strict bool
+
+	// permissions and ownership of retention sidecar files.
+	fileMode os.FileMode
+	fileUID  *int
+	fileGID  *int
+}
+
+func (l *localRetentionLocker) setImmutable(ctx context.Context, path string, immutable bool) error {
+	err := l.osi.SetImmutable(path, immutable)
+	if err == nil || l.osi.IsNotExist(err) {
+		return nil
+	}
+
+	if l.strict {
+		return errors.Wrapf(err, "unable to change immutable attribute of %v", path)
+	}
+
+	log(ctx).Debugf("unable to change immutable attribute of %v: %v", path, err)
+
+	return nil
+}
+
+func (l *localRetentionLocker) lock(ctx context.Context, path string, ri retentionInfo) error {
+	sidecar := path + retentionSidecarSuffix
+
+	existing, err := readRetentionInfo(l.osi, path)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		// retention can never be shortened or downgraded.
+		if existing.RetainUntil.After(ri.RetainUntil) {
+			ri.RetainUntil = existing.RetainUntil
+		}
+
+		if existing.Mode == blob.Compliance {
+			ri.Mode = blob.Compliance
+		}
+
+		if err := l.setImmutable(ctx, sidecar, false); err != nil {
+			return err
+		}
+	}
+
+	js, err := json.Marshal(ri)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize retention info")
+	}
+
+	if err := writeFileAtomically(l.osi, sidecar, gather.FromSlice(js), l.fileMode); err != nil {
+		return errors.Wrap(err, "unable to write retention info")
+	}
+
+	if l.fileUID != nil && l.fileGID != nil && l.osi.Geteuid() == 0 {
+		if err := l.osi.Chown(sidecar, *l.fileUID, *l.fileGID); err != nil {
+			log(ctx).Errorf("can't change retention info ownership: %v", err)
+		}
+	}
+
+	if err := l.setImmutable(ctx, sidecar, true); err != nil {
+		return err
+	}
+
+	return l.setImmutable(ctx, path, true)
+}
+
+func (l *localRetentionLocker) unlock(ctx context.Context, path string) error {
+	sidecar := path + retentionSidecarSuffix
+
+	ri, err := readRetentionInfo(l.osi, path)
+	if err != nil {
+		return err
+	}
+
+	if ri == nil {
+		return nil
+	}
+
+	if ri.isActive(clock.Now()) {
+		return errors.Wrapf(errBlobUnderRetention, "%v is retained until %v", path, ri.RetainUntil)
+	}
+
+	if err := l.setImmutable(ctx, path, false); err != nil {
+		return err
+	}
+
+	if err := l.setImmutable(ctx, sidecar, false); err != nil {
+		return err
+	}
+
+	if err := l.osi.Remove(sidecar); err != nil && !l.osi.IsNotExist(err) {
+		return errors.Wrap(err, "unable to remove retention info")
+	}
+
+	return nil
+}
+
+// commandRetentionLocker delegates locking to an external helper command, which typically
+// runs with privileges required to change immutable attributes (CAP_LINUX_IMMUTABLE).
+type commandRetentionLocker struct {
+	command []string
+}
+
+func (l *commandRetentionLocker) run(ctx context.Context, args ...string) error {
+	var stderr bytes.Buffer
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, l.command[0], append(append([]string(nil), l.command[1:]...), args...)...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "retention helper failed: %v", strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func (l *commandRetentionLocker) lock(ctx context.Context, path string, ri retentionInfo) error {
+	return l.run(ctx, retentionHelperLock, path, string(ri.Mode), ri.RetainUntil.UTC().Format(time.RFC3339Nano))
+}
+
+func (l *commandRetentionLocker) unlock(ctx context.Context, path string) error {
+	return l.run(ctx, retentionHelperUnlock, path)
+}
+
+func (fs *fsImpl) retentionLocker() retentionLocker {
+	fs.retentionSeen.Store(true)
+
+	if cmd := strings.Fields(fs.RetentionHelper); len(cmd) > 0 {
+		return &commandRetentionLocker{cmd}
+	}
+
+	// without a helper, only root can set immutable attributes, do it on a best-effort basis.
+	return &localRetentionLocker{
+		osi:      fs.osi,
+		fileMode: fs.fileMode(),
+		fileUID:  fs.FileUID,
+		fileGID:  fs.FileGID,
+	}
+}
+
+// readRetentionInfo returns retention info of the provided blob file or nil if the blob is not retained.
+func readRetentionInfo(osi osInterface, path string) (*retentionInfo, error) {
+	f, err := osi.Open(path + retentionSidecarSuffix)
+	if osi.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open retention info")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	ri := &retentionInfo{}
+	if err := json.NewDecoder(f).Decode(ri); err != nil {
+		return nil, errors.Wrap(err, "invalid retention info")
+	}
+
+	return ri, nil
+}
+
+func writeFileAtomically(osi osInterface, path string, data blob.Bytes, mode os.FileMode) error {
+	tmp := path + ".tmp"
+
+	// remove temporary file left behind by an interrupted write.
+	if err := osi.Remove(tmp); err != nil && !osi.IsNotExist(err) {
+		return errors.Wrap(err, "unable to remove stale temporary file")
+	}
+
+	f, err := osi.CreateNewFile(tmp, mode)
+	if err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if _, err := data.WriteTo(f); err != nil {
+		f.Close()       //nolint:errcheck
+		osi.Remove(tmp) //nolint:errcheck
+
+		return errors.Wrap(err, "write error")
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()       //nolint:errcheck
+		osi.Remove(tmp) //nolint:errcheck
+
+		return errors.Wrap(err, "sync error")
+	}
+
+	if err := f.Close(); err != nil {
+		osi.Remove(tmp) //nolint:errcheck
+
+		return errors.Wrap(err, "close error")
+	}
+
+	//nolint:wrapcheck
+	return osi.Rename(tmp, path)
+}
+
+// isDeletedRetainedBlob returns true if the blob file has been deleted while under retention.
+// Storage which never used retention can't have such blobs, which saves a Stat() on each read.
+func (fs *fsImpl) isDeletedRetainedBlob(path string) bool {
+	if !fs.retentionSeen.Load() {
+		return false
+	}
+
+	_, err := fs.osi.Stat(path + deletedMarkerSuffix)
+
+	return err == nil
+}
+
+// putRetainedBlob handles writes to a blob that is currently under retention, which is
+// only possible if the contents are identical, in which case the write is a no-op that
+// undeletes the blob if needed.
+func (fs *fsImpl) putRetainedBlob(path string, data blob.Bytes, opts blob.PutOptions) error {
+	f, err := fs.osi.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to open retained blob")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	var existing gather.WriteBuffer
+	defer existing.Close()
+
+	if err := iocopy.JustCopy(&existing, f); err != nil {
+		return errors.Wrap(err, "unable to read retained blob")
+	}
+
+	var incoming gather.WriteBuffer
+	defer incoming.Close()
+
+	if _, err := data.WriteTo(&incoming); err != nil {
+		return errors.Wrap(err, "unable to buffer blob")
+	}
+
+	if !bytes.Equal(existing.ToByteSlice(), incoming.ToByteSlice()) {
+		return errors.Wrapf(errBlobUnderRetention, "unable to overwrite %v", path)
+	}
+
+	if t := opts.GetModTime; t != nil {
+		fi, err := fs.osi.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "can't get mod time for file %q", path)
+		}
+
+		*t = fi.ModTime()
+	}
+
+	if err := fs.osi.Remove(path + deletedMarkerSuffix); err != nil && !fs.osi.IsNotExist(err) {
+		return errors.Wrap(err, "unable to undelete retained blob")
+	}
+
+	return nil
+}
+
+// deleteRetainedBlob deletes a blob file which may be under retention.
+// Returns true if the blob has been deleted or false if its retention period has not expired yet.
+func (fs *fsImpl) deleteRetainedBlob(ctx context.Context, path string) (bool, error) {
+	ri, err := readRetentionInfo(fs.osi, path)
+	if err != nil {
+		return false, err
+	}
+
+	if ri == nil {
+		return true, nil
+	}
+
+	if ri.isActive(clock.Now()) {
+		return false, nil
+	}
+
+	if err := fs.retentionLocker().unlock(ctx, path); err != nil {
+		return false, errors.Wrap(err, "unable to unlock blob")
+	}
+
+	if err := fs.osi.Remove(path + deletedMarkerSuffix); err != nil && !fs.osi.IsNotExist(err) {
+		return false, errors.Wrap(err, "unable to remove deletion marker")
+	}
+
+	return true, nil
+}
+
+// ExtendBlobRetention extends the retention period of a blob.
+func (fs *fsStorage) ExtendBlobRetention(ctx context.Context, blobID blob.ID, opts blob.ExtendOptions) error {
+	if !opts.RetentionMode.IsValid() {
+		return errors.Errorf("invalid retention mode: %q", opts.RetentionMode)
+	}
+
+	_, path, err := fs.GetShardedPathAndFilePath(ctx, blobID)
+	if err != nil {
+		return errors.Wrap(err, "error getting sharded path")
+	}
+
+	impl := fs.Impl.(*fsImpl) //nolint:forcetypeassert
+
+	if _, err := impl.osi.Stat(path); err != nil {
+		if impl.osi.IsNotExist(err) {
+			return blob.ErrBlobNotFound
+		}
+
+		return errors.Wrap(err, "unable to stat blob")
+	}
+
+	return impl.retentionLocker().lock(ctx, path, retentionInfo{
+		Mode:        opts.RetentionMode,
+		RetainUntil: clock.Now().Add(opts.RetentionPeriod),
+	})
+}
+
+// RunRetentionHelper implements the retention helper protocol used by the RetentionHelper option:
+//
+//	lock <file> <mode> <retain-until-rfc3339>
+//	unlock <file>
+//
+// The helper typically runs with elevated privileges, so it only accepts blob files located under
+// the provided repository root and refuses to follow symbolic links.
+// Unlike retention applied without a helper, failure to change immutable attributes is an error.
+func RunRetentionHelper(ctx context.Context, root string, args []string) error {
+	l := &localRetentionLocker{osi: realOS{}, strict: true}
+
+	switch {
+	case len(args) == 4 && args[0] == retentionHelperLock: //nolint:mnd
+		if err := validateRetentionHelperPath(root, args[1]); err != nil {
+			return err
+		}
+
+		// sidecar files inherit permissions and ownership of the blob file.
+		fi, err := l.osi.Stat(args[1])
+		if err != nil {
+			return errors.Wrap(err, "unable to stat blob")
+		}
+
+		l.fileMode = fi.Mode().Perm()
+		l.fileUID, l.fileGID = fileOwner(fi)
+
+		t, err := time.Parse(time.RFC3339Nano, args[3])
+		if err != nil {
+			return errors.Wrap(err, "invalid retain-until time")
+		}
+
+		mode := blob.RetentionMode(args[2])
+		if !mode.IsValid() {
+			return errors.Errorf("invalid retention mode: %q", args[2])
+		}
+
+		return l.lock(ctx, args[1], retentionInfo{mode, t})
+
+	case len(args) == 2 && args[0] == retentionHelperUnlock: //nolint:mnd
+		if err := validateRetentionHelperPath(root, args[1]); err != nil {
+			return err
+		}
+
+		return l.unlock(ctx, args[1])
+
+	default:
+		return errors.New("usage: lock <file> <mode> <retain-until> | unlock <file>")
+	}
+}
+
+// validateRetentionHelperPath ensures that the path refers to an existing blob file under the
+// repository root, without symbolic links along the way.
+func validateRetentionHelperPath(root, path string) error {
+	if !filepath.IsAbs(root) {
+		return errors.Errorf("repository root must be an absolute path: %q", root)
+	}
+
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return errors.Errorf("blob file must be an absolute, clean path: %q", path)
+	}
+
+	if !strings.HasSuffix(path, sharded.CompleteBlobSuffix) {
+		return errors.Errorf("not a blob file: %q", path)
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(root), path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.Errorf("%q is outside of repository root %q", path, root)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve repository root")
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve blob file")
+	}
+
+	// any symbolic link below the root would make the resolved path differ.
+	if realPath != filepath.Join(realRoot, rel) {
+		return errors.Errorf("%q must not contain symbolic links", path)
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to stat blob")
+	}
+
+	if !fi.Mode().IsRegular() {
+		return errors.Errorf("%q is not a regular file", path)
+	}
+
+	return nil
+}
+
+func (fs *fsImpl) markRetainedBlobDeleted(ctx context.Context, path string) error {
+	f, err := fs.osi.CreateNewFile(path+deletedMarkerSuffix, fs.fileMode())
+	if fs.osi.IsExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to mark retained blob as deleted")
+	}
+
+	fs.retentionSeen.Store(true)
+
+	if fs.FileUID != nil && fs.FileGID != nil && fs.osi.Geteuid() == 0 {
+		if chownErr := fs.osi.Chown(path+deletedMarkerSuffix, *fs.FileUID, *fs.FileGID); chownErr != nil {
+			log(ctx).Errorf("can't change file permissions: %v", chownErr)
+		}
+	}
+
+	return errors.Wrap(f.Close(), "unable to mark retained blob as deleted")
+}
+
+// withoutDeletedRetainedBlobs removes blob files that have been deleted while under retention
+// from the directory listing. Listing never modifies the storage, expired blobs are removed
+// by purgeExpiredRetainedBlobs() when other blobs are deleted.
+func (fs *fsImpl) withoutDeletedRetainedBlobs(fileInfos []os.FileInfo) []os.FileInfo {
+	var deleted map[string]bool
+
+	for _, fi := range fileInfos {
+		blobFile, ok := strings.CutSuffix(fi.Name(), deletedMarkerSuffix)
+		if !ok {
+			continue
+		}
+
+		if deleted == nil {
+			deleted = map[string]bool{}
+		}
+
+		deleted[blobFile] = true
+
+		fs.retentionSeen.Store(true)
+	}
+
+	if deleted == nil {
+		return fileInfos
+	}
+
+	result := fileInfos[:0]
+
+	for _, fi := range fileInfos {
+		if !deleted[fi.Name()] {
+			result = append(result, fi)
+		}
+	}
+
+	return result
+}
+
+// purgeExpiredRetainedBlobs removes blobs in the provided directory which have been deleted
+// while under retention and whose retention has since expired.
+func (fs *fsImpl) purgeExpiredRetainedBlobs(ctx context.Context, dirPath string) {
+	entries, err := fs.osi.ReadDir(dirPath)
+	if err != nil {
+		log(ctx).Debugf("unable to list %v: %v", dirPath, err)
+		return
+	}
+
+	for _, e := range entries {
+		blobFile, ok := strings.CutSuffix(e.Name(), deletedMarkerSuffix)
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dirPath, blobFile)
+
+		canDelete, err := fs.deleteRetainedBlob(ctx, path)
+		if err != nil {
+			log(ctx).Debugf("unable to remove expired blob %v: %v", path, err)
+			continue
+		}
+
+		if !canDelete {
+			continue
+		}
+
+		if err := fs.osi.Remove(path); err != nil && !fs.osi.IsNotExist(err) {
+			log(ctx).Debugf("unable to remove expired blob %v: %v", path, err)
+		}
+	}
+}
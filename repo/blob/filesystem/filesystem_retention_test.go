@@ -0,0 +1,266 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/sharded"
+)
+
+func TestFileStorage_Retention(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	st, err := New(ctx, &Options{
+		Path: testutil.TempDirectory(t),
+		Options: sharded.Options{
+			DirectoryShards: []int{1},
+		},
+		osInterfaceOverride: newMockOS(),
+	}, true)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	const blobID = "someblob1234567812345678"
+
+	data := gather.FromSlice([]byte{1, 2, 3})
+
+	require.NoError(t, st.PutBlob(ctx, blobID, data, blob.PutOptions{
+		RetentionMode:   blob.Governance,
+		RetentionPeriod: time.Hour,
+	}))
+
+	_, path, err := testutil.EnsureType[*fsStorage](t, st).GetShardedPathAndFilePath(ctx, blobID)
+	require.NoError(t, err)
+
+	ri := readSidecar(t, path)
+	require.Equal(t, blob.Governance, ri.Mode)
+
+	// retention can be extended and upgraded, but never shortened.
+	require.NoError(t, st.ExtendBlobRetention(ctx, blobID, blob.ExtendOptions{
+		RetentionMode:   blob.Compliance,
+		RetentionPeriod: time.Minute,
+	}))
+
+	ri2 := readSidecar(t, path)
+	require.Equal(t, blob.Compliance, ri2.Mode)
+	require.Equal(t, ri.RetainUntil, ri2.RetainUntil)
+
+	// overwriting with different contents is not allowed, identical contents are fine.
+	require.ErrorIs(t, st.PutBlob(ctx, blobID, gather.FromSlice([]byte{4, 5, 6}), blob.PutOptions{}), errBlobUnderRetention)
+	require.NoError(t, st.PutBlob(ctx, blobID, data, blob.PutOptions{}))
+
+	// deleting a retained blob hides it, but keeps the file.
+	require.NoError(t, st.DeleteBlob(ctx, blobID))
+	require.FileExists(t, path)
+
+	_, err = st.GetMetadata(ctx, blobID)
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.ErrorIs(t, st.GetBlob(ctx, blobID, 0, -1, &tmp), blob.ErrBlobNotFound)
+
+	blobs, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Empty(t, blobs)
+
+	// re-creating the blob with the same contents brings it back.
+	require.NoError(t, st.PutBlob(ctx, blobID, data, blob.PutOptions{}))
+
+	_, err = st.GetMetadata(ctx, blobID)
+	require.NoError(t, err)
+
+	// once retention expires, the blob can be deleted.
+	writeSidecar(t, path, retentionInfo{blob.Compliance, time.Now().Add(-time.Minute)})
+
+	require.NoError(t, st.DeleteBlob(ctx, blobID))
+	require.NoFileExists(t, path)
+	require.NoFileExists(t, path+retentionSidecarSuffix)
+}
+
+func TestFileStorage_Retention_ExpiredDeletedBlobsArePurged(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	st, err := New(ctx, &Options{
+		Path:                testutil.TempDirectory(t),
+		osInterfaceOverride: newMockOS(),
+	}, true)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	const blobID = "someblob1234567812345678"
+
+	require.NoError(t, st.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{
+		RetentionMode:   blob.Governance,
+		RetentionPeriod: time.Hour,
+	}))
+	require.NoError(t, st.DeleteBlob(ctx, blobID))
+
+	_, path, err := testutil.EnsureType[*fsStorage](t, st).GetShardedPathAndFilePath(ctx, blobID)
+	require.NoError(t, err)
+
+	require.FileExists(t, path+deletedMarkerSuffix)
+
+	writeSidecar(t, path, retentionInfo{blob.Governance, time.Now().Add(-time.Minute)})
+
+	// listing hides deleted blobs, but never removes them.
+	blobs, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Empty(t, blobs)
+
+	require.FileExists(t, path)
+	require.FileExists(t, path+deletedMarkerSuffix)
+
+	// deleting another blob in the same directory removes expired ones.
+	const otherBlobID = "someblob8765432187654321"
+
+	require.NoError(t, st.PutBlob(ctx, otherBlobID, gather.FromSlice([]byte{4, 5, 6}), blob.PutOptions{}))
+	require.NoError(t, st.DeleteBlob(ctx, otherBlobID))
+
+	require.NoFileExists(t, path)
+	require.NoFileExists(t, path+deletedMarkerSuffix)
+	require.NoFileExists(t, path+retentionSidecarSuffix)
+}
+
+func TestFileStorage_Retention_SidecarFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	st, err := New(ctx, &Options{
+		Path:                testutil.TempDirectory(t),
+		FileMode:            0o640,
+		osInterfaceOverride: newMockOS(),
+	}, true)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	const blobID = "someblob1234567812345678"
+
+	require.NoError(t, st.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+
+	_, path, err := testutil.EnsureType[*fsStorage](t, st).GetShardedPathAndFilePath(ctx, blobID)
+	require.NoError(t, err)
+
+	// temporary file left behind by an interrupted write does not prevent locking.
+	require.NoError(t, os.WriteFile(path+retentionSidecarSuffix+".tmp", []byte("garbage"), 0o600))
+
+	require.NoError(t, st.ExtendBlobRetention(ctx, blobID, blob.ExtendOptions{
+		RetentionMode:   blob.Governance,
+		RetentionPeriod: time.Hour,
+	}))
+
+	require.Equal(t, blob.Governance, readSidecar(t, path).Mode)
+	require.NoFileExists(t, path+retentionSidecarSuffix+".tmp")
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path + retentionSidecarSuffix)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+	}
+}
+
+func TestRunRetentionHelper_InvalidArgs(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	root := testutil.TempDirectory(t)
+	file := filepath.Join(root, "someblob.f")
+
+	require.NoError(t, os.WriteFile(file, []byte{1, 2, 3}, 0o600))
+
+	require.Error(t, RunRetentionHelper(ctx, root, nil))
+	require.Error(t, RunRetentionHelper(ctx, root, []string{"lock", file, "GOVERNANCE", "not-a-time"}))
+	require.Error(t, RunRetentionHelper(ctx, root, []string{"lock", file, "bad-mode", time.Now().Format(time.RFC3339Nano)}))
+}
+
+func TestRunRetentionHelper_RejectsUnsafePaths(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	root := testutil.TempDirectory(t)
+	outside := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "abc"), 0o700))
+
+	writeFile := func(path string) string {
+		require.NoError(t, os.WriteFile(path, []byte{1, 2, 3}, 0o600))
+		return path
+	}
+
+	outsideFile := writeFile(filepath.Join(outside, "someblob.f"))
+	notBlob := writeFile(filepath.Join(root, "abc", "someblob.retention"))
+
+	cases := map[string]string{
+		"relative":     filepath.Join("abc", "someblob.f"),
+		"not clean":    filepath.Join(root, "abc") + "/../abc/someblob.f",
+		"not a blob":   notBlob,
+		"outside root": outsideFile,
+		"missing":      filepath.Join(root, "abc", "missing.f"),
+		"root itself":  root,
+	}
+
+	if runtime.GOOS != "windows" {
+		link := filepath.Join(root, "abc", "link.f")
+		require.NoError(t, os.Symlink(outsideFile, link))
+
+		cases["symlink"] = link
+
+		require.NoError(t, os.Symlink(outside, filepath.Join(root, "linkdir")))
+
+		cases["symlinked directory"] = filepath.Join(root, "linkdir", "someblob.f")
+	}
+
+	for name, path := range cases {
+		require.Error(t, RunRetentionHelper(ctx, root, []string{"unlock", path}), name)
+		require.Error(t, RunRetentionHelper(ctx, root, []string{"lock", path, "GOVERNANCE", time.Now().Format(time.RFC3339Nano)}), name)
+	}
+
+	require.Error(t, RunRetentionHelper(ctx, "relative-root", []string{"unlock", filepath.Join(root, "abc", "someblob.f")}))
+
+	// files without retention can always be unlocked.
+	blobFile := writeFile(filepath.Join(root, "abc", "someblob.f"))
+	require.NoError(t, RunRetentionHelper(ctx, root, []string{"unlock", blobFile}))
+}
+
+func readSidecar(t *testing.T, path string) retentionInfo {
+	t.Helper()
+
+	b, err := os.ReadFile(path + retentionSidecarSuffix)
+	require.NoError(t, err)
+
+	var ri retentionInfo
+
+	require.NoError(t, json.Unmarshal(b, &ri))
+
+	return ri
+}
+
+func writeSidecar(t *testing.T, path string, ri retentionInfo) {
+	t.Helper()
+
+	b, err := json.Marshal(ri)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path+retentionSidecarSuffix, b, 0o600))
+}
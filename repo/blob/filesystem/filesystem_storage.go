@@ -10,6 +10,7 @@
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -42,6 +43,10 @@ type fsImpl struct {
 	Options
 
 	osi osInterface
+
+	// retentionSeen is set once this storage has applied or encountered blob retention,
+	// only then reads need to check for deletion markers of retained blobs.
+	retentionSeen atomic.Bool
 }
 
 var errRetriableInvalidLength = errors.New("invalid length (retriable)")
@@ -129,6 +134,12 @@ func (fs *fsImpl) GetBlobFromPath(ctx context.Context, dirPath, path string, off
 		return err
 	}
 
+	if fs.isDeletedRetainedBlob(path) {
+		output.Reset()
+
+		return blob.ErrBlobNotFound
+	}
+
 	//nolint:wrapcheck
 	return blob.EnsureLengthExactly(output.Length(), length)
 }
@@ -148,6 +159,10 @@ func (fs *fsImpl) GetMetadataFromPath(ctx context.Context, dirPath, path string)
 			return blob.Metadata{}, err
 		}
 
+		if fs.isDeletedRetainedBlob(path) {
+			return blob.Metadata{}, blob.ErrBlobNotFound
+		}
+
 		return blob.Metadata{
 			Length:    fi.Size(),
 			Timestamp: fi.ModTime(),
@@ -159,16 +174,34 @@ func (fs *fsImpl) GetMetadataFromPath(ctx context.Context, dirPath, path string)
 func (fs *fsImpl) PutBlobInPath(ctx context.Context, dirPath, path string, data blob.Bytes, opts blob.PutOptions) error {
 	_ = dirPath
 
-	switch {
-	case opts.HasRetentionOptions():
-		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "blob-retention")
-	case opts.DoNotRecreate:
+	if opts.DoNotRecreate {
 		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "do-not-recreate")
 	}
 
+	ri, err := readRetentionInfo(fs.osi, path)
+	if err != nil {
+		return err
+	}
+
+	if ri != nil {
+		fs.retentionSeen.Store(true)
+
+		if ri.isActive(clock.Now()) {
+			if err := fs.putRetainedBlob(path, data, opts); err != nil {
+				return err
+			}
+
+			return fs.finishPutBlob(ctx, path, true, opts)
+		}
+
+		if err := fs.retentionLocker().unlock(ctx, path); err != nil {
+			return errors.Wrap(err, "unable to unlock blob")
+		}
+	}
+
 	const maxAttempts = 2
 
-	_, err := retry.WithExponentialBackoffMaxRetries(ctx, maxAttempts, "PutBlobInPath:"+path, retry.NoValueFn(func() error {
+	_, err = retry.WithExponentialBackoffMaxRetries(ctx, maxAttempts, "PutBlobInPath:"+path, retry.NoValueFn(func() error {
 		tempFile, err := fs.createTempFileWithData(path, data)
 		if err != nil {
 			return err
@@ -207,8 +240,30 @@ func (fs *fsImpl) PutBlobInPath(ctx context.Context, dirPath, path string, data
 
 		return nil
 	}), fs.isRetriable)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return fs.finishPutBlob(ctx, path, ri != nil, opts)
+}
+
+// finishPutBlob applies retention options to a blob that has been written and removes
+// any deletion marker left behind by an earlier retained version of the blob.
+func (fs *fsImpl) finishPutBlob(ctx context.Context, path string, wasRetained bool, opts blob.PutOptions) error {
+	if wasRetained {
+		if err := fs.osi.Remove(path + deletedMarkerSuffix); err != nil && !fs.osi.IsNotExist(err) {
+			return errors.Wrap(err, "unable to remove deletion marker")
+		}
+	}
+
+	if !opts.HasRetentionOptions() {
+		return nil
+	}
+
+	return fs.retentionLocker().lock(ctx, path, retentionInfo{
+		Mode:        opts.RetentionMode,
+		RetainUntil: clock.Now().Add(opts.RetentionPeriod),
+	})
 }
 
 // createTempFileWithData creates a temporary file, writes data to it, syncs and closes it.
@@ -275,7 +330,23 @@ func (fs *fsImpl) createTempFileAndDir(tempFile string) (osWriteFile, error) {
 }
 
 func (fs *fsImpl) DeleteBlobInPath(ctx context.Context, dirPath, path string) error {
-	_ = dirPath
+	defer func() {
+		// blobs deleted while under retention are removed here once their retention expires,
+		// listing never changes the storage.
+		if fs.retentionSeen.Load() {
+			fs.purgeExpiredRetainedBlobs(ctx, dirPath)
+		}
+	}()
+
+	canDelete, err := fs.deleteRetainedBlob(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if !canDelete {
+		// blob is still retained, hide it until retention expires.
+		return fs.markRetainedBlobDeleted(ctx, path)
+	}
 
 	//nolint:wrapcheck
 	return retry.WithExponentialBackoffNoValue(ctx, "DeleteBlobInPath:"+path, func() error {
@@ -317,7 +388,7 @@ func (fs *fsImpl) ReadDir(ctx context.Context, dirname string) ([]os.FileInfo, e
 		fileInfos = append(fileInfos, fi)
 	}
 
-	return fileInfos, nil
+	return fs.withoutDeletedRetainedBlobs(fileInfos), nil
 }
 
 // TouchBlob updates file modification time to current time if it's sufficiently old.
@@ -389,10 +460,19 @@ func New(ctx context.Context, opts *Options, isCreate bool) (blob.Storage, error
 	}
 
 	return &fsStorage{
-		Storage: sharded.New(&fsImpl{*opts, osi}, opts.Path, opts.Options, isCreate),
+		Storage: sharded.New(newFSImpl(opts, osi), opts.Path, opts.Options, isCreate),
 	}, nil
 }
 
+func newFSImpl(opts *Options, osi osInterface) *fsImpl {
+	fs := &fsImpl{Options: *opts, osi: osi}
+
+	// with a helper, retention is expected to be used.
+	fs.retentionSeen.Store(opts.RetentionHelper != "")
+
+	return fs
+}
+
 func init() {
 	blob.AddSupportedStorage(fsStorageType, Options{}, New)
 }
@@ -28,6 +28,7 @@ type osInterface interface {
 	Chtimes(fname string, atime, mtime time.Time) error
 	Geteuid() int
 	Chown(fname string, uid, gid int) error
+	SetImmutable(fname string, immutable bool) error
 }
 
 type osReadFile interface {
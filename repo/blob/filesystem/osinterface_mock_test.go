@@ -207,3 +207,8 @@ type mockDirEntryInfoError struct {
 func (e mockDirEntryInfoError) Info() (fs.FileInfo, error) {
 	return nil, e.err
 }
+
+func (osi *mockOS) SetImmutable(fname string, immutable bool) error {
+	// do not depend on the test file system or privileges to support immutable files.
+	return errImmutableUnsupported
+}
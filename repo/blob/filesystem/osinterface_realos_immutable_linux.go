@@ -0,0 +1,36 @@
+package filesystem
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/kopia/kopia/internal/ospath"
+)
+
+// fsImmutableFlag is FS_IMMUTABLE_FL from linux/fs.h, the flag set by 'chattr +i'.
+const fsImmutableFlag = 0x00000010
+
+func (realOS) SetImmutable(fname string, immutable bool) error {
+	f, err := os.Open(ospath.SafeLongFilename(fname))
+	if err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	flags, err := unix.IoctlGetInt(int(f.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return errors.Wrap(err, "unable to get file attributes")
+	}
+
+	if immutable {
+		flags |= fsImmutableFlag
+	} else {
+		flags &^= fsImmutableFlag
+	}
+
+	return errors.Wrap(unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, flags), "unable to set file attributes")
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package filesystem
+
+//nolint:revive
+func (realOS) SetImmutable(fname string, immutable bool) error {
+	return errImmutableUnsupported
+}
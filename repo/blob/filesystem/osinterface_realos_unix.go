@@ -3,6 +3,7 @@
 package filesystem
 
 import (
+	"os"
 	"syscall"
 
 	"github.com/pkg/errors"
@@ -11,3 +12,15 @@
 func (realOS) IsStale(err error) bool {
 	return errors.Is(err, syscall.ESTALE)
 }
+
+// fileOwner returns the owner of the file described by fi or nils if not known.
+func fileOwner(fi os.FileInfo) (uid, gid *int) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, nil
+	}
+
+	u, g := int(st.Uid), int(st.Gid)
+
+	return &u, &g
+}
@@ -99,6 +99,13 @@ func (gcs *gcsStorage) getBlobMeta(attrs *gcsclient.ObjectAttrs) blob.Metadata {
 		bm.Timestamp = t
 	}
 
+	bm.Checksums = append(bm.Checksums, blob.CRC32CChecksumFromUint32(attrs.CRC32C))
+
+	// MD5 is not available for composite objects.
+	if c, ok := blob.MD5ChecksumFromBytes(attrs.MD5); ok {
+		bm.Checksums = append(bm.Checksums, c)
+	}
+
 	return bm
 }
 
@@ -7,8 +7,8 @@
 	"fmt"
 
 	"github.com/kopia/kopia/internal/retry"
-	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/format"
 )
 
 // retryingStorage adds retry loop around all operations of the underlying storage.
@@ -42,6 +42,22 @@ func (s retryingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
 	}, isRetriable)
 }
 
+// ListBlobs retries listing which failed before any blob was returned, afterwards the error
+// is returned as-is because the callback can't be invoked again for the same blobs.
+func (s retryingStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	var started bool
+
+	return retry.WithExponentialBackoffNoValue(ctx, "ListBlobs("+string(prefix)+")", func() error {
+		return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			started = true
+
+			return callback(bm)
+		})
+	}, func(err error) bool {
+		return !started && isRetriable(err)
+	})
+}
+
 // NewWrapper returns a Storage wrapper that adds retry loop around all operations of the underlying storage.
 func NewWrapper(wrapped blob.Storage) blob.Storage {
 	return &retryingStorage{Storage: wrapped}
@@ -67,7 +83,7 @@ func isRetriable(err error) bool {
 	case errors.Is(err, blob.ErrBlobAlreadyExists):
 		return false
 
-	case errors.Is(err, repo.ErrRepositoryUnavailableDueToUpgradeInProgress):
+	case errors.Is(err, format.ErrRepositoryUnavailableDueToUpgradeInProgress):
 		// hard-fail when upgrade is in progress
 		return false
 
@@ -25,6 +25,7 @@ func TestRetrying(t *testing.T) {
 	fs.AddFault(blobtesting.MethodGetBlob).ErrorInstead(someError)
 	fs.AddFault(blobtesting.MethodGetMetadata).ErrorInstead(someError)
 	fs.AddFault(blobtesting.MethodDeleteBlob).ErrorInstead(someError)
+	fs.AddFault(blobtesting.MethodListBlobs).ErrorInstead(someError)
 
 	rs := retrying.NewWrapper(fs)
 	blobID := blob.ID("deadcafe")
@@ -47,6 +48,10 @@ func TestRetrying(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	blobs, err := blob.ListAllBlobs(ctx, rs, "")
+	require.NoError(t, err)
+	require.Len(t, blobs, 2)
+
 	require.NoError(t, rs.DeleteBlob(ctx, blobID))
 
 	if err = rs.GetBlob(ctx, blobID, 0, -1, &tmp); !errors.Is(err, blob.ErrBlobNotFound) {
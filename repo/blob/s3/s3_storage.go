@@ -271,6 +271,7 @@ func (s *s3Storage) ListBlobs(ctx context.Context, prefix blob.ID, callback func
 			BlobID:    blob.ID(o.Key[len(s.Prefix):]),
 			Length:    o.Size,
 			Timestamp: o.LastModified,
+			Checksums: checksumsFromObjectInfo(&o),
 		}
 
 		if bm.BlobID == ConfigName {
@@ -101,6 +101,8 @@ func infoToVersionMetadata(prefix string, oi *minio.ObjectInfo) versionMetadata
 		Timestamp: oi.LastModified,
 	}
 
+	bm.Checksums = checksumsFromObjectInfo(oi)
+
 	return versionMetadata{
 		Metadata:       bm,
 		IsLatest:       oi.IsLatest,
@@ -108,3 +110,41 @@ func infoToVersionMetadata(prefix string, oi *minio.ObjectInfo) versionMetadata
 		Version:        oi.VersionID,
 	}
 }
+
+// checksumsFromObjectInfo returns checksums reported by S3 for the given object.
+// ETag is only an MD5 for objects uploaded in a single part without SSE-KMS/SSE-C, so it's
+// only used when response headers confirm the object is not encrypted with such keys.
+// Listings don't include encryption headers, so their ETags are never used.
+func checksumsFromObjectInfo(oi *minio.ObjectInfo) []blob.Checksum {
+	var result []blob.Checksum
+
+	if etagIsMD5(oi) {
+		if c, ok := blob.MD5ChecksumFromETag(oi.ETag); ok {
+			result = append(result, c)
+		}
+	}
+
+	if c, ok := blob.CRC32CChecksumFromBase64(oi.ChecksumCRC32C); ok {
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// etagIsMD5 returns true if the object metadata shows it's stored without SSE-KMS or SSE-C encryption.
+func etagIsMD5(oi *minio.ObjectInfo) bool {
+	if oi.Metadata == nil {
+		return false
+	}
+
+	if oi.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+		return false
+	}
+
+	switch oi.Metadata.Get("X-Amz-Server-Side-Encryption") {
+	case "", "AES256":
+		return true
+	default:
+		return false
+	}
+}
@@ -7,6 +7,7 @@
 	"io"
 	"math"
 	"math/rand"
+	"net/http"
 	"path"
 	"sort"
 	"sync"
@@ -372,6 +373,30 @@ func TestInfoToVersionMetadata(t *testing.T) {
 	}
 }
 
+func TestChecksumsFromObjectInfo(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"0123456789abcdef0123456789abcdef"`
+
+	md5 := blob.Checksum{Algorithm: blob.ChecksumMD5, Value: "0123456789abcdef0123456789abcdef"}
+
+	cases := []struct {
+		name     string
+		metadata http.Header
+		expected []blob.Checksum
+	}{
+		{"listing", nil, nil},
+		{"unencrypted", http.Header{}, []blob.Checksum{md5}},
+		{"sse-s3", http.Header{"X-Amz-Server-Side-Encryption": {"AES256"}}, []blob.Checksum{md5}},
+		{"sse-kms", http.Header{"X-Amz-Server-Side-Encryption": {"aws:kms"}}, nil},
+		{"sse-c", http.Header{"X-Amz-Server-Side-Encryption-Customer-Algorithm": {"AES256"}}, nil},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.expected, checksumsFromObjectInfo(&minio.ObjectInfo{ETag: etag, Metadata: tc.metadata}), tc.name)
+	}
+}
+
 func TestGetOlderThan(t *testing.T) {
 	t.Parallel()
 
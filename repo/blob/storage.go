@@ -234,6 +234,9 @@ type Metadata struct {
 	BlobID    ID        `json:"id"`
 	Length    int64     `json:"length"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// Checksums reported by the storage provider, if any.
+	Checksums []Checksum `json:"checksums,omitempty"`
 }
 
 func (m *Metadata) String() string {
@@ -0,0 +1,330 @@
+package throttling
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const minutesPerDay = 24 * 60
+
+// Unlimited is the value of a schedule window limit which removes the corresponding base limit while the window is active.
+const Unlimited = -1
+
+// unlimitedString is the representation of Unlimited in schedule window specifications.
+const unlimitedString = "unlimited"
+
+//nolint:gochecknoglobals
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleWindow defines limits that apply during a time-of-day window on selected days of week.
+//
+// Times are in the local time zone in 24-hour HH:MM format. When End is not after Start the window wraps
+// past midnight and the days of week refer to the day on which the window starts.
+type ScheduleWindow struct {
+	// days of week ("mon", "tue", ...) when the window applies, empty means every day.
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+
+	// non-zero limits override the corresponding base limits while the window is active,
+	// Unlimited removes the corresponding base limit.
+	Limits Limits `json:"limits"`
+}
+
+// Validate checks the window for correctness.
+func (w ScheduleWindow) Validate() error {
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return errors.Wrap(err, "invalid start time")
+	}
+
+	if _, err := parseTimeOfDay(w.End); err != nil {
+		return errors.Wrap(err, "invalid end time")
+	}
+
+	for _, d := range w.Days {
+		if _, err := parseWeekday(d); err != nil {
+			return err
+		}
+	}
+
+	if len(w.Limits.Schedule) > 0 {
+		return errors.New("schedule windows cannot be nested")
+	}
+
+	return nil
+}
+
+// String returns the representation of the window accepted by ParseScheduleWindow.
+func (w ScheduleWindow) String() string {
+	days := "*"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+
+	parts := []string{days, w.Start + "-" + w.End}
+
+	for _, kv := range []struct {
+		key string
+		val float64
+	}{
+		{"upload", w.Limits.UploadBytesPerSecond},
+		{"download", w.Limits.DownloadBytesPerSecond},
+		{"reads", w.Limits.ReadsPerSecond},
+		{"writes", w.Limits.WritesPerSecond},
+		{"lists", w.Limits.ListsPerSecond},
+		{"concurrent-reads", float64(w.Limits.ConcurrentReads)},
+		{"concurrent-writes", float64(w.Limits.ConcurrentWrites)},
+	} {
+		switch {
+		case kv.val < 0:
+			parts = append(parts, kv.key+"="+unlimitedString)
+		case kv.val != 0:
+			parts = append(parts, kv.key+"="+strconv.FormatFloat(kv.val, 'f', -1, 64))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// activeAt returns true if the window is active at the provided time.
+func (w ScheduleWindow) activeAt(t time.Time) bool {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute() //nolint:mnd
+
+	if start < end {
+		return minute >= start && minute < end && w.appliesOnDay(t.Weekday())
+	}
+
+	// window wraps past midnight, it's active in the evening of the starting day
+	// and in the morning of the following day.
+	if minute >= start {
+		return w.appliesOnDay(t.Weekday())
+	}
+
+	return minute < end && w.appliesOnDay((t.Weekday()+6)%7) //nolint:mnd
+}
+
+func (w ScheduleWindow) appliesOnDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, s := range w.Days {
+		if wd, err := parseWeekday(s); err == nil && wd == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EffectiveAt returns the limits in effect at the provided time, which are base limits overridden
+// by non-zero limits of the first schedule window active at that time, zero means no limit.
+func (l Limits) EffectiveAt(t time.Time) Limits {
+	result := l
+	result.Schedule = nil
+
+	w := l.ActiveWindow(t)
+	if w == nil {
+		return result
+	}
+
+	overrideFloat(&result.ReadsPerSecond, w.Limits.ReadsPerSecond)
+	overrideFloat(&result.WritesPerSecond, w.Limits.WritesPerSecond)
+	overrideFloat(&result.ListsPerSecond, w.Limits.ListsPerSecond)
+	overrideFloat(&result.UploadBytesPerSecond, w.Limits.UploadBytesPerSecond)
+	overrideFloat(&result.DownloadBytesPerSecond, w.Limits.DownloadBytesPerSecond)
+	overrideInt(&result.ConcurrentReads, w.Limits.ConcurrentReads)
+	overrideInt(&result.ConcurrentWrites, w.Limits.ConcurrentWrites)
+
+	return result
+}
+
+// ActiveWindow returns the first schedule window active at the provided time or nil if none.
+func (l Limits) ActiveWindow(t time.Time) *ScheduleWindow {
+	for i := range l.Schedule {
+		if l.Schedule[i].activeAt(t) {
+			return &l.Schedule[i]
+		}
+	}
+
+	return nil
+}
+
+func overrideFloat(v *float64, o float64) {
+	switch {
+	case o < 0:
+		*v = 0
+	case o != 0:
+		*v = o
+	}
+}
+
+func overrideInt(v *int, o int) {
+	switch {
+	case o < 0:
+		*v = 0
+	case o != 0:
+		*v = o
+	}
+}
+
+// ParseScheduleWindow parses the schedule window specification in the form
+// "DAYS HH:MM-HH:MM KEY=VALUE...", where DAYS is '*' or a comma-separated list of days
+// or day ranges (e.g. "mon-fri,sun") and keys are 'upload', 'download', 'reads', 'writes', 'lists',
+// 'concurrent-reads' and 'concurrent-writes'. The value 'unlimited' removes the base limit during the window.
+func ParseScheduleWindow(s string) (ScheduleWindow, error) {
+	var w ScheduleWindow
+
+	parts := strings.Fields(s)
+	if len(parts) < 3 { //nolint:mnd
+		return w, errors.Errorf("invalid schedule window %q, expected 'DAYS HH:MM-HH:MM KEY=VALUE...'", s)
+	}
+
+	days, err := parseDays(parts[0])
+	if err != nil {
+		return w, err
+	}
+
+	w.Days = days
+
+	var ok bool
+
+	w.Start, w.End, ok = strings.Cut(parts[1], "-")
+	if !ok {
+		return w, errors.Errorf("invalid time range %q, expected HH:MM-HH:MM", parts[1])
+	}
+
+	for _, kv := range parts[2:] {
+		if err := setScheduleLimit(&w.Limits, kv); err != nil {
+			return w, err
+		}
+	}
+
+	if err := w.Validate(); err != nil {
+		return w, err
+	}
+
+	return w, nil
+}
+
+func setScheduleLimit(l *Limits, kv string) error {
+	key, val, ok := strings.Cut(kv, "=")
+	if !ok {
+		return errors.Errorf("invalid limit %q, expected KEY=VALUE", kv)
+	}
+
+	v, err := strconv.ParseFloat(val, 64)
+
+	switch {
+	case val == unlimitedString:
+		v = Unlimited
+	case err != nil || v < 0:
+		return errors.Errorf("invalid value of %q: %q", key, val)
+	}
+
+	switch key {
+	case "upload":
+		l.UploadBytesPerSecond = v
+	case "download":
+		l.DownloadBytesPerSecond = v
+	case "reads":
+		l.ReadsPerSecond = v
+	case "writes":
+		l.WritesPerSecond = v
+	case "lists":
+		l.ListsPerSecond = v
+	case "concurrent-reads":
+		l.ConcurrentReads = int(v)
+	case "concurrent-writes":
+		l.ConcurrentWrites = int(v)
+	default:
+		return errors.Errorf("unknown limit %q", key)
+	}
+
+	return nil
+}
+
+func parseDays(s string) ([]string, error) {
+	if s == "*" {
+		return nil, nil
+	}
+
+	var result []string
+
+	for _, p := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(p, "-")
+
+		first, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isRange {
+			result = append(result, weekdayNames[first])
+			continue
+		}
+
+		last, err := parseWeekday(to)
+		if err != nil {
+			return nil, err
+		}
+
+		for d := first; ; d = (d + 1) % 7 { //nolint:mnd
+			result = append(result, weekdayNames[d])
+
+			if d == last {
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for i, n := range weekdayNames {
+		if strings.EqualFold(s, n) {
+			return time.Weekday(i), nil
+		}
+	}
+
+	return 0, errors.Errorf("invalid day of week %q, expected one of %v", s, strings.Join(weekdayNames, ", "))
+}
+
+// parseTimeOfDay parses HH:MM and returns the number of minutes since midnight.
+func parseTimeOfDay(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, errors.Errorf("invalid time %q, expected HH:MM", s)
+	}
+
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 24 {
+		return 0, errors.Errorf("invalid hour in %q", s)
+	}
+
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 {
+		return 0, errors.Errorf("invalid minute in %q", s)
+	}
+
+	result := h*60 + m //nolint:mnd
+	if result > minutesPerDay {
+		return 0, errors.Errorf("invalid time %q", s)
+	}
+
+	return result, nil
+}
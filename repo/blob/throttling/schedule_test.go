@@ -0,0 +1,189 @@
+package throttling
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleWindow(t *testing.T) {
+	w, err := ParseScheduleWindow("mon-fri 08:00-18:00 upload=2000000 concurrent-writes=2")
+	require.NoError(t, err)
+	require.Equal(t, ScheduleWindow{
+		Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+		Start: "08:00",
+		End:   "18:00",
+		Limits: Limits{
+			UploadBytesPerSecond: 2000000,
+			ConcurrentWrites:     2,
+		},
+	}, w)
+	require.Equal(t, "mon,tue,wed,thu,fri 08:00-18:00 upload=2000000 concurrent-writes=2", w.String())
+
+	w, err = ParseScheduleWindow("* 22:00-06:00 download=1000")
+	require.NoError(t, err)
+	require.Empty(t, w.Days)
+
+	w, err = ParseScheduleWindow("fri-mon,wed 00:00-24:00 reads=5")
+	require.NoError(t, err)
+	require.Equal(t, []string{"fri", "sat", "sun", "mon", "wed"}, w.Days)
+
+	w, err = ParseScheduleWindow("sat,sun 00:00-24:00 upload=unlimited concurrent-reads=unlimited")
+	require.NoError(t, err)
+	require.Equal(t, Limits{UploadBytesPerSecond: Unlimited, ConcurrentReads: Unlimited}, w.Limits)
+	require.Equal(t, "sat,sun 00:00-24:00 upload=unlimited concurrent-reads=unlimited", w.String())
+
+	for _, bad := range []string{
+		"",
+		"mon-fri 08:00-18:00",
+		"mon-fri 08:00 upload=1",
+		"xyz 08:00-18:00 upload=1",
+		"mon 8-18 upload=1",
+		"mon 08:00-25:00 upload=1",
+		"mon 08:60-18:00 upload=1",
+		"mon 08:00-18:00 upload",
+		"mon 08:00-18:00 upload=-1",
+		"mon 08:00-18:00 bogus=1",
+	} {
+		_, err := ParseScheduleWindow(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestLimitsEffectiveAt(t *testing.T) {
+	l := Limits{
+		DownloadBytesPerSecond: 5000,
+		ConcurrentReads:        3,
+		Schedule: []ScheduleWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00", Limits: Limits{UploadBytesPerSecond: 2000}},
+			{Days: []string{"fri"}, Start: "22:00", End: "02:00", Limits: Limits{DownloadBytesPerSecond: 100}},
+			{Start: "07:00", End: "09:00", Limits: Limits{ConcurrentReads: 1}},
+			{Days: []string{"sun"}, Start: "00:00", End: "24:00", Limits: Limits{DownloadBytesPerSecond: Unlimited, ConcurrentReads: Unlimited}},
+		},
+	}
+
+	base := Limits{DownloadBytesPerSecond: 5000, ConcurrentReads: 3}
+
+	cases := []struct {
+		when string
+		want Limits
+	}{
+		// 2024-01-01 is a Monday.
+		{"2024-01-01T07:59:00", Limits{DownloadBytesPerSecond: 5000, ConcurrentReads: 1}},
+		{"2024-01-01T08:00:00", Limits{DownloadBytesPerSecond: 5000, ConcurrentReads: 3, UploadBytesPerSecond: 2000}},
+		{"2024-01-01T17:59:59", Limits{DownloadBytesPerSecond: 5000, ConcurrentReads: 3, UploadBytesPerSecond: 2000}},
+		{"2024-01-01T18:00:00", base},
+		{"2024-01-05T23:00:00", Limits{DownloadBytesPerSecond: 100, ConcurrentReads: 3}},
+		{"2024-01-06T01:59:00", Limits{DownloadBytesPerSecond: 100, ConcurrentReads: 3}},
+		{"2024-01-06T02:00:00", base},
+		{"2024-01-06T07:30:00", Limits{DownloadBytesPerSecond: 5000, ConcurrentReads: 1}},
+		{"2024-01-06T10:00:00", base},
+		{"2024-01-01T01:00:00", base},
+		{"2024-01-07T12:00:00", Limits{}},
+	}
+
+	for _, tc := range cases {
+		tm, err := time.ParseInLocation("2006-01-02T15:04:05", tc.when, time.Local)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, l.EffectiveAt(tm), tc.when)
+	}
+}
+
+func TestThrottlerSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 17, 59, 30, 0, time.Local)
+
+	th, err := NewThrottler(Limits{}, time.Second, 1.0)
+	require.NoError(t, err)
+
+	tbt := th.(*tokenBucketBasedThrottler)
+	tbt.now = func() time.Time { return now }
+
+	limits := Limits{
+		Schedule: []ScheduleWindow{
+			{Start: "08:00", End: "18:00", Limits: Limits{UploadBytesPerSecond: 2000}},
+		},
+	}
+
+	require.NoError(t, th.SetLimits(limits))
+
+	// configured limits are returned unchanged.
+	require.Equal(t, limits, th.Limits())
+	require.InDelta(t, 2000.0, tbt.upload.maxTokens, 0.001)
+
+	th.BeforeUpload(ctx, 1)
+	require.InDelta(t, 2000.0, tbt.upload.maxTokens, 0.001)
+
+	// crossing the end of the window removes the limit on next operation.
+	now = now.Add(time.Minute)
+
+	th.BeforeUpload(ctx, 1)
+	require.Zero(t, tbt.upload.maxTokens)
+
+	require.Error(t, th.SetLimits(Limits{Schedule: []ScheduleWindow{{Start: "8", End: "18:00"}}}))
+	require.Equal(t, limits, th.Limits())
+
+	// removing the schedule stops re-evaluation.
+	require.NoError(t, th.SetLimits(Limits{}))
+	require.Zero(t, tbt.nextScheduleCheck.Load())
+}
+
+func TestThrottlerScheduleKeepsConcurrencySlots(t *testing.T) {
+	ctx := context.Background()
+
+	// the clock is also read by the goroutine waiting for a slot.
+	var now atomic.Int64
+
+	now.Store(time.Date(2024, 1, 1, 17, 58, 30, 0, time.Local).UnixNano())
+
+	th, err := NewThrottler(Limits{}, time.Second, 1.0)
+	require.NoError(t, err)
+
+	tbt := th.(*tokenBucketBasedThrottler)
+	tbt.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	require.NoError(t, th.SetLimits(Limits{
+		ConcurrentReads: 2,
+		Schedule: []ScheduleWindow{
+			{Start: "08:00", End: "18:00", Limits: Limits{UploadBytesPerSecond: 2000}},
+		},
+	}))
+
+	th.BeforeOperation(ctx, operationGetBlob)
+	th.BeforeOperation(ctx, operationGetBlob)
+
+	acquired := make(chan struct{})
+
+	go func() {
+		th.BeforeOperation(ctx, operationGetBlob)
+		close(acquired)
+	}()
+
+	// schedule ticks within the same window and into a different one don't release held slots.
+	for range 2 {
+		now.Add(time.Minute.Nanoseconds())
+
+		th.BeforeUpload(ctx, 1)
+
+		select {
+		case <-acquired:
+			t.Fatal("slot acquired while all slots are held")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	th.AfterOperation(ctx, operationGetBlob)
+	<-acquired
+
+	th.AfterOperation(ctx, operationGetBlob)
+	th.AfterOperation(ctx, operationGetBlob)
+
+	tbt.concurrentReads.mu.Lock()
+	defer tbt.concurrentReads.mu.Unlock()
+
+	require.Zero(t, tbt.concurrentReads.inUse)
+}
@@ -2,10 +2,14 @@
 
 import (
 	"context"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
 )
 
 // SettableThrottler exposes methods to set throttling limits.
@@ -25,6 +29,10 @@ type tokenBucketBasedThrottler struct {
 	// +checklocks:mu
 	limits Limits
 
+	// limits currently applied to token buckets and semaphores.
+	// +checklocks:mu
+	effective Limits
+
 	readOps  *tokenBucket
 	writeOps *tokenBucket
 	listOps  *tokenBucket
@@ -36,10 +44,32 @@ type tokenBucketBasedThrottler struct {
 
 	window time.Duration // +checklocksignore
 
+	now func() time.Time // +checklocksignore
+
+	// time (in Unix nanoseconds) when schedule windows need to be re-evaluated, zero if limits have no schedule.
+	nextScheduleCheck atomic.Int64
+
 	onUpdate []UpdatedHandler
 }
 
+// maybeApplySchedule re-applies effective limits when the schedule may have moved to a different window.
+func (t *tokenBucketBasedThrottler) maybeApplySchedule(ctx context.Context) {
+	next := t.nextScheduleCheck.Load()
+	if next == 0 || t.now().UnixNano() < next {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.applyLimitsLocked(t.limits, true); err != nil {
+		log(ctx).Errorf("unable to apply scheduled throttling limits: %v", err)
+	}
+}
+
 func (t *tokenBucketBasedThrottler) BeforeOperation(ctx context.Context, op string) {
+	t.maybeApplySchedule(ctx)
+
 	switch op {
 	case operationListBlobs:
 		t.listOps.Take(ctx, 1)
@@ -63,6 +93,7 @@ func (t *tokenBucketBasedThrottler) AfterOperation(_ context.Context, op string)
 }
 
 func (t *tokenBucketBasedThrottler) BeforeDownload(ctx context.Context, numBytes int64) {
+	t.maybeApplySchedule(ctx)
 	t.download.Take(ctx, float64(numBytes))
 }
 
@@ -71,6 +102,7 @@ func (t *tokenBucketBasedThrottler) ReturnUnusedDownloadBytes(ctx context.Contex
 }
 
 func (t *tokenBucketBasedThrottler) BeforeUpload(ctx context.Context, numBytes int64) {
+	t.maybeApplySchedule(ctx)
 	t.upload.Take(ctx, float64(numBytes))
 }
 
@@ -86,8 +118,14 @@ func (t *tokenBucketBasedThrottler) SetLimits(limits Limits) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if err := t.setLimits(limits); err != nil {
-		_ = t.setLimits(t.limits)
+	for i, w := range limits.Schedule {
+		if err := w.Validate(); err != nil {
+			return errors.Wrapf(err, "schedule window #%v", i+1)
+		}
+	}
+
+	if err := t.applyLimitsLocked(limits, false); err != nil {
+		_ = t.applyLimitsLocked(t.limits, false)
 		return err
 	}
 
@@ -102,6 +140,34 @@ func (t *tokenBucketBasedThrottler) SetLimits(limits Limits) error {
 	return nil
 }
 
+// applyLimitsLocked applies limits effective at the current time and schedules the next re-evaluation.
+// When onlyIfChanged is true, nothing is applied unless effective limits differ from the ones currently applied.
+//
+// +checklocks:t.mu
+func (t *tokenBucketBasedThrottler) applyLimitsLocked(limits Limits, onlyIfChanged bool) error {
+	now := t.now()
+
+	if len(limits.Schedule) == 0 {
+		t.nextScheduleCheck.Store(0)
+	} else {
+		// windows have minute granularity, re-evaluate at the beginning of the next minute.
+		t.nextScheduleCheck.Store(now.Truncate(time.Minute).Add(time.Minute).UnixNano())
+	}
+
+	effective := limits.EffectiveAt(now)
+	if onlyIfChanged && reflect.DeepEqual(effective, t.effective) {
+		return nil
+	}
+
+	if err := t.setLimits(effective); err != nil {
+		return err
+	}
+
+	t.effective = effective
+
+	return nil
+}
+
 func (t *tokenBucketBasedThrottler) setLimits(limits Limits) error {
 	if err := t.readOps.SetLimit(limits.ReadsPerSecond * t.window.Seconds()); err != nil {
 		return errors.Wrap(err, "ReadsPerSecond")
@@ -147,6 +213,9 @@ type Limits struct {
 	DownloadBytesPerSecond float64 `json:"maxDownloadSpeedBytesPerSecond,omitempty"`
 	ConcurrentReads        int     `json:"concurrentReads,omitempty"`
 	ConcurrentWrites       int     `json:"concurrentWrites,omitempty"`
+
+	// Schedule contains time windows during which different limits apply.
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
 }
 
 var _ Throttler = (*tokenBucketBasedThrottler)(nil)
@@ -162,6 +231,7 @@ func NewThrottler(limits Limits, window time.Duration, initialFillRatio float64)
 		concurrentReads:  newSemaphore(),
 		concurrentWrites: newSemaphore(),
 		window:           window,
+		now:              clock.Now,
 	}
 
 	if err := t.SetLimits(limits); err != nil {
@@ -6,39 +6,39 @@
 	"github.com/pkg/errors"
 )
 
+// semaphore limits the number of concurrent operations, the limit can be changed at any time
+// without affecting operations that are in progress or waiting.
 type semaphore struct {
-	mu sync.Mutex
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// +checklocks:mu
+	limit int // zero means unlimited
 	// +checklocks:mu
-	sem chan struct{}
+	inUse int
 }
 
-func (s *semaphore) getChan() chan struct{} {
+func (s *semaphore) Acquire() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.sem
-}
-
-func (s *semaphore) Acquire() {
-	ch := s.getChan()
-	if ch == nil {
-		return
+	// wait until there's room, may block
+	for s.limit > 0 && s.inUse >= s.limit {
+		s.cond.Wait()
 	}
 
-	// push to channel, may block
-	ch <- struct{}{}
+	s.inUse++
 }
 
 func (s *semaphore) Release() {
-	ch := s.getChan()
-	if ch == nil {
-		return
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	select {
-	case <-ch: // removed one entry from channel
-	default: // this can happen when we reset a channel to a lower value
+	if s.inUse > 0 {
+		s.inUse--
 	}
+
+	s.cond.Signal()
 }
 
 func (s *semaphore) SetLimit(limit int) error {
@@ -49,15 +49,17 @@ func (s *semaphore) SetLimit(limit int) error {
 		return errors.New("invalid limit")
 	}
 
-	if limit > 0 {
-		s.sem = make(chan struct{}, limit)
-	} else {
-		s.sem = nil
-	}
+	s.limit = limit
+
+	// the limit may have been raised, let all waiters re-check.
+	s.cond.Broadcast()
 
 	return nil
 }
 
 func newSemaphore() *semaphore {
-	return &semaphore{}
+	s := &semaphore{}
+	s.cond = sync.NewCond(&s.mu)
+
+	return s
 }
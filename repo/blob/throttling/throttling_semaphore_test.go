@@ -61,3 +61,42 @@ func TestThrottlingSemaphore(t *testing.T) {
 		require.Positive(t, maxConcurrency)
 	}
 }
+
+func TestThrottlingSemaphoreSetLimitWhileHeld(t *testing.T) {
+	s := newSemaphore()
+	require.NoError(t, s.SetLimit(2))
+
+	s.Acquire()
+	s.Acquire()
+
+	acquired := make(chan struct{})
+
+	go func() {
+		s.Acquire()
+		close(acquired)
+	}()
+
+	// lowering and restoring the limit keeps track of slots in use.
+	require.NoError(t, s.SetLimit(1))
+	require.NoError(t, s.SetLimit(2))
+
+	select {
+	case <-acquired:
+		t.Fatal("slot acquired while all slots are held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Release()
+	<-acquired
+
+	// raising the limit wakes up waiters.
+	blocked := make(chan struct{})
+
+	go func() {
+		s.Acquire()
+		close(blocked)
+	}()
+
+	require.NoError(t, s.SetLimit(3))
+	<-blocked
+}
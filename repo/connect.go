@@ -3,6 +3,7 @@
 import (
 	"context"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 
@@ -53,6 +54,16 @@ func Connect(ctx context.Context, configFile string, st blob.Storage, password s
 	lc.Storage = &ci
 	lc.ClientOptions = opt.ApplyDefaults(ctx, "Repository in "+st.DisplayName())
 
+	if lc.LocalReplica != "" {
+		if lc.LocalReplica, err = filepath.Abs(lc.LocalReplica); err != nil {
+			return errors.Wrap(err, "unable to resolve local replica path")
+		}
+
+		if err := os.MkdirAll(lc.LocalReplica, 0o700); err != nil { //nolint:mnd
+			return errors.Wrap(err, "unable to create local replica directory")
+		}
+	}
+
 	if err = setupCachingOptionsWithDefaults(ctx, configFile, &lc, &opt.CachingOptions, f.UniqueID); err != nil {
 		return errors.Wrap(err, "unable to set up caching")
 	}
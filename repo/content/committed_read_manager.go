@@ -100,6 +100,11 @@ type SharedManager struct {
 
 	format format.Provider
 
+	packChecksumsMutex sync.Mutex
+	// decoded contents of pack checksum blobs, which are immutable.
+	// +checklocks:packChecksumsMutex
+	packChecksumsCache map[blob.ID]PackChecksums
+
 	checkInvariantsOnUnlock bool
 	minPreambleLength       int
 	maxPreambleLength       int
@@ -628,6 +633,10 @@ func NewSharedManager(ctx context.Context, st blob.Storage, prov format.Provider
 
 	sm.log = sm.repoLogManager.NewLogger("shared-manager")
 
+	if opts.LocalReplica != nil {
+		sm.st = newLocalReplicaStorage(st, opts.LocalReplica, sm.ReadPackChecksums)
+	}
+
 	caching = caching.CloneOrDefault()
 
 	if err := sm.setupCachesAndIndexManagers(ctx, caching, mr); err != nil {
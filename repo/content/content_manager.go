@@ -91,6 +91,8 @@ type WriteManager struct {
 	failedPacks []*pendingPackInfo // list of packs that failed to write, will be retried
 	// +checklocks:mu
 	packIndexBuilder index.Builder // contents that are in index currently being built (all packs saved but not committed)
+	// +checklocks:mu
+	pendingPackChecksums PackChecksums // checksums of packs written but not yet recorded in storage
 
 	// +checklocks:mu
 	disableIndexFlushCount int
@@ -110,6 +112,7 @@ type pendingPackInfo struct {
 	currentPackItems map[ID]Info         // contents that are in the pack content currently being built (all inline)
 	currentPackData  *gather.WriteBuffer // total length of all items in the current pack content
 	finalized        bool                // indicates whether currentPackData has local index appended to it
+	checksums        []blob.Checksum     // checksums of the written pack, if enabled
 }
 
 // Revision returns data revision number that changes on each write or refresh.
@@ -472,6 +475,10 @@ func (bm *WriteManager) flushPackIndexesLocked(ctx context.Context, mp format.Mu
 		bm.indexesLock.RLock()
 		defer bm.indexesLock.RUnlock()
 
+		if err := bm.writePackChecksumsLocked(ctx); err != nil {
+			return errors.Wrap(err, "error writing pack checksums")
+		}
+
 		indexBlobMDs, err := bm.writeIndexBlobs(ctx, dataShards, bm.currentSessionInfo.ID)
 		if err != nil {
 			return errors.Wrap(err, "error writing index blob")
@@ -545,6 +552,10 @@ func (bm *WriteManager) processWritePackResultLocked(pp *pendingPackInfo, packFi
 			bm.packIndexBuilder.Add(info)
 		}
 
+		if len(pp.checksums) > 0 {
+			bm.pendingPackChecksums[pp.packBlobID] = pp.checksums
+		}
+
 		pp.currentPackData.Close()
 
 		return nil
@@ -571,6 +582,15 @@ func (sm *SharedManager) prepareAndWritePackInternal(ctx context.Context, pp *pe
 	}
 
 	if pp.currentPackData.Length() > 0 {
+		if mp.PackChecksums {
+			cs, err := blob.ComputeChecksums(pp.currentPackData.Bytes(), blob.AllChecksumAlgorithms...)
+			if err != nil {
+				return nil, errors.Wrap(err, "error computing pack checksums")
+			}
+
+			pp.checksums = cs
+		}
+
 		if err := sm.writePackFileNotLocked(ctx, pp.packBlobID, pp.currentPackData.Bytes(), onUpload); err != nil {
 			return nil, errors.Wrapf(err, "can't save pack data blob %v", pp.packBlobID)
 		}
@@ -961,6 +981,7 @@ func (bm *WriteManager) MetadataCache() cache.ContentCache {
 type ManagerOptions struct {
 	TimeNow                func() time.Time // Time provider
 	PermissiveCacheLoading bool
+	LocalReplica           blob.Storage // Local replica of the repository used to read pack blobs
 }
 
 // CloneOrDefault returns a clone of provided ManagerOptions or default empty struct if nil.
@@ -1008,6 +1029,7 @@ func NewWriteManager(_ context.Context, sm *SharedManager, options SessionOption
 		flushPackIndexesAfter: sm.timeNow().Add(flushPackIndexTimeout),
 		pendingPacks:          map[blob.ID]*pendingPackInfo{},
 		packIndexBuilder:      make(index.Builder),
+		pendingPackChecksums:  PackChecksums{},
 		sessionUser:           options.SessionUser,
 		sessionHost:           options.SessionHost,
 		onUpload: func(numBytes int64) {
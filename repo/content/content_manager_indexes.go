@@ -4,6 +4,9 @@
 	"context"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/internal/blobcrypto"
 	"github.com/kopia/kopia/internal/contentlog"
@@ -41,6 +44,12 @@ func (sm *SharedManager) Refresh(ctx context.Context) error {
 
 // CompactIndexes performs compaction of index blobs ensuring that # of small index blobs is below opt.maxSmallBlobs.
 func (sm *SharedManager) CompactIndexes(ctx context.Context, opt indexblob.CompactOptions) (*maintenancestats.CompactIndexesStats, error) {
+	ctx, span := tracer.Start(ctx, "CompactIndexes", trace.WithAttributes(
+		attribute.Bool("allIndexes", opt.AllIndexes),
+		attribute.Int("maxSmallBlobs", opt.MaxSmallBlobs),
+	))
+	defer span.End()
+
 	// we must hold the lock here to avoid the race with Refresh() which can reload the
 	// current set of indexes while we process them.
 	sm.indexesLock.Lock()
@@ -59,6 +68,9 @@ func (sm *SharedManager) CompactIndexes(ctx context.Context, opt indexblob.Compa
 
 	stats, err := ibm.Compact(ctx, opt)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error performing compaction")
+
 		return nil, errors.Wrap(err, "error performing compaction")
 	}
 
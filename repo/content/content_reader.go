@@ -21,4 +21,5 @@ type Reader interface {
 	ListActiveSessions(ctx context.Context) (map[SessionID]*SessionInfo, error)
 	EpochManager(ctx context.Context) (*epoch.Manager, bool, error)
 	VerifyContents(ctx context.Context, o VerifyOptions) error
+	ReadPackChecksums(ctx context.Context) (PackChecksums, error)
 }
@@ -0,0 +1,209 @@
+package content
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// localReplicaStorage serves reads of pack blobs from a local replica of the repository
+// (such as one maintained by 'kopia repository sync-to'), falling back to the remote storage for
+// packs that are missing from the replica or fail verification. Packs written through it are
+// also written to the replica.
+type localReplicaStorage struct {
+	blob.Storage // remote storage
+
+	local         blob.Storage
+	packChecksums func(ctx context.Context) (PackChecksums, error)
+
+	// fetchMu serializes fetching of pack checksums without blocking other operations on mu.
+	fetchMu sync.Mutex
+
+	mu sync.Mutex
+	// +checklocks:mu
+	checksums PackChecksums
+	// +checklocks:mu
+	verified map[blob.ID]bool // verification outcome of each replica pack
+}
+
+func newLocalReplicaStorage(remote, local blob.Storage, packChecksums func(ctx context.Context) (PackChecksums, error)) *localReplicaStorage {
+	return &localReplicaStorage{
+		Storage:       remote,
+		local:         local,
+		packChecksums: packChecksums,
+		verified:      map[blob.ID]bool{},
+	}
+}
+
+func isPackBlobID(id blob.ID) bool {
+	for _, p := range PackBlobIDPrefixes {
+		if id != "" && id[0:1] == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *localReplicaStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	if isPackBlobID(id) && s.isVerified(ctx, id) {
+		err := s.local.GetBlob(ctx, id, offset, length, output)
+		if err == nil {
+			return nil
+		}
+
+		log(ctx).Debugf("unable to read %v from local replica, falling back to remote: %v", id, err)
+
+		output.Reset()
+	}
+
+	return s.Storage.GetBlob(ctx, id, offset, length, output) //nolint:wrapcheck
+}
+
+func (s *localReplicaStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if err := s.Storage.PutBlob(ctx, id, data, opts); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if !isPackBlobID(id) {
+		return nil
+	}
+
+	// keep the replica up-to-date, failures are not fatal since reads fall back to remote.
+	if err := s.local.PutBlob(ctx, id, data, blob.PutOptions{}); err != nil {
+		log(ctx).Warnf("unable to write %v to local replica: %v", id, err)
+		return nil
+	}
+
+	s.mu.Lock()
+	s.verified[id] = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *localReplicaStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if err := s.Storage.DeleteBlob(ctx, id); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if !isPackBlobID(id) {
+		return nil
+	}
+
+	s.mu.Lock()
+	delete(s.verified, id)
+	s.mu.Unlock()
+
+	if err := s.local.DeleteBlob(ctx, id); err != nil {
+		log(ctx).Warnf("unable to delete %v from local replica: %v", id, err)
+	}
+
+	return nil
+}
+
+// isVerified determines whether the replica copy of a pack can be used, the outcome is cached
+// for the lifetime of the storage.
+func (s *localReplicaStorage) isVerified(ctx context.Context, id blob.ID) bool {
+	s.mu.Lock()
+	v, ok := s.verified[id]
+	s.mu.Unlock()
+
+	if ok {
+		return v
+	}
+
+	err := s.verify(ctx, id)
+	if err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+		log(ctx).Warnf("local replica of %v is not usable: %v", id, err)
+	}
+
+	s.mu.Lock()
+	s.verified[id] = err == nil
+	s.mu.Unlock()
+
+	return err == nil
+}
+
+// verify ensures that the replica of a pack is identical to the remote one by comparing it
+// against checksums recorded when the pack was written or, when those are not available,
+// against checksums reported by the remote storage. Replicas that can't be verified are not used.
+func (s *localReplicaStorage) verify(ctx context.Context, id blob.ID) error {
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	if err := s.local.GetBlob(ctx, id, 0, -1, &data); err != nil {
+		return errors.Wrap(err, "error reading replica")
+	}
+
+	checksums, err := s.getPackChecksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	want := checksums[id]
+	if len(want) == 0 {
+		bm, err := s.Storage.GetMetadata(ctx, id)
+		if err != nil {
+			return errors.Wrap(err, "error getting remote metadata")
+		}
+
+		want = bm.Checksums
+	}
+
+	if len(want) == 0 {
+		return errors.New("no checksums available")
+	}
+
+	var algorithms []blob.ChecksumAlgorithm
+	for _, w := range want {
+		algorithms = append(algorithms, w.Algorithm)
+	}
+
+	actual, err := blob.ComputeChecksums(data.Bytes(), algorithms...)
+	if err != nil {
+		return errors.Wrap(err, "error computing checksums")
+	}
+
+	if compared, mismatches := blob.CompareChecksums(want, actual); compared == 0 || len(mismatches) > 0 {
+		return errors.Errorf("checksum mismatch: %v", mismatches)
+	}
+
+	return nil
+}
+
+func (s *localReplicaStorage) getPackChecksums(ctx context.Context) (PackChecksums, error) {
+	if pc := s.cachedPackChecksums(); pc != nil {
+		return pc, nil
+	}
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	// another goroutine may have fetched checksums while we were waiting.
+	if pc := s.cachedPackChecksums(); pc != nil {
+		return pc, nil
+	}
+
+	pc, err := s.packChecksums(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading pack checksums")
+	}
+
+	s.mu.Lock()
+	s.checksums = pc
+	s.mu.Unlock()
+
+	return pc, nil
+}
+
+func (s *localReplicaStorage) cachedPackChecksums() PackChecksums {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checksums
+}
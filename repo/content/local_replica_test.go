@@ -0,0 +1,130 @@
+package content
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestLocalReplicaStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	remoteData := blobtesting.DataMap{}
+	localData := blobtesting.DataMap{}
+
+	remote := &checksummingStorage{Storage: blobtesting.NewMapStorage(remoteData, nil, clock.Now), reads: map[blob.ID]int{}}
+	local := blobtesting.NewMapStorage(localData, nil, clock.Now)
+
+	good := []byte("good pack contents")
+
+	remoteData["pverified"] = good
+	remoteData["pcorrupt"] = good
+	remoteData["punrecorded"] = good
+	remoteData["pshorter"] = good
+	remoteData["pmissing"] = good
+	remoteData["premote"] = []byte("remote checksummed")
+	remoteData["xindex"] = good
+
+	localData["pverified"] = []byte("local pack contents!")
+	localData["pcorrupt"] = []byte("bad pack contents!")
+	localData["punrecorded"] = []byte("local pack conten2")
+	localData["pshorter"] = []byte("shorty")
+	localData["premote"] = []byte("remote checksummed")
+	localData["xindex"] = []byte("local index blob")
+
+	checksums, err := blob.ComputeChecksums(gather.FromSlice(localData["pverified"]), blob.AllChecksumAlgorithms...)
+	require.NoError(t, err)
+
+	numChecksumReads := 0
+
+	st := newLocalReplicaStorage(remote, local, func(ctx context.Context) (PackChecksums, error) {
+		numChecksumReads++
+
+		return PackChecksums{
+			"pverified": checksums,
+			"pcorrupt":  checksums,
+		}, nil
+	})
+
+	// replica matching recorded checksums is used.
+	blobtesting.AssertGetBlob(ctx, t, st, "pverified", localData["pverified"])
+
+	// replica not matching recorded checksum is not used.
+	blobtesting.AssertGetBlob(ctx, t, st, "pcorrupt", good)
+
+	// without recorded or remote checksums the replica can't be verified and remote is used,
+	// even if its length matches.
+	blobtesting.AssertGetBlob(ctx, t, st, "punrecorded", good)
+	blobtesting.AssertGetBlob(ctx, t, st, "pshorter", good)
+
+	// checksums reported by the remote storage are used when none were recorded.
+	remote.withChecksums = true
+
+	blobtesting.AssertGetBlob(ctx, t, st, "premote", localData["premote"])
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	remote.reads["premote"] = 0
+
+	require.NoError(t, st.GetBlob(ctx, "premote", 0, -1, &tmp))
+	require.Zero(t, remote.reads["premote"])
+
+	// missing replica and non-pack blobs are read from remote.
+	blobtesting.AssertGetBlob(ctx, t, st, "pmissing", good)
+	blobtesting.AssertGetBlob(ctx, t, st, "xindex", good)
+
+	require.Equal(t, 1, numChecksumReads)
+
+	// writes of packs go to both storages, deletes remove from both.
+	require.NoError(t, st.PutBlob(ctx, "pnew", gather.FromSlice([]byte{1, 2, 3, 4}), blob.PutOptions{}))
+	require.NoError(t, st.PutBlob(ctx, "qnew", gather.FromSlice([]byte{1, 2, 3, 4}), blob.PutOptions{}))
+	require.NoError(t, st.PutBlob(ctx, "xnew", gather.FromSlice([]byte{1, 2, 3, 4}), blob.PutOptions{}))
+
+	require.Contains(t, localData, blob.ID("pnew"))
+	require.Contains(t, localData, blob.ID("qnew"))
+	require.NotContains(t, localData, blob.ID("xnew"))
+
+	require.NoError(t, st.DeleteBlob(ctx, "pnew"))
+	require.NotContains(t, localData, blob.ID("pnew"))
+	require.NotContains(t, remoteData, blob.ID("pnew"))
+}
+
+// checksummingStorage counts reads and optionally reports checksums in blob metadata, like some cloud providers do.
+type checksummingStorage struct {
+	blob.Storage
+
+	withChecksums bool
+	reads         map[blob.ID]int
+}
+
+func (s *checksummingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	s.reads[id]++
+
+	return s.Storage.GetBlob(ctx, id, offset, length, output)
+}
+
+func (s *checksummingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err != nil || !s.withChecksums {
+		return bm, err
+	}
+
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	if err := s.Storage.GetBlob(ctx, id, 0, -1, &data); err != nil {
+		return bm, err
+	}
+
+	bm.Checksums, err = blob.ComputeChecksums(data.Bytes(), blob.ChecksumMD5)
+
+	return bm, err
+}
@@ -0,0 +1,220 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/blobcrypto"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/maintenancestats"
+)
+
+// BlobIDPrefixPackChecksums is the prefix for blobs holding checksums of pack blobs,
+// computed when the packs were written.
+// Each blob ID will consist of {prefix}{hash}-{sessionID}.
+//
+// Checksums are kept outside of the index, since index entries describe individual contents
+// and their format can't be extended without breaking older clients. Checksum blobs are
+// merged by maintenance, which also drops checksums of packs that no longer exist.
+const BlobIDPrefixPackChecksums blob.ID = "c"
+
+// PackChecksums maps pack blob IDs to checksums computed locally when the pack was written.
+type PackChecksums map[blob.ID][]blob.Checksum
+
+// writePackChecksumsLocked writes checksums of all packs written since last flush.
+// +checklocks:bm.mu
+func (bm *WriteManager) writePackChecksumsLocked(ctx context.Context) error {
+	if len(bm.pendingPackChecksums) == 0 {
+		return nil
+	}
+
+	js, err := json.Marshal(bm.pendingPackChecksums)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize pack checksums")
+	}
+
+	var encrypted gather.WriteBuffer
+	defer encrypted.Close()
+
+	blobID, err := blobcrypto.Encrypt(bm.format, gather.FromSlice(js), BlobIDPrefixPackChecksums, blob.ID(bm.currentSessionInfo.ID), &encrypted)
+	if err != nil {
+		return errors.Wrap(err, "unable to encrypt pack checksums")
+	}
+
+	bm.onUpload(int64(encrypted.Length()))
+
+	if err := bm.st.PutBlob(ctx, blobID, encrypted.Bytes(), blob.PutOptions{}); err != nil {
+		return errors.Wrapf(err, "unable to write pack checksums: %v", blobID)
+	}
+
+	bm.pendingPackChecksums = PackChecksums{}
+
+	return nil
+}
+
+// ReadPackChecksums reads checksums of all pack blobs recorded in the repository.
+// Packs written while checksum recording was disabled are not included.
+//
+// Pack checksum blobs are immutable, so their decoded contents are cached and only
+// blobs written since the previous call are fetched from storage.
+func (sm *SharedManager) ReadPackChecksums(ctx context.Context) (PackChecksums, error) {
+	perBlob, err := sm.readPackChecksumBlobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := PackChecksums{}
+
+	for _, pc := range perBlob {
+		for k, v := range pc {
+			result[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+// readPackChecksumBlobs returns the decoded contents of all pack checksum blobs keyed by blob ID.
+func (sm *SharedManager) readPackChecksumBlobs(ctx context.Context) (map[blob.ID]PackChecksums, error) {
+	blobs, err := blob.ListAllBlobs(ctx, sm.st, BlobIDPrefixPackChecksums)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list pack checksum blobs")
+	}
+
+	sm.packChecksumsMutex.Lock()
+	defer sm.packChecksumsMutex.Unlock()
+
+	result := map[blob.ID]PackChecksums{}
+
+	for _, b := range blobs {
+		if pc, ok := sm.packChecksumsCache[b.BlobID]; ok {
+			result[b.BlobID] = pc
+			continue
+		}
+
+		pc, err := sm.loadPackChecksumBlob(ctx, b.BlobID)
+		if err != nil {
+			if errors.Is(err, blob.ErrBlobNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		result[b.BlobID] = pc
+	}
+
+	// replace the cache to drop entries for blobs that were compacted away.
+	sm.packChecksumsCache = result
+
+	return result, nil
+}
+
+func (sm *SharedManager) loadPackChecksumBlob(ctx context.Context, blobID blob.ID) (PackChecksums, error) {
+	var payload gather.WriteBuffer
+	defer payload.Close()
+
+	var decrypted gather.WriteBuffer
+	defer decrypted.Close()
+
+	if err := sm.st.GetBlob(ctx, blobID, 0, -1, &payload); err != nil {
+		return nil, errors.Wrapf(err, "error loading pack checksums: %v", blobID)
+	}
+
+	if err := blobcrypto.Decrypt(sm.format, payload.Bytes(), blobID, &decrypted); err != nil {
+		return nil, errors.Wrapf(err, "error decrypting pack checksums: %v", blobID)
+	}
+
+	var pc PackChecksums
+
+	if err := json.NewDecoder(decrypted.Bytes().Reader()).Decode(&pc); err != nil {
+		return nil, errors.Wrapf(err, "error parsing pack checksums: %v", blobID)
+	}
+
+	return pc, nil
+}
+
+// CompactPackChecksums merges all pack checksum blobs into a single blob, dropping checksums
+// of pack blobs which no longer exist, and deletes the merged blobs.
+func (bm *WriteManager) CompactPackChecksums(ctx context.Context) (*maintenancestats.CompactPackChecksumsStats, error) {
+	perBlob, err := bm.readPackChecksumBlobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &maintenancestats.CompactPackChecksumsStats{}
+
+	if len(perBlob) == 0 {
+		return stats, nil
+	}
+
+	// packs are always written before the checksum blob which references them,
+	// so listing them after checksum blobs sees every pack that was recorded.
+	existingPacks := map[blob.ID]bool{}
+
+	for _, prefix := range PackBlobIDPrefixes {
+		if err := bm.st.ListBlobs(ctx, prefix, func(m blob.Metadata) error {
+			existingPacks[m.BlobID] = true
+			return nil
+		}); err != nil {
+			return nil, errors.Wrap(err, "error listing pack blobs")
+		}
+	}
+
+	merged := PackChecksums{}
+
+	for _, pc := range perBlob {
+		for k, v := range pc {
+			if !existingPacks[k] {
+				stats.DroppedChecksumCount++
+				continue
+			}
+
+			merged[k] = v
+		}
+	}
+
+	stats.RetainedChecksumCount = uint64(len(merged))
+
+	if len(perBlob) == 1 && stats.DroppedChecksumCount == 0 {
+		return stats, nil
+	}
+
+	var outputBlobID blob.ID
+
+	if len(merged) > 0 {
+		js, err := json.Marshal(merged)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to serialize pack checksums")
+		}
+
+		var encrypted gather.WriteBuffer
+		defer encrypted.Close()
+
+		outputBlobID, err = blobcrypto.Encrypt(bm.format, gather.FromSlice(js), BlobIDPrefixPackChecksums, "", &encrypted)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to encrypt pack checksums")
+		}
+
+		if err := bm.st.PutBlob(ctx, outputBlobID, encrypted.Bytes(), blob.PutOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "unable to write pack checksums: %v", outputBlobID)
+		}
+	}
+
+	for blobID := range perBlob {
+		if blobID == outputBlobID {
+			continue
+		}
+
+		if err := bm.st.DeleteBlob(ctx, blobID); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			return nil, errors.Wrapf(err, "unable to delete compacted pack checksums: %v", blobID)
+		}
+
+		stats.CompactedBlobCount++
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,72 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func (s *contentManagerSuite) TestCompactPackChecksums(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+
+	withChecksums := *s
+	withChecksums.mutableParameters.PackChecksums = true
+
+	bm := withChecksums.newTestContentManager(t, st)
+
+	for _, b := range [][]byte{seededRandomData(10, 100), seededRandomData(11, 100), seededRandomData(12, 100)} {
+		_, err := bm.WriteContent(ctx, gather.FromSlice(b), "", NoCompression)
+		require.NoError(t, err)
+		require.NoError(t, bm.Flush(ctx))
+	}
+
+	checksumBlobs, err := blob.ListAllBlobs(ctx, st, BlobIDPrefixPackChecksums)
+	require.NoError(t, err)
+	require.Len(t, checksumBlobs, 3)
+
+	checksums, err := bm.ReadPackChecksums(ctx)
+	require.NoError(t, err)
+	require.Len(t, checksums, 3)
+
+	// delete one of the packs, its checksum should be dropped.
+	var deletedPack blob.ID
+
+	for packID := range checksums {
+		deletedPack = packID
+		break
+	}
+
+	require.NoError(t, st.DeleteBlob(ctx, deletedPack))
+
+	stats, err := bm.CompactPackChecksums(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, stats.CompactedBlobCount)
+	require.EqualValues(t, 2, stats.RetainedChecksumCount)
+	require.EqualValues(t, 1, stats.DroppedChecksumCount)
+
+	checksumBlobs, err = blob.ListAllBlobs(ctx, st, BlobIDPrefixPackChecksums)
+	require.NoError(t, err)
+	require.Len(t, checksumBlobs, 1)
+
+	checksums, err = bm.ReadPackChecksums(ctx)
+	require.NoError(t, err)
+	require.Len(t, checksums, 2)
+	require.NotContains(t, checksums, deletedPack)
+
+	// compacting a single blob without any changes is a no-op.
+	stats, err = bm.CompactPackChecksums(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, stats.CompactedBlobCount)
+	require.EqualValues(t, 2, stats.RetainedChecksumCount)
+
+	checksumBlobs2, err := blob.ListAllBlobs(ctx, st, BlobIDPrefixPackChecksums)
+	require.NoError(t, err)
+	require.Equal(t, checksumBlobs, checksumBlobs2)
+}
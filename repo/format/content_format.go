@@ -63,10 +63,11 @@ func (f *ContentFormat) SupportsPasswordChange() bool {
 // MutableParameters represents parameters of the content manager that can be mutated after the repository
 // is created.
 type MutableParameters struct {
-	Version         Version          `json:"version,omitempty"`      // version number, must be "1", "2" or "3"
-	MaxPackSize     int              `json:"maxPackSize,omitempty"`  // maximum size of a pack object
-	IndexVersion    int              `json:"indexVersion,omitempty"` // force particular index format version (1,2,..)
-	EpochParameters epoch.Parameters `json:"epochParameters"`        // epoch manager parameters
+	Version         Version          `json:"version,omitempty"`       // version number, must be "1", "2" or "3"
+	MaxPackSize     int              `json:"maxPackSize,omitempty"`   // maximum size of a pack object
+	IndexVersion    int              `json:"indexVersion,omitempty"`  // force particular index format version (1,2,..)
+	EpochParameters epoch.Parameters `json:"epochParameters"`         // epoch manager parameters
+	PackChecksums   bool             `json:"packChecksums,omitempty"` // record checksums of pack blobs when writing them
 }
 
 // Validate validates the parameters.
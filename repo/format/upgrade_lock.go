@@ -23,6 +23,10 @@
 // on a repository that is already using the latest format version.
 var ErrFormatUptoDate = errors.New("repository format is up to date") // +checklocksignore
 
+// ErrRepositoryUnavailableDueToUpgradeInProgress is returned when repository
+// is undergoing upgrade that requires exclusive access.
+var ErrRepositoryUnavailableDueToUpgradeInProgress = errors.New("repository upgrade in progress") // +checklocksignore
+
 // BackupBlobID gets the upgrade backup blob-id from the lock.
 func BackupBlobID(l UpgradeLockIntent) blob.ID {
 	return blob.ID(BackupBlobIDPrefix + l.OwnerID)
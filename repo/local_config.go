@@ -39,6 +39,10 @@ type ClientOptions struct {
 	FormatBlobCacheDuration time.Duration `json:"formatBlobCacheDuration,omitempty"`
 
 	Throttling *throttling.Limits `json:"throttlingLimits,omitempty"`
+
+	// LocalReplica is the path to a local copy of the repository blobs, which is consulted
+	// before the repository storage when reading pack blobs.
+	LocalReplica string `json:"localReplica,omitempty"`
 }
 
 // ApplyDefaults returns a copy of ClientOptions with defaults filled out.
@@ -15,5 +15,6 @@ func GetLockingStoragePrefixes() []blob.ID {
 		blob.ID(epoch.EpochManagerIndexUberPrefix),
 		blob.ID(format.KopiaRepositoryBlobID),
 		blob.ID(format.KopiaBlobCfgBlobID),
+		content.BlobIDPrefixPackChecksums,
 	}, content.PackBlobIDPrefixes...)
 }
@@ -8,6 +8,10 @@
 
 	"github.com/gofrs/flock"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/contentlog"
@@ -23,6 +27,12 @@
 // User-visible log output.
 var userLog = logging.Module("maintenance")
 
+var tracer = otel.Tracer("kopia/maintenance")
+
+// ErrStorageFaultsInjected is returned when maintenance is attempted while storage faults are being
+// injected, since injected failures could cause maintenance to delete live data.
+var ErrStorageFaultsInjected = errors.New("maintenance is disabled while storage faults are injected")
+
 const maxClockSkew = 5 * time.Minute
 
 // Mode describes the mode of maintenance to perform.
@@ -48,6 +58,7 @@
 	TaskRewriteContentsFull          = "full-rewrite-contents"
 	TaskDropDeletedContentsFull      = "full-drop-deleted-content"
 	TaskIndexCompaction              = "index-compaction"
+	TaskCompactPackChecksums         = "compact-pack-checksums"
 	TaskExtendBlobRetentionTimeFull  = "extend-blob-retention-time"
 	TaskCleanupLogs                  = "cleanup-logs"
 	TaskEpochAdvance                 = "advance-epoch"
@@ -156,6 +167,15 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 	ctx = contentlog.WithParams(ctx,
 		logparam.String("span:maintenance", contentlog.RandomSpanID()))
 
+	if rep.StorageFaultsInjected() {
+		if mode == ModeAuto {
+			userLog(ctx).Debug("skipping automatic maintenance while storage faults are injected")
+			return nil
+		}
+
+		return ErrStorageFaultsInjected
+	}
+
 	rep.DisableIndexRefresh()
 
 	p, err := GetParams(ctx, rep)
@@ -244,6 +264,19 @@ func checkClockSkewBounds(rp RunParameters) error {
 
 // Run performs maintenance activities for a repository.
 func Run(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
+	ctx, span := tracer.Start(ctx, "Maintenance", trace.WithAttributes(attribute.String("mode", string(runParams.Mode))))
+	defer span.End()
+
+	err := runMaintenance(ctx, runParams, safety)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "maintenance failed")
+	}
+
+	return err
+}
+
+func runMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
 	switch runParams.Mode {
 	case ModeQuick:
 		return runQuickMaintenance(ctx, runParams, safety)
@@ -312,6 +345,10 @@ func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety Sa
 		return errors.Wrap(err, "error performing index compaction")
 	}
 
+	if err := runTaskCompactPackChecksums(ctx, runParams, s); err != nil {
+		return errors.Wrap(err, "error compacting pack checksums")
+	}
+
 	// clean up logs last
 	if err := runTaskCleanupLogs(contentlog.WithParams(ctx, logparam.String("span:cleanup-logs", contentlog.RandomSpanID())), runParams, s); err != nil {
 		return errors.Wrap(err, "error cleaning up logs")
@@ -345,6 +382,12 @@ func runTaskCleanupLogs(ctx context.Context, runParams RunParameters, s *Schedul
 	})
 }
 
+func runTaskCompactPackChecksums(ctx context.Context, runParams RunParameters, s *Schedule) error {
+	return ReportRun(ctx, runParams.rep, TaskCompactPackChecksums, s, func() (maintenancestats.Kind, error) {
+		return runParams.rep.ContentManager().CompactPackChecksums(ctx)
+	})
+}
+
 func runTaskEpochAdvance(ctx context.Context, em *epoch.Manager, runParams RunParameters, s *Schedule) error {
 	return reportRunAndMaybeCheckContentIndex(ctx, runParams.rep, TaskEpochAdvance, s, func() (maintenancestats.Kind, error) {
 		userLog(ctx).Info("Advancing epoch markers...")
@@ -544,6 +587,11 @@ func runFullMaintenance(ctx context.Context, runParams RunParameters, safety Saf
 		return errors.Wrap(err, "error cleaning up epoch manager")
 	}
 
+	// drop checksums of deleted packs and merge checksum blobs.
+	if err := runTaskCompactPackChecksums(ctx, runParams, s); err != nil {
+		return errors.Wrap(err, "error compacting pack checksums")
+	}
+
 	// clean up logs last
 	if err := runTaskCleanupLogs(ctx, runParams, s); err != nil {
 		return errors.Wrap(err, "error cleaning up logs")
@@ -9,6 +9,9 @@
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/gather"
@@ -197,6 +200,9 @@ func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType Ta
 		}
 	}
 
+	ctx, span := tracer.Start(ctx, string(taskType), trace.WithAttributes(attribute.String("task", string(taskType))))
+	defer span.End()
+
 	ri := RunInfo{
 		Start: rep.Time(),
 	}
@@ -207,6 +213,9 @@ func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType Ta
 
 	if runErr != nil {
 		ri.Error = runErr.Error()
+
+		span.RecordError(runErr)
+		span.SetStatus(codes.Error, "maintenance task failed")
 	} else {
 		ri.Success = true
 		ri.Extra = buildRunStats(ctx, stats)
@@ -34,6 +34,10 @@ func DeleteUnreferencedPacks(ctx context.Context, rep repo.DirectRepositoryWrite
 
 	log := rep.LogManager().NewLogger("maintenance-pack-gc")
 
+	if !opt.DryRun && rep.StorageFaultsInjected() {
+		return nil, ErrStorageFaultsInjected
+	}
+
 	if opt.Parallel == 0 {
 		opt.Parallel = 16
 	}
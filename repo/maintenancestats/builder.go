@@ -60,6 +60,8 @@ func BuildFromExtra(stats Extra) (Summarizer, error) {
 		result = &CompactSingleEpochStats{}
 	case compactIndexesStatsKind:
 		result = &CompactIndexesStats{}
+	case compactPackChecksumsStatsKind:
+		result = &CompactPackChecksumsStats{}
 	case deleteUnreferencedPacksStatsKind:
 		result = &DeleteUnreferencedPacksStats{}
 	case extendBlobRetentionStatsKind:
@@ -104,6 +104,18 @@ func TestBuildExtraSuccess(t *testing.T) {
 				Data: []byte(`{"toExtendBlobCount":10,"extendedBlobCount":10,"retentionPeriod":"360h0m0s"}`),
 			},
 		},
+		{
+			name: "CompactPackChecksumsStats",
+			stats: &CompactPackChecksumsStats{
+				CompactedBlobCount:    3,
+				RetainedChecksumCount: 20,
+				DroppedChecksumCount:  5,
+			},
+			expected: Extra{
+				Kind: compactPackChecksumsStatsKind,
+				Data: []byte(`{"compactedBlobCount":3,"retainedChecksumCount":20,"droppedChecksumCount":5}`),
+			},
+		},
 		{
 			name: "CleanupLogsStats",
 			stats: &CleanupLogsStats{
@@ -299,6 +311,18 @@ func TestBuildFromExtraSuccess(t *testing.T) {
 				RetentionPeriod:   (time.Hour * 24 * 15).String(),
 			},
 		},
+		{
+			name: "CompactPackChecksumsStats",
+			stats: Extra{
+				Kind: compactPackChecksumsStatsKind,
+				Data: []byte(`{"compactedBlobCount":3,"retainedChecksumCount":20,"droppedChecksumCount":5}`),
+			},
+			expected: &CompactPackChecksumsStats{
+				CompactedBlobCount:    3,
+				RetainedChecksumCount: 20,
+				DroppedChecksumCount:  5,
+			},
+		},
 		{
 			name: "CleanupLogsStats",
 			stats: Extra{
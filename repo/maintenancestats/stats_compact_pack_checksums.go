@@ -0,0 +1,36 @@
+package maintenancestats
+
+import (
+	"fmt"
+
+	"github.com/kopia/kopia/internal/contentlog"
+)
+
+const compactPackChecksumsStatsKind = "compactPackChecksumsStats"
+
+// CompactPackChecksumsStats are the stats for compacting pack checksum blobs.
+type CompactPackChecksumsStats struct {
+	CompactedBlobCount    uint64 `json:"compactedBlobCount"`
+	RetainedChecksumCount uint64 `json:"retainedChecksumCount"`
+	DroppedChecksumCount  uint64 `json:"droppedChecksumCount"`
+}
+
+// WriteValueTo writes the stats to JSONWriter.
+func (cs *CompactPackChecksumsStats) WriteValueTo(jw *contentlog.JSONWriter) {
+	jw.BeginObjectField(cs.Kind())
+	jw.UInt64Field("compactedBlobCount", cs.CompactedBlobCount)
+	jw.UInt64Field("retainedChecksumCount", cs.RetainedChecksumCount)
+	jw.UInt64Field("droppedChecksumCount", cs.DroppedChecksumCount)
+	jw.EndObject()
+}
+
+// Summary generates a human readable summary for the stats.
+func (cs *CompactPackChecksumsStats) Summary() string {
+	return fmt.Sprintf("Compacted %v pack checksum blobs, retained checksums of %v packs and dropped %v of deleted packs.",
+		cs.CompactedBlobCount, cs.RetainedChecksumCount, cs.DroppedChecksumCount)
+}
+
+// Kind returns the kind name for the stats.
+func (cs *CompactPackChecksumsStats) Kind() string {
+	return compactPackChecksumsStatsKind
+}
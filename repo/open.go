@@ -24,8 +24,11 @@
 	"github.com/kopia/kopia/internal/retry"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/beforeop"
+	"github.com/kopia/kopia/repo/blob/faultinjection"
+	"github.com/kopia/kopia/repo/blob/filesystem"
 	loggingwrapper "github.com/kopia/kopia/repo/blob/logging"
 	"github.com/kopia/kopia/repo/blob/readonly"
+	"github.com/kopia/kopia/repo/blob/retrying"
 	"github.com/kopia/kopia/repo/blob/storagemetrics"
 	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/content"
@@ -76,6 +79,7 @@ type Options struct {
 	UpgradeOwnerID       string                     // Owner-ID of any upgrade in progress, when this is not set the access may be restricted
 	DoNotWaitForUpgrade  bool                       // Disable the exponential forever backoff on an upgrade lock.
 	BeforeFlush          []RepositoryWriterCallback // list of callbacks to invoke before every flush
+	StorageFaultProfile  string                     // Path to a fault profile to inject storage failures, for disaster recovery drills
 
 	OnFatalError func(err error) // function to invoke when repository encounters a fatal error, usually invokes os.Exit
 
@@ -91,7 +95,7 @@ type Options struct {
 
 // ErrRepositoryUnavailableDueToUpgradeInProgress is returned when repository
 // is undergoing upgrade that requires exclusive access.
-var ErrRepositoryUnavailableDueToUpgradeInProgress = errors.New("repository upgrade in progress")
+var ErrRepositoryUnavailableDueToUpgradeInProgress = format.ErrRepositoryUnavailableDueToUpgradeInProgress
 
 // Open opens a Repository specified in the configuration file.
 func Open(ctx context.Context, configFile, password string, options *Options) (rep Repository, err error) {
@@ -221,6 +225,20 @@ func openDirect(ctx context.Context, configFile string, lc *LocalConfig, passwor
 		return nil, errors.Wrap(err, "cannot open storage")
 	}
 
+	if options.StorageFaultProfile != "" {
+		p, err := faultinjection.LoadProfile(options.StorageFaultProfile)
+		if err != nil {
+			st.Close(ctx) //nolint:errcheck
+			return nil, errors.Wrap(err, "unable to load storage fault profile")
+		}
+
+		log(ctx).Warnf("Injecting storage faults according to %v, maintenance and garbage collection are disabled", options.StorageFaultProfile)
+
+		// errors of the underlying provider are retried, injected faults are surfaced to the caller
+		// as-is so that they exercise the retry logic of the callers instead of being masked.
+		st = faultinjection.NewWrapper(retrying.NewWrapper(st), p)
+	}
+
 	if lc.ReadOnly {
 		st = readonly.NewWrapper(st)
 	}
@@ -331,6 +349,19 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 		st = loggingwrapper.NewWrapper(st, log(ctx), logManager.NewLogger("storage"), "[STORAGE] ")
 	}
 
+	closeFuncs := []closeFunc{dw.Wait, mr.Close, st.Close}
+
+	if cliOpts.LocalReplica != "" {
+		replica, err := filesystem.New(ctx, &filesystem.Options{Path: cliOpts.LocalReplica}, false)
+		if err != nil {
+			// the replica only speeds up reads, the repository is fully usable without it.
+			log(ctx).Warnf("unable to open local replica %v, reading from remote storage only: %v", cliOpts.LocalReplica, err)
+		} else {
+			cmOpts.LocalReplica = replica
+			closeFuncs = append(closeFuncs, replica.Close)
+		}
+	}
+
 	scm, ferr := content.NewSharedManager(ctx, st, fmgr, cacheOpts, cmOpts, logManager, mr)
 	if ferr != nil {
 		return nil, errors.Wrap(ferr, "unable to create shared content manager")
@@ -351,12 +382,7 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 		return nil, errors.Wrap(ferr, "unable to open manifests")
 	}
 
-	closer := newRefCountedCloser(
-		scm.CloseShared,
-		dw.Wait,
-		mr.Close,
-		st.Close,
-	)
+	closer := newRefCountedCloser(append([]closeFunc{scm.CloseShared}, closeFuncs...)...)
 
 	dr := &directRepository{
 		cmgr:  cm,
@@ -376,6 +402,8 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 			refCountedCloser: closer,
 			beforeFlush:      options.BeforeFlush,
 			logManager:       logManager,
+
+			storageFaultsInjected: options.StorageFaultProfile != "",
 		},
 	}
 
@@ -89,6 +89,7 @@ type DirectRepository interface {
 	Throttler() throttling.SettableThrottler
 	DisableIndexRefresh()
 	LogManager() *repodiag.LogManager
+	StorageFaultsInjected() bool
 }
 
 // DirectRepositoryWriter provides low-level write access to the repository.
@@ -111,6 +112,9 @@ type immutableDirectRepositoryParameters struct {
 	beforeFlush     []RepositoryWriterCallback
 	logManager      *repodiag.LogManager
 
+	// storageFaultsInjected is set when the storage is wrapped with a fault profile.
+	storageFaultsInjected bool
+
 	*refCountedCloser
 }
 
@@ -213,6 +217,11 @@ func (r *directRepository) LogManager() *repodiag.LogManager {
 	return r.logManager
 }
 
+// StorageFaultsInjected returns true if storage faults are being injected according to a fault profile.
+func (r *directRepository) StorageFaultsInjected() bool {
+	return r.storageFaultsInjected
+}
+
 // OpenObject opens the reader for a given object, returns object.ErrNotFound.
 func (r *directRepository) OpenObject(ctx context.Context, id object.ID) (object.Reader, error) {
 	//nolint:wrapcheck
@@ -0,0 +1,284 @@
+// Package legalhold manages legal holds which prevent snapshots and their contents from being deleted.
+package legalhold
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+var log = logging.Module("kopia/legalhold")
+
+// ManifestType is the value of the "type" label for legal hold manifests.
+const ManifestType = "legalhold"
+
+// SnapshotIDLabel is the label holding the ID of the held snapshot.
+const SnapshotIDLabel = "snapshotID"
+
+var (
+	// ErrNotFound is returned when a legal hold is not found.
+	ErrNotFound = errors.New("legal hold not found")
+
+	// ErrSnapshotHeld is returned when an operation would delete a snapshot which is under an active legal hold.
+	ErrSnapshotHeld = errors.New("snapshot is under legal hold")
+
+	// ErrHoldActive is returned when an operation would delete a legal hold which is still active
+	// or whose object lock did not end yet.
+	ErrHoldActive = errors.New("legal hold is active")
+)
+
+// Hold describes a legal hold placed on a single snapshot.
+//
+// The hold keeps a copy of the snapshot manifest, so that contents of the snapshot are protected from
+// garbage collection even if the snapshot manifest itself is removed.
+type Hold struct {
+	ID          manifest.ID `json:"id"`
+	Reason      string      `json:"reason"`
+	Owner       string      `json:"owner"`
+	CreatedTime time.Time   `json:"created"`
+
+	// ExpiresTime is the time after which the hold no longer applies, zero means the hold never expires.
+	ExpiresTime time.Time `json:"expires,omitzero"`
+
+	// RetainedUntil is the time until which storage-level object lock was extended on pack blobs of the snapshot
+	// and on pack blobs holding manifests, including the snapshot manifest and the hold itself.
+	// The hold can't be released before that time.
+	//
+	// Only pack blobs existing when the hold was placed are locked. Contents moved to new pack blobs
+	// by maintenance (rewrite or compaction) are not locked again, although the original pack blobs
+	// remain in storage until their lock ends.
+	RetainedUntil time.Time `json:"retainedUntil,omitzero"`
+
+	Snapshot *snapshot.Manifest `json:"snapshot"`
+}
+
+// IsActive returns true if the hold applies at the provided time.
+func (h *Hold) IsActive(now time.Time) bool {
+	return h.ExpiresTime.IsZero() || now.Before(h.ExpiresTime)
+}
+
+// Add persists the provided legal hold and returns its ID.
+func Add(ctx context.Context, rep repo.RepositoryWriter, h *Hold) (manifest.ID, error) {
+	if h.Snapshot == nil || h.Snapshot.ID == "" {
+		return "", errors.New("missing snapshot")
+	}
+
+	if h.Reason == "" {
+		return "", errors.New("missing reason")
+	}
+
+	if h.Owner == "" {
+		return "", errors.New("missing owner")
+	}
+
+	if !h.ExpiresTime.IsZero() && !h.ExpiresTime.After(h.CreatedTime) {
+		return "", errors.New("expiration time must be after creation time")
+	}
+
+	id, err := rep.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		SnapshotIDLabel:       string(h.Snapshot.ID),
+	}, h)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to save legal hold")
+	}
+
+	h.ID = id
+
+	return id, nil
+}
+
+// Load loads the legal hold with a given ID.
+func Load(ctx context.Context, rep repo.Repository, id manifest.ID) (*Hold, error) {
+	h := &Hold{}
+
+	em, err := rep.GetManifest(ctx, id, h)
+	if err != nil {
+		if errors.Is(err, manifest.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, errors.Wrap(err, "unable to load legal hold")
+	}
+
+	if em.Labels[manifest.TypeLabelKey] != ManifestType {
+		return nil, ErrNotFound
+	}
+
+	h.ID = id
+
+	return h, nil
+}
+
+// List returns all legal holds in the repository, including expired ones, ordered by creation time.
+func List(ctx context.Context, rep repo.Repository) ([]*Hold, error) {
+	return find(ctx, rep, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+	})
+}
+
+// ListForSnapshot returns all legal holds placed on a given snapshot, including expired ones.
+func ListForSnapshot(ctx context.Context, rep repo.Repository, snapshotID manifest.ID) ([]*Hold, error) {
+	return find(ctx, rep, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		SnapshotIDLabel:       string(snapshotID),
+	})
+}
+
+func find(ctx context.Context, rep repo.Repository, labels map[string]string) ([]*Hold, error) {
+	entries, err := rep.FindManifests(ctx, labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find legal holds")
+	}
+
+	var result []*Hold
+
+	for _, e := range entries {
+		h, err := Load(ctx, rep, e.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, h)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedTime.Before(result[j].CreatedTime)
+	})
+
+	return result, nil
+}
+
+// Active returns legal holds which apply at the provided time.
+func Active(ctx context.Context, rep repo.Repository, now time.Time) ([]*Hold, error) {
+	holds, err := List(ctx, rep)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Hold
+
+	for _, h := range holds {
+		if h.IsActive(now) {
+			result = append(result, h)
+		}
+	}
+
+	return result, nil
+}
+
+// HeldSnapshotIDs returns the set of IDs of snapshots which are under an active legal hold.
+func HeldSnapshotIDs(ctx context.Context, rep repo.Repository, now time.Time) (map[manifest.ID]bool, error) {
+	holds, err := Active(ctx, rep, now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[manifest.ID]bool{}
+
+	for _, h := range holds {
+		result[h.Snapshot.ID] = true
+	}
+
+	return result, nil
+}
+
+// CheckNotHeld returns an error wrapping ErrSnapshotHeld if any of the provided snapshots is under an active legal hold.
+func CheckNotHeld(ctx context.Context, rep repo.Repository, now time.Time, snapshotIDs ...manifest.ID) error {
+	for _, id := range snapshotIDs {
+		holds, err := ListForSnapshot(ctx, rep, id)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range holds {
+			if h.IsActive(now) {
+				return errors.Wrapf(ErrSnapshotHeld, "snapshot %v is held by %v (%v)", id, h.Owner, h.Reason)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateSnapshot updates the provided snapshot manifest unless the snapshot is under an active legal hold,
+// since updating replaces the manifest ID the hold refers to.
+func UpdateSnapshot(ctx context.Context, rep repo.RepositoryWriter, m *snapshot.Manifest, now time.Time) error {
+	if err := CheckNotHeld(ctx, rep, now, m.ID); err != nil {
+		return err
+	}
+
+	return errors.Wrap(snapshot.UpdateSnapshot(ctx, rep, m), "error updating snapshot")
+}
+
+// CheckDeleteManifest returns an error if the manifest with the provided metadata must not be deleted,
+// because it's a snapshot under an active legal hold (ErrSnapshotHeld) or a legal hold which can't be
+// released yet (ErrHoldActive).
+func CheckDeleteManifest(ctx context.Context, rep repo.Repository, now time.Time, em *manifest.EntryMetadata) error {
+	switch em.Labels[manifest.TypeLabelKey] {
+	case snapshot.ManifestType:
+		return CheckNotHeld(ctx, rep, now, em.ID)
+
+	case ManifestType:
+		h, err := Load(ctx, rep, em.ID)
+		if err != nil {
+			return err
+		}
+
+		if h.IsActive(now) {
+			return errors.Wrapf(ErrHoldActive, "legal hold %v on snapshot %v did not expire yet", h.ID, h.Snapshot.ID)
+		}
+
+		return h.checkRetention(now)
+
+	default:
+		return nil
+	}
+}
+
+func (h *Hold) checkRetention(now time.Time) error {
+	if now.Before(h.RetainedUntil) {
+		return errors.Wrapf(ErrHoldActive, "legal hold %v on snapshot %v retains object lock until %v", h.ID, h.Snapshot.ID, h.RetainedUntil)
+	}
+
+	return nil
+}
+
+// Release removes the legal hold with a given ID.
+//
+// Holds which are still active can only be released with force, which is only permitted over a direct
+// repository connection, that is by the owner of the repository storage. Clients of the repository server
+// can't delete active holds. Holds are never released before the object lock extended for them ends.
+func Release(ctx context.Context, rep repo.RepositoryWriter, id manifest.ID, now time.Time, force bool) error {
+	h, err := Load(ctx, rep, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.checkRetention(now); err != nil {
+		return err
+	}
+
+	if h.IsActive(now) {
+		if !force {
+			return errors.Wrapf(ErrHoldActive, "legal hold %v is still active, releasing it requires force", id)
+		}
+
+		if _, ok := rep.(repo.DirectRepositoryWriter); !ok {
+			return errors.Wrapf(ErrHoldActive, "legal hold %v is still active, releasing it requires direct repository connection", id)
+		}
+
+		log(ctx).Infof("forcibly releasing active legal hold %v on snapshot %v", id, h.Snapshot.ID)
+	} else {
+		log(ctx).Debugf("releasing legal hold %v on snapshot %v", id, h.Snapshot.ID)
+	}
+
+	return errors.Wrap(rep.DeleteManifest(ctx, id), "unable to delete legal hold")
+}
@@ -0,0 +1,151 @@
+package legalhold_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
+)
+
+func TestLegalHold(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	s1 := mustSaveSnapshot(ctx, t, env, now.Add(-2*time.Hour))
+	s2 := mustSaveSnapshot(ctx, t, env, now.Add(-time.Hour))
+
+	_, err := legalhold.Add(ctx, env.RepositoryWriter, &legalhold.Hold{Owner: "legal@host", CreatedTime: now, Snapshot: s1})
+	require.ErrorContains(t, err, "missing reason")
+
+	_, err = legalhold.Add(ctx, env.RepositoryWriter, &legalhold.Hold{Reason: "audit", Owner: "legal@host", CreatedTime: now, ExpiresTime: now, Snapshot: s1})
+	require.ErrorContains(t, err, "expiration time must be after creation time")
+
+	indefinite, err := legalhold.Add(ctx, env.RepositoryWriter, &legalhold.Hold{
+		Reason:      "litigation",
+		Owner:       "legal@host",
+		CreatedTime: now,
+		Snapshot:    s1,
+	})
+	require.NoError(t, err)
+
+	expiring, err := legalhold.Add(ctx, env.RepositoryWriter, &legalhold.Hold{
+		Reason:      "audit",
+		Owner:       "auditor@host",
+		CreatedTime: now.Add(time.Minute),
+		ExpiresTime: now.Add(24 * time.Hour),
+		Snapshot:    s2,
+	})
+	require.NoError(t, err)
+
+	holds, err := legalhold.List(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, holds, 2)
+	require.Equal(t, indefinite, holds[0].ID)
+	require.Equal(t, expiring, holds[1].ID)
+	require.Equal(t, s2.RootObjectID(), holds[1].Snapshot.RootObjectID())
+
+	require.ErrorIs(t, legalhold.CheckNotHeld(ctx, env.RepositoryWriter, now, s1.ID), legalhold.ErrSnapshotHeld)
+	require.ErrorIs(t, legalhold.CheckNotHeld(ctx, env.RepositoryWriter, now, s2.ID), legalhold.ErrSnapshotHeld)
+
+	// held snapshots can't be updated, since that would replace the manifest the hold refers to.
+	s1.Description = "updated"
+	require.ErrorIs(t, legalhold.UpdateSnapshot(ctx, env.RepositoryWriter, s1, now), legalhold.ErrSnapshotHeld)
+	s1.Description = ""
+
+	// after the second hold expires only the first snapshot remains held.
+	later := now.Add(48 * time.Hour)
+
+	require.NoError(t, legalhold.CheckNotHeld(ctx, env.RepositoryWriter, later, s2.ID))
+
+	s2.Description = "updated"
+	require.NoError(t, legalhold.UpdateSnapshot(ctx, env.RepositoryWriter, s2, later))
+
+	held, err := legalhold.HeldSnapshotIDs(ctx, env.RepositoryWriter, later)
+	require.NoError(t, err)
+	require.Equal(t, map[manifest.ID]bool{s1.ID: true}, held)
+
+	// active holds can't be deleted as manifests, expired ones can.
+	require.ErrorIs(t, legalhold.CheckDeleteManifest(ctx, env.RepositoryWriter, later, mustGetManifest(ctx, t, env, indefinite)), legalhold.ErrHoldActive)
+	require.ErrorIs(t, legalhold.CheckDeleteManifest(ctx, env.RepositoryWriter, later, mustGetManifest(ctx, t, env, s1.ID)), legalhold.ErrSnapshotHeld)
+	require.NoError(t, legalhold.CheckDeleteManifest(ctx, env.RepositoryWriter, later, mustGetManifest(ctx, t, env, expiring)))
+	require.NoError(t, legalhold.CheckDeleteManifest(ctx, env.RepositoryWriter, later, mustGetManifest(ctx, t, env, s2.ID)))
+
+	// active holds can only be released with force.
+	require.ErrorIs(t, legalhold.Release(ctx, env.RepositoryWriter, indefinite, later, false), legalhold.ErrHoldActive)
+	require.NoError(t, legalhold.Release(ctx, env.RepositoryWriter, expiring, later, false))
+	require.NoError(t, legalhold.Release(ctx, env.RepositoryWriter, indefinite, later, true))
+
+	require.ErrorIs(t, legalhold.Release(ctx, env.RepositoryWriter, indefinite, later, true), legalhold.ErrNotFound)
+
+	_, err = legalhold.Load(ctx, env.RepositoryWriter, s1.ID)
+	require.ErrorIs(t, err, legalhold.ErrNotFound)
+
+	holds, err = legalhold.List(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Empty(t, holds)
+}
+
+func TestLegalHold_RetainedUntil(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	s1 := mustSaveSnapshot(ctx, t, env, now.Add(-time.Hour))
+
+	id, err := legalhold.Add(ctx, env.RepositoryWriter, &legalhold.Hold{
+		Reason:        "audit",
+		Owner:         "auditor@host",
+		CreatedTime:   now,
+		ExpiresTime:   now.Add(24 * time.Hour),
+		RetainedUntil: now.Add(48 * time.Hour),
+		Snapshot:      s1,
+	})
+	require.NoError(t, err)
+
+	// the hold can't be released before object lock ends, even after it expires or with force.
+	require.ErrorIs(t, legalhold.Release(ctx, env.RepositoryWriter, id, now, true), legalhold.ErrHoldActive)
+	require.ErrorIs(t, legalhold.Release(ctx, env.RepositoryWriter, id, now.Add(36*time.Hour), false), legalhold.ErrHoldActive)
+	require.ErrorIs(t, legalhold.CheckDeleteManifest(ctx, env.RepositoryWriter, now.Add(36*time.Hour), mustGetManifest(ctx, t, env, id)), legalhold.ErrHoldActive)
+
+	require.NoError(t, legalhold.Release(ctx, env.RepositoryWriter, id, now.Add(48*time.Hour), false))
+}
+
+func mustGetManifest(ctx context.Context, t *testing.T, env *repotesting.Environment, id manifest.ID) *manifest.EntryMetadata {
+	t.Helper()
+
+	var data json.RawMessage
+
+	em, err := env.RepositoryWriter.GetManifest(ctx, id, &data)
+	require.NoError(t, err)
+
+	return em
+}
+
+func mustSaveSnapshot(ctx context.Context, t *testing.T, env *repotesting.Environment, startTime time.Time) *snapshot.Manifest {
+	t.Helper()
+
+	rootID, err := object.ParseID("k0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+
+	m := &snapshot.Manifest{
+		Source:    snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/path"},
+		StartTime: fs.UTCTimestampFromTime(startTime),
+		EndTime:   fs.UTCTimestampFromTime(startTime.Add(time.Minute)),
+		RootEntry: &snapshot.DirEntry{ObjectID: rootID},
+	}
+
+	_, err = snapshot.SaveSnapshot(ctx, env.RepositoryWriter, m)
+	require.NoError(t, err)
+
+	return m
+}
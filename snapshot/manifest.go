@@ -37,6 +37,22 @@ type Manifest struct {
 
 	// list of manually-defined pins which prevent the snapshot from being deleted.
 	Pins []string `json:"pins,omitempty"`
+
+	// results of commands whose output was snapshotted instead of the source directory.
+	CommandResults []CommandResult `json:"commandResults,omitempty"`
+
+	// hash of the policies that applied to the snapshot, used to detect policy changes between snapshots.
+	PolicyFingerprint string `json:"policyFingerprint,omitempty"`
+}
+
+// CommandResult describes the outcome of a command whose standard output was stored as a file in the snapshot.
+type CommandResult struct {
+	Name      string          `json:"name"`
+	Command   string          `json:"command"`
+	ExitCode  int             `json:"exitCode"`
+	Stderr    string          `json:"stderr,omitempty"`
+	StartTime fs.UTCTimestamp `json:"startTime"`
+	EndTime   fs.UTCTimestamp `json:"endTime"`
 }
 
 // UpdatePins updates pins in the provided manifest.
@@ -0,0 +1,30 @@
+package policy
+
+// CommandSourcePolicy describes commands whose standard output is snapshotted instead of the contents
+// of the source directory, which does not need to exist (not inherited).
+type CommandSourcePolicy struct {
+	Streams []CommandStream `json:"streams,omitempty"`
+}
+
+// CommandSourcePolicyDefinition specifies which policy definition provided the value of a particular field.
+type CommandSourcePolicyDefinition struct{}
+
+// CommandStream configures a command whose standard output is stored as a file with the provided name.
+type CommandStream struct {
+	Name      string   `json:"name"`
+	Command   string   `json:"path"`
+	Arguments []string `json:"args,omitempty"`
+
+	// maximum time the command is allowed to run, zero means no limit.
+	TimeoutSeconds int `json:"timeout,omitempty"`
+}
+
+// MergeNonInheritable copies non-inheritable properties from the provided command source policy.
+func (p *CommandSourcePolicy) MergeNonInheritable(src CommandSourcePolicy) {
+	p.Streams = src.Streams
+}
+
+// IsCommandSource returns true if the policy defines command streams.
+func (p *CommandSourcePolicy) IsCommandSource() bool {
+	return len(p.Streams) > 0
+}
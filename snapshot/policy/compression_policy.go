@@ -18,6 +18,10 @@ type CompressionPolicy struct {
 	NoParentNeverCompress bool             `json:"noParentNeverCompress,omitempty"`
 	MinSize               int64            `json:"minSize,omitempty"`
 	MaxSize               int64            `json:"maxSize,omitempty"`
+
+	// AutoCompression selects no, fast or strong compression for each file which would be compressed
+	// by sampling its contents. Clients which don't support it use CompressorName.
+	AutoCompression *OptionalBool `json:"autoCompression,omitempty"`
 }
 
 // MetadataCompressionPolicy specifies compression policy for metadata.
@@ -32,6 +36,8 @@ type CompressionPolicyDefinition struct {
 	NeverCompress  snapshot.SourceInfo `json:"neverCompress,omitempty"`
 	MinSize        snapshot.SourceInfo `json:"minSize,omitempty"`
 	MaxSize        snapshot.SourceInfo `json:"maxSize,omitempty"`
+
+	AutoCompression snapshot.SourceInfo `json:"autoCompression,omitempty"`
 }
 
 // MetadataCompressionPolicyDefinition specifies which policy definition provided the value of a particular field.
@@ -72,6 +78,7 @@ func (p *CompressionPolicy) Merge(src CompressionPolicy, def *CompressionPolicyD
 	mergeCompressionName(&p.CompressorName, src.CompressorName, &def.CompressorName, si)
 	mergeInt64(&p.MinSize, src.MinSize, &def.MinSize, si)
 	mergeInt64(&p.MaxSize, src.MaxSize, &def.MaxSize, si)
+	mergeOptionalBool(&p.AutoCompression, src.AutoCompression, &def.AutoCompression, si)
 
 	mergeStrings(&p.OnlyCompress, &p.NoParentOnlyCompress, src.OnlyCompress, src.NoParentOnlyCompress, &def.OnlyCompress, si)
 	mergeStrings(&p.NeverCompress, &p.NoParentNeverCompress, src.NeverCompress, src.NoParentNeverCompress, &def.NeverCompress, si)
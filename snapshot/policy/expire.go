@@ -6,9 +6,11 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 )
 
 // ApplyRetentionPolicy applies retention policy to a given source by deleting expired snapshots.
@@ -49,8 +51,13 @@ func ApplyRetentionPolicy(ctx context.Context, rep repo.RepositoryWriter, source
 func getExpiredSnapshots(ctx context.Context, rep repo.Repository, snapshots []*snapshot.Manifest) ([]manifest.ID, error) {
 	var toDelete []manifest.ID
 
+	held, err := legalhold.HeldSnapshotIDs(ctx, rep, clock.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list legal holds")
+	}
+
 	for _, snapshotGroup := range snapshot.GroupBySource(snapshots) {
-		td, err := getExpiredSnapshotsForSource(ctx, rep, snapshotGroup)
+		td, err := getExpiredSnapshotsForSource(ctx, rep, snapshotGroup, held)
 		if err != nil {
 			return nil, err
 		}
@@ -61,7 +68,7 @@ func getExpiredSnapshots(ctx context.Context, rep repo.Repository, snapshots []*
 	return toDelete, nil
 }
 
-func getExpiredSnapshotsForSource(ctx context.Context, rep repo.Repository, snapshots []*snapshot.Manifest) ([]manifest.ID, error) {
+func getExpiredSnapshotsForSource(ctx context.Context, rep repo.Repository, snapshots []*snapshot.Manifest, held map[manifest.ID]bool) ([]manifest.ID, error) {
 	src := snapshots[0].Source
 
 	pol, _, _, err := GetEffectivePolicy(ctx, rep, src)
@@ -74,6 +81,11 @@ func getExpiredSnapshotsForSource(ctx context.Context, rep repo.Repository, snap
 	var toDelete []manifest.ID
 
 	for _, s := range snapshots {
+		if held[s.ID] {
+			log(ctx).Debugf("  keeping %v under legal hold", s.StartTime.ToTime())
+			continue
+		}
+
 		if len(s.RetentionReasons) == 0 && len(s.Pins) == 0 {
 			log(ctx).Debugf("  deleting %v", s.StartTime)
 			toDelete = append(toDelete, s.ID)
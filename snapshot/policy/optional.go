@@ -1,5 +1,14 @@
 package policy
 
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 // OptionalBool provides convenience methods for manipulating optional booleans.
 type OptionalBool bool
 
@@ -48,3 +57,117 @@ func (b *OptionalInt64) OrDefault(def int64) int64 {
 func newOptionalInt64(b OptionalInt64) *OptionalInt64 {
 	return &b
 }
+
+// OptionalDuration provides convenience methods for manipulating optional durations.
+type OptionalDuration time.Duration
+
+// OrDefault returns the value of the duration or provided default if it's nil.
+func (b *OptionalDuration) OrDefault(def time.Duration) time.Duration {
+	if b == nil {
+		return def
+	}
+
+	return time.Duration(*b)
+}
+
+// String returns the duration using 'd' units when it is a whole number of days.
+func (b OptionalDuration) String() string {
+	d := time.Duration(b)
+
+	if d > 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+
+	return d.String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b OptionalDuration) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *OptionalDuration) UnmarshalText(text []byte) error {
+	d, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = OptionalDuration(d)
+
+	return nil
+}
+
+func newOptionalDuration(d time.Duration) *OptionalDuration {
+	v := OptionalDuration(d)
+	return &v
+}
+
+const day = 24 * time.Hour
+
+//nolint:gochecknoglobals
+var longDurationUnits = map[byte]time.Duration{
+	'd': day,
+	'w': 7 * day,   //nolint:mnd
+	'y': 365 * day, //nolint:mnd
+}
+
+// ParseDuration parses a duration string. In addition to units supported by time.ParseDuration
+// it accepts leading whole numbers of days ('d'), weeks ('w') and years of 365 days ('y'),
+// for example '90d', '1y' or '1d12h'. Negative durations and durations which don't fit in time.Duration
+// (about 292 years) are rejected.
+func ParseDuration(s string) (time.Duration, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return 0, errors.New("empty duration")
+	}
+
+	var total time.Duration
+
+	for rest != "" {
+		n := 0
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+
+		if n == 0 || n == len(rest) {
+			break
+		}
+
+		unit, ok := longDurationUnits[rest[n]]
+		if !ok {
+			break
+		}
+
+		v, err := strconv.ParseInt(rest[0:n], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid duration %q", s)
+		}
+
+		if v > int64(math.MaxInt64-total)/int64(unit) {
+			return 0, errors.Errorf("duration %q is too long", s)
+		}
+
+		total += time.Duration(v) * unit
+		rest = rest[n+1:]
+	}
+
+	if rest == "" {
+		return total, nil
+	}
+
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid duration %q", s)
+	}
+
+	if d < 0 {
+		return 0, errors.Errorf("negative duration %q", s)
+	}
+
+	if d > math.MaxInt64-total {
+		return 0, errors.Errorf("duration %q is too long", s)
+	}
+
+	return total + d, nil
+}
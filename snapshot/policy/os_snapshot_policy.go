@@ -5,16 +5,19 @@
 // OSSnapshotPolicy describes settings for OS-level snapshots.
 type OSSnapshotPolicy struct {
 	VolumeShadowCopy VolumeShadowCopyPolicy `json:"volumeShadowCopy,omitempty"`
+	LinuxSnapshot    LinuxSnapshotPolicy    `json:"linuxSnapshot,omitempty"`
 }
 
 // OSSnapshotPolicyDefinition specifies which policy definition provided the value of a particular field.
 type OSSnapshotPolicyDefinition struct {
 	VolumeShadowCopy VolumeShadowCopyPolicyDefinition `json:"volumeShadowCopy,omitempty"`
+	LinuxSnapshot    LinuxSnapshotPolicyDefinition    `json:"linuxSnapshot,omitempty"`
 }
 
 // Merge applies default values from the provided policy.
 func (p *OSSnapshotPolicy) Merge(src OSSnapshotPolicy, def *OSSnapshotPolicyDefinition, si snapshot.SourceInfo) {
 	p.VolumeShadowCopy.Merge(src.VolumeShadowCopy, &def.VolumeShadowCopy, si)
+	p.LinuxSnapshot.Merge(src.LinuxSnapshot, &def.LinuxSnapshot, si)
 }
 
 // VolumeShadowCopyPolicy describes settings for Windows Volume Shadow Copy
@@ -34,6 +37,27 @@ func (p *VolumeShadowCopyPolicy) Merge(src VolumeShadowCopyPolicy, def *VolumeSh
 	mergeOSSnapshotMode(&p.Enable, src.Enable, &def.Enable, si)
 }
 
+// LinuxSnapshotPolicy describes settings for btrfs, LVM thin and ZFS snapshots on Linux.
+type LinuxSnapshotPolicy struct {
+	Enable *OSSnapshotMode `json:"enable,omitempty"`
+
+	// Provider is one of "auto", "btrfs", "lvm" or "zfs", empty means "auto".
+	Provider string `json:"provider,omitempty"`
+}
+
+// LinuxSnapshotPolicyDefinition specifies which policy definition provided
+// the value of a particular field.
+type LinuxSnapshotPolicyDefinition struct {
+	Enable   snapshot.SourceInfo `json:"enable,omitempty"`
+	Provider snapshot.SourceInfo `json:"provider,omitempty"`
+}
+
+// Merge applies default values from the provided policy.
+func (p *LinuxSnapshotPolicy) Merge(src LinuxSnapshotPolicy, def *LinuxSnapshotPolicyDefinition, si snapshot.SourceInfo) {
+	mergeOSSnapshotMode(&p.Enable, src.Enable, &def.Enable, si)
+	mergeString(&p.Provider, src.Provider, &def.Provider, si)
+}
+
 // OSSnapshotMode specifies whether OS-level snapshots are used for file systems
 // that support them.
 //
@@ -33,6 +33,8 @@ type Policy struct {
 	OSSnapshotPolicy          OSSnapshotPolicy          `json:"osSnapshots,omitempty"`
 	LoggingPolicy             LoggingPolicy             `json:"logging,omitempty"`
 	UploadPolicy              UploadPolicy              `json:"upload,omitempty"`
+	CommandSource             CommandSourcePolicy       `json:"commandSource,omitempty"`
+	QuotaPolicy               QuotaPolicy               `json:"quota,omitempty"`
 	NoParent                  bool                      `json:"noParent,omitempty"`
 }
 
@@ -50,6 +52,8 @@ type Definition struct {
 	OSSnapshotPolicy          OSSnapshotPolicyDefinition          `json:"osSnapshots,omitempty"`
 	LoggingPolicy             LoggingPolicyDefinition             `json:"logging,omitempty"`
 	UploadPolicy              UploadPolicyDefinition              `json:"upload,omitempty"`
+	CommandSource             CommandSourcePolicyDefinition       `json:"commandSource,omitempty"`
+	QuotaPolicy               QuotaPolicyDefinition               `json:"quota,omitempty"`
 }
 
 func (p *Policy) String() string {
@@ -90,6 +94,10 @@ func ValidatePolicy(si snapshot.SourceInfo, pol *Policy) error {
 		return errors.Wrap(err, "invalid upload policy")
 	}
 
+	if err := ValidateQuotaPolicy(pol.QuotaPolicy); err != nil {
+		return errors.Wrap(err, "invalid quota policy")
+	}
+
 	return nil
 }
 
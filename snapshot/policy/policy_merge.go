@@ -29,6 +29,7 @@ func MergePolicies(policies []*Policy, si snapshot.SourceInfo) (*Policy, *Defini
 		merged.Actions.Merge(p.Actions, &def.Actions, p.Target())
 		merged.OSSnapshotPolicy.Merge(p.OSSnapshotPolicy, &def.OSSnapshotPolicy, p.Target())
 		merged.LoggingPolicy.Merge(p.LoggingPolicy, &def.LoggingPolicy, p.Target())
+		merged.QuotaPolicy.Merge(p.QuotaPolicy, &def.QuotaPolicy, p.Target())
 
 		if p.NoParent {
 			return &merged, &def
@@ -50,6 +51,7 @@ func MergePolicies(policies []*Policy, si snapshot.SourceInfo) (*Policy, *Defini
 
 	if len(policies) > 0 {
 		merged.Actions.MergeNonInheritable(policies[0].Actions)
+		merged.CommandSource.MergeNonInheritable(policies[0].CommandSource)
 	}
 
 	return &merged, &def
@@ -82,6 +84,15 @@ func mergeOptionalInt64(target **OptionalInt64, src *OptionalInt64, def *snapsho
 	}
 }
 
+func mergeOptionalDuration(target **OptionalDuration, src *OptionalDuration, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
+	if *target == nil && src != nil {
+		v := *src
+
+		*target = &v
+		*def = si
+	}
+}
+
 func mergeStringsReplace(target *[]string, src []string, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
 	if len(*target) == 0 && len(src) > 0 {
 		*target = src
@@ -4,6 +4,7 @@
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -20,6 +21,8 @@
 	"SchedulingPolicyDefinition.NoParentTimesOfDay":     true, // special
 	"CompressionPolicyDefinition.NoParentOnlyCompress":  true,
 	"CompressionPolicyDefinition.NoParentNeverCompress": true,
+	"SplitterPolicyDefinition.NoParentRules":            true,
+	"CommandSourcePolicyDefinition.Streams":             true, // non-inheritable field
 }
 
 func TestPolicyDefinition(t *testing.T) {
@@ -127,6 +130,14 @@ func testPolicyMergeSingleField(t *testing.T, fieldName string, typ reflect.Type
 		v1 = reflect.ValueOf(&ob1)
 		v2 = reflect.ValueOf(&ob2)
 
+	case "*policy.OptionalDuration":
+		od1 := policy.OptionalDuration(time.Hour)
+		od2 := policy.OptionalDuration(7 * 24 * time.Hour)
+
+		v0 = reflect.ValueOf((*policy.OptionalDuration)(nil))
+		v1 = reflect.ValueOf(&od1)
+		v2 = reflect.ValueOf(&od2)
+
 	case "bool":
 		v0 = reflect.ValueOf(false)
 		v1 = reflect.ValueOf(false)
@@ -158,6 +169,10 @@ func testPolicyMergeSingleField(t *testing.T, fieldName string, typ reflect.Type
 		v0 = reflect.ValueOf((*policy.OSSnapshotMode)(nil))
 		v1 = reflect.ValueOf(policy.NewOSSnapshotMode(policy.OSSnapshotNever))
 		v2 = reflect.ValueOf(policy.NewOSSnapshotMode(policy.OSSnapshotAlways))
+	case "[]policy.SplitterRule":
+		v0 = reflect.ValueOf([]policy.SplitterRule{})
+		v1 = reflect.ValueOf([]policy.SplitterRule{{Algorithm: "FIXED-2M"}})
+		v2 = reflect.ValueOf([]policy.SplitterRule{{Algorithm: "FIXED-4M", Extensions: []string{".img"}}})
 	case "string":
 		v0 = reflect.ValueOf("")
 		v1 = reflect.ValueOf("FIXED-2M")
@@ -231,6 +246,7 @@ func disableParentMerging(p *policy.Policy) {
 	p.CompressionPolicy.NoParentNeverCompress = true
 	p.CompressionPolicy.NoParentOnlyCompress = true
 	p.SchedulingPolicy.NoParentTimesOfDay = true
+	p.SplitterPolicy.NoParentRules = true
 }
 
 func TestPolicyMergeOnlyCompressIncludingParents(t *testing.T) {
@@ -1,6 +1,11 @@
 package policy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
 	"strings"
 )
 
@@ -62,6 +67,9 @@
 		VolumeShadowCopy: VolumeShadowCopyPolicy{
 			Enable: NewOSSnapshotMode(OSSnapshotNever),
 		},
+		LinuxSnapshot: LinuxSnapshotPolicy{
+			Enable: NewOSSnapshotMode(OSSnapshotNever),
+		},
 	}
 
 	defaultUploadPolicy = UploadPolicy{
@@ -125,6 +133,11 @@ func (t *Tree) IsInherited() bool {
 	return t.inherited
 }
 
+// HasDescendantPolicies returns true if a policy has been explicitly defined for any descendant of the tree node.
+func (t *Tree) HasDescendantPolicies() bool {
+	return t != nil && len(t.children) > 0
+}
+
 // Child gets a subtree for an entry with a given name.
 func (t *Tree) Child(name string) *Tree {
 	if t == nil {
@@ -205,3 +218,32 @@ func childrenWithPrefix(m map[string]*Policy, path string) map[string]map[string
 
 	return result
 }
+
+// Fingerprint returns a hash of the effective policies of the tree node and all its descendants,
+// which changes whenever any policy applicable to the tree is defined, changed or removed.
+func (t *Tree) Fingerprint() string {
+	h := sha256.New()
+
+	t.writeFingerprint(h, ".")
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *Tree) writeFingerprint(w io.Writer, path string) {
+	fmt.Fprintf(w, "%s:%v:%s\n", path, t.IsInherited(), t.EffectivePolicy())
+
+	if t == nil {
+		return
+	}
+
+	names := make([]string, 0, len(t.children))
+	for name := range t.children {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		t.children[name].writeFingerprint(w, path+"/"+name)
+	}
+}
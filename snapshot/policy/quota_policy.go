@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/snapshot"
+)
+
+// Supported pre-flight quota check modes.
+const (
+	QuotaPreflightNone = "none"
+	QuotaPreflightWarn = "warn"
+	QuotaPreflightFail = "fail"
+)
+
+// QuotaPolicy describes limits on the size of snapshots which are enforced during upload.
+type QuotaPolicy struct {
+	// maximum total size of files in a snapshot.
+	MaxTotalSize *OptionalInt64 `json:"maxTotalSize,omitempty"`
+
+	// maximum number of bytes uploaded to the repository by a single snapshot.
+	MaxNewBytes *OptionalInt64 `json:"maxNewBytes,omitempty"`
+
+	// maximum number of files in a snapshot.
+	MaxFileCount *OptionalInt64 `json:"maxFileCount,omitempty"`
+
+	// Preflight is one of "none", "warn" or "fail" and determines what happens when the size of the source
+	// estimated before the snapshot starts exceeds the quota or grew by more than MaxGrowthPercent.
+	Preflight string `json:"preflight,omitempty"`
+
+	// maximum growth of the estimated size compared to the previous complete snapshot.
+	MaxGrowthPercent *OptionalInt `json:"maxGrowthPercent,omitempty"`
+}
+
+// QuotaPolicyDefinition specifies which policy definition provided the value of a particular field.
+type QuotaPolicyDefinition struct {
+	MaxTotalSize     snapshot.SourceInfo `json:"maxTotalSize,omitempty"`
+	MaxNewBytes      snapshot.SourceInfo `json:"maxNewBytes,omitempty"`
+	MaxFileCount     snapshot.SourceInfo `json:"maxFileCount,omitempty"`
+	Preflight        snapshot.SourceInfo `json:"preflight,omitempty"`
+	MaxGrowthPercent snapshot.SourceInfo `json:"maxGrowthPercent,omitempty"`
+}
+
+// Merge applies default values from the provided policy.
+func (p *QuotaPolicy) Merge(src QuotaPolicy, def *QuotaPolicyDefinition, si snapshot.SourceInfo) {
+	mergeOptionalInt64(&p.MaxTotalSize, src.MaxTotalSize, &def.MaxTotalSize, si)
+	mergeOptionalInt64(&p.MaxNewBytes, src.MaxNewBytes, &def.MaxNewBytes, si)
+	mergeOptionalInt64(&p.MaxFileCount, src.MaxFileCount, &def.MaxFileCount, si)
+	mergeString(&p.Preflight, src.Preflight, &def.Preflight, si)
+	mergeOptionalInt(&p.MaxGrowthPercent, src.MaxGrowthPercent, &def.MaxGrowthPercent, si)
+}
+
+// PreflightEnabled returns true if the source must be estimated before the snapshot starts.
+func (p *QuotaPolicy) PreflightEnabled() bool {
+	return p.Preflight == QuotaPreflightWarn || p.Preflight == QuotaPreflightFail
+}
+
+// ValidateQuotaPolicy returns an error if the quota policy is invalid.
+func ValidateQuotaPolicy(p QuotaPolicy) error {
+	switch p.Preflight {
+	case "", QuotaPreflightNone, QuotaPreflightWarn, QuotaPreflightFail:
+	default:
+		return errors.Errorf("invalid preflight mode %q", p.Preflight)
+	}
+
+	for _, v := range []*OptionalInt64{p.MaxTotalSize, p.MaxNewBytes, p.MaxFileCount} {
+		if v != nil && *v < 0 {
+			return errors.New("quota cannot be negative")
+		}
+	}
+
+	if p.MaxGrowthPercent != nil && *p.MaxGrowthPercent < 0 {
+		return errors.New("max growth percentage cannot be negative")
+	}
+
+	return nil
+}
@@ -28,6 +28,14 @@ type RetentionPolicy struct {
 	KeepMonthly              *OptionalInt  `json:"keepMonthly,omitempty"`
 	KeepAnnual               *OptionalInt  `json:"keepAnnual,omitempty"`
 	IgnoreIdenticalSnapshots *OptionalBool `json:"ignoreIdenticalSnapshots,omitempty"`
+
+	// duration-based retention, measured back from the start time of the latest complete snapshot.
+	KeepWithin        *OptionalDuration `json:"keepWithin,omitempty"`
+	KeepHourlyWithin  *OptionalDuration `json:"keepHourlyWithin,omitempty"`
+	KeepDailyWithin   *OptionalDuration `json:"keepDailyWithin,omitempty"`
+	KeepWeeklyWithin  *OptionalDuration `json:"keepWeeklyWithin,omitempty"`
+	KeepMonthlyWithin *OptionalDuration `json:"keepMonthlyWithin,omitempty"`
+	KeepAnnualWithin  *OptionalDuration `json:"keepAnnualWithin,omitempty"`
 }
 
 // RetentionPolicyDefinition specifies which policy definition provided the value of a particular field.
@@ -39,6 +47,12 @@ type RetentionPolicyDefinition struct {
 	KeepMonthly              snapshot.SourceInfo `json:"keepMonthly,omitempty"`
 	KeepAnnual               snapshot.SourceInfo `json:"keepAnnual,omitempty"`
 	IgnoreIdenticalSnapshots snapshot.SourceInfo `json:"ignoreIdenticalSnapshots,omitempty"`
+	KeepWithin               snapshot.SourceInfo `json:"keepWithin,omitempty"`
+	KeepHourlyWithin         snapshot.SourceInfo `json:"keepHourlyWithin,omitempty"`
+	KeepDailyWithin          snapshot.SourceInfo `json:"keepDailyWithin,omitempty"`
+	KeepWeeklyWithin         snapshot.SourceInfo `json:"keepWeeklyWithin,omitempty"`
+	KeepMonthlyWithin        snapshot.SourceInfo `json:"keepMonthlyWithin,omitempty"`
+	KeepAnnualWithin         snapshot.SourceInfo `json:"keepAnnualWithin,omitempty"`
 }
 
 // ComputeRetentionReasons computes the reasons why each snapshot is retained, based on
@@ -74,12 +88,27 @@ func (r *RetentionPolicy) ComputeRetentionReasons(manifests []*snapshot.Manifest
 		return maxTime
 	}
 
+	withinCutoffTime := func(setting *OptionalDuration) time.Time {
+		if setting != nil {
+			return maxCompleteStartTime.Add(-time.Duration(*setting))
+		}
+
+		return maxTime
+	}
+
 	cutoff := &cutoffTimes{
 		annual:  cutoffTime(r.KeepAnnual, yearsAgo),
 		monthly: cutoffTime(r.KeepMonthly, monthsAgo),
 		daily:   cutoffTime(r.KeepDaily, daysAgo),
 		hourly:  cutoffTime(r.KeepHourly, hoursAgo),
 		weekly:  cutoffTime(r.KeepWeekly, weeksAgo),
+
+		within:        withinCutoffTime(r.KeepWithin),
+		annualWithin:  withinCutoffTime(r.KeepAnnualWithin),
+		monthlyWithin: withinCutoffTime(r.KeepMonthlyWithin),
+		weeklyWithin:  withinCutoffTime(r.KeepWeeklyWithin),
+		dailyWithin:   withinCutoffTime(r.KeepDailyWithin),
+		hourlyWithin:  withinCutoffTime(r.KeepHourlyWithin),
 	}
 
 	ids := make(map[string]bool)
@@ -117,13 +146,33 @@ func (r *RetentionPolicy) ComputeRetentionReasons(manifests []*snapshot.Manifest
 // EffectiveKeepLatest returns the number of "latest" snapshots to keep. If all
 // retention values are set to 0 then returns MaxInt.
 func (r *RetentionPolicy) EffectiveKeepLatest() *OptionalInt {
-	if r.KeepLatest.OrDefault(0)+r.KeepHourly.OrDefault(0)+r.KeepDaily.OrDefault(0)+r.KeepWeekly.OrDefault(0)+r.KeepMonthly.OrDefault(0)+r.KeepAnnual.OrDefault(0) == 0 {
+	if r.KeepLatest.OrDefault(0)+r.KeepHourly.OrDefault(0)+r.KeepDaily.OrDefault(0)+r.KeepWeekly.OrDefault(0)+r.KeepMonthly.OrDefault(0)+r.KeepAnnual.OrDefault(0) == 0 && !r.hasDurationRetention() {
 		return newOptionalInt(math.MaxInt)
 	}
 
 	return r.KeepLatest
 }
 
+func (r *RetentionPolicy) hasDurationRetention() bool {
+	for _, d := range []*OptionalDuration{r.KeepWithin, r.KeepHourlyWithin, r.KeepDailyWithin, r.KeepWeeklyWithin, r.KeepMonthlyWithin, r.KeepAnnualWithin} {
+		if d.OrDefault(0) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keepAllWithin returns the maximum number of snapshots retained by a duration-based setting, which is
+// unlimited when the duration is set.
+func keepAllWithin(d *OptionalDuration) *OptionalInt {
+	if d.OrDefault(0) <= 0 {
+		return nil
+	}
+
+	return newOptionalInt(math.MaxInt)
+}
+
 func (r *RetentionPolicy) getRetentionReasons(i int, s *snapshot.Manifest, cutoff *cutoffTimes, ids map[string]bool, idCounters map[string]int) []string {
 	if s.IncompleteReason != "" {
 		return nil
@@ -134,6 +183,7 @@ func (r *RetentionPolicy) getRetentionReasons(i int, s *snapshot.Manifest, cutof
 	var zeroTime time.Time
 
 	yyyy, wk := s.StartTime.ToTime().ISOWeek()
+	weekID := fmt.Sprintf("%04v-%02v", yyyy, wk)
 
 	effectiveKeepLatest := r.EffectiveKeepLatest()
 
@@ -146,9 +196,17 @@ func (r *RetentionPolicy) getRetentionReasons(i int, s *snapshot.Manifest, cutof
 		{zeroTime, strconv.Itoa(i), "latest", effectiveKeepLatest},
 		{cutoff.annual, s.StartTime.Format("2006"), "annual", r.KeepAnnual},
 		{cutoff.monthly, s.StartTime.Format("2006-01"), "monthly", r.KeepMonthly},
-		{cutoff.weekly, fmt.Sprintf("%04v-%02v", yyyy, wk), "weekly", r.KeepWeekly},
+		{cutoff.weekly, weekID, "weekly", r.KeepWeekly},
 		{cutoff.daily, s.StartTime.Format("2006-01-02"), "daily", r.KeepDaily},
 		{cutoff.hourly, s.StartTime.Format("2006-01-02 15"), "hourly", r.KeepHourly},
+
+		// duration-based cases use distinct time period IDs, so that they are independent of count-based ones.
+		{cutoff.within, "within:" + strconv.Itoa(i), "within", keepAllWithin(r.KeepWithin)},
+		{cutoff.annualWithin, "annual-within:" + s.StartTime.Format("2006"), "annual-within", keepAllWithin(r.KeepAnnualWithin)},
+		{cutoff.monthlyWithin, "monthly-within:" + s.StartTime.Format("2006-01"), "monthly-within", keepAllWithin(r.KeepMonthlyWithin)},
+		{cutoff.weeklyWithin, "weekly-within:" + weekID, "weekly-within", keepAllWithin(r.KeepWeeklyWithin)},
+		{cutoff.dailyWithin, "daily-within:" + s.StartTime.Format("2006-01-02"), "daily-within", keepAllWithin(r.KeepDailyWithin)},
+		{cutoff.hourlyWithin, "hourly-within:" + s.StartTime.Format("2006-01-02 15"), "hourly-within", keepAllWithin(r.KeepHourlyWithin)},
 	}
 
 	for _, c := range cases {
@@ -182,6 +240,13 @@ type cutoffTimes struct {
 	daily   time.Time
 	hourly  time.Time
 	weekly  time.Time
+
+	within        time.Time
+	annualWithin  time.Time
+	monthlyWithin time.Time
+	weeklyWithin  time.Time
+	dailyWithin   time.Time
+	hourlyWithin  time.Time
 }
 
 func yearsAgo(base time.Time, n int) time.Time {
@@ -223,6 +288,12 @@ func (r *RetentionPolicy) Merge(src RetentionPolicy, def *RetentionPolicyDefinit
 	mergeOptionalInt(&r.KeepMonthly, src.KeepMonthly, &def.KeepMonthly, si)
 	mergeOptionalInt(&r.KeepAnnual, src.KeepAnnual, &def.KeepAnnual, si)
 	mergeOptionalBool(&r.IgnoreIdenticalSnapshots, src.IgnoreIdenticalSnapshots, &def.IgnoreIdenticalSnapshots, si)
+	mergeOptionalDuration(&r.KeepWithin, src.KeepWithin, &def.KeepWithin, si)
+	mergeOptionalDuration(&r.KeepHourlyWithin, src.KeepHourlyWithin, &def.KeepHourlyWithin, si)
+	mergeOptionalDuration(&r.KeepDailyWithin, src.KeepDailyWithin, &def.KeepDailyWithin, si)
+	mergeOptionalDuration(&r.KeepWeeklyWithin, src.KeepWeeklyWithin, &def.KeepWeeklyWithin, si)
+	mergeOptionalDuration(&r.KeepMonthlyWithin, src.KeepMonthlyWithin, &def.KeepMonthlyWithin, si)
+	mergeOptionalDuration(&r.KeepAnnualWithin, src.KeepAnnualWithin, &def.KeepAnnualWithin, si)
 }
 
 // CompactRetentionReasons returns compressed retention reasons given a list of retention reasons.
@@ -322,6 +393,13 @@ func SortRetentionTags(tags []string) {
 		"weekly":  4, //nolint:mnd
 		"monthly": 5, //nolint:mnd
 		"annual":  6, //nolint:mnd
+
+		"within":         7,  //nolint:mnd
+		"hourly-within":  8,  //nolint:mnd
+		"daily-within":   9,  //nolint:mnd
+		"weekly-within":  10, //nolint:mnd
+		"monthly-within": 11, //nolint:mnd
+		"annual-within":  12, //nolint:mnd
 	}
 
 	sort.Slice(tags, func(i, j int) bool {
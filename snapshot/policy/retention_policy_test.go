@@ -1,7 +1,9 @@
 package policy
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -158,6 +160,47 @@ func TestRetentionPolicyTest(t *testing.T) {
 				"2020-01-15T12:00:00Z": {"weekly-1"},
 			},
 		},
+		{
+			&RetentionPolicy{
+				KeepWithin: newOptionalDuration(24 * time.Hour),
+			},
+			map[string][]string{
+				"2020-01-01T12:00:00Z": {}, // not retained, more than a day older than latest snapshot
+				"2020-01-01T15:00:00Z": {"within-4"},
+				"2020-01-02T12:00:00Z": {"within-3"},
+				"2020-01-02T14:00:00Z": {"within-2"},
+				"2020-01-02T15:00:00Z": {"within-1"},
+			},
+		},
+		{
+			&RetentionPolicy{
+				KeepLatest:      newOptionalInt(2),
+				KeepDailyWithin: newOptionalDuration(48 * time.Hour),
+			},
+			map[string][]string{
+				"2020-01-01T12:00:00Z": {},
+				"2020-01-02T12:00:00Z": {}, // not retained since it's before latest - 2 days
+				"2020-01-02T15:00:00Z": {"daily-within-3"},
+				"2020-01-03T12:00:00Z": {}, // not retained since there's a newer snapshot for that day
+				"2020-01-03T15:00:00Z": {"daily-within-2"},
+				"2020-01-04T12:00:00Z": {"latest-2"},
+				"2020-01-04T15:00:00Z": {"latest-1", "daily-within-1"},
+			},
+		},
+		{
+			&RetentionPolicy{
+				KeepWithin:        newOptionalDuration(2 * time.Hour),
+				KeepMonthlyWithin: newOptionalDuration(60 * 24 * time.Hour),
+			},
+			map[string][]string{
+				"2020-01-15T12:00:00Z": {}, // not retained since it's before latest - 60 days
+				"2020-02-15T12:00:00Z": {"monthly-within-2"},
+				"2020-03-15T12:00:00Z": {}, // not retained since there's a newer snapshot for that month
+				"2020-03-16T09:00:00Z": {},
+				"2020-03-16T10:00:00Z": {"within-2"},
+				"2020-03-16T12:00:00Z": {"within-1", "monthly-within-1"},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -243,3 +286,64 @@ func TestCompactRetentionReasons(t *testing.T) {
 		require.Equal(t, tc.want, CompactRetentionReasons(tc.input))
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1y", 365 * 24 * time.Hour},
+		{"1y2w3d", (365 + 14 + 3) * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{" 90m ", 90 * time.Minute},
+		{"1.5h", 90 * time.Minute},
+		{"292y", 292 * 365 * 24 * time.Hour},
+		{"106751d23h47m16.854775807s", math.MaxInt64},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseDuration(tc.input)
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.want, got, tc.input)
+	}
+
+	for _, input := range []string{"", "d", "7", "1.5d", "1x", "1d2"} {
+		_, err := ParseDuration(input)
+		require.Error(t, err, input)
+	}
+
+	// negative durations are rejected.
+	for _, input := range []string{"-1h", "-1d", "1d-1h", "-0.5s"} {
+		_, err := ParseDuration(input)
+		require.Error(t, err, input)
+	}
+
+	// durations which don't fit in time.Duration are rejected instead of overflowing.
+	for _, input := range []string{"300y", "293y", "106752d", "292y25w", "106751d23h47m17s", "9223372036854775807d", "99999999999999999999d", "3000000h"} {
+		_, err := ParseDuration(input)
+		require.Error(t, err, input)
+	}
+
+	_, err := ParseDuration("300y")
+	require.ErrorContains(t, err, "too long")
+}
+
+func TestOptionalDurationJSON(t *testing.T) {
+	rp := &RetentionPolicy{
+		KeepWithin:      newOptionalDuration(36 * time.Hour),
+		KeepDailyWithin: newOptionalDuration(90 * 24 * time.Hour),
+	}
+
+	b, err := json.Marshal(rp)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"keepWithin":"36h0m0s","keepDailyWithin":"90d"}`, string(b))
+
+	var rp2 RetentionPolicy
+
+	require.NoError(t, json.Unmarshal(b, &rp2))
+	require.Equal(t, rp, &rp2)
+
+	require.Error(t, json.Unmarshal([]byte(`{"keepWithin":"7x"}`), &rp2))
+}
@@ -140,6 +140,41 @@ func (p *SchedulingPolicy) NextSnapshotTime(previousSnapshotTime, now time.Time)
 	return nextSnapshotTime, ok
 }
 
+// SnapshotDueTime returns the time of the first snapshot scheduled after the provided previous snapshot time,
+// or false if the policy does not schedule snapshots.
+func (p *SchedulingPolicy) SnapshotDueTime(previousSnapshotTime time.Time) (time.Time, bool) {
+	if p.Manual {
+		return time.Time{}, false
+	}
+
+	var (
+		dueTime time.Time
+		ok      bool
+	)
+
+	previousSnapshotTime = previousSnapshotTime.Local()
+
+	if interval := p.Interval(); interval != 0 {
+		dueTime = previousSnapshotTime.Add(interval).Truncate(interval)
+		ok = true
+	}
+
+	// add a second to ensure that the next possible snapshot is after the previous one
+	momentAfterSnapshot := previousSnapshotTime.Add(time.Second)
+
+	if todSnapshot, todOk := p.getNextTimeOfDaySnapshot(momentAfterSnapshot); todOk && (!ok || todSnapshot.Before(dueTime)) {
+		dueTime = todSnapshot
+		ok = true
+	}
+
+	if cronSnapshot, cronOk := p.getNextCronSnapshot(momentAfterSnapshot); cronOk && (!ok || cronSnapshot.Before(dueTime)) {
+		dueTime = cronSnapshot
+		ok = true
+	}
+
+	return dueTime, ok
+}
+
 // Get next ToD snapshot.
 func (p *SchedulingPolicy) getNextTimeOfDaySnapshot(now time.Time) (time.Time, bool) {
 	const oneDay = 24 * time.Hour
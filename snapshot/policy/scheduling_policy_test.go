@@ -289,6 +289,61 @@ func TestNextSnapshotTime(t *testing.T) {
 	}
 }
 
+func TestSnapshotDueTime(t *testing.T) {
+	previous := time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local)
+
+	cases := []struct {
+		name     string
+		pol      policy.SchedulingPolicy
+		wantTime time.Time
+		wantOK   bool
+	}{
+		{name: "empty policy"},
+		{
+			name: "manual policy",
+			pol:  policy.SchedulingPolicy{IntervalSeconds: 60, Manual: true},
+		},
+		{
+			name:     "interval truncated to full hours",
+			pol:      policy.SchedulingPolicy{IntervalSeconds: 3600},
+			wantTime: time.Date(2020, time.January, 1, 12, 0, 0, 0, time.Local),
+			wantOK:   true,
+		},
+		{
+			name:     "time of day on the following day",
+			pol:      policy.SchedulingPolicy{TimesOfDay: []policy.TimeOfDay{{10, 0}}},
+			wantTime: time.Date(2020, time.January, 2, 10, 0, 0, 0, time.Local),
+			wantOK:   true,
+		},
+		{
+			name: "earliest of interval and time of day",
+			pol: policy.SchedulingPolicy{
+				IntervalSeconds: 86400,
+				TimesOfDay:      []policy.TimeOfDay{{11, 55}},
+			},
+			wantTime: time.Date(2020, time.January, 1, 11, 55, 0, 0, time.Local),
+			wantOK:   true,
+		},
+		{
+			name:     "cron",
+			pol:      policy.SchedulingPolicy{Cron: []string{"30 * * * *"}},
+			wantTime: time.Date(2020, time.January, 1, 12, 30, 0, 0, time.Local),
+			wantOK:   true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case-%v", i), func(t *testing.T) {
+			gotTime, gotOK := tc.pol.SnapshotDueTime(previous)
+			require.Equal(t, tc.wantOK, gotOK, tc.name)
+
+			if tc.wantOK {
+				require.True(t, tc.wantTime.Equal(gotTime), "%v: got %v, want %v", tc.name, gotTime, tc.wantTime)
+			}
+		})
+	}
+}
+
 func TestSortAndDedupeTimesOfDay(t *testing.T) {
 	cases := []struct {
 		input []policy.TimeOfDay
@@ -1,26 +1,92 @@
 package policy
 
 import (
+	"path/filepath"
+
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/snapshot"
 )
 
-// SplitterPolicy specifies compression policy.
+// SplitterPolicy specifies splitter policy.
 type SplitterPolicy struct {
-	Algorithm string `json:"algorithm,omitempty"`
+	Algorithm     string         `json:"algorithm,omitempty"`
+	Rules         []SplitterRule `json:"rules,omitempty"`
+	NoParentRules bool           `json:"noParentRules,omitempty"`
+}
+
+// SplitterRule selects splitter algorithm for files matching the provided extensions and size bounds.
+type SplitterRule struct {
+	Algorithm  string   `json:"algorithm"`
+	Extensions []string `json:"extensions,omitempty"`
+	MinSize    int64    `json:"minSize,omitempty"`
+	MaxSize    int64    `json:"maxSize,omitempty"`
 }
 
 // SplitterPolicyDefinition specifies which policy definition provided the value of a particular field.
 type SplitterPolicyDefinition struct {
 	Algorithm snapshot.SourceInfo `json:"algorithm,omitempty"`
+	Rules     snapshot.SourceInfo `json:"rules,omitempty"`
 }
 
-// SplitterForFile returns splitter algorithm.
-func (p *SplitterPolicy) SplitterForFile(_ fs.Entry) string {
+// Matches determines whether the rule applies to a file with the provided extension and size.
+func (r *SplitterRule) Matches(ext string, size int64) bool {
+	if v := r.MinSize; v > 0 && size < v {
+		return false
+	}
+
+	if v := r.MaxSize; v > 0 && size > v {
+		return false
+	}
+
+	if len(r.Extensions) == 0 {
+		return true
+	}
+
+	for _, e := range r.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SplitterForFile returns splitter algorithm to be used for a given file according to policy, using attributes such as name or size.
+// The first matching rule wins, files not matching any rule use the algorithm override.
+func (p *SplitterPolicy) SplitterForFile(e fs.Entry) string {
+	ext := filepath.Ext(e.Name())
+	size := e.Size()
+
+	for i := range p.Rules {
+		if r := &p.Rules[i]; r.Matches(ext, size) {
+			return r.Algorithm
+		}
+	}
+
 	return p.Algorithm
 }
 
 // Merge applies default values from the provided policy.
 func (p *SplitterPolicy) Merge(src SplitterPolicy, def *SplitterPolicyDefinition, si snapshot.SourceInfo) {
 	mergeString(&p.Algorithm, src.Algorithm, &def.Algorithm, si)
+	mergeSplitterRules(&p.Rules, &p.NoParentRules, src.Rules, src.NoParentRules, &def.Rules, si)
+}
+
+// mergeSplitterRules appends rules from less specific policies after the current ones, so that
+// rules defined closer to the target take precedence.
+func mergeSplitterRules(target *[]SplitterRule, targetNoParent *bool, src []SplitterRule, noParent bool, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
+	if *targetNoParent {
+		// merges prevented
+		return
+	}
+
+	if len(src) > 0 {
+		*target = append(append([]SplitterRule(nil), *target...), src...)
+		*def = si
+	}
+
+	if noParent {
+		// prevent future merges.
+		*targetNoParent = noParent
+	}
 }
@@ -0,0 +1,61 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func TestSplitterForFile(t *testing.T) {
+	p := &policy.SplitterPolicy{
+		Algorithm: "DYNAMIC-1M-BUZHASH",
+		Rules: []policy.SplitterRule{
+			{Algorithm: "FIXED-4M", Extensions: []string{".vmdk", ".qcow2"}},
+			{Algorithm: "FIXED-1M", MinSize: 1000},
+			{Algorithm: "DYNAMIC-128K-BUZHASH", Extensions: []string{".txt"}, MaxSize: 100},
+		},
+	}
+
+	cases := []struct {
+		name string
+		size int
+		want string
+	}{
+		{"disk.vmdk", 10, "FIXED-4M"},
+		{"disk.qcow2", 10000, "FIXED-4M"},
+		{"big.txt", 5000, "FIXED-1M"},
+		{"small.txt", 50, "DYNAMIC-128K-BUZHASH"},
+		{"medium.txt", 500, "DYNAMIC-1M-BUZHASH"},
+		{"other.bin", 10, "DYNAMIC-1M-BUZHASH"},
+	}
+
+	for _, tc := range cases {
+		f := mockfs.NewFile(tc.name, make([]byte, tc.size), 0o644)
+		require.Equal(t, tc.want, p.SplitterForFile(f), tc.name)
+	}
+}
+
+func TestPolicyMergeSplitterRulesIncludingParents(t *testing.T) {
+	r0 := policy.SplitterRule{Algorithm: "FIXED-4M", Extensions: []string{".img"}}
+	r1 := policy.SplitterRule{Algorithm: "FIXED-1M", Extensions: []string{".img", ".iso"}}
+	r2 := policy.SplitterRule{Algorithm: "FIXED-2M"}
+
+	p0 := &policy.Policy{SplitterPolicy: policy.SplitterPolicy{Rules: []policy.SplitterRule{r0}}}
+	p1 := &policy.Policy{SplitterPolicy: policy.SplitterPolicy{Rules: []policy.SplitterRule{r1}}}
+	p2 := &policy.Policy{SplitterPolicy: policy.SplitterPolicy{Rules: []policy.SplitterRule{r2}}}
+
+	// rules from more specific policies come first.
+	result, _ := policy.MergePolicies([]*policy.Policy{p0, p1, p2}, p0.Target())
+	require.Equal(t, []policy.SplitterRule{r0, r1, r2}, result.SplitterPolicy.Rules)
+
+	p1.SplitterPolicy.NoParentRules = true
+	result, _ = policy.MergePolicies([]*policy.Policy{p0, p1, p2}, p0.Target())
+	require.Equal(t, []policy.SplitterRule{r0, r1}, result.SplitterPolicy.Rules)
+
+	p0.SplitterPolicy.NoParentRules = true
+	result, _ = policy.MergePolicies([]*policy.Policy{p0, p1, p2}, p0.Target())
+	require.Equal(t, []policy.SplitterRule{r0}, result.SplitterPolicy.Rules)
+}
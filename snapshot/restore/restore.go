@@ -9,6 +9,10 @@
 	"sync/atomic"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/internal/parallelwork"
@@ -19,6 +23,8 @@
 
 var log = logging.Module("restore")
 
+var tracer = otel.Tracer("kopia/restore")
+
 // FileWriteProgress is a callback used to report amount of data sent to the output.
 type FileWriteProgress func(chunkSize int64)
 
@@ -114,6 +120,9 @@ type Options struct {
 //
 //nolint:revive
 func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs.Entry, options Options) (Stats, error) {
+	ctx, span := tracer.Start(ctx, "Restore", trace.WithAttributes(attribute.String("root", rootEntry.Name())))
+	defer span.End()
+
 	c := copier{
 		output:           output,
 		shallowoutput:    makeShallowFilesystemOutput(output, options),
@@ -123,6 +132,7 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 		ignoreErrors:     options.IgnoreErrors,
 		cancel:           options.Cancel,
 		progressCallback: options.ProgressCallback,
+		traceEnabled:     span.IsRecording(),
 	}
 
 	c.q.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
@@ -146,14 +156,30 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 	}
 
 	if err := c.q.Process(ctx, numWorkers); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "restore error")
+
 		return Stats{}, errors.Wrap(err, "restore error")
 	}
 
 	if err := c.output.Close(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "error closing output")
+
 		return Stats{}, errors.Wrap(err, "error closing output")
 	}
 
-	return c.stats.clone(), nil
+	st := c.stats.clone()
+
+	span.SetAttributes(
+		attribute.Int("files", int(st.RestoredFileCount)),
+		attribute.Int("dirs", int(st.RestoredDirCount)),
+		attribute.Int64("totalSize", st.RestoredTotalFileSize),
+		attribute.Int("skipped", int(st.SkippedCount)),
+		attribute.Int("ignoredErrors", int(st.IgnoredErrorCount)),
+	)
+
+	return st, nil
 }
 
 type copier struct {
@@ -165,6 +191,7 @@ type copier struct {
 	deleteExtra   bool
 	ignoreErrors  bool
 	cancel        chan struct{}
+	traceEnabled  bool
 
 	progressCallback ProgressCallback
 }
@@ -230,27 +257,10 @@ func (c *copier) copyEntryInternal(ctx context.Context, e fs.Entry, targetPath s
 	case fs.File:
 		log(ctx).Debugf("file: '%v'", targetPath)
 
-		bytesExpected := e.Size()
-		bytesWritten := int64(0)
-		progressCallback := func(chunkSize int64) {
-			bytesWritten += chunkSize
-			c.stats.RestoredTotalFileSize.Add(chunkSize)
-			c.reportProgress(ctx)
+		if err := c.copyFile(ctx, e, targetPath, currentdepth, maxdepth); err != nil {
+			return err
 		}
 
-		if currentdepth > maxdepth {
-			if err := c.shallowoutput.WriteFile(ctx, targetPath, e, progressCallback); err != nil {
-				return errors.Wrap(err, "copy file")
-			}
-		} else {
-			if err := c.output.WriteFile(ctx, targetPath, e, progressCallback); err != nil {
-				return errors.Wrap(err, "copy file")
-			}
-		}
-
-		c.stats.RestoredFileCount.Add(1)
-		c.stats.RestoredTotalFileSize.Add(bytesExpected - bytesWritten)
-
 		return onCompletion()
 
 	case fs.Symlink:
@@ -268,6 +278,48 @@ func (c *copier) copyEntryInternal(ctx context.Context, e fs.Entry, targetPath s
 	}
 }
 
+func (c *copier) copyFile(ctx context.Context, f fs.File, targetPath string, currentdepth, maxdepth int32) (resultErr error) {
+	if c.traceEnabled {
+		var span trace.Span
+
+		ctx, span = tracer.Start(ctx, "RestoreFile", trace.WithAttributes(
+			attribute.String("path", targetPath),
+			attribute.Int64("size", f.Size()),
+		))
+
+		defer func() {
+			if resultErr != nil {
+				span.RecordError(resultErr)
+				span.SetStatus(codes.Error, "error restoring file")
+			}
+
+			span.End()
+		}()
+	}
+
+	bytesExpected := f.Size()
+	bytesWritten := int64(0)
+	progressCallback := func(chunkSize int64) {
+		bytesWritten += chunkSize
+		c.stats.RestoredTotalFileSize.Add(chunkSize)
+		c.reportProgress(ctx)
+	}
+
+	out := c.output
+	if currentdepth > maxdepth {
+		out = c.shallowoutput
+	}
+
+	if err := out.WriteFile(ctx, targetPath, f, progressCallback); err != nil {
+		return errors.Wrap(err, "copy file")
+	}
+
+	c.stats.RestoredFileCount.Add(1)
+	c.stats.RestoredTotalFileSize.Add(bytesExpected - bytesWritten)
+
+	return nil
+}
+
 func (c *copier) copyDirectory(ctx context.Context, d fs.Directory, targetPath string, currentdepth, maxdepth int32, onCompletion parallelwork.CallbackFunc) error {
 	c.stats.RestoredDirCount.Add(1)
 
@@ -0,0 +1,106 @@
+// Package snapshotfreshness detects sources whose snapshots are overdue according to their scheduling policies.
+package snapshotfreshness
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// DefaultGracePeriod is the default amount of time a snapshot may be late before the source is considered stale.
+const DefaultGracePeriod = time.Hour
+
+// FindStaleSources returns the sources whose latest complete snapshot is older than permitted by their
+// scheduling policy, extended by the provided grace period. When no sources are provided, all sources
+// in the repository are checked. Sources without scheduled snapshots are never stale.
+func FindStaleSources(ctx context.Context, rep repo.Repository, sources []snapshot.SourceInfo, now time.Time, gracePeriod time.Duration) (*notifydata.StaleSources, error) {
+	if len(sources) == 0 {
+		var err error
+
+		sources, err = snapshot.ListSources(ctx, rep)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to list sources")
+		}
+	}
+
+	result := &notifydata.StaleSources{
+		CheckTime:   now,
+		GracePeriod: gracePeriod,
+	}
+
+	for _, si := range sources {
+		ss, err := checkSource(ctx, rep, si, now, gracePeriod)
+		if err != nil {
+			return nil, err
+		}
+
+		if ss != nil {
+			result.Sources = append(result.Sources, ss)
+		}
+	}
+
+	sort.Slice(result.Sources, func(i, j int) bool {
+		return result.Sources[i].Source.String() < result.Sources[j].Source.String()
+	})
+
+	return result, nil
+}
+
+func checkSource(ctx context.Context, rep repo.Repository, si snapshot.SourceInfo, now time.Time, gracePeriod time.Duration) (*notifydata.StaleSource, error) {
+	manifests, err := snapshot.ListSnapshots(ctx, rep, si)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list snapshots of %v", si)
+	}
+
+	if len(manifests) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	pol, _, _, err := policy.GetEffectivePolicy(ctx, rep, si)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get effective policy for %v", si)
+	}
+
+	lastSnapshotTime := latestCompleteSnapshotTime(manifests)
+
+	// when the source was never snapshotted completely, the next snapshot was due after the first attempt.
+	referenceTime := lastSnapshotTime
+	if referenceTime.IsZero() {
+		referenceTime = snapshot.SortByTime(manifests, false)[0].StartTime.ToTime()
+	}
+
+	dueTime, ok := pol.SchedulingPolicy.SnapshotDueTime(referenceTime)
+	if !ok || !now.After(dueTime.Add(gracePeriod)) {
+		return nil, nil //nolint:nilnil
+	}
+
+	return &notifydata.StaleSource{
+		Source:           si,
+		LastSnapshotTime: lastSnapshotTime,
+		DueTime:          dueTime,
+		OverdueBy:        now.Sub(dueTime),
+	}, nil
+}
+
+func latestCompleteSnapshotTime(manifests []*snapshot.Manifest) time.Time {
+	var result time.Time
+
+	for _, m := range manifests {
+		if m.IncompleteReason != "" {
+			continue
+		}
+
+		if t := m.StartTime.ToTime(); t.After(result) {
+			result = t
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,90 @@
+package snapshotfreshness_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfreshness"
+)
+
+func TestFindStaleSources(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.Local)
+
+	hourly := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/hourly"}
+	daily := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/daily"}
+	manual := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/manual"}
+	unscheduled := snapshot.SourceInfo{Host: "other-host", UserName: "user", Path: "/unscheduled"}
+	incomplete := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/incomplete"}
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		// all sources of user@host are snapshotted hourly by default.
+		mustSetScheduling(ctx, t, w, snapshot.SourceInfo{Host: "host", UserName: "user"}, policy.SchedulingPolicy{IntervalSeconds: 3600})
+		mustSetScheduling(ctx, t, w, daily, policy.SchedulingPolicy{IntervalSeconds: 86400})
+		mustSetScheduling(ctx, t, w, manual, policy.SchedulingPolicy{Manual: true})
+
+		// last snapshot 3 hours ago, overdue by 2 hours.
+		mustSaveSnapshot(ctx, t, w, hourly, now.Add(-3*time.Hour), "")
+		mustSaveSnapshot(ctx, t, w, hourly, now.Add(-5*time.Hour), "")
+
+		// last snapshot 8 hours ago, not due until midnight.
+		mustSaveSnapshot(ctx, t, w, daily, now.Add(-8*time.Hour), "")
+
+		mustSaveSnapshot(ctx, t, w, manual, now.Add(-100*time.Hour), "")
+		mustSaveSnapshot(ctx, t, w, unscheduled, now.Add(-100*time.Hour), "")
+
+		// only a checkpoint was created 10 hours ago.
+		mustSaveSnapshot(ctx, t, w, incomplete, now.Add(-10*time.Hour), "checkpoint")
+
+		return nil
+	}))
+
+	st, err := snapshotfreshness.FindStaleSources(ctx, env.Repository, nil, now, 30*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, st.Sources, 2)
+
+	require.Equal(t, hourly, st.Sources[0].Source)
+	require.Equal(t, 2*time.Hour, st.Sources[0].OverdueBy)
+	require.True(t, now.Add(-3*time.Hour).Equal(st.Sources[0].LastSnapshotTime))
+
+	require.Equal(t, incomplete, st.Sources[1].Source)
+	require.True(t, st.Sources[1].LastSnapshotTime.IsZero())
+	require.Equal(t, 9*time.Hour, st.Sources[1].OverdueBy)
+
+	// grace period longer than the delay.
+	st, err = snapshotfreshness.FindStaleSources(ctx, env.Repository, []snapshot.SourceInfo{hourly}, now, 3*time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, st.Sources)
+
+	// daily source checked a day later.
+	st, err = snapshotfreshness.FindStaleSources(ctx, env.Repository, []snapshot.SourceInfo{daily}, now.Add(24*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, st.Sources, 1)
+}
+
+func mustSetScheduling(ctx context.Context, t *testing.T, w repo.RepositoryWriter, si snapshot.SourceInfo, sp policy.SchedulingPolicy) {
+	t.Helper()
+
+	require.NoError(t, policy.SetPolicy(ctx, w, si, &policy.Policy{SchedulingPolicy: sp}))
+}
+
+func mustSaveSnapshot(ctx context.Context, t *testing.T, w repo.RepositoryWriter, si snapshot.SourceInfo, startTime time.Time, incompleteReason string) {
+	t.Helper()
+
+	_, err := snapshot.SaveSnapshot(ctx, w, &snapshot.Manifest{
+		Source:           si,
+		StartTime:        fs.UTCTimestampFromTime(startTime),
+		EndTime:          fs.UTCTimestampFromTime(startTime.Add(time.Minute)),
+		IncompleteReason: incompleteReason,
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,311 @@
+package snapshotfs
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/internal/cache"
+	"github.com/kopia/kopia/internal/iocopy"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+var inventoryLog = logging.Module("inventory")
+
+// InventoryEntry describes a single file or symbolic link found in a snapshot.
+type InventoryEntry struct {
+	Path     string             `json:"path"`
+	Type     snapshot.EntryType `json:"type"`
+	Size     int64              `json:"size"`
+	ModTime  time.Time          `json:"mtime"`
+	UserID   uint32             `json:"uid"`
+	GroupID  uint32             `json:"gid"`
+	Mode     os.FileMode        `json:"mode"` // includes setuid, setgid and sticky bits
+	ObjectID object.ID          `json:"obj"`
+
+	// hex-encoded SHA-256 of file contents, empty for symbolic links.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// InventoryStats contains statistics about the inventory.
+type InventoryStats struct {
+	Files       int64 `json:"files"`
+	HashedFiles int64 `json:"hashedFiles"`
+	HashedBytes int64 `json:"hashedBytes"`
+}
+
+// InventoryBuilder lists all files in snapshots along with the SHA-256 hash of their contents.
+// Hashes are cached by object ID, so files that are shared between snapshots are only read once.
+// The cache can be persisted in a file, in which case files are only read once across invocations.
+type InventoryBuilder struct {
+	rep    repo.Repository
+	hashes *bigmap.Map
+
+	cacheMu sync.Mutex
+	// +checklocks:cacheMu
+	cacheFile *os.File
+
+	files       atomic.Int64
+	hashedFiles atomic.Int64
+	hashedBytes atomic.Int64
+}
+
+// NewInventoryBuilder creates new InventoryBuilder, which must be closed after use.
+// If cacheFile is not empty, hashes are loaded from and added to the provided file.
+func NewInventoryBuilder(ctx context.Context, rep repo.Repository, cacheFile string) (*InventoryBuilder, error) {
+	hashes, err := bigmap.NewMap(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create hash cache")
+	}
+
+	b := &InventoryBuilder{
+		rep:    rep,
+		hashes: hashes,
+	}
+
+	if cacheFile != "" {
+		// the cache only avoids reading files, so it's not fatal if it can't be used.
+		if err := b.openCacheFile(ctx, cacheFile); err != nil {
+			inventoryLog(ctx).Warnf("unable to use inventory cache: %v", err)
+		}
+	}
+
+	return b, nil
+}
+
+// openCacheFile loads hashes from the cache file, which has one line per object in the format
+// "<object-id> <sha256>", and opens it for appending.
+func (b *InventoryBuilder) openCacheFile(ctx context.Context, fname string) error {
+	if err := os.MkdirAll(filepath.Dir(fname), cache.DirMode); err != nil {
+		return errors.Wrap(err, "unable to create cache directory")
+	}
+
+	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600) //nolint:mnd
+	if err != nil {
+		return errors.Wrap(err, "unable to open cache file")
+	}
+
+	var keybuf [128]byte
+
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		oidStr, hashStr, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			// ignore lines truncated by a crash.
+			continue
+		}
+
+		oid, err := object.ParseID(oidStr)
+		if err != nil {
+			continue
+		}
+
+		h, err := hex.DecodeString(hashStr)
+		if err != nil || len(h) != sha256.Size {
+			continue
+		}
+
+		b.hashes.PutIfAbsent(ctx, oid.Append(keybuf[:0]), h)
+	}
+
+	if err := s.Err(); err != nil {
+		f.Close() //nolint:errcheck
+
+		return errors.Wrap(err, "unable to read cache file")
+	}
+
+	if err := terminateLastLine(f); err != nil {
+		f.Close() //nolint:errcheck
+
+		return err
+	}
+
+	b.cacheMu.Lock()
+	b.cacheFile = f
+	b.cacheMu.Unlock()
+
+	return nil
+}
+
+// terminateLastLine appends a newline to the file if its last line was truncated, so that appended lines remain valid.
+func terminateLastLine(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "unable to stat cache file")
+	}
+
+	if fi.Size() == 0 {
+		return nil
+	}
+
+	var last [1]byte
+
+	if _, err := f.ReadAt(last[:], fi.Size()-1); err != nil {
+		return errors.Wrap(err, "unable to read cache file")
+	}
+
+	if last[0] == '\n' {
+		return nil
+	}
+
+	_, err = f.WriteString("\n")
+
+	return errors.Wrap(err, "unable to write cache file")
+}
+
+func (b *InventoryBuilder) addToCacheFile(ctx context.Context, oid object.ID, h []byte) {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	if b.cacheFile == nil {
+		return
+	}
+
+	// each line is written with a single call, so a crash can only leave a truncated last line.
+	if _, err := b.cacheFile.WriteString(oid.String() + " " + hex.EncodeToString(h) + "\n"); err != nil {
+		inventoryLog(ctx).Warnf("unable to write inventory cache: %v", err)
+
+		b.cacheFile.Close() //nolint:errcheck
+		b.cacheFile = nil
+	}
+}
+
+// Close releases resources associated with the builder.
+func (b *InventoryBuilder) Close(ctx context.Context) {
+	b.cacheMu.Lock()
+	if b.cacheFile != nil {
+		if err := b.cacheFile.Close(); err != nil {
+			inventoryLog(ctx).Warnf("unable to close inventory cache: %v", err)
+		}
+
+		b.cacheFile = nil
+	}
+	b.cacheMu.Unlock()
+
+	b.hashes.Close(ctx)
+}
+
+// Stats returns the statistics of files processed so far.
+func (b *InventoryBuilder) Stats() InventoryStats {
+	return InventoryStats{
+		Files:       b.files.Load(),
+		HashedFiles: b.hashedFiles.Load(),
+		HashedBytes: b.hashedBytes.Load(),
+	}
+}
+
+// Walk invokes the provided callback for each file and symbolic link under the provided root in a depth-first order,
+// paths are relative to the root.
+func (b *InventoryBuilder) Walk(ctx context.Context, root fs.Entry, cb func(ctx context.Context, e *InventoryEntry) error) error {
+	w, err := NewTreeWalker(ctx, TreeWalkerOptions{
+		EntryCallback: func(ctx context.Context, entry fs.Entry, oid object.ID, entryPath string) error {
+			return b.processEntry(ctx, entry, oid, entryPath, cb)
+		},
+		// walk sequentially to produce stable ordering.
+		Parallelism:     1,
+		VisitDuplicates: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create tree walker")
+	}
+
+	defer w.Close(ctx)
+
+	return w.Process(ctx, root, "")
+}
+
+func (b *InventoryBuilder) processEntry(ctx context.Context, entry fs.Entry, oid object.ID, entryPath string, cb func(ctx context.Context, e *InventoryEntry) error) error {
+	if entryPath == "" {
+		// root of a snapshot of a single file.
+		entryPath = entry.Name()
+	}
+
+	e := &InventoryEntry{
+		Path:     entryPath,
+		Size:     entry.Size(),
+		ModTime:  entry.ModTime(),
+		UserID:   entry.Owner().UserID,
+		GroupID:  entry.Owner().GroupID,
+		Mode:     entry.Mode() & fs.ModBits,
+		ObjectID: oid,
+	}
+
+	switch entry.(type) {
+	case fs.Directory:
+		return nil
+
+	case fs.Symlink:
+		e.Type = snapshot.EntryTypeSymlink
+
+	case fs.File:
+		h, err := b.contentHash(ctx, oid)
+		if err != nil {
+			return err
+		}
+
+		e.Type = snapshot.EntryTypeFile
+		e.SHA256 = hex.EncodeToString(h)
+
+	default:
+		return nil
+	}
+
+	b.files.Add(1)
+
+	return cb(ctx, e)
+}
+
+func (b *InventoryBuilder) contentHash(ctx context.Context, oid object.ID) ([]byte, error) {
+	var keybuf [128]byte
+
+	key := oid.Append(keybuf[:0])
+
+	h, ok, err := b.hashes.Get(ctx, nil, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read cached hash")
+	}
+
+	if ok {
+		return h, nil
+	}
+
+	r, err := b.rep.OpenObject(ctx, oid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open object %v", oid)
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	hasher := sha256.New()
+
+	n, err := iocopy.Copy(hasher, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read object %v", oid)
+	}
+
+	b.hashedFiles.Add(1)
+	b.hashedBytes.Add(n)
+
+	h = hasher.Sum(nil)
+
+	if b.hashes.PutIfAbsent(ctx, key, h) {
+		b.addToCacheFile(ctx, oid, h)
+	}
+
+	return h, nil
+}
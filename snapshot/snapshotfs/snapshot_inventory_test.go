@@ -0,0 +1,133 @@
+package snapshotfs_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/snapshot/upload"
+)
+
+func TestInventoryBuilder(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	sourceRoot := mockfs.NewDirectory()
+
+	dir1 := sourceRoot.AddDir("dir1", 0o755)
+	dir2 := sourceRoot.AddDir("dir2", 0o755)
+
+	dir1.AddFile("file11", []byte{1, 2, 3}, 0o644)
+	dir1.AddSymlink("link", "file11", 0o777)
+	dir2.AddFile("file21", []byte{1, 2, 3, 4}, 0o700|os.ModeSetuid|os.ModeSticky)
+	dir2.AddFile("file22", []byte{1, 2, 3}, 0o644) // same content as dir1/file11
+	sourceRoot.AddFile("empty", nil, 0o644)
+
+	u := upload.NewUploader(env.RepositoryWriter)
+	man, err := u.Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	root, err := snapshotfs.SnapshotRoot(env.Repository, man)
+	require.NoError(t, err)
+
+	cacheFile := filepath.Join(testutil.TempDirectory(t), "inventory", "hashes")
+
+	b, err := snapshotfs.NewInventoryBuilder(ctx, env.Repository, cacheFile)
+	require.NoError(t, err)
+
+	defer b.Close(ctx)
+
+	var entries []*snapshotfs.InventoryEntry
+
+	collect := func(_ context.Context, e *snapshotfs.InventoryEntry) error {
+		entries = append(entries, e)
+		return nil
+	}
+
+	require.NoError(t, b.Walk(ctx, root, collect))
+
+	byPath := map[string]*snapshotfs.InventoryEntry{}
+
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+
+	// duplicate files are listed under each path.
+	require.Len(t, entries, 5)
+	require.Len(t, byPath, 5)
+
+	e := byPath["dir1/file11"]
+	require.Equal(t, snapshot.EntryTypeFile, e.Type)
+	require.Equal(t, sha256Hex([]byte{1, 2, 3}), e.SHA256)
+	require.Equal(t, int64(3), e.Size)
+	require.Equal(t, os.FileMode(0o644), e.Mode)
+
+	require.Equal(t, snapshot.EntryTypeSymlink, byPath["dir1/link"].Type)
+	require.Empty(t, byPath["dir1/link"].SHA256)
+
+	require.Equal(t, sha256Hex([]byte{1, 2, 3, 4}), byPath["dir2/file21"].SHA256)
+	require.Equal(t, 0o700|os.ModeSetuid|os.ModeSticky, byPath["dir2/file21"].Mode)
+	require.Equal(t, e.SHA256, byPath["dir2/file22"].SHA256)
+	require.Equal(t, sha256Hex(nil), byPath["empty"].SHA256)
+
+	// identical files are only read once.
+	require.Equal(t, snapshotfs.InventoryStats{Files: 5, HashedFiles: 3, HashedBytes: 7}, b.Stats())
+
+	// unchanged files are not read again when listing another snapshot.
+	dir2.AddFile("file23", []byte{5, 6, 7, 8, 9}, 0o644)
+
+	man, err = u.Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	root, err = snapshotfs.SnapshotRoot(env.Repository, man)
+	require.NoError(t, err)
+
+	entries = nil
+
+	require.NoError(t, b.Walk(ctx, root, collect))
+	require.Len(t, entries, 6)
+	require.Equal(t, snapshotfs.InventoryStats{Files: 11, HashedFiles: 4, HashedBytes: 12}, b.Stats())
+
+	// hashes are persisted in the cache file, a truncated last line is ignored.
+	f, err := os.OpenFile(cacheFile, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("truncated")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b2, err := snapshotfs.NewInventoryBuilder(ctx, env.Repository, cacheFile)
+	require.NoError(t, err)
+
+	defer b2.Close(ctx)
+
+	entries = nil
+
+	require.NoError(t, b2.Walk(ctx, root, collect))
+	require.Len(t, entries, 6)
+	require.Equal(t, snapshotfs.InventoryStats{Files: 6}, b2.Stats())
+
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+
+	require.Equal(t, sha256Hex([]byte{5, 6, 7, 8, 9}), byPath["dir2/file23"].SHA256)
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+
+	return hex.EncodeToString(h[:])
+}
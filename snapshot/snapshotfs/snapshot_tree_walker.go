@@ -96,6 +96,10 @@ func (w *TreeWalker) TooManyErrors() bool {
 }
 
 func (w *TreeWalker) alreadyProcessed(ctx context.Context, e fs.Entry) bool {
+	if w.options.VisitDuplicates {
+		return false
+	}
+
 	var idbuf [128]byte
 
 	return !w.enqueued.Put(ctx, oidOf(e).Append(idbuf[:0]))
@@ -183,6 +187,10 @@ type TreeWalkerOptions struct {
 
 	Parallelism int
 	MaxErrors   int
+
+	// VisitDuplicates causes the callback to be invoked for each path at which an object is found,
+	// instead of just the first one.
+	VisitDuplicates bool
 }
 
 // NewTreeWalker creates new tree walker.
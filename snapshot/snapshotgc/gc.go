@@ -22,13 +22,14 @@
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
 
 // User-visible log output.
 var userLog = logging.Module("snapshotgc")
 
-func findInUseContentIDs(ctx context.Context, log *contentlog.Logger, rep repo.Repository, used *bigmap.Set) error {
+func findInUseContentIDs(ctx context.Context, log *contentlog.Logger, rep repo.Repository, used *bigmap.Set, maintenanceStartTime time.Time) error {
 	ids, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
 	if err != nil {
 		return errors.Wrap(err, "unable to list snapshot manifest IDs")
@@ -39,6 +40,16 @@ func findInUseContentIDs(ctx context.Context, log *contentlog.Logger, rep repo.R
 		return errors.Wrap(err, "unable to load manifest IDs")
 	}
 
+	// contents of snapshots under legal hold remain in use even if their manifests were deleted.
+	holds, err := legalhold.Active(ctx, rep, maintenanceStartTime)
+	if err != nil {
+		return errors.Wrap(err, "unable to list legal holds")
+	}
+
+	for _, h := range holds {
+		manifests = append(manifests, h.Snapshot)
+	}
+
 	w, twerr := snapshotfs.NewTreeWalker(ctx, snapshotfs.TreeWalkerOptions{
 		EntryCallback: func(ctx context.Context, _ fs.Entry, oid object.ID, _ string) error {
 			contentIDs, verr := rep.VerifyObject(ctx, oid)
@@ -98,7 +109,7 @@ func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete
 	}
 	defer used.Close(ctx)
 
-	if err := findInUseContentIDs(ctx, log, rep, used); err != nil {
+	if err := findInUseContentIDs(ctx, log, rep, used, maintenanceStartTime); err != nil {
 		return nil, errors.Wrap(err, "unable to find in-use content ID")
 	}
 
@@ -3,36 +3,172 @@
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/repo/maintenancestats"
 	"github.com/kopia/kopia/snapshot/snapshotgc"
 )
 
+var userLog = logging.Module("snapshotmaintenance")
+
 // ErrReadonly indicates a failure when attempting to run maintenance on a read-only repository.
 var ErrReadonly = errors.New("not running maintenance on read-only repository connection")
 
 // Run runs the complete snapshot and repository maintenance.
 func Run(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters) error {
+	_, err := RunWithReport(ctx, dr, mode, force, safety)
+
+	return err
+}
+
+// RunWithReport runs the complete snapshot and repository maintenance and returns the report
+// summarizing the tasks that were executed. The report is nil if maintenance was not due or is already
+// running, if it could not start, the report describes the error. Automatic maintenance on a client
+// that doesn't own maintenance is expected not to run, so no report is returned in that case.
+func RunWithReport(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters) (*notifydata.MaintenanceReport, error) {
 	if dr.ClientOptions().ReadOnly {
-		return ErrReadonly
+		return errorReport(dr, mode, ErrReadonly), ErrReadonly
 	}
 
 	dr.LogManager().Enable()
 
-	//nolint:wrapcheck
-	return maintenance.RunExclusive(ctx, dr, mode, force,
+	var report *notifydata.MaintenanceReport
+
+	err := maintenance.RunExclusive(ctx, dr, mode, force,
 		func(ctx context.Context, runParams maintenance.RunParameters) error {
-			// run snapshot GC before full maintenance
-			if runParams.Mode == maintenance.ModeFull {
-				if err := snapshotgc.Run(ctx, dr, true, safety, runParams.MaintenanceStartTime); err != nil {
-					return errors.Wrap(err, "snapshot GC failure")
-				}
+			report = &notifydata.MaintenanceReport{
+				Mode:      string(runParams.Mode),
+				StartTime: dr.Time(),
 			}
 
-			//nolint:wrapcheck
-			return maintenance.Run(ctx, runParams, safety)
+			err := runSnapshotAndRepositoryMaintenance(ctx, dr, runParams, safety)
+
+			report.EndTime = dr.Time()
+			if err != nil {
+				report.Error = err.Error()
+			}
+
+			report.Tasks = tasksSince(ctx, dr, report.StartTime)
+
+			return err
 		})
+
+	var noe maintenance.NotOwnedError
+
+	if report == nil && err != nil && (mode != maintenance.ModeAuto || !errors.As(err, &noe)) {
+		report = errorReport(dr, mode, err)
+	}
+
+	//nolint:wrapcheck
+	return report, err
+}
+
+// ReportSeverity returns the severity of the maintenance report notification, failures are reported
+// as errors while successful quick maintenance is only reported verbosely.
+func ReportSeverity(r *notifydata.MaintenanceReport) sender.Severity {
+	switch {
+	case r.OverallStatusCode() == notifydata.StatusCodeFatal:
+		return sender.SeverityError
+	case r.Mode == string(maintenance.ModeQuick):
+		return sender.SeverityVerbose
+	default:
+		return sender.SeverityReport
+	}
+}
+
+// errorReport returns the report of maintenance that failed to start.
+func errorReport(dr repo.DirectRepository, mode maintenance.Mode, err error) *notifydata.MaintenanceReport {
+	now := dr.Time()
+
+	return &notifydata.MaintenanceReport{
+		Mode:      string(mode),
+		StartTime: now,
+		EndTime:   now,
+		Error:     err.Error(),
+	}
+}
+
+func runSnapshotAndRepositoryMaintenance(ctx context.Context, dr repo.DirectRepositoryWriter, runParams maintenance.RunParameters, safety maintenance.SafetyParameters) error {
+	// run snapshot GC before full maintenance
+	if runParams.Mode == maintenance.ModeFull {
+		if err := snapshotgc.Run(ctx, dr, true, safety, runParams.MaintenanceStartTime); err != nil {
+			return errors.Wrap(err, "snapshot GC failure")
+		}
+	}
+
+	//nolint:wrapcheck
+	return maintenance.Run(ctx, runParams, safety)
+}
+
+// tasksSince returns reports of maintenance tasks recorded in the schedule that started no earlier than the provided time.
+func tasksSince(ctx context.Context, dr repo.DirectRepository, since time.Time) []*notifydata.MaintenanceTaskReport {
+	s, err := maintenance.GetSchedule(ctx, dr)
+	if err != nil {
+		userLog(ctx).Debugf("unable to get maintenance schedule: %v", err)
+		return nil
+	}
+
+	var result []*notifydata.MaintenanceTaskReport
+
+	for taskType, runs := range s.Runs {
+		for _, ri := range runs {
+			if ri.Start.Before(since) {
+				continue
+			}
+
+			result = append(result, taskReport(taskType, ri))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].StartTime.Equal(result[j].StartTime) {
+			return result[i].StartTime.Before(result[j].StartTime)
+		}
+
+		return result[i].Task < result[j].Task
+	})
+
+	return result
+}
+
+func taskReport(taskType maintenance.TaskType, ri maintenance.RunInfo) *notifydata.MaintenanceTaskReport {
+	tr := &notifydata.MaintenanceTaskReport{
+		Task:      string(taskType),
+		StartTime: ri.Start,
+		EndTime:   ri.End,
+		Error:     ri.Error,
+	}
+
+	for _, extra := range ri.Extra {
+		stats, err := maintenancestats.BuildFromExtra(extra)
+		if err != nil {
+			continue
+		}
+
+		if tr.Summary != "" {
+			tr.Summary += " "
+		}
+
+		tr.Summary += stats.Summary()
+
+		switch st := stats.(type) {
+		case *maintenancestats.SnapshotGCStats:
+			tr.ContentsDropped += int64(st.DeletedContentCount) //nolint:gosec
+		case *maintenancestats.DeleteUnreferencedPacksStats:
+			tr.PacksDeleted += int64(st.DeletedPackCount)   //nolint:gosec
+			tr.BytesReclaimed += int64(st.DeletedTotalSize) //nolint:gosec
+		case *maintenancestats.CleanupLogsStats:
+			tr.BytesReclaimed += int64(st.DeletedBlobSize) //nolint:gosec
+		}
+	}
+
+	return tr
 }
@@ -16,12 +16,15 @@
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/notification/notifydata"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/legalhold"
 	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 )
 
@@ -81,6 +84,115 @@ func (s *formatSpecificTestSuite) TestSnapshotGCSimple(t *testing.T) {
 	t.Log("root info:", pretty.Sprint(info))
 }
 
+func (s *formatSpecificTestSuite) TestSnapshotGCLegalHold(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	th.sourceDir.AddFile("f1", []byte{1, 2, 3, 4, 5}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+	s1 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+
+	now := th.fakeTime.NowFunc()()
+
+	holdID, err := legalhold.Add(ctx, th.RepositoryWriter, &legalhold.Hold{
+		Reason:      "litigation",
+		Owner:       "legal@host",
+		CreatedTime: now,
+		ExpiresTime: now.Add(30 * 24 * time.Hour),
+		Snapshot:    s1,
+	})
+	require.NoError(t, err)
+
+	// the snapshot manifest is deleted bypassing legal hold checks.
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s1.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	cids := []content.ID{mustGetContentID(t, s1.RootObjectID())}
+	safety := maintenance.SafetyFull
+
+	th.fakeTime.Advance(safety.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, safety))
+	mustFlush(t, th.RepositoryWriter)
+
+	// contents of the held snapshot are still in use.
+	checkContentDeletion(t, th.RepositoryWriter, cids, false)
+
+	require.NoError(t, legalhold.Release(ctx, th.RepositoryWriter, holdID, th.fakeTime.NowFunc()(), true))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(safety.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, safety))
+	mustFlush(t, th.RepositoryWriter)
+
+	checkContentDeletion(t, th.RepositoryWriter, cids, true)
+}
+
+func (s *formatSpecificTestSuite) TestMaintenanceReport(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	th.sourceDir.AddDir("d1", defaultPermissions)
+	th.sourceDir.AddFile("d1/f2", []byte{1, 2, 3, 4}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+	s1 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s1.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	report, err := snapshotmaintenance.RunWithReport(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	require.Equal(t, "full", report.Mode)
+	require.Empty(t, report.Error)
+	require.Equal(t, notifydata.StatusCodeSuccess, report.OverallStatusCode())
+	require.False(t, report.EndTime.Before(report.StartTime))
+
+	var tasks []string
+
+	for _, tr := range report.Tasks {
+		tasks = append(tasks, tr.Task)
+		require.Empty(t, tr.Error)
+	}
+
+	require.Contains(t, tasks, maintenance.TaskSnapshotGarbageCollection)
+	require.Positive(t, report.TotalContentsDropped())
+
+	// maintenance that fails to start is reported as an error, except for automatic maintenance
+	// on a client that doesn't own maintenance.
+	p, err := maintenance.GetParams(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+
+	p.Owner = "someone@else"
+	require.NoError(t, maintenance.SetParams(ctx, th.RepositoryWriter, p))
+
+	report, err = snapshotmaintenance.RunWithReport(ctx, th.RepositoryWriter, maintenance.ModeQuick, false, maintenance.SafetyFull)
+	require.ErrorAs(t, err, &maintenance.NotOwnedError{})
+	require.NotNil(t, report)
+	require.Equal(t, "quick", report.Mode)
+	require.Equal(t, err.Error(), report.Error)
+	require.Equal(t, sender.SeverityError, snapshotmaintenance.ReportSeverity(report))
+
+	report, err = snapshotmaintenance.RunWithReport(ctx, th.RepositoryWriter, maintenance.ModeAuto, false, maintenance.SafetyFull)
+	require.ErrorAs(t, err, &maintenance.NotOwnedError{})
+	require.Nil(t, report)
+}
+
 // Test maintenance when a directory is deleted and then reused.
 // Scenario / events:
 //   - create snapshot s1 on a directory d is created
@@ -443,3 +555,9 @@ func checkContentDeletion(t *testing.T, r repo.Repository, cids []content.ID, de
 		require.Equalf(t, deleted, ci.Deleted, "i:%d cid:%s", i, cid)
 	}
 }
+
+func TestReportSeverity(t *testing.T) {
+	require.Equal(t, sender.SeverityVerbose, snapshotmaintenance.ReportSeverity(&notifydata.MaintenanceReport{Mode: string(maintenance.ModeQuick)}))
+	require.Equal(t, sender.SeverityReport, snapshotmaintenance.ReportSeverity(&notifydata.MaintenanceReport{Mode: string(maintenance.ModeFull)}))
+	require.Equal(t, sender.SeverityError, snapshotmaintenance.ReportSeverity(&notifydata.MaintenanceReport{Mode: string(maintenance.ModeQuick), Error: "failed"}))
+}
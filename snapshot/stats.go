@@ -30,6 +30,18 @@ type Stats struct {
 	// +checkatomic
 	ExcludedDirCount int32 `json:"excludedDirCount"`
 
+	// +checkatomic
+	ReusedDirCount int32 `json:"reusedDirCount,omitempty"`
+
+	// number of files for which automatic compression selection picked no compression,
+	// fast or strong compressor respectively.
+	// +checkatomic
+	AutoCompressionNoneCount int32 `json:"autoCompressionNone,omitempty"`
+	// +checkatomic
+	AutoCompressionFastCount int32 `json:"autoCompressionFast,omitempty"`
+	// +checkatomic
+	AutoCompressionStrongCount int32 `json:"autoCompressionStrong,omitempty"`
+
 	// +checkatomic
 	IgnoredErrorCount int32 `json:"ignoredErrorCount"`
 	// +checkatomic
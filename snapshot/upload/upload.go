@@ -11,6 +11,7 @@
 	"path"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +19,7 @@
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/fs"
@@ -60,6 +62,10 @@
 	IncompleteReasonCheckpoint   = "checkpoint"
 	IncompleteReasonCanceled     = "canceled"
 	IncompleteReasonLimitReached = "limit reached"
+
+	IncompleteReasonTotalSizeQuota = "total size quota exceeded"
+	IncompleteReasonNewBytesQuota  = "new data quota exceeded"
+	IncompleteReasonFileCountQuota = "file count quota exceeded"
 )
 
 // Uploader supports efficient uploading files and directories to repository.
@@ -98,6 +104,10 @@ type Uploader struct {
 	// Labels to apply to every checkpoint made for this snapshot.
 	CheckpointLabels map[string]string
 
+	// When set, directories reported as unchanged since the previous snapshot are not read,
+	// their entries are reused from the previous snapshot instead.
+	ChangeSource ChangeSource
+
 	repo repo.RepositoryWriter
 
 	// stats must be allocated on heap to enforce 64-bit alignment due to atomic access on ARM.
@@ -113,6 +123,15 @@ type Uploader struct {
 	// disable snapshot size estimation
 	disableEstimation bool
 
+	// time since which directories must be unchanged to be reused from previous snapshots.
+	unchangedSince time.Time
+
+	// local directory path to bool indicating whether dot-ignore files in it or its ancestors have changed.
+	ignoreFilesChangedByDir sync.Map
+
+	// quotas of the snapshot being uploaded, taken from the policy of the source root.
+	quota policy.QuotaPolicy
+
 	workerPool *workshare.Pool[*uploadWorkItem]
 
 	traceEnabled bool
@@ -133,6 +152,27 @@ func (u *Uploader) incompleteReason() string {
 		return IncompleteReasonLimitReached
 	}
 
+	return u.quotaExceededReason(wb)
+}
+
+func (u *Uploader) quotaExceededReason(writtenBytes int64) string {
+	if q := u.quota.MaxNewBytes.OrDefault(0); q > 0 && writtenBytes > q {
+		return IncompleteReasonNewBytesQuota
+	}
+
+	if u.stats == nil {
+		return ""
+	}
+
+	if q := u.quota.MaxTotalSize.OrDefault(0); q > 0 && atomic.LoadInt64(&u.stats.TotalFileSize) > q {
+		return IncompleteReasonTotalSizeQuota
+	}
+
+	numFiles := int64(atomic.LoadInt32(&u.stats.CachedFiles)) + int64(atomic.LoadInt32(&u.stats.NonCachedFiles))
+	if q := u.quota.MaxFileCount.OrDefault(0); q > 0 && numFiles > q {
+		return IncompleteReasonFileCountQuota
+	}
+
 	return ""
 }
 
@@ -159,10 +199,24 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 		}
 	}
 
-	comp := pol.CompressionPolicy.CompressorForFile(f)
+	comp, sampled := u.fileCompressor(ctx, f, pol)
+	defer sampled.release()
+
 	metadataComp := pol.MetadataCompressionPolicy.MetadataCompressor()
 	splitterName := pol.SplitterPolicy.SplitterForFile(f)
 
+	if sf, ok := f.(fs.SparseFile); ok {
+		return u.uploadSparseFile(ctx, sampledSparseFile{sampled, sf}, comp, metadataComp, splitterName)
+	}
+
+	f = sampled
+
+	if pol.UploadPolicy.SplitArchiveMembers.OrDefault(false) {
+		if offsets := archivePartOffsets(ctx, f); len(offsets) > 1 {
+			return u.uploadFileParts(ctx, parentCheckpointRegistry, f, offsets, comp, metadataComp, splitterName)
+		}
+	}
+
 	chunkSize := pol.UploadPolicy.ParallelUploadAboveSize.OrDefault(-1)
 	if chunkSize < 0 || f.Size() <= chunkSize {
 		// all data fits in 1 full chunks, upload directly
@@ -170,22 +224,30 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 	}
 
 	// we always have N+1 parts, first N are exactly chunkSize, last one has undetermined length
-	fullParts := f.Size() / chunkSize
+	offsets := make([]int64, f.Size()/chunkSize+1)
+	for i := range offsets {
+		offsets[i] = int64(i) * chunkSize
+	}
+
+	return u.uploadFileParts(ctx, parentCheckpointRegistry, f, offsets, comp, metadataComp, splitterName)
+}
 
+// uploadFileParts uploads parts of the file starting at provided offsets in parallel and concatenates the results.
+func (u *Uploader) uploadFileParts(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, f fs.File, offsets []int64, comp, metadataComp compression.Name, splitterName string) (*snapshot.DirEntry, error) {
 	// directory entries and errors for partial upload results
-	parts := make([]*snapshot.DirEntry, fullParts+1)
-	partErrors := make([]error, fullParts+1)
+	parts := make([]*snapshot.DirEntry, len(offsets))
+	partErrors := make([]error, len(offsets))
 
 	var wg workshare.AsyncGroup[*uploadWorkItem]
 	defer wg.Close()
 
 	for i := range parts {
-		offset := int64(i) * chunkSize
+		offset := offsets[i]
 
-		length := chunkSize
-		if i == len(parts)-1 {
-			// last part has unknown length to accommodate the file that may be growing as we're snapshotting it
-			length = -1
+		// last part has unknown length to accommodate the file that may be growing as we're snapshotting it
+		length := int64(-1)
+		if i < len(parts)-1 {
+			length = offsets[i+1] - offset
 		}
 
 		if wg.CanShareWork(u.workerPool) {
@@ -354,7 +416,7 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 		u.Progress.FinishedFile(relativePath, ret)
 	}()
 
-	comp := pol.CompressionPolicy.CompressorForFile(f)
+	comp, input := u.streamingFileCompressor(ctx, f, reader, pol)
 	metadataComp := pol.MetadataCompressionPolicy.MetadataCompressor()
 
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
@@ -366,7 +428,7 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 
 	defer writer.Close() //nolint:errcheck
 
-	written, err := u.copyWithProgress(writer, reader)
+	written, err := u.copyWithProgress(writer, input)
 	if err != nil {
 		return nil, err
 	}
@@ -881,6 +943,19 @@ func (u *Uploader) processSingle(
 		childTree := policyTree.Child(entry.Name())
 		childPrevDirs := uniqueChildDirectories(ctx, prevDirs, entry.Name())
 
+		if de := u.findUnchangedSubtree(ctx, entry, childLocalDirPathOrEmpty, childTree, childPrevDirs, policyTree, prevDirs); de != nil {
+			atomic.AddInt32(&u.stats.ReusedDirCount, 1)
+			atomic.AddInt32(&u.stats.TotalDirectoryCount, int32(de.DirSummary.TotalDirCount)) //nolint:gosec
+			atomic.AddInt32(&u.stats.CachedFiles, int32(de.DirSummary.TotalFileCount))        //nolint:gosec
+			atomic.AddInt64(&u.stats.TotalFileSize, de.DirSummary.TotalFileSize)
+			u.Progress.CachedFile(entryRelativePath, de.DirSummary.TotalFileSize)
+
+			return u.processEntryUploadResult(ctx, de, nil, entryRelativePath, parentDirBuilder,
+				false,
+				u.OverrideDirLogDetail.OrDefault(childTree.EffectivePolicy().LoggingPolicy.Directories.Snapshotted.OrDefault(policy.LogDetailNone)),
+				"unchanged directory", t0)
+		}
+
 		de, err := uploadDirInternal(ctx, u, entry, childTree, childPrevDirs, childLocalDirPathOrEmpty, entryRelativePath, childDirBuilder, parentCheckpointRegistry)
 		if errors.Is(err, errCanceled) {
 			return err
@@ -1132,7 +1207,20 @@ func uploadDirInternal(
 		var span trace.Span
 
 		ctx, span = uploadTracer.Start(ctx, "UploadDir", trace.WithAttributes(attribute.String("dir", dirRelativePath)))
-		defer span.End()
+
+		defer func() {
+			if resultErr != nil {
+				span.RecordError(resultErr)
+				span.SetStatus(codes.Error, "error uploading directory")
+			} else if resultDE != nil && resultDE.DirSummary != nil {
+				span.SetAttributes(
+					attribute.Int64("totalFiles", resultDE.DirSummary.TotalFileCount),
+					attribute.Int64("totalSize", resultDE.DirSummary.TotalFileSize),
+				)
+			}
+
+			span.End()
+		}()
 	}
 
 	t0 := timetrack.StartTimer()
@@ -1271,7 +1359,7 @@ func (u *Uploader) Upload(
 	sourceInfo snapshot.SourceInfo,
 	previousManifests ...*snapshot.Manifest,
 ) (*snapshot.Manifest, error) {
-	ctx, span := uploadTracer.Start(ctx, "Upload")
+	ctx, span := uploadTracer.Start(ctx, "Upload", trace.WithAttributes(attribute.String("source", sourceInfo.String())))
 	defer span.End()
 
 	ctx = contentlog.WithParams(ctx, logparam.String("span:upload", contentlog.HashSpanID(sourceInfo.String())))
@@ -1304,6 +1392,10 @@ func (u *Uploader) Upload(
 		StartTime: fs.UTCTimestampFromTime(u.repo.Time()),
 	}
 
+	if u.ChangeSource != nil {
+		s.PolicyFingerprint = policyTree.Fingerprint()
+	}
+
 	// prototypeMan is used to construct the manifests for the checkpoints
 	// and the final snapshot; it is passed using a pointer, however it should
 	// remain immutable.
@@ -1311,6 +1403,10 @@ func (u *Uploader) Upload(
 
 	u.stats = &snapshot.Stats{}
 	u.totalWrittenBytes.Store(0)
+	u.quota = policyTree.EffectivePolicy().QuotaPolicy
+
+	// quotas are per-source, don't let them affect subsequent uploads.
+	defer func() { u.quota = policy.QuotaPolicy{} }()
 
 	var err error
 
@@ -1327,6 +1423,9 @@ func (u *Uploader) Upload(
 	}
 
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "upload failed")
+
 		return nil, rootCauseError(err)
 	}
 
@@ -1334,6 +1433,19 @@ func (u *Uploader) Upload(
 	s.EndTime = fs.UTCTimestampFromTime(u.repo.Time())
 	s.Stats = *u.stats
 
+	span.SetAttributes(
+		attribute.Int("files", int(s.Stats.TotalFileCount)),
+		attribute.Int("cachedFiles", int(s.Stats.CachedFiles)),
+		attribute.Int("dirs", int(s.Stats.TotalDirectoryCount)),
+		attribute.Int64("totalSize", s.Stats.TotalFileSize),
+		attribute.Int("errors", int(s.Stats.ErrorCount)),
+		attribute.String("incompleteReason", s.IncompleteReason),
+	)
+
+	if cs, ok := source.(*CommandSource); ok {
+		s.CommandResults = cs.Results()
+	}
+
 	return &s, nil
 }
 
@@ -1352,6 +1464,9 @@ func (u *Uploader) uploadDir(
 		}
 	}
 
+	u.unchangedSince = unchangedSinceTime(previousManifests, prototypeManifest.PolicyFingerprint)
+	u.ignoreFilesChangedByDir.Clear()
+
 	estimationCtl := u.startDataSizeEstimation(ctx, entry, policyTree)
 	defer func() {
 		estimationCtl.Cancel()
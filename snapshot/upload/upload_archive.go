@@ -0,0 +1,121 @@
+package upload
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+)
+
+const (
+	// size of tar header and data blocks.
+	tarBlockSize = 512
+
+	// minimum size of archive member data to be uploaded as a separate part, smaller members are
+	// uploaded together with surrounding headers to avoid creating excessive number of tiny objects.
+	archiveMinSplitMemberSize = 64 << 10
+)
+
+// positionTrackingReader tracks the current position in the underlying reader.
+type positionTrackingReader struct {
+	r   fs.Reader
+	pos int64
+}
+
+func (r *positionTrackingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pos += int64(n)
+
+	return n, err //nolint:wrapcheck
+}
+
+func (r *positionTrackingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+
+	return pos, err //nolint:wrapcheck
+}
+
+// archivePartOffsets returns offsets at which the file should be split into separately-uploaded parts
+// so that data of each large archive member is stored in its own object, which allows it to be deduplicated
+// regardless of changes in other members or headers. Returns nil if the file is not an uncompressed tar archive.
+//
+// The offsets only affect how the file is chunked, the uploaded contents are always identical to the file,
+// so if the archive can't be fully parsed the offsets found so far are used.
+func archivePartOffsets(ctx context.Context, f fs.File) []int64 {
+	offsets, err := tarPartOffsets(ctx, f)
+	if err != nil {
+		uploadLog(ctx).Debugw("unable to parse archive", "file", f.Name(), "error", err)
+	}
+
+	return offsets
+}
+
+func tarPartOffsets(ctx context.Context, f fs.File) ([]int64, error) {
+	rd, err := f.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open file")
+	}
+
+	defer rd.Close() //nolint:errcheck
+
+	ptr := &positionTrackingReader{r: rd}
+	tr := tar.NewReader(ptr)
+
+	offsets := []int64{0}
+	size := f.Size()
+
+	addOffset := func(o int64) {
+		if o > offsets[len(offsets)-1] && o < size {
+			offsets = append(offsets, o)
+		}
+	}
+
+	// end of data of the previous member, which can only be determined once the next header is read.
+	var pendingDataEnd int64
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			if len(offsets) == 1 {
+				// not a tar archive.
+				return nil, nil
+			}
+
+			return offsets, errors.Wrap(err, "error reading tar header")
+		}
+
+		// header of this member starts right after the data of the previous one, tar headers may
+		// span multiple blocks (e.g. PAX or GNU long names) so the data end of the previous member
+		// is only used if it's consistent with the current header position.
+		dataStart := ptr.pos
+		if pendingDataEnd > 0 && pendingDataEnd <= dataStart-tarBlockSize {
+			addOffset(pendingDataEnd)
+		}
+
+		pendingDataEnd = 0
+
+		if hdr.Typeflag != tar.TypeReg || hdr.Size < archiveMinSplitMemberSize {
+			continue
+		}
+
+		addOffset(dataStart)
+
+		pendingDataEnd = dataStart + (hdr.Size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+
+	if pendingDataEnd > 0 && pendingDataEnd <= size-tarBlockSize {
+		addOffset(pendingDataEnd)
+	}
+
+	return offsets, nil
+}
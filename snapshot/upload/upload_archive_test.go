@@ -0,0 +1,197 @@
+package upload
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+type tarMember struct {
+	name string
+	data []byte
+}
+
+// makeTar returns tar archive with provided members and offsets of their data.
+func makeTar(t *testing.T, modTime time.Time, members ...tarMember) (archive []byte, dataOffsets []int64) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, m := range members {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     m.name,
+			Size:     int64(len(m.data)),
+			Mode:     0o644,
+			ModTime:  modTime,
+		}))
+
+		dataOffsets = append(dataOffsets, int64(buf.Len()))
+
+		_, err := tw.Write(m.data)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes(), dataOffsets
+}
+
+func TestTarPartOffsets(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	big1 := randomData(t, 100000)
+	big2 := randomData(t, 300000)
+
+	archive, dataOffsets := makeTar(t, time.Unix(1e9, 0),
+		tarMember{"small.txt", textData(100)},
+		tarMember{"big1", big1},
+		tarMember{strings.Repeat("long-name/", 20) + "big2", big2},
+		tarMember{"small2.txt", textData(1000)},
+	)
+
+	offsets, err := tarPartOffsets(ctx, mockfs.NewFile("a.tar", archive, defaultPermissions))
+	require.NoError(t, err)
+
+	roundUp := func(v int64) int64 {
+		return (v + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+	}
+
+	require.Equal(t, []int64{
+		0,
+		dataOffsets[1],
+		dataOffsets[1] + roundUp(int64(len(big1))),
+		dataOffsets[2],
+		dataOffsets[2] + roundUp(int64(len(big2))),
+	}, offsets)
+
+	// not a tar file.
+	offsets, err = tarPartOffsets(ctx, mockfs.NewFile("a.bin", randomData(t, 10000), defaultPermissions))
+	require.NoError(t, err)
+	require.Nil(t, offsets)
+
+	// truncated archive uses offsets found so far.
+	offsets, err = tarPartOffsets(ctx, mockfs.NewFile("a.tar", archive[0:dataOffsets[2]+1000], defaultPermissions))
+	require.Error(t, err)
+	require.Equal(t, []int64{0, dataOffsets[1], dataOffsets[1] + roundUp(int64(len(big1))), dataOffsets[2]}, offsets)
+}
+
+func TestUpload_SplitArchiveMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	big1 := randomData(t, 200000)
+	big2 := randomData(t, 300000)
+
+	// the same large members are stored at different offsets in both archives with different headers.
+	archive1, offsets1 := makeTar(t, time.Unix(1e9, 0),
+		tarMember{"small.txt", textData(100)},
+		tarMember{"big1", big1},
+		tarMember{"big2", big2},
+	)
+
+	archive2, _ := makeTar(t, time.Unix(2e9, 0),
+		tarMember{"small.txt", textData(3000)},
+		tarMember{"big1", big1},
+		tarMember{"big2", big2},
+	)
+
+	src := mockfs.NewDirectory()
+	src.AddFile("backup1.tar", archive1, defaultPermissions)
+	src.AddFile("backup2.tar", archive2, defaultPermissions)
+
+	policyTree := policy.BuildTree(map[string]*policy.Policy{
+		".": {
+			UploadPolicy: policy.UploadPolicy{
+				SplitArchiveMembers: policy.NewOptionalBool(true),
+			},
+		},
+	}, policy.DefaultPolicy)
+
+	man, err := NewUploader(th.repo).Upload(ctx, src, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	dir := testutil.EnsureType[fs.Directory](t, snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry))
+
+	f1 := verifyArchiveContents(t, dir, "backup1.tar", archive1)
+	f2 := verifyArchiveContents(t, dir, "backup2.tar", archive2)
+
+	oid1 := testutil.EnsureType[object.HasObjectID](t, f1).ObjectID()
+
+	indexID, ok := oid1.IndexObjectID()
+	require.True(t, ok)
+
+	entries, err := object.LoadIndexObject(ctx, testutil.EnsureType[repo.DirectRepositoryWriter](t, th.repo).ContentManager(), indexID)
+	require.NoError(t, err)
+
+	verifyContainsOffset(t, entries, offsets1[1])
+	verifyContainsOffset(t, entries, offsets1[2])
+
+	// data of large members is deduplicated between archives.
+	contents1, err := th.repo.VerifyObject(ctx, oid1)
+	require.NoError(t, err)
+
+	contents2, err := th.repo.VerifyObject(ctx, testutil.EnsureType[object.HasObjectID](t, f2).ObjectID())
+	require.NoError(t, err)
+
+	shared := map[content.ID]bool{}
+	for _, cid := range contents1 {
+		shared[cid] = true
+	}
+
+	var sharedCount int
+
+	for _, cid := range contents2 {
+		if shared[cid] {
+			sharedCount++
+		}
+	}
+
+	require.GreaterOrEqual(t, sharedCount, 2)
+}
+
+func verifyArchiveContents(t *testing.T, dir fs.Directory, name string, want []byte) fs.File {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	e, err := dir.Child(ctx, name)
+	require.NoError(t, err)
+
+	f := testutil.EnsureType[fs.File](t, e)
+
+	r, err := f.Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	return f
+}
@@ -0,0 +1,213 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+const (
+	// number of bytes at the beginning of each file used to estimate its compressibility.
+	autoCompressionSampleSize = 64 << 10
+
+	// samples with Shannon entropy (in bits per byte) at or above this threshold are considered
+	// incompressible (typically already compressed or encrypted) without attempting to compress them.
+	autoCompressionNoneMinEntropy = 7.5
+
+	// minimum fraction of the sample saved by the fast compressor to use compression at all and to use the strong
+	// compressor respectively, highly redundant data benefits most from stronger compression.
+	autoCompressionFastMinSavings   = 0.05
+	autoCompressionStrongMinSavings = 0.5
+
+	autoCompressionFastCompressor   compression.Name = "zstd-fastest"
+	autoCompressionStrongCompressor compression.Name = "zstd-better-compression"
+)
+
+// sampleEntropy returns Shannon entropy of the provided data in bits per byte.
+func sampleEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var entropy float64
+
+	total := float64(len(data))
+
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+// autoCompressorForSample picks the compressor for data similar to the provided sample based on its
+// entropy and the savings achieved by compressing it with the fast compressor.
+func autoCompressorForSample(sample []byte) compression.Name {
+	if len(sample) == 0 || sampleEntropy(sample) >= autoCompressionNoneMinEntropy {
+		return ""
+	}
+
+	var compressed bytes.Buffer
+
+	if err := compression.ByName[autoCompressionFastCompressor].Compress(&compressed, bytes.NewReader(sample)); err != nil {
+		return autoCompressionFastCompressor
+	}
+
+	switch savings := 1 - float64(compressed.Len())/float64(len(sample)); {
+	case savings >= autoCompressionStrongMinSavings:
+		return autoCompressionStrongCompressor
+	case savings >= autoCompressionFastMinSavings:
+		return autoCompressionFastCompressor
+	default:
+		return ""
+	}
+}
+
+// recordAutoCompression updates statistics to reflect a compressor chosen automatically.
+func (u *Uploader) recordAutoCompression(comp compression.Name) compression.Name {
+	switch comp {
+	case "":
+		atomic.AddInt32(&u.stats.AutoCompressionNoneCount, 1)
+	case autoCompressionFastCompressor:
+		atomic.AddInt32(&u.stats.AutoCompressionFastCount, 1)
+	default:
+		atomic.AddInt32(&u.stats.AutoCompressionStrongCount, 1)
+	}
+
+	return comp
+}
+
+// fileCompressor returns the compressor for a file, sampling its contents when the policy requests automatic
+// selection. The reader opened for sampling is reused for the upload, which is why the file to be uploaded
+// is returned, its release() must be called once the upload is done.
+func (u *Uploader) fileCompressor(ctx context.Context, f fs.File, pol *policy.Policy) (compression.Name, *sampledFile) {
+	sf := &sampledFile{File: f}
+
+	comp := pol.CompressionPolicy.CompressorForFile(f)
+	if comp == "" || !pol.CompressionPolicy.AutoCompression.OrDefault(false) {
+		return comp, sf
+	}
+
+	r, err := f.Open(ctx)
+	if err != nil {
+		// the error will be reported when opening the file for upload.
+		uploadLog(ctx).Debugw("unable to open file for compression sampling", "file", f.Name(), "error", err)
+
+		return comp, sf
+	}
+
+	sample, err := readSample(r)
+	if err != nil {
+		r.Close() //nolint:errcheck
+		uploadLog(ctx).Debugw("unable to sample file for compression", "file", f.Name(), "error", err)
+
+		return comp, sf
+	}
+
+	sf.reader = r
+
+	return u.recordAutoCompression(autoCompressorForSample(sample)), sf
+}
+
+// streamingFileCompressor is like fileCompressor for streaming files, the sample is taken by buffering
+// the beginning of the stream, which is why the reader to be used for the upload is returned.
+func (u *Uploader) streamingFileCompressor(ctx context.Context, f fs.StreamingFile, reader io.Reader, pol *policy.Policy) (compression.Name, io.Reader) {
+	comp := pol.CompressionPolicy.CompressorForFile(f)
+	if comp == "" || !pol.CompressionPolicy.AutoCompression.OrDefault(false) {
+		return comp, reader
+	}
+
+	br := bufio.NewReaderSize(reader, autoCompressionSampleSize)
+
+	sample, err := br.Peek(autoCompressionSampleSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		uploadLog(ctx).Debugw("unable to sample streaming file for compression", "file", f.Name(), "error", err)
+
+		return comp, br
+	}
+
+	return u.recordAutoCompression(autoCompressorForSample(sample)), br
+}
+
+// readSample reads the beginning of the file and rewinds it.
+func readSample(r fs.Reader) ([]byte, error) {
+	sample := make([]byte, autoCompressionSampleSize)
+
+	n, err := io.ReadFull(r, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errors.Wrap(err, "unable to read file")
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "unable to rewind file")
+	}
+
+	return sample[0:n], nil
+}
+
+// sampledFile is a file whose reader opened for sampling is returned by the first call to Open().
+type sampledFile struct {
+	fs.File
+
+	mu sync.Mutex
+	// +checklocks:mu
+	reader fs.Reader
+}
+
+func (f *sampledFile) Open(ctx context.Context) (fs.Reader, error) {
+	f.mu.Lock()
+	r := f.reader
+	f.reader = nil
+	f.mu.Unlock()
+
+	if r != nil {
+		return r, nil
+	}
+
+	//nolint:wrapcheck
+	return f.File.Open(ctx)
+}
+
+// release closes the reader opened for sampling if it has not been used.
+func (f *sampledFile) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.reader != nil {
+		f.reader.Close() //nolint:errcheck
+		f.reader = nil
+	}
+}
+
+// sampledSparseFile is a sampledFile which retains the ability to report data regions.
+type sampledSparseFile struct {
+	*sampledFile
+
+	sparse fs.SparseFile
+}
+
+func (f sampledSparseFile) DataRegions(ctx context.Context) ([]fs.Region, error) {
+	//nolint:wrapcheck
+	return f.sparse.DataRegions(ctx)
+}
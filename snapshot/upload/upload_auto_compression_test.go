@@ -0,0 +1,232 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/virtualfs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestAutoCompressorForSample(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, compression.Name(""), autoCompressorForSample(nil))
+	require.Equal(t, autoCompressionStrongCompressor, autoCompressorForSample(textData(1000)))
+	require.Equal(t, autoCompressionStrongCompressor, autoCompressorForSample(make([]byte, 1000)))
+	require.Equal(t, autoCompressionFastCompressor, autoCompressorForSample(partiallyRandomData(t, 100000)))
+	require.Equal(t, compression.Name(""), autoCompressorForSample(randomData(t, 100000)))
+
+	// low-entropy data that the fast compressor can't compress.
+	require.Less(t, sampleEntropy(sevenBitRandomData(t, 100000)), float64(autoCompressionNoneMinEntropy))
+	require.Equal(t, compression.Name(""), autoCompressorForSample(sevenBitRandomData(t, 100000)))
+
+	require.InDelta(t, 0, sampleEntropy(make([]byte, 100)), 0.001)
+	require.InDelta(t, 1, sampleEntropy([]byte{0, 1, 0, 1}), 0.001)
+	require.InDelta(t, 8, sampleEntropy(randomData(t, 1000000)), 0.01)
+}
+
+func TestUpload_AutoCompression(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	src := mockfs.NewDirectory()
+	src.AddFile("text.unknown", textData(10000), defaultPermissions)
+	src.AddFile("moderate.unknown", partiallyRandomData(t, 100000), defaultPermissions)
+	src.AddFile("random.unknown", randomData(t, 100000), defaultPermissions)
+
+	policyTree := policy.BuildTree(map[string]*policy.Policy{
+		".": {
+			CompressionPolicy: policy.CompressionPolicy{
+				CompressorName:  "zstd",
+				AutoCompression: policy.NewOptionalBool(true),
+			},
+		},
+	}, policy.DefaultPolicy)
+
+	u := NewUploader(th.repo)
+
+	man, err := u.Upload(ctx, src, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, int32(1), man.Stats.AutoCompressionNoneCount)
+	require.Equal(t, int32(1), man.Stats.AutoCompressionFastCount)
+	require.Equal(t, int32(1), man.Stats.AutoCompressionStrongCount)
+
+	dir, ok := snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry).(fs.Directory)
+	require.True(t, ok)
+
+	verifyFileCompressor(t, th.repo, dir, "text.unknown", compression.HeaderZstdBetterCompression)
+	verifyFileCompressor(t, th.repo, dir, "moderate.unknown", compression.HeaderZstdFastest)
+	verifyFileCompressor(t, th.repo, dir, "random.unknown", content.NoCompression)
+
+	// streaming files are sampled as well and their contents are uploaded in full.
+	text := textData(200000)
+
+	u = NewUploader(th.repo)
+
+	man, err = u.Upload(ctx, virtualfs.NewStaticDirectory("root", []fs.Entry{
+		virtualfs.StreamingFileFromReader("stream", io.NopCloser(bytes.NewReader(text))),
+	}), policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, int32(1), man.Stats.AutoCompressionStrongCount)
+	require.Equal(t, int64(len(text)), man.Stats.TotalFileSize)
+}
+
+func TestUpload_AutoCompressionOpensFilesOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	src := mockfs.NewDirectory()
+	text := textData(200000)
+	src.AddFile("text.unknown", text, defaultPermissions)
+
+	f, err := src.Child(ctx, "text.unknown")
+	require.NoError(t, err)
+
+	cf := &openCountingFile{File: testutil.EnsureType[fs.File](t, f)}
+
+	policyTree := policy.BuildTree(map[string]*policy.Policy{
+		".": {
+			CompressionPolicy: policy.CompressionPolicy{
+				CompressorName:  "zstd",
+				AutoCompression: policy.NewOptionalBool(true),
+			},
+		},
+	}, policy.DefaultPolicy)
+
+	u := NewUploader(th.repo)
+
+	man, err := u.Upload(ctx, virtualfs.NewStaticDirectory("root", []fs.Entry{cf}), policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, int32(1), man.Stats.AutoCompressionStrongCount)
+	require.Equal(t, int64(len(text)), man.Stats.TotalFileSize)
+	require.Equal(t, int32(1), cf.opens.Load())
+}
+
+func TestUpload_AutoCompressionIsIgnoredWhenCompressionIsDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	src := mockfs.NewDirectory()
+	src.AddFile("text.unknown", textData(10000), defaultPermissions)
+
+	policyTree := policy.BuildTree(map[string]*policy.Policy{
+		".": {
+			CompressionPolicy: policy.CompressionPolicy{
+				AutoCompression: policy.NewOptionalBool(true),
+			},
+		},
+	}, policy.DefaultPolicy)
+
+	u := NewUploader(th.repo)
+
+	man, err := u.Upload(ctx, src, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man.Stats.AutoCompressionStrongCount)
+
+	dir, ok := snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry).(fs.Directory)
+	require.True(t, ok)
+
+	verifyFileCompressor(t, th.repo, dir, "text.unknown", content.NoCompression)
+}
+
+type openCountingFile struct {
+	fs.File
+
+	opens atomic.Int32
+}
+
+func (f *openCountingFile) Open(ctx context.Context) (fs.Reader, error) {
+	f.opens.Add(1)
+
+	//nolint:wrapcheck
+	return f.File.Open(ctx)
+}
+
+func verifyFileCompressor(t *testing.T, rep repo.Repository, dir fs.Directory, name string, want compression.HeaderID) {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	e, err := dir.Child(ctx, name)
+	require.NoError(t, err)
+
+	cid, _, ok := testutil.EnsureType[object.HasObjectID](t, e).ObjectID().ContentID()
+	require.True(t, ok)
+
+	info, err := rep.ContentInfo(ctx, cid)
+	require.NoError(t, err)
+	require.Equal(t, want, info.CompressionHeaderID, name)
+}
+
+func textData(n int) []byte {
+	return []byte(strings.Repeat("hello world, how are you?\n", n/26+1))[0:n]
+}
+
+func randomData(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	return b
+}
+
+// partiallyRandomData returns data where 30% of each 640-byte block is zero.
+func partiallyRandomData(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := randomData(t, n)
+	for i := range b {
+		if i%640 >= 448 {
+			b[i] = 0
+		}
+	}
+
+	return b
+}
+
+func sevenBitRandomData(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := randomData(t, n)
+	for i := range b {
+		b[i] &= 0x7f
+	}
+
+	return b
+}
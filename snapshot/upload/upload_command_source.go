@@ -0,0 +1,217 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/virtualfs"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// maxCommandStderrLength is the maximum length of command stderr preserved in the snapshot manifest,
+// only the trailing part is kept since it usually contains the error message.
+const maxCommandStderrLength = 4096
+
+// ErrCommandSourceDisabled is returned when the source policy configures commands, which, like actions,
+// are only executed on clients where actions are enabled.
+var ErrCommandSourceDisabled = errors.New("source policy configures commands, which are disabled for this client, reconnect using --enable-actions to allow them")
+
+// CommandSource is a directory whose entries are streaming files holding standard output of commands,
+// configured using policy.CommandSourcePolicy. When snapshotted, results of the commands are
+// recorded in the snapshot manifest.
+type CommandSource struct {
+	fs.Directory
+
+	mu sync.Mutex
+	// +checklocks:mu
+	results []*snapshot.CommandResult
+}
+
+// NewCommandSource returns a CommandSource with the provided name and streams.
+// Commands are only started when the corresponding files are read.
+func NewCommandSource(name string, streams []policy.CommandStream) *CommandSource {
+	cs := &CommandSource{
+		results: make([]*snapshot.CommandResult, len(streams)),
+	}
+
+	var entries []fs.Entry
+
+	for i, s := range streams {
+		entries = append(entries, virtualfs.StreamingFileFromReader(s.Name, &commandStreamReader{
+			stream: s,
+			onDone: func(r *snapshot.CommandResult) {
+				cs.mu.Lock()
+				defer cs.mu.Unlock()
+
+				cs.results[i] = r
+			},
+		}))
+	}
+
+	cs.Directory = virtualfs.NewStaticDirectory(name, entries)
+
+	return cs
+}
+
+// Results returns results of commands that have run so far, in the order of streams.
+func (cs *CommandSource) Results() []snapshot.CommandResult {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var result []snapshot.CommandResult
+
+	for _, r := range cs.results {
+		if r != nil {
+			result = append(result, *r)
+		}
+	}
+
+	return result
+}
+
+// commandStreamReader starts the command on first read and returns its standard output.
+// Reads fail if the command exits with an error.
+type commandStreamReader struct {
+	stream policy.CommandStream
+	onDone func(r *snapshot.CommandResult)
+
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	stdout io.ReadCloser
+	stderr tailBuffer
+	result *snapshot.CommandResult
+	err    error
+}
+
+func (r *commandStreamReader) start() error {
+	// the command is killed when the reader is closed before the output has been fully read.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if r.stream.TimeoutSeconds != 0 {
+		timeoutCtx, cancelTimeout := context.WithTimeout(ctx, time.Duration(r.stream.TimeoutSeconds)*time.Second)
+		cancelCommand := cancel
+
+		ctx = timeoutCtx
+		cancel = func() {
+			cancelTimeout()
+			cancelCommand()
+		}
+	}
+
+	r.cmd = exec.CommandContext(ctx, r.stream.Command, r.stream.Arguments...) //nolint:gosec
+	r.cmd.Stderr = &r.stderr
+	r.cancel = cancel
+	r.result = &snapshot.CommandResult{
+		Name:      r.stream.Name,
+		Command:   strings.Join(append([]string{r.stream.Command}, r.stream.Arguments...), " "),
+		StartTime: fs.UTCTimestampFromTime(clock.Now()),
+	}
+
+	stdout, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "unable to create stdout pipe")
+	}
+
+	r.stdout = stdout
+
+	if err := r.cmd.Start(); err != nil {
+		return r.finish(errors.Wrapf(err, "unable to start command for %v", r.stream.Name))
+	}
+
+	return nil
+}
+
+// finish waits for the command to exit and records its result, returns the error, if any.
+func (r *commandStreamReader) finish(err error) error {
+	if r.cmd.Process != nil {
+		if werr := r.cmd.Wait(); werr != nil && err == nil {
+			err = errors.Wrapf(werr, "command for %v failed: %v", r.stream.Name, strings.TrimSpace(r.stderr.String()))
+		}
+	}
+
+	r.cancel()
+
+	// -1 if the command could not be started
+	r.result.ExitCode = r.cmd.ProcessState.ExitCode()
+
+	r.result.Stderr = r.stderr.String()
+	r.result.EndTime = fs.UTCTimestampFromTime(clock.Now())
+	r.onDone(r.result)
+	r.result = nil
+
+	if err != nil {
+		r.err = err
+	} else {
+		r.err = io.EOF
+	}
+
+	return err
+}
+
+func (r *commandStreamReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if r.cmd == nil {
+		if err := r.start(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		if ferr := r.finish(nil); ferr != nil {
+			return n, ferr
+		}
+	}
+
+	return n, err //nolint:wrapcheck
+}
+
+// Close terminates the command if it has not finished yet.
+func (r *commandStreamReader) Close() error {
+	if r.cmd == nil || r.result == nil {
+		return nil
+	}
+
+	r.cancel()
+
+	r.finish(errors.New("command output was not fully read")) //nolint:errcheck
+
+	return nil
+}
+
+// tailBuffer is an io.Writer that retains the last maxCommandStderrLength bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > maxCommandStderrLength {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-maxCommandStderrLength:]...)
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.buf)
+}
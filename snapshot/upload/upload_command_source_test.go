@@ -0,0 +1,122 @@
+package upload
+
+import (
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestUpload_CommandSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	cs := NewCommandSource("db", []policy.CommandStream{
+		{Name: "dump.sql", Command: "sh", Arguments: []string{"-c", "echo 'CREATE TABLE t;'; echo progress >&2"}},
+		{Name: "big.bin", Command: "sh", Arguments: []string{"-c", "head -c 3000000 /dev/zero"}},
+		{Name: "failed.sql", Command: "sh", Arguments: []string{"-c", "echo partial; echo 'connection refused' >&2; exit 3"}},
+		{Name: "missing.sql", Command: "/no/such/command"},
+	})
+
+	man, err := NewUploader(th.repo).Upload(ctx, cs, policy.BuildTree(nil, policy.DefaultPolicy), snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Len(t, man.CommandResults, 4)
+
+	require.Equal(t, "dump.sql", man.CommandResults[0].Name)
+	require.Equal(t, 0, man.CommandResults[0].ExitCode)
+	require.Equal(t, "progress\n", man.CommandResults[0].Stderr)
+	require.True(t, strings.HasPrefix(man.CommandResults[0].Command, "sh -c "), man.CommandResults[0].Command)
+	require.False(t, man.CommandResults[0].EndTime.Before(man.CommandResults[0].StartTime))
+
+	require.Equal(t, 0, man.CommandResults[1].ExitCode)
+
+	require.Equal(t, 3, man.CommandResults[2].ExitCode)
+	require.Equal(t, "connection refused\n", man.CommandResults[2].Stderr)
+
+	require.Equal(t, -1, man.CommandResults[3].ExitCode)
+
+	// failed commands are reported as errors, successful ones are stored.
+	require.Equal(t, 2, man.RootEntry.DirSummary.FatalErrorCount)
+
+	dir := testutil.EnsureType[fs.Directory](t, snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry))
+
+	entries, err := fs.GetAllEntries(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// FindByName requires sorted entries, directory iteration order is not guaranteed.
+	fs.Sort(entries)
+
+	require.Equal(t, "CREATE TABLE t;\n", string(readAllEntry(t, fs.FindByName(entries, "dump.sql"))))
+	require.Len(t, readAllEntry(t, fs.FindByName(entries, "big.bin")), 3000000)
+}
+
+func TestCommandStreamReaderClose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	var result *snapshot.CommandResult
+
+	r := &commandStreamReader{
+		stream: policy.CommandStream{Name: "endless", Command: "sh", Arguments: []string{"-c", "while true; do echo y; done"}},
+		onDone: func(r *snapshot.CommandResult) { result = r },
+	}
+
+	buf := make([]byte, 100)
+
+	_, err := io.ReadFull(r, buf)
+	require.NoError(t, err)
+
+	// closing before all output has been read kills the command.
+	require.NoError(t, r.Close())
+	require.NotNil(t, result)
+	require.NotEqual(t, 0, result.ExitCode)
+
+	_, err = r.Read(buf)
+	require.ErrorContains(t, err, "command output was not fully read")
+}
+
+func TestTailBuffer(t *testing.T) {
+	var b tailBuffer
+
+	b.Write([]byte(strings.Repeat("a", maxCommandStderrLength)))
+	b.Write([]byte("xyz"))
+
+	require.Len(t, b.String(), maxCommandStderrLength)
+	require.True(t, strings.HasSuffix(b.String(), "aaxyz"))
+}
+
+func readAllEntry(t *testing.T, e fs.Entry) []byte {
+	t.Helper()
+
+	f := testutil.EnsureType[fs.File](t, e)
+
+	r, err := f.Open(testlogging.Context(t))
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return data
+}
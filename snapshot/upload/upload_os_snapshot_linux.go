@@ -0,0 +1,49 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/fssnapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func osSnapshotMode(p *policy.OSSnapshotPolicy) policy.OSSnapshotMode {
+	return p.LinuxSnapshot.Enable.OrDefault(policy.OSSnapshotNever)
+}
+
+// createOSSnapshot creates btrfs, LVM or ZFS snapshot of the filesystem containing the root directory
+// and returns the directory corresponding to the root in the snapshot.
+func createOSSnapshot(ctx context.Context, root fs.Directory, p *policy.OSSnapshotPolicy) (newRoot fs.Directory, cleanup func(), finalErr error) {
+	local := root.LocalFilesystemPath()
+	if local == "" {
+		return nil, nil, errors.New("not a local filesystem")
+	}
+
+	snap, err := fssnapshot.NewCreator().Create(ctx, p.LinuxSnapshot.Provider, local)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to create filesystem snapshot")
+	}
+
+	cleanup = func() {
+		uploadLog(ctx).Infof("removing filesystem snapshot of %v", local)
+
+		if err := snap.Release(context.WithoutCancel(ctx)); err != nil {
+			uploadLog(ctx).Errorf("failed to remove filesystem snapshot: %v", err)
+		}
+	}
+
+	newRoot, err = localfs.Directory(snap.Path)
+	if err != nil {
+		cleanup()
+
+		return nil, nil, errors.Wrap(err, "unable to open filesystem snapshot")
+	}
+
+	uploadLog(ctx).Debugf("filesystem snapshot root is %s", newRoot.LocalFilesystemPath())
+
+	return newRoot, cleanup, nil
+}
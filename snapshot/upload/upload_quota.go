@@ -0,0 +1,132 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// ErrQuotaPreflightFailed is returned when the pre-flight quota check fails and the policy does not allow the snapshot to proceed.
+var ErrQuotaPreflightFailed = errors.New("pre-flight quota check failed")
+
+// QuotaPreflightResult contains the results of the pre-flight quota check.
+type QuotaPreflightResult struct {
+	EstimatedFileCount int64    `json:"estimatedFileCount"`
+	EstimatedTotalSize int64    `json:"estimatedTotalSize"`
+	PreviousTotalSize  int64    `json:"previousTotalSize,omitempty"`
+	Violations         []string `json:"violations,omitempty"`
+}
+
+// Error returns an error describing the quota violations or nil if there were none.
+func (r *QuotaPreflightResult) Error() error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+
+	return errors.Wrap(ErrQuotaPreflightFailed, strings.Join(r.Violations, "; "))
+}
+
+// QuotaPreflight estimates the size of the source before the snapshot starts and compares it against the quotas
+// and maximum growth defined in the policy of the source root. It returns nil result when pre-flight checks are
+// not enabled, and ErrQuotaPreflightFailed when they fail in the "fail" mode. In the "warn" mode the violations are
+// only reported in the result.
+func QuotaPreflight(ctx context.Context, source fs.Entry, policyTree *policy.Tree, previousManifests ...*snapshot.Manifest) (*QuotaPreflightResult, error) {
+	qp := policyTree.EffectivePolicy().QuotaPolicy
+	if !qp.PreflightEnabled() {
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &QuotaPreflightResult{}
+
+	switch entry := source.(type) {
+	case fs.Directory:
+		var ep quotaEstimateProgress
+
+		if err := Estimate(ctx, entry, policyTree, &ep, 0); err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(err, "estimate canceled")
+			}
+
+			// the upload will report errors according to the error handling policy.
+			uploadLog(ctx).Warnf("unable to estimate snapshot size, skipping pre-flight quota check: %v", err)
+
+			return nil, nil //nolint:nilnil
+		}
+
+		result.EstimatedFileCount = int64(ep.stats.TotalFileCount)
+		result.EstimatedTotalSize = ep.stats.TotalFileSize
+
+	case fs.File:
+		result.EstimatedFileCount = 1
+		result.EstimatedTotalSize = entry.Size()
+	}
+
+	result.PreviousTotalSize = previousCompleteTotalSize(previousManifests)
+
+	if q := qp.MaxTotalSize.OrDefault(0); q > 0 && result.EstimatedTotalSize > q {
+		result.Violations = append(result.Violations, fmt.Sprintf("estimated size %v exceeds quota of %v", units.BytesString(result.EstimatedTotalSize), units.BytesString(q)))
+	}
+
+	if q := qp.MaxFileCount.OrDefault(0); q > 0 && result.EstimatedFileCount > q {
+		result.Violations = append(result.Violations, fmt.Sprintf("estimated file count %v exceeds quota of %v", result.EstimatedFileCount, q))
+	}
+
+	if pct := qp.MaxGrowthPercent.OrDefault(0); pct > 0 && result.PreviousTotalSize > 0 {
+		if maxSize := result.PreviousTotalSize + result.PreviousTotalSize*int64(pct)/100; result.EstimatedTotalSize > maxSize { //nolint:mnd
+			result.Violations = append(result.Violations, fmt.Sprintf("estimated size %v grew by more than %v%% since previous snapshot (%v)",
+				units.BytesString(result.EstimatedTotalSize), pct, units.BytesString(result.PreviousTotalSize)))
+		}
+	}
+
+	if qp.Preflight == policy.QuotaPreflightFail {
+		return result, result.Error()
+	}
+
+	return result, nil
+}
+
+// previousCompleteTotalSize returns the total size of files in the most recent complete snapshot or zero.
+func previousCompleteTotalSize(previousManifests []*snapshot.Manifest) int64 {
+	var latest *snapshot.Manifest
+
+	for _, m := range previousManifests {
+		if m.IncompleteReason != "" || m.RootEntry == nil {
+			continue
+		}
+
+		if latest == nil || m.StartTime.After(latest.StartTime) {
+			latest = m
+		}
+	}
+
+	if latest == nil {
+		return 0
+	}
+
+	if ds := latest.RootEntry.DirSummary; ds != nil {
+		return ds.TotalFileSize
+	}
+
+	return latest.RootEntry.FileSize
+}
+
+type quotaEstimateProgress struct {
+	stats snapshot.Stats
+}
+
+func (p *quotaEstimateProgress) Processing(context.Context, string) {}
+
+func (p *quotaEstimateProgress) Error(context.Context, string, error, bool) {}
+
+func (p *quotaEstimateProgress) Stats(_ context.Context, s *snapshot.Stats, _, _ SampleBuckets, _ []string, final bool) {
+	if final {
+		p.stats = *s
+	}
+}
@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func quotaPolicyTree(qp policy.QuotaPolicy) *policy.Tree {
+	return policy.BuildTree(nil, &policy.Policy{
+		QuotaPolicy: qp,
+	})
+}
+
+func optionalInt64(v int64) *policy.OptionalInt64 {
+	o := policy.OptionalInt64(v)
+	return &o
+}
+
+func TestUpload_Quota(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	cases := []struct {
+		desc       string
+		quota      policy.QuotaPolicy
+		wantReason string
+	}{
+		{"no quota", policy.QuotaPolicy{}, ""},
+		{"quotas not exceeded", policy.QuotaPolicy{MaxTotalSize: optionalInt64(1000), MaxFileCount: optionalInt64(10), MaxNewBytes: optionalInt64(1 << 20)}, ""},
+		{"file count", policy.QuotaPolicy{MaxFileCount: optionalInt64(2)}, IncompleteReasonFileCountQuota},
+		{"total size", policy.QuotaPolicy{MaxTotalSize: optionalInt64(5)}, IncompleteReasonTotalSizeQuota},
+		{"new bytes", policy.QuotaPolicy{MaxNewBytes: optionalInt64(1)}, IncompleteReasonNewBytesQuota},
+	}
+
+	for _, tc := range cases {
+		u := NewUploader(th.repo)
+		u.ParallelUploads = 1
+
+		man, err := u.Upload(ctx, th.sourceDir, quotaPolicyTree(tc.quota), snapshot.SourceInfo{})
+		require.NoError(t, err, tc.desc)
+		require.Equal(t, tc.wantReason, man.IncompleteReason, tc.desc)
+
+		// quotas don't carry over to the next source.
+		require.False(t, u.IsCanceled(), tc.desc)
+	}
+}
+
+func TestQuotaPreflight(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	// harness source has 10 files with 37 bytes in total.
+	res, err := QuotaPreflight(ctx, th.sourceDir, quotaPolicyTree(policy.QuotaPolicy{MaxFileCount: optionalInt64(2)}))
+	require.NoError(t, err)
+	require.Nil(t, res, "pre-flight is disabled by default")
+
+	res, err = QuotaPreflight(ctx, th.sourceDir, quotaPolicyTree(policy.QuotaPolicy{
+		Preflight:    policy.QuotaPreflightWarn,
+		MaxFileCount: optionalInt64(20),
+		MaxTotalSize: optionalInt64(100),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &QuotaPreflightResult{EstimatedFileCount: 10, EstimatedTotalSize: 37}, res)
+	require.NoError(t, res.Error())
+
+	res, err = QuotaPreflight(ctx, th.sourceDir, quotaPolicyTree(policy.QuotaPolicy{
+		Preflight:    policy.QuotaPreflightWarn,
+		MaxFileCount: optionalInt64(2),
+		MaxTotalSize: optionalInt64(10),
+	}))
+	require.NoError(t, err)
+	require.Len(t, res.Violations, 2)
+	require.ErrorIs(t, res.Error(), ErrQuotaPreflightFailed)
+
+	_, err = QuotaPreflight(ctx, th.sourceDir, quotaPolicyTree(policy.QuotaPolicy{
+		Preflight:    policy.QuotaPreflightFail,
+		MaxFileCount: optionalInt64(2),
+	}))
+	require.ErrorIs(t, err, ErrQuotaPreflightFailed)
+
+	// growth is measured against the most recent complete snapshot.
+	small := &snapshot.Manifest{
+		StartTime: 2,
+		RootEntry: &snapshot.DirEntry{DirSummary: &fs.DirectorySummary{TotalFileSize: 20}},
+	}
+	incomplete := &snapshot.Manifest{
+		StartTime:        3,
+		IncompleteReason: IncompleteReasonCanceled,
+		RootEntry:        &snapshot.DirEntry{DirSummary: &fs.DirectorySummary{TotalFileSize: 36}},
+	}
+	older := &snapshot.Manifest{
+		StartTime: 1,
+		RootEntry: &snapshot.DirEntry{DirSummary: &fs.DirectorySummary{TotalFileSize: 36}},
+	}
+
+	growthPolicy := func(pct int) *policy.Tree {
+		p := policy.OptionalInt(pct)
+
+		return quotaPolicyTree(policy.QuotaPolicy{Preflight: policy.QuotaPreflightFail, MaxGrowthPercent: &p})
+	}
+
+	res, err = QuotaPreflight(ctx, th.sourceDir, growthPolicy(50), older, small, incomplete)
+	require.ErrorIs(t, err, ErrQuotaPreflightFailed)
+	require.Equal(t, int64(20), res.PreviousTotalSize)
+
+	_, err = QuotaPreflight(ctx, th.sourceDir, growthPolicy(100), older, small, incomplete)
+	require.NoError(t, err)
+
+	// no previous snapshots - growth can't be determined.
+	_, err = QuotaPreflight(ctx, th.sourceDir, growthPolicy(1))
+	require.NoError(t, err)
+}
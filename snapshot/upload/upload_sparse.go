@@ -0,0 +1,305 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"math/bits"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+const (
+	// granularity at which zero blocks are detected in sparse files.
+	sparseBlockSize = 64 << 10
+
+	// runs of zero bytes shorter than this following data are appended to it to avoid fragmenting the file
+	// into excessive number of objects.
+	sparseMinHoleSize = 1 << 20
+
+	// maximum size of a zero object written directly, larger zero objects are concatenations of smaller ones.
+	sparseMaxZeroObjectSize = 4 << 20
+)
+
+var zeroBlock = make([]byte, sparseBlockSize)
+
+// sparseFileWriter writes a file as a sequence of data objects and references to zero objects
+// representing holes, which are concatenated into a single object.
+type sparseFileWriter struct {
+	u    *Uploader
+	opts object.WriterOptions
+
+	objectIDs    []object.ID
+	dataWriter   object.Writer
+	pendingZeros int64
+	holeBytes    int64
+
+	// zero objects by their length.
+	zeroObjects map[int64]object.ID
+}
+
+func (w *sparseFileWriter) writeData(ctx context.Context, data []byte) error {
+	if err := w.flushZeros(ctx); err != nil {
+		return err
+	}
+
+	if w.dataWriter == nil {
+		w.dataWriter = w.u.repo.NewObjectWriter(ctx, w.opts)
+	}
+
+	if _, err := w.dataWriter.Write(data); err != nil {
+		return errors.Wrap(err, "unable to write data")
+	}
+
+	w.u.totalWrittenBytes.Add(int64(len(data)))
+	w.u.Progress.HashedBytes(int64(len(data)))
+
+	return nil
+}
+
+// flushZeros stores pending zeros as a hole or appends them to the current data object if they are too short.
+func (w *sparseFileWriter) flushZeros(ctx context.Context) error {
+	n := w.pendingZeros
+	if n == 0 {
+		return nil
+	}
+
+	w.pendingZeros = 0
+
+	if n >= sparseMinHoleSize || w.dataWriter == nil {
+		if err := w.finishData(); err != nil {
+			return err
+		}
+
+		return w.writeHole(ctx, n)
+	}
+
+	for n > 0 {
+		chunk := min(n, int64(len(zeroBlock)))
+
+		if _, err := w.dataWriter.Write(zeroBlock[0:chunk]); err != nil {
+			return errors.Wrap(err, "unable to write data")
+		}
+
+		w.u.totalWrittenBytes.Add(chunk)
+		w.u.Progress.HashedBytes(chunk)
+
+		n -= chunk
+	}
+
+	return nil
+}
+
+func (w *sparseFileWriter) finishData() error {
+	if w.dataWriter == nil {
+		return nil
+	}
+
+	defer w.dataWriter.Close() //nolint:errcheck
+
+	oid, err := w.dataWriter.Result()
+	if err != nil {
+		return errors.Wrap(err, "unable to get result")
+	}
+
+	w.dataWriter = nil
+	w.objectIDs = append(w.objectIDs, oid)
+
+	return nil
+}
+
+// writeHole references zero objects totaling n bytes, the zero objects have power-of-two sizes so that
+// only a small number of them needs to be created regardless of hole sizes.
+func (w *sparseFileWriter) writeHole(ctx context.Context, n int64) error {
+	w.holeBytes += n
+	w.u.Progress.HashedBytes(n)
+
+	for n > 0 {
+		size := int64(1) << (bits.Len64(uint64(n)) - 1)
+
+		oid, err := w.zeroObject(ctx, size)
+		if err != nil {
+			return err
+		}
+
+		w.objectIDs = append(w.objectIDs, oid)
+		n -= size
+	}
+
+	return nil
+}
+
+// zeroObject returns object consisting of the provided number of zero bytes. Large zero objects are
+// concatenations of two halves, so holes are represented by small indexes that are cheap to concatenate.
+func (w *sparseFileWriter) zeroObject(ctx context.Context, size int64) (object.ID, error) {
+	if oid, ok := w.zeroObjects[size]; ok {
+		return oid, nil
+	}
+
+	if size > sparseMaxZeroObjectSize {
+		half, err := w.zeroObject(ctx, size/2) //nolint:mnd
+		if err != nil {
+			return object.EmptyID, err
+		}
+
+		oid, err := w.u.repo.ConcatenateObjects(ctx, []object.ID{half, half}, repo.ConcatenateOptions{Compressor: w.opts.MetadataCompressor})
+		if err != nil {
+			return object.EmptyID, errors.Wrap(err, "unable to concatenate zero object")
+		}
+
+		w.zeroObjects[size] = oid
+
+		return oid, nil
+	}
+
+	zw := w.u.repo.NewObjectWriter(ctx, w.opts)
+	defer zw.Close() //nolint:errcheck
+
+	for remaining := size; remaining > 0; {
+		chunk := min(remaining, int64(len(zeroBlock)))
+
+		if _, err := zw.Write(zeroBlock[0:chunk]); err != nil {
+			return object.EmptyID, errors.Wrap(err, "unable to write zero object")
+		}
+
+		remaining -= chunk
+	}
+
+	oid, err := zw.Result()
+	if err != nil {
+		return object.EmptyID, errors.Wrap(err, "unable to get zero object result")
+	}
+
+	w.zeroObjects[size] = oid
+
+	return oid, nil
+}
+
+// uploadSparseFile uploads a file that reports its data regions, reading only the data regions and
+// storing holes and zero blocks as references to shared zero objects.
+func (u *Uploader) uploadSparseFile(ctx context.Context, f fs.SparseFile, comp, metadataComp compression.Name, splitterName string) (*snapshot.DirEntry, error) {
+	regions, err := f.DataRegions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to determine data regions")
+	}
+
+	rd, err := f.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open file")
+	}
+
+	defer rd.Close() //nolint:errcheck
+
+	w := &sparseFileWriter{
+		u: u,
+		opts: object.WriterOptions{
+			Description:        "FILE:" + f.Name(),
+			Compressor:         comp,
+			MetadataCompressor: metadataComp,
+			Splitter:           splitterName,
+			AsyncWrites:        1,
+		},
+		zeroObjects: map[int64]object.ID{},
+	}
+
+	defer func() {
+		if w.dataWriter != nil {
+			w.dataWriter.Close() //nolint:errcheck
+		}
+	}()
+
+	buf := make([]byte, sparseBlockSize)
+
+	var offset int64
+
+	for _, r := range regions {
+		if r.Offset < offset {
+			return nil, errors.Errorf("invalid data region %v", r)
+		}
+
+		w.pendingZeros += r.Offset - offset
+		offset = r.Offset
+
+		if _, err := rd.Seek(offset, io.SeekStart); err != nil {
+			return nil, errors.Wrap(err, "seek error")
+		}
+
+		for end := r.Offset + r.Length; offset < end; {
+			if u.IsCanceled() {
+				return nil, errors.Wrap(errCanceled, "canceled when copying data")
+			}
+
+			n, err := io.ReadFull(rd, buf[0:min(int64(len(buf)), end-offset)])
+			if n > 0 {
+				if isZero(buf[0:n]) {
+					w.pendingZeros += int64(n)
+				} else if werr := w.writeData(ctx, buf[0:n]); werr != nil {
+					return nil, werr
+				}
+
+				offset += int64(n)
+			}
+
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// file got shorter since regions were determined, the rest is treated as a hole.
+				break
+			}
+
+			if err != nil {
+				return nil, errors.Wrap(err, "read error")
+			}
+		}
+	}
+
+	// trailing hole.
+	w.pendingZeros += max(f.Size()-offset, 0)
+
+	if err := w.flushZeros(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := w.finishData(); err != nil {
+		return nil, err
+	}
+
+	if len(w.objectIDs) == 0 {
+		// empty file
+		emptyID, err := w.zeroObject(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		w.objectIDs = append(w.objectIDs, emptyID)
+	}
+
+	oid, err := u.repo.ConcatenateObjects(ctx, w.objectIDs, repo.ConcatenateOptions{Compressor: metadataComp})
+	if err != nil {
+		return nil, errors.Wrap(err, "concatenate")
+	}
+
+	de, err := newDirEntry(f, f.Name(), oid)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create dir entry")
+	}
+
+	de.FileSize = max(offset, f.Size())
+
+	uploadLog(ctx).Debugw("uploaded sparse file", "file", f.Name(), "size", de.FileSize, "holeBytes", w.holeBytes)
+
+	return de, nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+
+	return true
+}
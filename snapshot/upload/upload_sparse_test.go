@@ -0,0 +1,133 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+// sparseTestFile is a file reporting data regions, which counts bytes read from it.
+type sparseTestFile struct {
+	*mockfs.File
+
+	regions   []fs.Region
+	bytesRead *atomic.Int64
+}
+
+func (f *sparseTestFile) DataRegions(_ context.Context) ([]fs.Region, error) {
+	return f.regions, nil
+}
+
+func (f *sparseTestFile) Open(ctx context.Context) (fs.Reader, error) {
+	r, err := f.File.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingReader{r, f.bytesRead}, nil
+}
+
+type countingReader struct {
+	fs.Reader
+
+	bytesRead *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.bytesRead.Add(int64(n))
+
+	return n, err
+}
+
+func TestUpload_SparseFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	const (
+		mib  = 1 << 20
+		size = 20*mib + 123
+	)
+
+	data := make([]byte, size)
+
+	// data at the beginning followed by a hole not reported in data regions.
+	copy(data[0:], randomData(t, 100000))
+
+	// data region containing a run of zeros, which is detected when reading it.
+	copy(data[10*mib:], randomData(t, mib))
+	copy(data[13*mib:], randomData(t, mib))
+
+	// short run of zeros followed by data, stored as data.
+	copy(data[14*mib+100000:], randomData(t, 1000))
+
+	regions := []fs.Region{
+		{Offset: 0, Length: 100000},
+		{Offset: 10 * mib, Length: 4*mib + 101000},
+	}
+
+	var bytesRead atomic.Int64
+
+	f := &sparseTestFile{
+		File:      mockfs.NewFile("disk.img", data, defaultPermissions),
+		regions:   regions,
+		bytesRead: &bytesRead,
+	}
+
+	man, err := NewUploader(th.repo).Upload(ctx, f, policy.BuildTree(nil, policy.DefaultPolicy), snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(size), man.RootEntry.FileSize)
+
+	// only data regions are read.
+	require.Equal(t, int64(100000+4*mib+101000), bytesRead.Load())
+
+	e := testutil.EnsureType[fs.File](t, snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry))
+
+	r, err := e.Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	// empty and entirely sparse files.
+	for _, sz := range []int{0, 3*mib + 5} {
+		f := &sparseTestFile{
+			File:      mockfs.NewFile("empty.img", make([]byte, sz), defaultPermissions),
+			bytesRead: &bytesRead,
+		}
+
+		man, err := NewUploader(th.repo).Upload(ctx, f, policy.BuildTree(nil, policy.DefaultPolicy), snapshot.SourceInfo{})
+		require.NoError(t, err)
+		require.Equal(t, int64(sz), man.RootEntry.FileSize)
+
+		e := testutil.EnsureType[fs.File](t, snapshotfs.EntryFromDirEntry(th.repo, man.RootEntry))
+
+		r, err := e.Open(ctx)
+		require.NoError(t, err)
+
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, make([]byte, sz), got)
+
+		r.Close()
+	}
+}
@@ -1819,3 +1819,163 @@ func verifyLogDetails(t *testing.T, desc string, wantDetailKeys []string, keysAn
 	sort.Strings(wantDetailKeys)
 	require.Equal(t, wantDetailKeys, gotDetailKeys, "invalid details for "+desc)
 }
+
+type unchangedDirsChangeSource map[string]bool
+
+func (s unchangedDirsChangeSource) SubtreeUnchangedSince(localDir string, _ time.Time) bool {
+	return s[localDir]
+}
+
+func TestUpload_ChangeSourceReusesUnchangedSubtrees(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	td := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "d1", "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "d2"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d1", "f1"), []byte{1, 2, 3}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d1", "sub", "f2"), []byte{1, 2, 3, 4}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d2", "f3"), []byte{1, 2, 3, 4, 5}, 0o600))
+
+	srcdir, err := localfs.Directory(td)
+	require.NoError(t, err)
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	u := NewUploader(th.repo)
+	u.ChangeSource = unchangedDirsChangeSource{
+		filepath.Join(td, "d1"): true,
+		filepath.Join(td, "d2"): true,
+	}
+
+	// without previous snapshot nothing can be reused.
+	man1, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man1.Stats.ReusedDirCount)
+
+	// change contents of d1 without changing its metadata and mark d2 as changed,
+	// the reused d1 will not reflect the change.
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d1", "sub", "f2"), []byte{5, 6, 7, 8}, 0o600))
+
+	u = NewUploader(th.repo)
+	u.ChangeSource = unchangedDirsChangeSource{
+		filepath.Join(td, "d1"): true,
+	}
+
+	man2, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man1)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), man2.Stats.ReusedDirCount)
+	require.Equal(t, man1.Stats.TotalDirectoryCount, man2.Stats.TotalDirectoryCount)
+	require.Equal(t, man1.RootEntry.DirSummary.TotalFileSize, man2.RootEntry.DirSummary.TotalFileSize)
+
+	d1v1 := mustGetChildDirEntry(ctx, t, th.repo, man1, "d1")
+	d1v2 := mustGetChildDirEntry(ctx, t, th.repo, man2, "d1")
+	require.Equal(t, d1v1.ObjectID, d1v2.ObjectID)
+
+	// without change source, the change is picked up.
+	u = NewUploader(th.repo)
+
+	man3, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man2)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man3.Stats.ReusedDirCount)
+
+	d1v3 := mustGetChildDirEntry(ctx, t, th.repo, man3, "d1")
+	require.NotEqual(t, d1v1.ObjectID, d1v3.ObjectID)
+
+	u = NewUploader(th.repo)
+	u.ChangeSource = unchangedDirsChangeSource{
+		filepath.Join(td, "d1"): true,
+	}
+
+	man4, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man2)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), man4.Stats.ReusedDirCount)
+
+	// changing the policy of another directory disables reuse, since the policy may have been inherited.
+	changedPolicyTree := policy.BuildTree(map[string]*policy.Policy{
+		"./d2": {FilesPolicy: policy.FilesPolicy{IgnoreRules: []string{"f3"}}},
+	}, policy.DefaultPolicy)
+
+	man5, err := u.Upload(ctx, srcdir, changedPolicyTree, snapshot.SourceInfo{}, man4)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man5.Stats.ReusedDirCount)
+}
+
+func TestUpload_ChangeSourceDetectsAncestorIgnoreFileChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	t.Cleanup(th.cleanup)
+
+	td := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(td, "d1", "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(td, "d1", "sub", "f1"), []byte{1, 2, 3}, 0o600))
+
+	srcdir, err := localfs.Directory(td)
+	require.NoError(t, err)
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	// the change source only reports changes below d1, dot-ignore files of the root are not covered.
+	u := NewUploader(th.repo)
+	u.ChangeSource = unchangedDirsChangeSource{
+		filepath.Join(td, "d1"):        true,
+		filepath.Join(td, "d1", "sub"): true,
+	}
+
+	man1, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	man2, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man1)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), man2.Stats.ReusedDirCount)
+
+	ignoreFile := filepath.Join(td, ".kopiaignore")
+
+	// adding, modifying and removing an ignore file of an ancestor disables reuse once.
+	require.NoError(t, os.WriteFile(ignoreFile, []byte("f1\n"), 0o600))
+
+	man3, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man2)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man3.Stats.ReusedDirCount)
+
+	man4, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man3)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), man4.Stats.ReusedDirCount)
+
+	require.NoError(t, os.WriteFile(ignoreFile, []byte("f2\n"), 0o600))
+	require.NoError(t, os.Chtimes(ignoreFile, time.Time{}, time.Now().Add(time.Hour)))
+
+	man5, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man4)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man5.Stats.ReusedDirCount)
+
+	require.NoError(t, os.Remove(ignoreFile))
+
+	man6, err := u.Upload(ctx, srcdir, policyTree, snapshot.SourceInfo{}, man5)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), man6.Stats.ReusedDirCount)
+}
+
+func mustGetChildDirEntry(ctx context.Context, t *testing.T, rep repo.Repository, man *snapshot.Manifest, name string) *snapshot.DirEntry {
+	t.Helper()
+
+	root, ok := snapshotfs.EntryFromDirEntry(rep, man.RootEntry).(fs.Directory)
+	require.True(t, ok)
+
+	child, err := root.Child(ctx, name)
+	require.NoError(t, err)
+
+	hde, ok := child.(snapshot.HasDirEntry)
+	require.True(t, ok)
+
+	return hde.DirEntry()
+}
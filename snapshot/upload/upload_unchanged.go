@@ -0,0 +1,143 @@
+package upload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// ChangeSource reports local directories known to be unchanged, which allows the uploader to reuse
+// entire subtrees of the previous snapshot without reading them.
+type ChangeSource interface {
+	// SubtreeUnchangedSince returns true if no entry in the provided local directory or any of its
+	// subdirectories has changed since the given time.
+	SubtreeUnchangedSince(localDir string, t time.Time) bool
+}
+
+// unchangedSinceTime returns the time since which directories must be unchanged for their previous
+// snapshot entries to be reused, which is the start time of the oldest previous snapshot.
+// Returns zero time, which disables reuse, if any previous snapshot was taken with different policies,
+// since defining, changing or removing any applicable policy may change the contents of any subtree.
+func unchangedSinceTime(previousManifests []*snapshot.Manifest, policyFingerprint string) time.Time {
+	var result time.Time
+
+	for _, m := range previousManifests {
+		if m == nil {
+			continue
+		}
+
+		if policyFingerprint == "" || m.PolicyFingerprint != policyFingerprint {
+			return time.Time{}
+		}
+
+		if st := m.StartTime.ToTime(); result.IsZero() || st.Before(result) {
+			result = st
+		}
+	}
+
+	return result
+}
+
+// findUnchangedSubtree returns the entry of a directory from the previous snapshot when the change source
+// reports that neither it nor any of its subdirectories have changed since then and dot-ignore files
+// of its parent directories, which apply to the entire subtree, are unchanged.
+func (u *Uploader) findUnchangedSubtree(ctx context.Context, dir fs.Directory, localDirPathOrEmpty string, policyTree *policy.Tree, prevDirs []fs.Directory, parentPolicyTree *policy.Tree, parentPrevDirs []fs.Directory) *snapshot.DirEntry {
+	if u.ChangeSource == nil || u.unchangedSince.IsZero() || localDirPathOrEmpty == "" || u.ForceHashPercentage > 0 {
+		return nil
+	}
+
+	// must be determined for every directory, since the outcome for its subdirectories depends on it.
+	if u.ignoreFilesChanged(ctx, filepath.Dir(localDirPathOrEmpty), parentPolicyTree, parentPrevDirs) {
+		return nil
+	}
+
+	// subtrees with their own policies, which may define folder actions, must be uploaded normally.
+	if policyTree.DefinedPolicy() != nil || policyTree.HasDescendantPolicies() || len(prevDirs) != 1 {
+		return nil
+	}
+
+	hde, ok := prevDirs[0].(snapshot.HasDirEntry)
+	if !ok {
+		return nil
+	}
+
+	prev := hde.DirEntry()
+
+	// retry directories that were not snapshotted completely.
+	if ds := prev.DirSummary; ds == nil || ds.IncompleteReason != "" || ds.FatalErrorCount > 0 || ds.IgnoredErrorCount > 0 {
+		return nil
+	}
+
+	if !commonMetadataEquals(dir, prevDirs[0]) {
+		return nil
+	}
+
+	if !u.ChangeSource.SubtreeUnchangedSince(localDirPathOrEmpty, u.unchangedSince) {
+		return nil
+	}
+
+	de := *prev
+	de.Name = dir.Name()
+
+	return &de
+}
+
+// ignoreFilesChanged returns true if dot-ignore files in the provided local directory or any of its
+// ancestors included in the snapshot have changed since the previous snapshot. The outcome is remembered
+// for each directory, which relies on directories being processed before their subdirectories.
+func (u *Uploader) ignoreFilesChanged(ctx context.Context, localDir string, policyTree *policy.Tree, prevDirs []fs.Directory) bool {
+	if v, ok := u.ignoreFilesChangedByDir.Load(localDir); ok {
+		return v.(bool) //nolint:forcetypeassert
+	}
+
+	changed := dirIgnoreFilesChanged(ctx, localDir, policyTree, prevDirs)
+
+	if v, ok := u.ignoreFilesChangedByDir.Load(filepath.Dir(localDir)); ok && v.(bool) { //nolint:forcetypeassert
+		changed = true
+	}
+
+	u.ignoreFilesChangedByDir.Store(localDir, changed)
+
+	return changed
+}
+
+// dirIgnoreFilesChanged compares dot-ignore files in the provided local directory with their entries
+// in the previous snapshot of the directory.
+func dirIgnoreFilesChanged(ctx context.Context, localDir string, policyTree *policy.Tree, prevDirs []fs.Directory) bool {
+	names := policyTree.EffectivePolicy().FilesPolicy.DotIgnoreFiles
+	if len(names) == 0 {
+		return false
+	}
+
+	if len(prevDirs) != 1 {
+		return true
+	}
+
+	for _, name := range names {
+		prev, err := prevDirs[0].Child(ctx, name)
+		if err != nil && !errors.Is(err, fs.ErrEntryNotFound) {
+			return true
+		}
+
+		fi, lerr := os.Lstat(filepath.Join(localDir, name))
+		if lerr != nil && !os.IsNotExist(lerr) {
+			return true
+		}
+
+		switch {
+		case (err == nil) != (lerr == nil):
+			return true
+		case lerr == nil && (prev.Size() != fi.Size() || !prev.ModTime().Equal(fi.ModTime())):
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package endtoend_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/clitestutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestOTLPExport(t *testing.T) {
+	t.Parallel()
+
+	collector := testenv.NewOTLPCollector(t)
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewExeRunner(t))
+
+	e.Environment["OTEL_EXPORTER_OTLP_ENDPOINT"] = collector.Endpoint
+	e.Environment["OTEL_EXPORTER_OTLP_INSECURE"] = "true"
+	e.Environment["KOPIA_ENABLE_OTLP_TRACE"] = "true"
+	e.Environment["KOPIA_ENABLE_OTLP_METRICS"] = "true"
+
+	// without index epochs, maintenance compacts indexes using CompactIndexes().
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--format-version=1")
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	si := clitestutil.ListSnapshotsAndExpectSuccess(t, e, sharedTestDataDir1)
+	require.Len(t, si, 1)
+	require.Len(t, si[0].Snapshots, 1)
+
+	e.RunAndExpectSuccess(t, "restore", si[0].Snapshots[0].SnapshotID, testutil.TempDirectory(t))
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	// all telemetry is flushed before each command exits.
+	spans := collector.SpanNames()
+
+	for _, name := range []string{"Upload", "UploadDir", "Restore", "RestoreFile", "CompactIndexes", "Maintenance", "snapshot-gc", "cleanup-logs"} {
+		require.Positive(t, spans[name], "missing span %v", name)
+	}
+
+	metrics := collector.MetricNames()
+
+	for _, name := range []string{"kopia_content_write_bytes_total", "kopia_blob_upload_bytes_total"} {
+		require.Positive(t, metrics[name], "missing metric %v", name)
+	}
+}
@@ -645,6 +645,98 @@ func TestSnapshotCreateWithStdinStream(t *testing.T) {
 	}
 }
 
+func TestSnapshotCreateBlockDeviceImage(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	// sparse disk image with some data in the middle.
+	img := filepath.Join(testutil.TempDirectory(t), "disk.img")
+
+	f, err := os.Create(img)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(50<<20))
+
+	_, err = f.WriteAt([]byte("some data"), 20<<20)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", img, "--block-device")
+
+	si := clitestutil.ListSnapshotsAndExpectSuccess(t, e, img)
+	require.Len(t, si, 1)
+	require.Len(t, si[0].Snapshots, 1)
+
+	restored := filepath.Join(testutil.TempDirectory(t), "restored.img")
+	e.RunAndExpectSuccess(t, "snapshot", "restore", si[0].Snapshots[0].SnapshotID, "--write-sparse-files", restored)
+
+	want, err := os.ReadFile(img)
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(restored)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	e.RunAndExpectFailure(t, "snapshot", "create", testutil.TempDirectory(t), "--block-device")
+}
+
+func TestSnapshotCreateCommandSource(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	// command sources don't need the source directory to exist.
+	src := filepath.Join(testutil.TempDirectory(t), "postgres")
+
+	e.RunAndExpectSuccess(t, "policy", "set", src,
+		"--add-command-stream", `dump.sql=sh -c "echo CREATE TABLE t"`,
+		"--add-command-stream", `globals.sql=sh -c "echo connection refused >&2; exit 2"`,
+		"--command-stream-timeout=1m")
+
+	out := strings.Join(e.RunAndExpectSuccess(t, "policy", "show", src), "\n")
+	require.Contains(t, out, "dump.sql:")
+	require.Contains(t, out, "sh -c echo connection refused >&2; exit 2")
+
+	// commands are not executed unless actions are enabled for the client.
+	_, stderr := e.RunAndExpectFailure(t, "snapshot", "create", src)
+	require.Contains(t, strings.Join(stderr, "\n"), "disabled for this client")
+	e.RunAndExpectFailure(t, "snapshot", "create", src, "--force-disable-actions")
+
+	e.RunAndExpectSuccess(t, "repo", "disconnect")
+	e.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", e.RepoDir, "--enable-actions")
+
+	e.RunAndExpectFailure(t, "snapshot", "create", src)
+
+	si := clitestutil.ListSnapshotsAndExpectSuccess(t, e, src)
+	require.Len(t, si, 1)
+	require.Len(t, si[0].Snapshots, 1)
+
+	require.Contains(t, strings.Join(e.RunAndExpectSuccess(t, "snapshot", "list", src), "\n"), "command-failed:globals.sql(exit 2)")
+	require.Equal(t, []string{"CREATE TABLE t"}, e.RunAndExpectSuccess(t, "show", si[0].Snapshots[0].ObjectID+"/dump.sql"))
+
+	e.RunAndExpectSuccess(t, "policy", "set", src, "--remove-command-stream", "globals.sql")
+	e.RunAndExpectSuccess(t, "snapshot", "create", src)
+
+	e.RunAndExpectSuccess(t, "policy", "set", src, "--clear-command-streams")
+	e.RunAndExpectFailure(t, "snapshot", "create", src)
+	e.RunAndExpectFailure(t, "policy", "set", src, "--remove-command-stream", "dump.sql")
+	e.RunAndExpectFailure(t, "policy", "set", src, "--add-command-stream", "no-command")
+}
+
 func appendIfMissing(slice []string, i string) []string {
 	if slices.Contains(slice, i) {
 		return slice
@@ -0,0 +1,108 @@
+package testenv
+
+import (
+	"context"
+	"maps"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// OTLPCollector is an in-process OTLP collector which receives traces and metrics over gRPC.
+type OTLPCollector struct {
+	// Endpoint is the address of the collector, suitable for OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string
+
+	mu          sync.Mutex
+	spanNames   map[string]int
+	metricNames map[string]int
+}
+
+// SpanNames returns the number of received spans keyed by span name.
+func (c *OTLPCollector) SpanNames() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.spanNames)
+}
+
+// MetricNames returns the number of received exports keyed by metric name.
+func (c *OTLPCollector) MetricNames() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.metricNames)
+}
+
+type otlpTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	c *OTLPCollector
+}
+
+// Export implements coltracepb.TraceServiceServer.
+func (s otlpTraceService) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, sp := range ss.GetSpans() {
+				s.c.spanNames[sp.GetName()]++
+			}
+		}
+	}
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type otlpMetricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	c *OTLPCollector
+}
+
+// Export implements colmetricpb.MetricsServiceServer.
+func (s otlpMetricsService) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				s.c.metricNames[m.GetName()]++
+			}
+		}
+	}
+
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+// NewOTLPCollector starts an in-process OTLP collector which is stopped at the end of the test.
+func NewOTLPCollector(tb testing.TB) *OTLPCollector {
+	tb.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+
+	c := &OTLPCollector{
+		Endpoint:    "http://" + l.Addr().String(),
+		spanNames:   map[string]int{},
+		metricNames: map[string]int{},
+	}
+
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, otlpTraceService{c: c})
+	colmetricpb.RegisterMetricsServiceServer(srv, otlpMetricsService{c: c})
+
+	go srv.Serve(l) //nolint:errcheck
+
+	tb.Cleanup(srv.Stop)
+
+	return c
+}